@@ -0,0 +1,151 @@
+package application
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/bandvov/social-media-go/domain"
+)
+
+const (
+	// DefaultViewDedupWindow is how long repeated impressions of a post by the
+	// same viewer count as a single view.
+	DefaultViewDedupWindow = 30 * time.Minute
+
+	// DefaultAnalyticsRollupInterval is how often views, reactions and
+	// comments are rolled up into the stats tables.
+	DefaultAnalyticsRollupInterval = 10 * time.Minute
+
+	// Buckets this far back are recomputed on every rollup, so late writes
+	// and removed reactions are picked up.
+	hourlyRollupLookback = 2 * time.Hour
+	dailyRollupLookback  = 48 * time.Hour
+
+	// Raw views are only needed until the daily rollup is final.
+	viewRetention = 7 * 24 * time.Hour
+)
+
+// AnalyticsServiceInterface defines methods for post impressions and
+// engagement stats.
+type AnalyticsServiceInterface interface {
+	RecordViews(viewerID int, posts []domain.Post, source domain.ViewSource)
+	GetPostAnalytics(viewer Viewer, postID int, query domain.AnalyticsQuery) (*domain.Analytics, error)
+	GetAuthorAnalytics(authorID int, query domain.AnalyticsQuery) (*domain.Analytics, error)
+}
+
+type AnalyticsService struct {
+	repo        domain.AnalyticsRepository
+	dedup       domain.ViewDeduplicator
+	postRepo    domain.PostRepository
+	dedupWindow time.Duration
+}
+
+func NewAnalyticsService(repo domain.AnalyticsRepository, dedup domain.ViewDeduplicator, postRepo domain.PostRepository, dedupWindow time.Duration) *AnalyticsService {
+	return &AnalyticsService{repo: repo, dedup: dedup, postRepo: postRepo, dedupWindow: dedupWindow}
+}
+
+// RecordViews counts an impression of every post the viewer did not write
+// and has not seen within the dedup window. Analytics must never fail a read,
+// so errors are only logged.
+func (s *AnalyticsService) RecordViews(viewerID int, posts []domain.Post, source domain.ViewSource) {
+	postIDs := make([]int, 0, len(posts))
+	for _, post := range posts {
+		if post.AuthorID != viewerID {
+			postIDs = append(postIDs, post.ID)
+		}
+	}
+	if len(postIDs) == 0 {
+		return
+	}
+
+	fresh, err := s.dedup.MarkViewed(viewerID, postIDs, s.dedupWindow)
+	if err != nil {
+		log.Printf("failed to deduplicate views of user %d: %v", viewerID, err)
+		return
+	}
+	if err := s.repo.RecordViews(viewerID, fresh, source); err != nil {
+		log.Printf("failed to record views of user %d: %v", viewerID, err)
+	}
+}
+
+// GetPostAnalytics returns the stats of a post to its author or an admin.
+func (s *AnalyticsService) GetPostAnalytics(viewer Viewer, postID int, query domain.AnalyticsQuery) (*domain.Analytics, error) {
+	if err := query.Validate(); err != nil {
+		return nil, err
+	}
+
+	post, err := s.postRepo.GetByID(postID)
+	if err != nil {
+		return nil, err
+	}
+	if post.AuthorID != viewer.ID && !viewer.IsAdmin {
+		return nil, ErrForbidden
+	}
+
+	series, err := s.repo.GetPostSeries(postID, query)
+	if err != nil {
+		return nil, err
+	}
+	return newAnalytics(query, series), nil
+}
+
+// GetAuthorAnalytics returns the combined stats of all posts of the author.
+func (s *AnalyticsService) GetAuthorAnalytics(authorID int, query domain.AnalyticsQuery) (*domain.Analytics, error) {
+	if err := query.Validate(); err != nil {
+		return nil, err
+	}
+
+	series, err := s.repo.GetAuthorSeries(authorID, query)
+	if err != nil {
+		return nil, err
+	}
+	return newAnalytics(query, series), nil
+}
+
+func newAnalytics(query domain.AnalyticsQuery, series []domain.AnalyticsPoint) *domain.Analytics {
+	analytics := &domain.Analytics{
+		Granularity: query.Granularity,
+		From:        query.From,
+		To:          query.To,
+		Series:      series,
+	}
+	for _, point := range series {
+		analytics.Totals.Add(point)
+	}
+	// Unique viewers of different buckets overlap, their sum is meaningless.
+	analytics.Totals.UniqueViewers = 0
+	if analytics.Series == nil {
+		analytics.Series = []domain.AnalyticsPoint{}
+	}
+	return analytics
+}
+
+// Rollup refreshes the recent hourly and daily buckets and drops raw views
+// that are no longer needed.
+func (s *AnalyticsService) Rollup(now time.Time) error {
+	if err := s.repo.Rollup(domain.GranularityHour, now.Add(-hourlyRollupLookback)); err != nil {
+		return err
+	}
+	if err := s.repo.Rollup(domain.GranularityDay, now.Add(-dailyRollupLookback)); err != nil {
+		return err
+	}
+	return s.repo.DeleteViewsBefore(now.Add(-viewRetention))
+}
+
+// RunRollups rolls up stats every interval until the context is cancelled.
+func (s *AnalyticsService) RunRollups(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if err := s.Rollup(now); err != nil {
+				log.Printf("failed to roll up analytics: %v", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package application
+
+import (
+	"database/sql"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/bandvov/social-media-go/domain"
+	"github.com/bandvov/social-media-go/infrastructure"
+)
+
+type stubAnalyticsRepository struct {
+	domain.AnalyticsRepository
+	recorded []int
+	series   []domain.AnalyticsPoint
+}
+
+func (r *stubAnalyticsRepository) RecordViews(viewerID int, postIDs []int, source domain.ViewSource) error {
+	r.recorded = append(r.recorded, postIDs...)
+	return nil
+}
+
+func (r *stubAnalyticsRepository) GetPostSeries(postID int, query domain.AnalyticsQuery) ([]domain.AnalyticsPoint, error) {
+	return r.series, nil
+}
+
+// stubViewDeduplicator remembers seen posts forever.
+type stubViewDeduplicator map[[2]int]bool
+
+func (d stubViewDeduplicator) MarkViewed(viewerID int, postIDs []int, window time.Duration) ([]int, error) {
+	var fresh []int
+	for _, postID := range postIDs {
+		if !d[[2]int{viewerID, postID}] {
+			d[[2]int{viewerID, postID}] = true
+			fresh = append(fresh, postID)
+		}
+	}
+	return fresh, nil
+}
+
+func TestAnalyticsServiceGetPostAnalytics(t *testing.T) {
+	from := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	query := domain.AnalyticsQuery{Granularity: domain.GranularityHour, From: from, To: from.Add(2 * time.Hour)}
+	repo := &stubAnalyticsRepository{series: []domain.AnalyticsPoint{
+		{Bucket: from, Views: 3, UniqueViewers: 2, Reactions: 1},
+		{Bucket: from.Add(time.Hour), Views: 4, UniqueViewers: 3, Comments: 2},
+	}}
+	postRepo := &infrastructure.MockPostRepository{
+		GetByIDFunc: func(id int) (*domain.Post, error) {
+			if id == 1 {
+				return postWithVisibility(1, domain.Public), nil
+			}
+			return nil, sql.ErrNoRows
+		},
+	}
+	service := NewAnalyticsService(repo, stubViewDeduplicator{}, postRepo, DefaultViewDedupWindow)
+
+	tests := []struct {
+		name     string
+		viewer   Viewer
+		postID   int
+		expected error
+	}{
+		{name: "author", viewer: Viewer{ID: testAuthorID}, postID: 1},
+		{name: "admin", viewer: Viewer{ID: testAdminID, IsAdmin: true, IsModerator: true}, postID: 1},
+		{name: "moderator", viewer: Viewer{ID: testAdminID, IsModerator: true}, postID: 1, expected: ErrForbidden},
+		{name: "follower", viewer: Viewer{ID: testFollowerID}, postID: 1, expected: ErrForbidden},
+		{name: "missing post", viewer: Viewer{ID: testAuthorID}, postID: 2, expected: sql.ErrNoRows},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			analytics, err := service.GetPostAnalytics(tt.viewer, tt.postID, query)
+			if !errors.Is(err, tt.expected) {
+				t.Fatalf("expected error %v, got %v", tt.expected, err)
+			}
+			if tt.expected != nil {
+				return
+			}
+			expected := domain.AnalyticsPoint{Views: 7, Reactions: 1, Comments: 2}
+			if analytics.Totals != expected {
+				t.Errorf("expected totals %+v without unique viewers, got %+v", expected, analytics.Totals)
+			}
+		})
+	}
+
+	if _, err := service.GetPostAnalytics(Viewer{ID: testAuthorID}, 1, domain.AnalyticsQuery{Granularity: "week", From: from, To: from.Add(time.Hour)}); !errors.Is(err, domain.ErrInvalidGranularity) {
+		t.Errorf("expected an unknown granularity to be refused, got %v", err)
+	}
+}
+
+func TestAnalyticsServiceRecordViews(t *testing.T) {
+	repo := &stubAnalyticsRepository{}
+	service := NewAnalyticsService(repo, stubViewDeduplicator{}, nil, DefaultViewDedupWindow)
+	posts := []domain.Post{{ID: 1, AuthorID: testAuthorID}, {ID: 2, AuthorID: testStrangerID}, {ID: 3, AuthorID: testAuthorID}}
+
+	service.RecordViews(testStrangerID, posts, domain.ViewSourceFeed)
+	service.RecordViews(testStrangerID, posts[:1], domain.ViewSourcePost)
+
+	// Authors do not count as viewers of their own posts, and seen posts
+	// are not counted again.
+	if !reflect.DeepEqual(repo.recorded, []int{1, 3}) {
+		t.Errorf("expected views of posts [1 3], got %v", repo.recorded)
+	}
+}
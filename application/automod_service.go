@@ -0,0 +1,278 @@
+package application
+
+import (
+	"fmt"
+	"log"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bandvov/social-media-go/domain"
+	"github.com/bandvov/social-media-go/utils"
+)
+
+const (
+	// DefaultAutomodRefreshInterval is how long rules are cached before
+	// they are reloaded, so changes made on other instances are picked up.
+	DefaultAutomodRefreshInterval = time.Minute
+
+	// maxAutomodLinks bounds the links checked by link_domain rules.
+	maxAutomodLinks = 50
+)
+
+// AutomodServiceInterface defines methods for auto-moderation rules.
+type AutomodServiceInterface interface {
+	ListRules() ([]domain.AutomodRule, error)
+	GetRule(id int) (*domain.AutomodRule, error)
+	CreateRule(createdBy int, req *domain.AutomodRuleRequest) (*domain.AutomodRule, error)
+	UpdateRule(id int, req *domain.AutomodRuleRequest) (*domain.AutomodRule, error)
+	DeleteRule(id int) error
+	// TestContent evaluates content without counting hits, for trying out
+	// rules.
+	TestContent(scope domain.AutomodScope, content string) (*domain.AutomodVerdict, error)
+	// Evaluate evaluates new or edited content and counts the hits.
+	Evaluate(scope domain.AutomodScope, content string) (*domain.AutomodVerdict, error)
+	// HoldForReview puts held content in the moderation queue.
+	HoldForReview(targetType domain.ReportTargetType, targetID, ownerID int, verdict *domain.AutomodVerdict) error
+}
+
+type compiledAutomodRule struct {
+	rule    domain.AutomodRule
+	pattern *regexp.Regexp // Unset for link_domain rules
+}
+
+// matches reports whether the rule matches the content or one of the hosts
+// it links to.
+func (c *compiledAutomodRule) matches(content string, hosts []string) bool {
+	if c.rule.Type != domain.AutomodLinkDomain {
+		return c.pattern.MatchString(content)
+	}
+	for _, host := range hosts {
+		if host == c.rule.Pattern || strings.HasSuffix(host, "."+c.rule.Pattern) {
+			return true
+		}
+	}
+	return false
+}
+
+// AutomodService evaluates posts and comments against admin-managed rules.
+// Enabled rules are compiled once and cached.
+type AutomodService struct {
+	repo       domain.AutomodRepository
+	reportRepo domain.ReportRepository
+	refresh    time.Duration
+
+	mu       sync.RWMutex
+	rules    []compiledAutomodRule
+	loadedAt time.Time
+	now      func() time.Time
+}
+
+func NewAutomodService(repo domain.AutomodRepository, reportRepo domain.ReportRepository, refresh time.Duration) *AutomodService {
+	return &AutomodService{repo: repo, reportRepo: reportRepo, refresh: refresh, now: time.Now}
+}
+
+func (s *AutomodService) ListRules() ([]domain.AutomodRule, error) {
+	return s.repo.ListRules()
+}
+
+func (s *AutomodService) GetRule(id int) (*domain.AutomodRule, error) {
+	return s.repo.GetRule(id)
+}
+
+func (s *AutomodService) CreateRule(createdBy int, req *domain.AutomodRuleRequest) (*domain.AutomodRule, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+
+	rule := ruleFromRequest(req)
+	rule.CreatedBy = createdBy
+	if err := s.repo.CreateRule(rule); err != nil {
+		return nil, err
+	}
+	s.invalidate()
+	return rule, nil
+}
+
+func (s *AutomodService) UpdateRule(id int, req *domain.AutomodRuleRequest) (*domain.AutomodRule, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+
+	rule := ruleFromRequest(req)
+	rule.ID = id
+	if err := s.repo.UpdateRule(rule); err != nil {
+		return nil, err
+	}
+	s.invalidate()
+	return rule, nil
+}
+
+func (s *AutomodService) DeleteRule(id int) error {
+	if err := s.repo.DeleteRule(id); err != nil {
+		return err
+	}
+	s.invalidate()
+	return nil
+}
+
+func ruleFromRequest(req *domain.AutomodRuleRequest) *domain.AutomodRule {
+	enabled := true
+	if req.Enabled != nil {
+		enabled = *req.Enabled
+	}
+	return &domain.AutomodRule{
+		Type:           req.Type,
+		Pattern:        req.Pattern,
+		Action:         req.Action,
+		Scope:          req.Scope,
+		ContentWarning: req.ContentWarning,
+		DryRun:         req.DryRun,
+		Enabled:        enabled,
+	}
+}
+
+func (s *AutomodService) TestContent(scope domain.AutomodScope, content string) (*domain.AutomodVerdict, error) {
+	return s.evaluate(scope, content)
+}
+
+func (s *AutomodService) Evaluate(scope domain.AutomodScope, content string) (*domain.AutomodVerdict, error) {
+	verdict, err := s.evaluate(scope, content)
+	if err != nil {
+		return nil, err
+	}
+
+	// Statistics must not get in the way of posting.
+	if len(verdict.Matches) > 0 {
+		if err := s.repo.RecordHits(verdict.Matches); err != nil {
+			log.Printf("failed to record automod hits: %v", err)
+		}
+	}
+	return verdict, nil
+}
+
+// evaluate matches the content against every enabled rule of the scope.
+// Rules in dry-run mode are reported as matches but don't change the
+// verdict.
+func (s *AutomodService) evaluate(scope domain.AutomodScope, content string) (*domain.AutomodVerdict, error) {
+	rules, err := s.loadRules()
+	if err != nil {
+		return nil, err
+	}
+
+	hosts := linkHosts(content)
+	verdict := &domain.AutomodVerdict{}
+	for i := range rules {
+		rule := &rules[i]
+		if !rule.rule.AppliesTo(scope) || !rule.matches(content, hosts) {
+			continue
+		}
+
+		verdict.Matches = append(verdict.Matches, domain.AutomodMatch{RuleID: rule.rule.ID, Action: rule.rule.Action, DryRun: rule.rule.DryRun})
+		if rule.rule.DryRun {
+			continue
+		}
+		if rule.rule.Action.Severity() > verdict.Action.Severity() {
+			verdict.Action = rule.rule.Action
+		}
+		if rule.rule.Action == domain.AutomodContentWarning && verdict.ContentWarning == "" {
+			verdict.ContentWarning = rule.rule.ContentWarning
+		}
+	}
+	return verdict, nil
+}
+
+func linkHosts(content string) []string {
+	var hosts []string
+	for _, link := range utils.ExtractURLs(content, maxAutomodLinks) {
+		parsed, err := url.Parse(link)
+		if err != nil {
+			continue
+		}
+		hosts = append(hosts, strings.TrimSuffix(strings.ToLower(parsed.Hostname()), "."))
+	}
+	return hosts
+}
+
+func (s *AutomodService) invalidate() {
+	s.mu.Lock()
+	s.loadedAt = time.Time{}
+	s.mu.Unlock()
+}
+
+// loadRules returns the compiled enabled rules, reloading them when the
+// cache is older than the refresh interval.
+func (s *AutomodService) loadRules() ([]compiledAutomodRule, error) {
+	s.mu.RLock()
+	if !s.loadedAt.IsZero() && s.now().Sub(s.loadedAt) < s.refresh {
+		rules := s.rules
+		s.mu.RUnlock()
+		return rules, nil
+	}
+	s.mu.RUnlock()
+
+	stored, err := s.repo.ListRules()
+	if err != nil {
+		return nil, err
+	}
+
+	var rules []compiledAutomodRule
+	for _, rule := range stored {
+		if !rule.Enabled {
+			continue
+		}
+		compiled, err := compileAutomodRule(rule)
+		if err != nil {
+			log.Printf("skipping automod rule %d: %v", rule.ID, err)
+			continue
+		}
+		rules = append(rules, compiled)
+	}
+
+	s.mu.Lock()
+	s.rules = rules
+	s.loadedAt = s.now()
+	s.mu.Unlock()
+	return rules, nil
+}
+
+func compileAutomodRule(rule domain.AutomodRule) (compiledAutomodRule, error) {
+	compiled := compiledAutomodRule{rule: rule}
+
+	var err error
+	switch rule.Type {
+	case domain.AutomodKeyword:
+		// Match the keyword as a whole word or phrase, whatever the case and
+		// spacing.
+		words := strings.Fields(rule.Pattern)
+		for i, word := range words {
+			words[i] = regexp.QuoteMeta(word)
+		}
+		compiled.pattern, err = regexp.Compile(`(?i)(?:^|[^\p{L}\p{N}_])` + strings.Join(words, `\s+`) + `(?:$|[^\p{L}\p{N}_])`)
+	case domain.AutomodRegex:
+		compiled.pattern, err = regexp.Compile(rule.Pattern)
+	case domain.AutomodLinkDomain:
+	default:
+		err = fmt.Errorf("unknown rule type %q", rule.Type)
+	}
+	return compiled, err
+}
+
+func (s *AutomodService) HoldForReview(targetType domain.ReportTargetType, targetID, ownerID int, verdict *domain.AutomodVerdict) error {
+	var ruleIDs []string
+	for _, match := range verdict.Matches {
+		if !match.DryRun && match.Action == domain.AutomodFlag {
+			ruleIDs = append(ruleIDs, fmt.Sprintf("#%d", match.RuleID))
+		}
+	}
+
+	return s.reportRepo.Create(&domain.Report{
+		TargetType:    targetType,
+		TargetID:      targetID,
+		TargetOwnerID: ownerID,
+		Reason:        domain.ReportReasonAutomod,
+		Details:       "Held for review by auto-moderation rules " + strings.Join(ruleIDs, ", "),
+	})
+}
@@ -0,0 +1,129 @@
+package application
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/bandvov/social-media-go/domain"
+)
+
+type stubAutomodRepository struct {
+	rules []domain.AutomodRule
+	hits  []domain.AutomodMatch
+}
+
+func (r *stubAutomodRepository) CreateRule(rule *domain.AutomodRule) error {
+	rule.ID = len(r.rules) + 1
+	r.rules = append(r.rules, *rule)
+	return nil
+}
+
+func (r *stubAutomodRepository) UpdateRule(rule *domain.AutomodRule) error {
+	r.rules[rule.ID-1] = *rule
+	return nil
+}
+
+func (r *stubAutomodRepository) DeleteRule(id int) error {
+	r.rules[id-1].Enabled = false
+	return nil
+}
+
+func (r *stubAutomodRepository) GetRule(id int) (*domain.AutomodRule, error) {
+	return &r.rules[id-1], nil
+}
+
+func (r *stubAutomodRepository) ListRules() ([]domain.AutomodRule, error) {
+	return r.rules, nil
+}
+
+func (r *stubAutomodRepository) RecordHits(matches []domain.AutomodMatch) error {
+	r.hits = append(r.hits, matches...)
+	return nil
+}
+
+func TestAutomodServiceEvaluate(t *testing.T) {
+	repo := &stubAutomodRepository{}
+	service := NewAutomodService(repo, nil, DefaultAutomodRefreshInterval)
+
+	disabled := false
+	for _, req := range []domain.AutomodRuleRequest{
+		{Type: domain.AutomodKeyword, Pattern: "buy  now", Action: domain.AutomodFlag},
+		{Type: domain.AutomodRegex, Pattern: `(?i)free\s+crypto`, Action: domain.AutomodReject, Scope: domain.AutomodScopeComments},
+		{Type: domain.AutomodLinkDomain, Pattern: "Spam.example", Action: domain.AutomodReject, DryRun: true},
+		{Type: domain.AutomodKeyword, Pattern: "spoiler", Action: domain.AutomodContentWarning, Scope: domain.AutomodScopePosts, ContentWarning: "Spoilers"},
+		{Type: domain.AutomodKeyword, Pattern: "ignored", Action: domain.AutomodReject, Enabled: &disabled},
+	} {
+		req := req
+		if _, err := service.CreateRule(testAdminID, &req); err != nil {
+			t.Fatalf("create rule %q: %v", req.Pattern, err)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		scope    domain.AutomodScope
+		content  string
+		action   domain.AutomodAction
+		warning  string
+		matchIDs []int
+	}{
+		{"no match", domain.AutomodScopePosts, "hello there", "", "", nil},
+		{"keyword whole phrase", domain.AutomodScopePosts, "BUY NOW, limited offer", domain.AutomodFlag, "", []int{1}},
+		{"keyword inside a word", domain.AutomodScopePosts, "buy nowhere", "", "", nil},
+		{"regex only on comments", domain.AutomodScopePosts, "free crypto", "", "", nil},
+		{"regex on comments", domain.AutomodScopeComments, "Free   Crypto here, buy now", domain.AutomodReject, "", []int{1, 2}},
+		{"dry run subdomain", domain.AutomodScopePosts, "see https://www.spam.example/offer", "", "", []int{3}},
+		{"other domain", domain.AutomodScopePosts, "see https://notspam.example/offer", "", "", nil},
+		{"content warning", domain.AutomodScopePosts, "spoiler: it was a dream", domain.AutomodContentWarning, "Spoilers", []int{4}},
+		{"content warning only on posts", domain.AutomodScopeComments, "spoiler: it was a dream", "", "", nil},
+		{"disabled rule", domain.AutomodScopePosts, "ignored", "", "", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			verdict, err := service.Evaluate(tt.scope, tt.content)
+			if err != nil {
+				t.Fatalf("evaluate: %v", err)
+			}
+			if verdict.Action != tt.action {
+				t.Errorf("expected action %q, got %q", tt.action, verdict.Action)
+			}
+			if verdict.ContentWarning != tt.warning {
+				t.Errorf("expected warning %q, got %q", tt.warning, verdict.ContentWarning)
+			}
+			var ids []int
+			for _, match := range verdict.Matches {
+				ids = append(ids, match.RuleID)
+			}
+			if !reflect.DeepEqual(ids, tt.matchIDs) {
+				t.Errorf("expected matches %v, got %v", tt.matchIDs, ids)
+			}
+		})
+	}
+
+	if len(repo.hits) != 5 || !repo.hits[3].DryRun {
+		t.Errorf("expected 5 recorded hits with the dry-run one flagged, got %+v", repo.hits)
+	}
+}
+
+func TestAutomodRuleRequestValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		req  domain.AutomodRuleRequest
+	}{
+		{"empty pattern", domain.AutomodRuleRequest{Type: domain.AutomodKeyword, Pattern: " ", Action: domain.AutomodReject}},
+		{"bad regex", domain.AutomodRuleRequest{Type: domain.AutomodRegex, Pattern: "(", Action: domain.AutomodReject}},
+		{"url as domain", domain.AutomodRuleRequest{Type: domain.AutomodLinkDomain, Pattern: "https://spam.example", Action: domain.AutomodReject}},
+		{"warning on comments", domain.AutomodRuleRequest{Type: domain.AutomodKeyword, Pattern: "x", Action: domain.AutomodContentWarning, ContentWarning: "x"}},
+		{"warning without text", domain.AutomodRuleRequest{Type: domain.AutomodKeyword, Pattern: "x", Action: domain.AutomodContentWarning, Scope: domain.AutomodScopePosts}},
+		{"unknown action", domain.AutomodRuleRequest{Type: domain.AutomodKeyword, Pattern: "x", Action: "ban"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.req.Validate(); err == nil {
+				t.Error("expected the rule to be invalid")
+			}
+		})
+	}
+}
@@ -0,0 +1,41 @@
+package application
+
+import (
+	"errors"
+
+	"github.com/bandvov/social-media-go/domain"
+)
+
+// BlockServiceInterface defines methods for blocking users.
+type BlockServiceInterface interface {
+	BlockUser(blockerID, blockedID int) error
+	UnblockUser(blockerID, blockedID int) error
+}
+
+type BlockService struct {
+	blockRepo    domain.BlockRepository
+	followerRepo domain.FollowerRepository
+}
+
+func NewBlockService(blockRepo domain.BlockRepository, followerRepo domain.FollowerRepository) *BlockService {
+	return &BlockService{blockRepo: blockRepo, followerRepo: followerRepo}
+}
+
+// BlockUser blocks a user and removes the follow relations between the two.
+func (s *BlockService) BlockUser(blockerID, blockedID int) error {
+	if blockerID == blockedID {
+		return errors.New("user cannot block themselves")
+	}
+
+	if err := s.blockRepo.AddBlock(domain.NewBlock(blockerID, blockedID)); err != nil {
+		return err
+	}
+	if err := s.followerRepo.RemoveFollower(domain.NewFollower(blockerID, blockedID)); err != nil {
+		return err
+	}
+	return s.followerRepo.RemoveFollower(domain.NewFollower(blockedID, blockerID))
+}
+
+func (s *BlockService) UnblockUser(blockerID, blockedID int) error {
+	return s.blockRepo.RemoveBlock(domain.NewBlock(blockerID, blockedID))
+}
@@ -0,0 +1,131 @@
+package application
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/bandvov/social-media-go/domain"
+)
+
+var (
+	ErrCollectionNotFound = errors.New("collection not found")
+	ErrPostNotFound       = errors.New("post not found")
+)
+
+// BookmarkServiceInterface defines methods for bookmark-related operations.
+type BookmarkServiceInterface interface {
+	AddBookmark(viewer Viewer, postID int, collectionID *int) (*domain.Bookmark, error)
+	RemoveBookmark(userID, postID int) error
+	GetBookmarks(userID int, collectionID *int, page domain.PageRequest) ([]domain.Bookmark, *domain.Cursor, error)
+	GetBookmarkedPostIDs(userID int, postIDs []int) (map[int]bool, error)
+	CreateCollection(userID int, name string) (*domain.BookmarkCollection, error)
+	GetCollections(userID int) ([]domain.BookmarkCollection, error)
+	DeleteCollection(userID, id int) error
+}
+
+type BookmarkService struct {
+	repo       domain.BookmarkRepository
+	visibility *VisibilityPolicy
+}
+
+func NewBookmarkService(repo domain.BookmarkRepository, visibility *VisibilityPolicy) *BookmarkService {
+	return &BookmarkService{repo: repo, visibility: visibility}
+}
+
+// AddBookmark saves a post the viewer may see. Posts the viewer may not see
+// are reported as missing, like posts that do not exist.
+func (s *BookmarkService) AddBookmark(viewer Viewer, postID int, collectionID *int) (*domain.Bookmark, error) {
+	_, err := s.visibility.CheckPost(viewer, postID)
+	if errors.Is(err, sql.ErrNoRows) || errors.Is(err, ErrForbidden) {
+		return nil, ErrPostNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if collectionID != nil {
+		if err := s.checkCollectionOwner(viewer.ID, *collectionID); err != nil {
+			return nil, err
+		}
+	}
+
+	bookmark := &domain.Bookmark{UserID: viewer.ID, PostID: postID, CollectionID: collectionID}
+	if err := s.repo.AddBookmark(bookmark); err != nil {
+		return nil, err
+	}
+	return bookmark, nil
+}
+
+func (s *BookmarkService) RemoveBookmark(userID, postID int) error {
+	return s.repo.RemoveBookmark(userID, postID)
+}
+
+func (s *BookmarkService) GetBookmarks(userID int, collectionID *int, page domain.PageRequest) ([]domain.Bookmark, *domain.Cursor, error) {
+	if collectionID != nil {
+		if err := s.checkCollectionOwner(userID, *collectionID); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	bookmarks, err := s.repo.GetBookmarks(userID, collectionID, page)
+	if err != nil {
+		return nil, nil, err
+	}
+	bookmarks, next := domain.NextPage(bookmarks, page, bookmarkCursor)
+	return bookmarks, next, nil
+}
+
+func bookmarkCursor(bookmark domain.Bookmark) domain.Cursor {
+	return domain.Cursor{CreatedAt: bookmark.CreatedAt, ID: bookmark.ID}
+}
+
+// GetBookmarkedPostIDs returns the subset of postIDs saved by the user.
+func (s *BookmarkService) GetBookmarkedPostIDs(userID int, postIDs []int) (map[int]bool, error) {
+	ids, err := s.repo.GetBookmarkedPostIDs(userID, postIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	bookmarked := make(map[int]bool, len(ids))
+	for _, id := range ids {
+		bookmarked[id] = true
+	}
+	return bookmarked, nil
+}
+
+func (s *BookmarkService) CreateCollection(userID int, name string) (*domain.BookmarkCollection, error) {
+	collection := &domain.BookmarkCollection{UserID: userID, Name: name}
+	if err := collection.Validate(); err != nil {
+		return nil, err
+	}
+	if err := s.repo.CreateCollection(collection); err != nil {
+		return nil, err
+	}
+	return collection, nil
+}
+
+func (s *BookmarkService) GetCollections(userID int) ([]domain.BookmarkCollection, error) {
+	return s.repo.GetCollections(userID)
+}
+
+func (s *BookmarkService) DeleteCollection(userID, id int) error {
+	if err := s.checkCollectionOwner(userID, id); err != nil {
+		return err
+	}
+	return s.repo.DeleteCollection(userID, id)
+}
+
+// checkCollectionOwner makes sure the collection exists and belongs to the
+// user. Collections of other users are reported as missing.
+func (s *BookmarkService) checkCollectionOwner(userID, collectionID int) error {
+	collection, err := s.repo.GetCollectionByID(collectionID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrCollectionNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if collection.UserID != userID {
+		return ErrCollectionNotFound
+	}
+	return nil
+}
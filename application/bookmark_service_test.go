@@ -0,0 +1,61 @@
+package application
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/bandvov/social-media-go/domain"
+)
+
+type stubBookmarkRepository struct {
+	domain.BookmarkRepository
+	added []domain.Bookmark
+}
+
+func (r *stubBookmarkRepository) AddBookmark(bookmark *domain.Bookmark) error {
+	r.added = append(r.added, *bookmark)
+	return nil
+}
+
+func TestBookmarkServiceAddBookmarkChecksVisibility(t *testing.T) {
+	posts := map[int]*domain.Post{
+		1: postWithVisibility(1, domain.Public),
+		2: postWithVisibility(2, domain.Followers),
+		3: postWithVisibility(3, domain.Private),
+	}
+	repo := &stubBookmarkRepository{}
+	service := NewBookmarkService(repo, newTestVisibilityPolicy(posts, nil))
+
+	tests := []struct {
+		name     string
+		viewer   Viewer
+		postID   int
+		expected error
+	}{
+		{name: "public post", viewer: Viewer{ID: testStrangerID}, postID: 1},
+		{name: "followers post as follower", viewer: Viewer{ID: testFollowerID}, postID: 2},
+		{name: "followers post as stranger", viewer: Viewer{ID: testStrangerID}, postID: 2, expected: ErrPostNotFound},
+		{name: "private post as author", viewer: Viewer{ID: testAuthorID}, postID: 3},
+		{name: "private post as follower", viewer: Viewer{ID: testFollowerID}, postID: 3, expected: ErrPostNotFound},
+		{name: "missing post", viewer: Viewer{ID: testStrangerID}, postID: 4, expected: ErrPostNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo.added = nil
+			bookmark, err := service.AddBookmark(tt.viewer, tt.postID, nil)
+			if !errors.Is(err, tt.expected) {
+				t.Fatalf("expected error %v, got %v", tt.expected, err)
+			}
+			if tt.expected != nil {
+				if len(repo.added) != 0 {
+					t.Errorf("expected no bookmark to be stored, got %v", repo.added)
+				}
+				return
+			}
+			if bookmark.UserID != tt.viewer.ID || bookmark.PostID != tt.postID || len(repo.added) != 1 {
+				t.Errorf("expected post %d to be bookmarked by user %d, got %+v", tt.postID, tt.viewer.ID, repo.added)
+			}
+		})
+	}
+}
@@ -1,37 +1,556 @@
 package application
 
-import "github.com/bandvov/social-media-go/domain"
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"strings"
+
+	"github.com/bandvov/social-media-go/domain"
+	"github.com/bandvov/social-media-go/utils"
+)
 
 // CommentServiceInterface defines methods for tags-related operations.
 type CommentServiceInterface interface {
-	AddComment(c *domain.Comment) error
-	GetCommentsByEntityID(entityID, userID, offset, limit int) ([]domain.Comment, error)
+	AddComment(viewer Viewer, c *domain.Comment) error
+	GetCommentsByEntityID(entityID int, viewer Viewer, sort domain.CommentSort, page domain.PageRequest) ([]domain.Comment, *domain.Cursor, error)
+	EditComment(viewer Viewer, id int, content string) (*domain.Comment, error)
+	DeleteComment(viewer Viewer, id int) error
+	GetCommentTree(postID int, viewer Viewer, sort domain.CommentSort, page domain.PageRequest, depth, perBranch int) ([]domain.CommentNode, *domain.Cursor, error)
+	GetReplies(commentID int, viewer Viewer, page domain.PageRequest, depth, perBranch int) ([]domain.CommentNode, *domain.Cursor, error)
 	GetCommentsByEntityIDs(entityIDs []int) (map[int][]domain.Comment, []int, []int, error)
 	GetCommentsAndRepliesCount(entityIDs []int) ([]domain.CommentCount, error)
+	SetCommentPolicy(viewer Viewer, postID int, policy domain.CommentPolicy) error
+	PinComment(viewer Viewer, postID int, commentID *int) error
+	SetCommentHidden(viewer Viewer, id int, hidden bool) error
 }
 type CommentService struct {
-	commentRepo domain.CommentRepository
+	commentRepo  domain.CommentRepository
+	postRepo     domain.PostRepository
+	userRepo     domain.UserRepository
+	visibility   *VisibilityPolicy
+	linkPreviews LinkPreviewServiceInterface
+	automod      AutomodServiceInterface
+	spam         SpamServiceInterface
+	mentions     MentionServiceInterface
+	counters     CounterServiceInterface
 }
 
-func NewCommentService(repo domain.CommentRepository) *CommentService {
+func NewCommentService(repo domain.CommentRepository, postRepo domain.PostRepository, userRepo domain.UserRepository, visibility *VisibilityPolicy, linkPreviews LinkPreviewServiceInterface, automod AutomodServiceInterface, spam SpamServiceInterface, mentions MentionServiceInterface, counters CounterServiceInterface) *CommentService {
 	return &CommentService{
-		commentRepo: repo,
+		commentRepo:  repo,
+		postRepo:     postRepo,
+		userRepo:     userRepo,
+		visibility:   visibility,
+		linkPreviews: linkPreviews,
+		automod:      automod,
+		spam:         spam,
+		mentions:     mentions,
+		counters:     counters,
 	}
 }
 
-func (s *CommentService) AddComment(c *domain.Comment) error {
+// AddComment comments on a post, or replies to a comment, that the viewer
+// is allowed to see and whose comment policy lets them comment. Comments held by auto-moderation or the spam filter
+// are stored as Flagged and only listed to others once a moderator releases
+// them. Comments of shadow-limited accounts are stored the same way, but
+// without a report and without telling the author.
+func (s *CommentService) AddComment(viewer Viewer, c *domain.Comment) error {
+	post, err := s.checkEntity(viewer, c.EntityType, c.EntityID)
+	if err != nil {
+		return err
+	}
+	if err := s.checkCommentPolicy(viewer, post); err != nil {
+		return err
+	}
+
+	spamVerdict, err := s.spam.Check(c.AuthorID, domain.SpamActivityComment, c.Content)
+	if err != nil {
+		return err
+	}
+
+	verdict, err := s.automod.Evaluate(domain.AutomodScopeComments, c.Content)
+	if err != nil {
+		return err
+	}
+	if verdict.Action == domain.AutomodReject {
+		return domain.ErrContentRejected
+	}
+
 	comment := domain.Comment{
-		EntityID:   c.EntityID,
-		EntityType: c.EntityType,
-		Content:    c.Content,
-		AuthorID:   c.AuthorID,
-		Status:     domain.Active,
+		EntityID:    c.EntityID,
+		EntityType:  c.EntityType,
+		Content:     c.Content,
+		ContentHTML: utils.RenderMarkdown(c.Content),
+		AuthorID:    c.AuthorID,
+		Status:      domain.CommentActive,
+	}
+	held := verdict.Held() || spamVerdict.Held()
+	if held || spamVerdict.ShadowLimited() {
+		comment.Status = domain.CommentFlagged
+	}
+	id, err := s.commentRepo.AddComment(comment)
+	if err != nil {
+		return err
+	}
+	c.ID = id
+	c.Status = domain.CommentActive
+	countCommentStatus(s.counters, post.ID, comment.EntityType == domain.CommentTypeReply, "", comment.Status)
+
+	if held {
+		c.Status = domain.CommentFlagged
+		if verdict.Held() {
+			err = s.automod.HoldForReview(domain.ReportTargetComment, id, comment.AuthorID, verdict)
+		} else {
+			err = s.spam.HoldForReview(domain.ReportTargetComment, id, comment.AuthorID, spamVerdict)
+		}
+		if err != nil {
+			log.Printf("failed to queue held comment %d for review: %v", id, err)
+		}
+	}
+
+	go func() {
+		if err := s.linkPreviews.Unfurl(domain.LinkEntityComment, id, comment.Content); err != nil {
+			log.Printf("failed to unfurl links of comment %d: %v", id, err)
+		}
+	}()
+	go s.recordMentions(id, comment.AuthorID, comment.Content, post, comment.Status == domain.CommentActive)
+
+	return nil
+}
+
+// EditComment replaces the content of the viewer's own comment. The new
+// content goes through auto-moderation again; a comment held for review
+// stays held.
+func (s *CommentService) EditComment(viewer Viewer, id int, content string) (*domain.Comment, error) {
+	if strings.TrimSpace(content) == "" {
+		return nil, domain.ErrCommentContentRequired
+	}
+
+	comment, err := s.commentRepo.GetCommentByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if comment.AuthorID != viewer.ID {
+		return nil, ErrForbidden
+	}
+	if comment.Status != domain.CommentActive && comment.Status != domain.CommentFlagged {
+		return nil, domain.ErrCommentNotEditable
+	}
+
+	verdict, err := s.automod.Evaluate(domain.AutomodScopeComments, content)
+	if err != nil {
+		return nil, err
+	}
+	if verdict.Action == domain.AutomodReject {
+		return nil, domain.ErrContentRejected
+	}
+
+	previous := comment.Status
+	wasActive := previous == domain.CommentActive
+	comment.Content = content
+	comment.ContentHTML = utils.RenderMarkdown(content)
+	if verdict.Held() {
+		comment.Status = domain.CommentFlagged
+	}
+	if err := s.commentRepo.UpdateContent(comment); err != nil {
+		return nil, err
+	}
+	countCommentStatus(s.counters, comment.PostID, comment.ParentID != nil, previous, comment.Status)
+
+	if verdict.Held() && wasActive {
+		if err := s.automod.HoldForReview(domain.ReportTargetComment, id, comment.AuthorID, verdict); err != nil {
+			log.Printf("failed to queue held comment %d for review: %v", id, err)
+		}
+	}
+
+	go func() {
+		if err := s.linkPreviews.Unfurl(domain.LinkEntityComment, id, content); err != nil {
+			log.Printf("failed to unfurl links of comment %d: %v", id, err)
+		}
+	}()
+	go func() {
+		post, err := s.postRepo.GetByID(comment.PostID)
+		if err != nil {
+			log.Printf("failed to record mentions of comment %d: %v", id, err)
+			return
+		}
+		s.recordMentions(id, comment.AuthorID, content, post, comment.Status == domain.CommentActive)
+	}()
+
+	return comment, nil
+}
+
+// DeleteComment deletes a comment on behalf of its author, the author of
+// the post or a moderator. The comment is replaced by a tombstone so the
+// replies below it keep their place.
+func (s *CommentService) DeleteComment(viewer Viewer, id int) error {
+	comment, err := s.commentRepo.GetCommentByID(id)
+	if err != nil {
+		return err
+	}
+	if comment.Status == domain.CommentDeleted {
+		return nil
+	}
+
+	if comment.AuthorID != viewer.ID && !viewer.IsModerator {
+		post, err := s.visibility.CheckPost(viewer, comment.PostID)
+		if err != nil {
+			return err
+		}
+		if post.AuthorID != viewer.ID {
+			return ErrForbidden
+		}
+	}
+
+	if err := s.linkPreviews.RemoveLinks(domain.LinkEntityComment, id); err != nil {
+		return err
+	}
+	if err := s.mentions.RemoveMentions(domain.MentionEntityComment, id); err != nil {
+		return err
+	}
+	if err := s.commentRepo.Delete(id); err != nil {
+		return err
+	}
+	countCommentStatus(s.counters, comment.PostID, comment.ParentID != nil, comment.Status, domain.CommentDeleted)
+	return nil
+}
+
+// countCommentStatus counts a comment in or out of the comment or reply
+// count of its post when its status changes. Only active comments are
+// counted; a new comment changes from no status.
+func countCommentStatus(counters CounterServiceInterface, postID int, reply bool, from, to domain.CommentStatus) {
+	delta := 0
+	switch {
+	case from != domain.CommentActive && to == domain.CommentActive:
+		delta = 1
+	case from == domain.CommentActive && to != domain.CommentActive:
+		delta = -1
+	default:
+		return
+	}
+
+	field := domain.CounterComments
+	if reply {
+		field = domain.CounterReplies
+	}
+	counters.Add(postID, field, delta)
+}
+
+// recordMentions stores the mentions of a comment on post and, when notify
+// is set, notifies the users mentioned for the first time.
+func (s *CommentService) recordMentions(id, authorID int, content string, post *domain.Post, notify bool) {
+	if err := s.mentions.RecordMentions(domain.MentionEntityComment, id, authorID, content, post, notify); err != nil {
+		log.Printf("failed to record mentions of comment %d: %v", id, err)
+	}
+}
+
+// GetCommentsByEntityID lists the comments of a post the viewer is allowed
+// to see, in the given order.
+func (s *CommentService) GetCommentsByEntityID(entityID int, viewer Viewer, sort domain.CommentSort, page domain.PageRequest) ([]domain.Comment, *domain.Cursor, error) {
+	if _, err := s.visibility.CheckPost(viewer, entityID); err != nil {
+		return nil, nil, err
+	}
+
+	comments, next, err := s.fetchComments(entityID, viewer, sort, page)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	commentIDs := make([]int, 0, len(comments))
+	contents := make(map[int]string, len(comments))
+	for _, comment := range comments {
+		commentIDs = append(commentIDs, comment.ID)
+		contents[comment.ID] = comment.Content
+	}
+	previews, err := s.linkPreviews.GetPreviews(domain.LinkEntityComment, commentIDs)
+	if err != nil {
+		return nil, nil, err
+	}
+	mentions, err := s.mentions.GetMentions(domain.MentionEntityComment, contents)
+	if err != nil {
+		return nil, nil, err
+	}
+	for i, comment := range comments {
+		comments[i].LinkPreviews = previews[comment.ID]
+		comments[i].Mentions = mentions[comment.ID]
+	}
+
+	return comments, next, nil
+}
+
+// GetCommentTree lists a page of a post's top-level comments, each with its
+// replies down to depth levels below it and at most perBranch replies per
+// branch.
+func (s *CommentService) GetCommentTree(postID int, viewer Viewer, sort domain.CommentSort, page domain.PageRequest, depth, perBranch int) ([]domain.CommentNode, *domain.Cursor, error) {
+	if _, err := s.visibility.CheckPost(viewer, postID); err != nil {
+		return nil, nil, err
+	}
+
+	comments, next, err := s.fetchComments(postID, viewer, sort, page)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	nodes, err := s.buildTree(comments, viewer, depth, perBranch)
+	if err != nil {
+		return nil, nil, err
+	}
+	return nodes, next, nil
+}
+
+// GetReplies lists a page of the direct replies of a comment, oldest first,
+// each with its own replies. It loads more of a branch of the tree.
+func (s *CommentService) GetReplies(commentID int, viewer Viewer, page domain.PageRequest, depth, perBranch int) ([]domain.CommentNode, *domain.Cursor, error) {
+	if err := s.visibility.CheckComment(viewer, commentID); err != nil {
+		return nil, nil, err
+	}
+
+	replies, err := s.commentRepo.FetchReplies([]int{commentID}, viewer.ID, page.After, page.Limit)
+	if err != nil {
+		return nil, nil, err
+	}
+	replies, next := domain.NextPage(replies, page, commentCursor)
+
+	nodes, err := s.buildTree(replies, viewer, depth, perBranch)
+	if err != nil {
+		return nil, nil, err
+	}
+	return nodes, next, nil
+}
+
+// buildTree loads the replies below comments one level at a time, so a tree
+// costs one query per level whatever its width.
+func (s *CommentService) buildTree(comments []domain.Comment, viewer Viewer, depth, perBranch int) ([]domain.CommentNode, error) {
+	children := make(map[int][]domain.Comment)
+	cursors := make(map[int]*domain.Cursor)
+	loaded := make(map[int]bool)
+
+	contents := make(map[int]string)
+	var ids, level []int
+	for _, comment := range comments {
+		level = append(level, comment.ID)
+		contents[comment.ID] = comment.Content
+	}
+	ids = append(ids, level...)
+
+	for i := 0; i < depth && len(level) > 0; i++ {
+		replies, err := s.commentRepo.FetchReplies(level, viewer.ID, nil, perBranch)
+		if err != nil {
+			return nil, err
+		}
+		for _, id := range level {
+			loaded[id] = true
+		}
+
+		branches := make(map[int][]domain.Comment)
+		for _, reply := range replies {
+			branches[*reply.ParentID] = append(branches[*reply.ParentID], reply)
+		}
+
+		level = nil
+		for parentID, branch := range branches {
+			branch, next := domain.NextPage(branch, domain.PageRequest{Limit: perBranch}, commentCursor)
+			children[parentID] = branch
+			if next != nil {
+				cursors[parentID] = next
+			}
+			for _, reply := range branch {
+				level = append(level, reply.ID)
+				contents[reply.ID] = reply.Content
+			}
+		}
+		ids = append(ids, level...)
+	}
+
+	previews, err := s.linkPreviews.GetPreviews(domain.LinkEntityComment, ids)
+	if err != nil {
+		return nil, err
+	}
+	mentions, err := s.mentions.GetMentions(domain.MentionEntityComment, contents)
+	if err != nil {
+		return nil, err
+	}
+
+	var build func(comment domain.Comment) domain.CommentNode
+	build = func(comment domain.Comment) domain.CommentNode {
+		comment.LinkPreviews = previews[comment.ID]
+		comment.Mentions = mentions[comment.ID]
+		node := domain.CommentNode{Comment: comment}
+		for _, reply := range children[comment.ID] {
+			node.Replies = append(node.Replies, build(reply))
+		}
+		// A loaded branch has more replies only if it has a cursor. Branches
+		// below the loaded depth are loaded from the start; they have
+		// replies to list while any reply below them is up, even behind a
+		// deleted one.
+		if next, ok := cursors[comment.ID]; ok {
+			node.MoreReplies = true
+			node.RepliesAfter = next
+		} else if !loaded[comment.ID] && comment.DescendantsCount > 0 {
+			node.MoreReplies = true
+		}
+		return node
+	}
+
+	nodes := make([]domain.CommentNode, 0, len(comments))
+	for _, comment := range comments {
+		nodes = append(nodes, build(comment))
+	}
+	return nodes, nil
+}
+
+// checkEntity checks the post a new comment or reply would be attached to.
+// Only active comments can be replied to.
+func (s *CommentService) checkEntity(viewer Viewer, entityType domain.CommentType, entityID int) (*domain.Post, error) {
+	if entityType == domain.CommentTypeReply {
+		parent, err := s.commentRepo.GetCommentByID(entityID)
+		if err != nil {
+			return nil, err
+		}
+		if parent.Status != domain.CommentActive {
+			return nil, sql.ErrNoRows
+		}
+		return s.visibility.CheckPost(viewer, parent.PostID)
+	}
+	return s.visibility.CheckPost(viewer, entityID)
+}
+
+// checkCommentPolicy returns an error when the post's comment policy keeps
+// the viewer from commenting on it. The author can always comment.
+func (s *CommentService) checkCommentPolicy(viewer Viewer, post *domain.Post) error {
+	if post.AuthorID == viewer.ID {
+		return nil
+	}
+
+	switch post.CommentPolicy {
+	case domain.CommentPolicyDisabled:
+		return domain.ErrCommentsDisabled
+	case domain.CommentPolicyFollowers:
+		following, err := s.visibility.Follows(viewer, post.AuthorID)
+		if err != nil {
+			return err
+		}
+		if !following {
+			return domain.ErrCommentsRestricted
+		}
+	case domain.CommentPolicyMentioned:
+		user, err := s.userRepo.GetUserByID(viewer.ID)
+		if err != nil {
+			return err
+		}
+		if user.Username == nil {
+			return domain.ErrCommentsRestricted
+		}
+		for _, username := range utils.ExtractMentions(post.Content) {
+			if username == *user.Username {
+				return nil
+			}
+		}
+		return domain.ErrCommentsRestricted
+	}
+	return nil
+}
+
+// checkPostAuthor loads a post the viewer can see and returns ErrForbidden
+// unless the viewer wrote it.
+func (s *CommentService) checkPostAuthor(viewer Viewer, postID int) (*domain.Post, error) {
+	post, err := s.visibility.CheckPost(viewer, postID)
+	if err != nil {
+		return nil, err
+	}
+	if post.AuthorID != viewer.ID {
+		return nil, ErrForbidden
+	}
+	return post, nil
+}
+
+// SetCommentPolicy changes who may comment on the viewer's post. Existing
+// comments stay up.
+func (s *CommentService) SetCommentPolicy(viewer Viewer, postID int, policy domain.CommentPolicy) error {
+	if err := policy.Validate(); err != nil {
+		return err
+	}
+	if _, err := s.checkPostAuthor(viewer, postID); err != nil {
+		return err
+	}
+	return s.postRepo.SetCommentPolicy(postID, policy)
+}
+
+// PinComment pins an active top-level comment of the viewer's post above the
+// other comments, replacing any pinned before. A nil commentID unpins.
+func (s *CommentService) PinComment(viewer Viewer, postID int, commentID *int) error {
+	if _, err := s.checkPostAuthor(viewer, postID); err != nil {
+		return err
+	}
+
+	if commentID != nil {
+		comment, err := s.commentRepo.GetCommentByID(*commentID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return domain.ErrInvalidPinnedComment
+		}
+		if err != nil {
+			return err
+		}
+		if comment.PostID != postID || comment.ParentID != nil || comment.Status != domain.CommentActive {
+			return domain.ErrInvalidPinnedComment
+		}
+	}
+	return s.postRepo.SetPinnedComment(postID, commentID)
+}
+
+// SetCommentHidden lets the author of a post hide a comment on it, or list
+// it again. A hidden comment stays visible to both authors; comments taken
+// down by a moderator cannot be listed again this way.
+func (s *CommentService) SetCommentHidden(viewer Viewer, id int, hidden bool) error {
+	comment, err := s.commentRepo.GetCommentByID(id)
+	if err != nil {
+		return err
+	}
+	if _, err := s.checkPostAuthor(viewer, comment.PostID); err != nil {
+		return err
+	}
+	if err := s.commentRepo.SetHiddenByAuthor(id, hidden); err != nil {
+		return err
+	}
+
+	status := domain.CommentActive
+	if hidden {
+		status = domain.CommentHidden
 	}
-	return s.commentRepo.AddComment(comment)
+	countCommentStatus(s.counters, comment.PostID, comment.ParentID != nil, comment.Status, status)
+	return nil
 }
 
-func (s *CommentService) GetCommentsByEntityID(entityID, userID, offset, limit int) ([]domain.Comment, error) {
-	return s.commentRepo.FetchCommentsByEntityID(entityID, userID, offset, limit)
+// fetchComments lists a page of a post's top-level comments in the given
+// order.
+func (s *CommentService) fetchComments(postID int, viewer Viewer, sort domain.CommentSort, page domain.PageRequest) ([]domain.Comment, *domain.Cursor, error) {
+	if err := sort.Validate(); err != nil {
+		return nil, nil, err
+	}
+	if page.After != nil && sort.Scored() != (page.After.Score != nil) {
+		return nil, nil, domain.ErrCommentSortMismatch
+	}
+
+	comments, err := s.commentRepo.FetchCommentsByEntityID(postID, viewer.ID, sort, page)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// The pinned comment comes on top of the page, not in place of another.
+	var pinned *domain.Comment
+	if len(comments) > 0 && comments[0].Pinned {
+		pinned, comments = &comments[0], comments[1:]
+	}
+	comments, next := domain.NextPage(comments, page, sort.CursorOf)
+	if pinned != nil {
+		comments = append([]domain.Comment{*pinned}, comments...)
+	}
+	return comments, next, nil
+}
+
+func commentCursor(comment domain.Comment) domain.Cursor {
+	return domain.Cursor{CreatedAt: comment.CreatedAt, ID: comment.ID}
 }
 
 func (s *CommentService) GetCommentsByEntityIDs(entityIDs []int) (map[int][]domain.Comment, []int, []int, error) {
@@ -53,6 +572,18 @@ func (s *CommentService) GetCommentsByEntityIDs(entityIDs []int) (map[int][]doma
 	return commentMap, commentIDList, userIDList, nil
 }
 
+// GetCommentsAndRepliesCount returns the active top-level comments and
+// replies of posts, from the post counters.
 func (s *CommentService) GetCommentsAndRepliesCount(entityIDs []int) ([]domain.CommentCount, error) {
-	return s.commentRepo.CountByEntityIDs(entityIDs)
+	counters, err := s.counters.GetPostCounters(entityIDs)
+	if err != nil {
+		return nil, err
+	}
+	counts := make([]domain.CommentCount, 0, len(counters))
+	for _, postID := range entityIDs {
+		if counter, ok := counters[postID]; ok {
+			counts = append(counts, domain.CommentCount{EntityID: postID, CommentCount: counter.Comments, ReplyCount: counter.Replies})
+		}
+	}
+	return counts, nil
 }
@@ -0,0 +1,407 @@
+package application
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/bandvov/social-media-go/domain"
+	"github.com/bandvov/social-media-go/infrastructure"
+)
+
+type stubMentions struct{}
+
+func (stubMentions) RecordMentions(entityType string, entityID, authorID int, content string, post *domain.Post, notify bool) error {
+	return nil
+}
+
+func (stubMentions) RemoveMentions(entityType string, entityID int) error { return nil }
+
+func (stubMentions) GetMentions(entityType string, contents map[int]string) (map[int][]domain.Mention, error) {
+	return nil, nil
+}
+
+// newTestCommentTree stores a post with two top-level comments, 1 and 2.
+// Comment 1 has four replies, 10 to 13; reply 10 has a reply 20, which has
+// a reply 30. Comment 2 has a deleted reply 21, which has a reply 31.
+func newTestCommentTree() *CommentService {
+	created := time.Now()
+	var comments []domain.Comment
+	add := func(id int, parentID *int) {
+		created = created.Add(time.Second)
+		comments = append(comments, domain.Comment{ID: id, ParentID: parentID, PostID: 1, Status: domain.CommentActive, CreatedAt: created})
+	}
+	parent := func(id int) *int { return &id }
+
+	add(1, nil)
+	add(2, nil)
+	for id := 10; id <= 13; id++ {
+		add(id, parent(1))
+	}
+	add(20, parent(10))
+	add(30, parent(20))
+	add(21, parent(2))
+	comments[len(comments)-1].Status = domain.CommentDeleted
+	add(31, parent(21))
+
+	// Like the repository, only active replies are counted.
+	var countReplies func(id int) (int, int)
+	countReplies = func(id int) (replies, descendants int) {
+		for _, comment := range comments {
+			if comment.ParentID == nil || *comment.ParentID != id {
+				continue
+			}
+			if comment.Status == domain.CommentActive {
+				replies++
+				descendants++
+			}
+			_, below := countReplies(comment.ID)
+			descendants += below
+		}
+		return replies, descendants
+	}
+	for i := range comments {
+		comments[i].RepliesCount, comments[i].DescendantsCount = countReplies(comments[i].ID)
+	}
+
+	commentRepo := &infrastructure.MockCommentRepository{
+		FetchCommentsByEntityIDFunc: func(entityID, userID int, sort domain.CommentSort, page domain.PageRequest) ([]domain.Comment, error) {
+			var roots []domain.Comment
+			for _, comment := range comments {
+				if comment.ParentID == nil {
+					roots = append(roots, comment)
+				}
+			}
+			return roots, nil
+		},
+		FetchRepliesFunc: func(parentIDs []int, userID int, after *domain.Cursor, limit int) ([]domain.Comment, error) {
+			perParent := make(map[int]int)
+			var replies []domain.Comment
+			for _, comment := range comments {
+				if comment.ParentID == nil {
+					continue
+				}
+				for _, parentID := range parentIDs {
+					if *comment.ParentID != parentID || (after != nil && !comment.CreatedAt.After(after.CreatedAt)) {
+						continue
+					}
+					if perParent[parentID]++; perParent[parentID] <= limit+1 {
+						replies = append(replies, comment)
+					}
+				}
+			}
+			return replies, nil
+		},
+	}
+	posts := map[int]*domain.Post{1: postWithVisibility(1, domain.Public)}
+	policy := newTestVisibilityPolicy(posts, map[int]*domain.Comment{1: &comments[0]})
+	return NewCommentService(commentRepo, nil, nil, policy, stubLinkPreviews{}, nil, nil, stubMentions{}, &stubCounters{})
+}
+
+// treeShape describes a node as its ID, its replies and whether it has more.
+type treeShape struct {
+	ID      int
+	Replies []treeShape
+	More    bool
+	Cursor  bool
+}
+
+func shapeOf(nodes []domain.CommentNode) []treeShape {
+	var shapes []treeShape
+	for _, node := range nodes {
+		shapes = append(shapes, treeShape{ID: node.ID, Replies: shapeOf(node.Replies), More: node.MoreReplies, Cursor: node.RepliesAfter != nil})
+	}
+	return shapes
+}
+
+func TestCommentServiceGetCommentTree(t *testing.T) {
+	service := newTestCommentTree()
+	viewer := Viewer{ID: testStrangerID}
+
+	nodes, _, err := service.GetCommentTree(1, viewer, domain.CommentSortOldest, domain.PageRequest{Limit: 10}, 2, 2)
+	if err != nil {
+		t.Fatalf("get tree: %v", err)
+	}
+
+	// Comment 1 shows two of its four replies and a cursor for the rest.
+	// Reply 20 is at the loaded depth, so reply 30 is left to load from
+	// the start. The deleted reply 21 is listed without more to load.
+	expected := []treeShape{
+		{ID: 1, More: true, Cursor: true, Replies: []treeShape{
+			{ID: 10, Replies: []treeShape{{ID: 20, More: true}}},
+			{ID: 11},
+		}},
+		{ID: 2, Replies: []treeShape{{ID: 21, Replies: []treeShape{{ID: 31}}}}},
+	}
+	if shape := shapeOf(nodes); !reflect.DeepEqual(shape, expected) {
+		t.Errorf("expected tree %+v, got %+v", expected, shape)
+	}
+
+	more, next, err := service.GetReplies(1, viewer, domain.PageRequest{Limit: 10, After: nodes[0].RepliesAfter}, 0, 2)
+	if err != nil {
+		t.Fatalf("get replies: %v", err)
+	}
+	if shape := shapeOf(more); !reflect.DeepEqual(shape, []treeShape{{ID: 12}, {ID: 13}}) || next != nil {
+		t.Errorf("expected the remaining replies 12 and 13, got %+v (next %v)", shape, next)
+	}
+
+	// Comment 2 has no active replies, but one below its deleted reply.
+	nodes, _, err = service.GetCommentTree(1, viewer, domain.CommentSortOldest, domain.PageRequest{Limit: 10}, 0, 2)
+	if err != nil {
+		t.Fatalf("get tree: %v", err)
+	}
+	if shape := shapeOf(nodes); !reflect.DeepEqual(shape, []treeShape{{ID: 1, More: true}, {ID: 2, More: true}}) {
+		t.Errorf("expected both comments to have replies to load, got %+v", shape)
+	}
+}
+
+func TestCommentServiceEditAndDelete(t *testing.T) {
+	const commenterID = testStrangerID
+	comments := map[int]*domain.Comment{
+		1: {ID: 1, PostID: 1, AuthorID: commenterID, Status: domain.CommentActive},
+		2: {ID: 2, PostID: 1, AuthorID: commenterID, Status: domain.CommentDeleted},
+	}
+	var deleted []int
+	commentRepo := &infrastructure.MockCommentRepository{
+		GetCommentByIDFunc: func(id int) (*domain.Comment, error) {
+			comment := *comments[id]
+			return &comment, nil
+		},
+		UpdateContentFunc: func(comment *domain.Comment) error {
+			now := time.Now()
+			comment.EditedAt = &now
+			comments[comment.ID] = comment
+			return nil
+		},
+		DeleteFunc: func(id int) error {
+			deleted = append(deleted, id)
+			return nil
+		},
+	}
+	posts := map[int]*domain.Post{1: postWithVisibility(1, domain.Public)}
+	automod := NewAutomodService(&stubAutomodRepository{}, nil, DefaultAutomodRefreshInterval)
+	service := NewCommentService(commentRepo, &infrastructure.MockPostRepository{}, nil, newTestVisibilityPolicy(posts, nil), stubLinkPreviews{}, automod, nil, stubMentions{}, &stubCounters{})
+
+	commenter := Viewer{ID: commenterID}
+	postAuthor := Viewer{ID: testAuthorID}
+	follower := Viewer{ID: testFollowerID}
+	moderator := Viewer{ID: testAdminID, IsModerator: true}
+
+	edited, err := service.EditComment(commenter, 1, "*edited*")
+	if err != nil {
+		t.Fatalf("edit: %v", err)
+	}
+	if edited.EditedAt == nil || edited.ContentHTML == "" || edited.Status != domain.CommentActive {
+		t.Errorf("unexpected edited comment %+v", edited)
+	}
+	if _, err := service.EditComment(postAuthor, 1, "not mine"); !errors.Is(err, ErrForbidden) {
+		t.Errorf("expected only the author to edit, got %v", err)
+	}
+	if _, err := service.EditComment(commenter, 1, "  "); !errors.Is(err, domain.ErrCommentContentRequired) {
+		t.Errorf("expected empty content to be refused, got %v", err)
+	}
+	if _, err := service.EditComment(commenter, 2, "back"); !errors.Is(err, domain.ErrCommentNotEditable) {
+		t.Errorf("expected deleted comments to be read-only, got %v", err)
+	}
+
+	if err := service.DeleteComment(follower, 1); !errors.Is(err, ErrForbidden) {
+		t.Errorf("expected other users to be forbidden, got %v", err)
+	}
+	for _, viewer := range []Viewer{commenter, postAuthor, moderator} {
+		if err := service.DeleteComment(viewer, 1); err != nil {
+			t.Errorf("viewer %d: delete: %v", viewer.ID, err)
+		}
+	}
+	if err := service.DeleteComment(commenter, 2); err != nil {
+		t.Errorf("expected deleting a tombstone to be a no-op, got %v", err)
+	}
+	if !reflect.DeepEqual(deleted, []int{1, 1, 1}) {
+		t.Errorf("expected three deletions of comment 1, got %v", deleted)
+	}
+}
+
+func TestCommentServiceSortCursors(t *testing.T) {
+	comments := []domain.Comment{
+		{ID: 1, PostID: 1, TotaReactionslCount: 9},
+		{ID: 2, PostID: 1, TotaReactionslCount: 4},
+		{ID: 3, PostID: 1, TotaReactionslCount: 1},
+	}
+	var sorts []domain.CommentSort
+	commentRepo := &infrastructure.MockCommentRepository{
+		FetchCommentsByEntityIDFunc: func(entityID, userID int, sort domain.CommentSort, page domain.PageRequest) ([]domain.Comment, error) {
+			sorts = append(sorts, sort)
+			return comments, nil
+		},
+	}
+	posts := map[int]*domain.Post{1: postWithVisibility(1, domain.Public)}
+	service := NewCommentService(commentRepo, nil, nil, newTestVisibilityPolicy(posts, nil), stubLinkPreviews{}, nil, nil, stubMentions{}, &stubCounters{})
+	viewer := Viewer{ID: testStrangerID}
+
+	_, next, err := service.GetCommentsByEntityID(1, viewer, domain.CommentSortTop, domain.PageRequest{Limit: 2})
+	if err != nil {
+		t.Fatalf("top: %v", err)
+	}
+	if next == nil || next.ID != 2 || next.Score == nil || *next.Score != 4 {
+		t.Fatalf("expected a cursor after comment 2 with score 4, got %+v", next)
+	}
+
+	if _, _, err := service.GetCommentsByEntityID(1, viewer, domain.CommentSortTop, domain.PageRequest{Limit: 2, After: next}); err != nil {
+		t.Errorf("next top page: %v", err)
+	}
+	if _, _, err := service.GetCommentsByEntityID(1, viewer, domain.CommentSortNewest, domain.PageRequest{Limit: 2, After: next}); !errors.Is(err, domain.ErrCommentSortMismatch) {
+		t.Errorf("expected a top cursor to be refused for newest, got %v", err)
+	}
+	if _, _, err := service.GetCommentsByEntityID(1, viewer, "best", domain.PageRequest{Limit: 2}); !errors.Is(err, domain.ErrInvalidCommentSort) {
+		t.Errorf("expected an invalid sort error, got %v", err)
+	}
+	if !reflect.DeepEqual(sorts, []domain.CommentSort{domain.CommentSortTop, domain.CommentSortTop}) {
+		t.Errorf("expected only valid requests to reach the repository, got %v", sorts)
+	}
+}
+
+func TestCommentServiceCommentPolicy(t *testing.T) {
+	post := func(id int, policy domain.CommentPolicy, content string) *domain.Post {
+		post := postWithVisibility(id, domain.Public)
+		post.CommentPolicy, post.Content = policy, content
+		return post
+	}
+	posts := map[int]*domain.Post{
+		1: post(1, domain.CommentPolicyEveryone, "hello"),
+		2: post(2, domain.CommentPolicyFollowers, "hello"),
+		3: post(3, domain.CommentPolicyMentioned, "thanks @stranger"),
+		4: post(4, domain.CommentPolicyDisabled, "hello"),
+	}
+	usernames := map[int]string{testAuthorID: "author", testFollowerID: "follower", testStrangerID: "stranger"}
+	users := &infrastructure.MockUserRepository{
+		GetUserByIDFunc: func(id int) (*domain.User, error) {
+			username := usernames[id]
+			return &domain.User{ID: id, Username: &username}, nil
+		},
+	}
+	spam, _ := newTestSpamService()
+	automod := NewAutomodService(&stubAutomodRepository{}, nil, DefaultAutomodRefreshInterval)
+	service := NewCommentService(&infrastructure.MockCommentRepository{}, nil, users, newTestVisibilityPolicy(posts, nil), stubLinkPreviews{}, automod, spam, stubMentions{}, &stubCounters{})
+
+	expected := map[int]map[int]error{
+		testAuthorID:   {1: nil, 2: nil, 3: nil, 4: nil},
+		testFollowerID: {1: nil, 2: nil, 3: domain.ErrCommentsRestricted, 4: domain.ErrCommentsDisabled},
+		testStrangerID: {1: nil, 2: domain.ErrCommentsRestricted, 3: nil, 4: domain.ErrCommentsDisabled},
+	}
+	for viewerID, byPost := range expected {
+		for postID, want := range byPost {
+			comment := &domain.Comment{EntityID: postID, EntityType: domain.CommentTypeComment, AuthorID: viewerID, Content: "nice"}
+			if err := service.AddComment(Viewer{ID: viewerID}, comment); !errors.Is(err, want) {
+				t.Errorf("user %d on post %d: expected %v, got %v", viewerID, postID, want, err)
+			}
+		}
+	}
+}
+
+func TestCommentServicePinnedComment(t *testing.T) {
+	parent := 1
+	comments := map[int]*domain.Comment{
+		1: {ID: 1, PostID: 1, Status: domain.CommentActive},
+		2: {ID: 2, PostID: 1, Status: domain.CommentActive, ParentID: &parent},
+		3: {ID: 3, PostID: 2, Status: domain.CommentActive},
+	}
+	var pinned []int
+	commentRepo := &infrastructure.MockCommentRepository{
+		GetCommentByIDFunc: func(id int) (*domain.Comment, error) {
+			return comments[id], nil
+		},
+		FetchCommentsByEntityIDFunc: func(entityID, userID int, sort domain.CommentSort, page domain.PageRequest) ([]domain.Comment, error) {
+			return []domain.Comment{{ID: 1, Pinned: true}, {ID: 10}, {ID: 11}, {ID: 12}}, nil
+		},
+	}
+	postRepo := &infrastructure.MockPostRepository{
+		SetPinnedCommentFunc: func(postID int, commentID *int) error {
+			pinned = append(pinned, *commentID)
+			return nil
+		},
+	}
+	posts := map[int]*domain.Post{1: postWithVisibility(1, domain.Public), 2: postWithVisibility(2, domain.Public)}
+	service := NewCommentService(commentRepo, postRepo, nil, newTestVisibilityPolicy(posts, nil), stubLinkPreviews{}, nil, nil, stubMentions{}, &stubCounters{})
+	author := Viewer{ID: testAuthorID}
+
+	if err := service.PinComment(Viewer{ID: testStrangerID}, 1, &parent); !errors.Is(err, ErrForbidden) {
+		t.Errorf("expected only the author of the post to pin, got %v", err)
+	}
+	for _, id := range []int{2, 3} {
+		if err := service.PinComment(author, 1, &id); !errors.Is(err, domain.ErrInvalidPinnedComment) {
+			t.Errorf("comment %d: expected it not to be pinnable, got %v", id, err)
+		}
+	}
+	if err := service.PinComment(author, 1, &parent); err != nil {
+		t.Fatalf("pin: %v", err)
+	}
+	if !reflect.DeepEqual(pinned, []int{1}) {
+		t.Errorf("expected comment 1 to be pinned, got %v", pinned)
+	}
+
+	// The pinned comment does not take the place of another one.
+	listed, next, err := service.GetCommentsByEntityID(1, author, domain.CommentSortNewest, domain.PageRequest{Limit: 2})
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	var ids []int
+	for _, comment := range listed {
+		ids = append(ids, comment.ID)
+	}
+	if !reflect.DeepEqual(ids, []int{1, 10, 11}) || next == nil || next.ID != 11 {
+		t.Errorf("expected comments 1, 10 and 11 and a cursor after 11, got %v (next %+v)", ids, next)
+	}
+}
+
+func TestCommentServiceCountsStatusChanges(t *testing.T) {
+	parent := 1
+	comments := map[int]*domain.Comment{
+		1: {ID: 1, PostID: 1, AuthorID: testStrangerID, Status: domain.CommentActive},
+		2: {ID: 2, PostID: 1, AuthorID: testStrangerID, Status: domain.CommentActive, ParentID: &parent},
+	}
+	commentRepo := &infrastructure.MockCommentRepository{
+		GetCommentByIDFunc: func(id int) (*domain.Comment, error) {
+			comment := *comments[id]
+			return &comment, nil
+		},
+		SetHiddenByAuthorFunc: func(id int, hidden bool) error {
+			comments[id].Status = domain.CommentActive
+			if hidden {
+				comments[id].Status = domain.CommentHidden
+			}
+			return nil
+		},
+		DeleteFunc: func(id int) error {
+			comments[id].Status = domain.CommentDeleted
+			return nil
+		},
+	}
+	posts := map[int]*domain.Post{1: postWithVisibility(1, domain.Public)}
+	counters := &stubCounters{}
+	service := NewCommentService(commentRepo, nil, nil, newTestVisibilityPolicy(posts, nil), stubLinkPreviews{}, nil, nil, stubMentions{}, counters)
+	author := Viewer{ID: testAuthorID}
+
+	steps := []struct {
+		name     string
+		run      func() error
+		comments int
+		replies  int
+	}{
+		{"hide a comment", func() error { return service.SetCommentHidden(author, 1, true) }, -1, 0},
+		{"hide a reply", func() error { return service.SetCommentHidden(author, 2, true) }, -1, -1},
+		{"list the comment again", func() error { return service.SetCommentHidden(author, 1, false) }, 0, -1},
+		{"delete the comment", func() error { return service.DeleteComment(Viewer{ID: testStrangerID}, 1) }, -1, -1},
+		{"delete the hidden reply", func() error { return service.DeleteComment(Viewer{ID: testStrangerID}, 2) }, -1, -1},
+	}
+	for _, step := range steps {
+		if err := step.run(); err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+		if got := counters.added[domain.CounterComments][1]; got != step.comments {
+			t.Errorf("%s: expected comments to change by %d, got %d", step.name, step.comments, got)
+		}
+		if got := counters.added[domain.CounterReplies][1]; got != step.replies {
+			t.Errorf("%s: expected replies to change by %d, got %d", step.name, step.replies, got)
+		}
+	}
+}
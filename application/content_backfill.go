@@ -0,0 +1,44 @@
+package application
+
+import (
+	"github.com/bandvov/social-media-go/domain"
+	"github.com/bandvov/social-media-go/utils"
+)
+
+const contentBackfillBatchSize = 500
+
+// BackfillContentHTML renders the Markdown of posts and comments written
+// before content was stored as HTML. New content is rendered on write, so
+// this only has work to do once after the upgrade.
+func BackfillContentHTML(postRepo domain.PostRepository, commentRepo domain.CommentRepository) error {
+	for {
+		posts, err := postRepo.GetPostsWithoutHTML(contentBackfillBatchSize)
+		if err != nil {
+			return err
+		}
+		for _, post := range posts {
+			if err := postRepo.SetContentHTML(post.ID, utils.RenderMarkdown(post.Content)); err != nil {
+				return err
+			}
+		}
+		if len(posts) < contentBackfillBatchSize {
+			break
+		}
+	}
+
+	for {
+		comments, err := commentRepo.GetCommentsWithoutHTML(contentBackfillBatchSize)
+		if err != nil {
+			return err
+		}
+		for _, comment := range comments {
+			if err := commentRepo.SetContentHTML(comment.ID, utils.RenderMarkdown(comment.Content)); err != nil {
+				return err
+			}
+		}
+		if len(comments) < contentBackfillBatchSize {
+			break
+		}
+	}
+	return nil
+}
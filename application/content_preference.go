@@ -0,0 +1,35 @@
+package application
+
+import "github.com/bandvov/social-media-go/domain"
+
+// ApplyContentPreference prepares posts with a content warning or sensitive
+// media for the viewer: with the "hide" preference they are left out, with
+// "show" they are expanded and otherwise they are collapsed behind their
+// warning. The viewer's own posts are always expanded.
+func ApplyContentPreference(viewer Viewer, posts []domain.Post) []domain.Post {
+	shown := posts[:0]
+	for _, post := range posts {
+		if !post.IsFlagged() || post.AuthorID == viewer.ID {
+			shown = append(shown, post)
+			continue
+		}
+
+		switch viewer.SensitiveContent {
+		case domain.SensitiveContentHide:
+			continue
+		case domain.SensitiveContentShow:
+		default:
+			post.Collapsed = true
+		}
+		shown = append(shown, post)
+	}
+	return shown
+}
+
+// ApplyContentPreferenceToPost is ApplyContentPreference for a post that was
+// opened directly. It is never hidden, only collapsed.
+func ApplyContentPreferenceToPost(viewer Viewer, post *domain.Post) {
+	if post.IsFlagged() && post.AuthorID != viewer.ID && viewer.SensitiveContent != domain.SensitiveContentShow {
+		post.Collapsed = true
+	}
+}
@@ -0,0 +1,93 @@
+package application
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/bandvov/social-media-go/domain"
+	"github.com/bandvov/social-media-go/infrastructure"
+)
+
+func TestApplyContentPreference(t *testing.T) {
+	posts := []domain.Post{
+		{ID: 1, AuthorID: testAuthorID},
+		{ID: 2, AuthorID: testAuthorID, ContentWarning: "spoilers"},
+		{ID: 3, AuthorID: testAuthorID, Sensitive: true},
+		{ID: 4, AuthorID: testStrangerID, ContentWarning: "my own"},
+	}
+
+	tests := []struct {
+		preference string
+		shown      []int
+		collapsed  []int
+	}{
+		{preference: domain.SensitiveContentHide, shown: []int{1, 4}, collapsed: []int{}},
+		{preference: domain.SensitiveContentBlur, shown: []int{1, 2, 3, 4}, collapsed: []int{2, 3}},
+		{preference: domain.SensitiveContentShow, shown: []int{1, 2, 3, 4}, collapsed: []int{}},
+		{preference: "", shown: []int{1, 2, 3, 4}, collapsed: []int{2, 3}},
+	}
+
+	for _, tt := range tests {
+		t.Run("preference "+tt.preference, func(t *testing.T) {
+			viewer := Viewer{ID: testStrangerID, SensitiveContent: tt.preference}
+			shown, collapsed := []int{}, []int{}
+			for _, post := range ApplyContentPreference(viewer, append([]domain.Post(nil), posts...)) {
+				shown = append(shown, post.ID)
+				if post.Collapsed {
+					collapsed = append(collapsed, post.ID)
+				}
+			}
+			if !reflect.DeepEqual(shown, tt.shown) {
+				t.Errorf("expected posts %v to be shown, got %v", tt.shown, shown)
+			}
+			if !reflect.DeepEqual(collapsed, tt.collapsed) {
+				t.Errorf("expected posts %v to be collapsed, got %v", tt.collapsed, collapsed)
+			}
+		})
+	}
+}
+
+func TestApplyContentPreferenceToPost(t *testing.T) {
+	tests := []struct {
+		name      string
+		viewer    Viewer
+		collapsed bool
+	}{
+		{name: "hide only collapses an opened post", viewer: Viewer{ID: testStrangerID, SensitiveContent: domain.SensitiveContentHide}, collapsed: true},
+		{name: "blur", viewer: Viewer{ID: testStrangerID, SensitiveContent: domain.SensitiveContentBlur}, collapsed: true},
+		{name: "show", viewer: Viewer{ID: testStrangerID, SensitiveContent: domain.SensitiveContentShow}, collapsed: false},
+		{name: "author", viewer: Viewer{ID: testAuthorID, SensitiveContent: domain.SensitiveContentHide}, collapsed: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			post := &domain.Post{ID: 1, AuthorID: testAuthorID, Sensitive: true}
+			ApplyContentPreferenceToPost(tt.viewer, post)
+			if post.Collapsed != tt.collapsed {
+				t.Errorf("expected collapsed to be %v, got %v", tt.collapsed, post.Collapsed)
+			}
+		})
+	}
+}
+
+func TestPostServiceSetContentWarning(t *testing.T) {
+	var forced []string
+	postRepo := &infrastructure.MockPostRepository{
+		SetContentWarningFunc: func(postID int, warning string, sensitive bool) error {
+			forced = append(forced, warning)
+			return nil
+		},
+	}
+	service := NewPostService(postRepo, nil, nil, nil, nil, nil, nil, nil)
+
+	if err := service.SetContentWarning(1, Viewer{ID: testAuthorID}, "spoilers", false); !errors.Is(err, ErrForbidden) {
+		t.Errorf("expected the author to be refused, got %v", err)
+	}
+	if err := service.SetContentWarning(1, Viewer{ID: testAdminID, IsModerator: true}, "spoilers", true); err != nil {
+		t.Fatalf("moderator: %v", err)
+	}
+	if !reflect.DeepEqual(forced, []string{"spoilers"}) {
+		t.Errorf("expected only the moderator's warning to be stored, got %v", forced)
+	}
+}
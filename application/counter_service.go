@@ -0,0 +1,223 @@
+package application
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/bandvov/social-media-go/domain"
+)
+
+const (
+	// DefaultCounterFlushInterval is how often changed post counters are
+	// written back to Postgres.
+	DefaultCounterFlushInterval = 30 * time.Second
+	// DefaultCounterReconcileInterval is how often post counters are
+	// recounted from reactions and comments.
+	DefaultCounterReconcileInterval = time.Hour
+
+	counterFlushBatch     = 500
+	counterReconcileBatch = 500
+)
+
+// CounterServiceInterface defines methods for the counters shown with posts.
+type CounterServiceInterface interface {
+	GetPostCounters(postIDs []int) (map[int]domain.PostCounters, error)
+	Add(postID int, field string, delta int)
+}
+
+// CounterService keeps the reaction, comment and reply counts of posts in a
+// counter store, written back to denormalized columns in Postgres
+// (write-behind). Changes the store does not hear about, like reactions
+// removed along with their post, are repaired by reconciliation.
+type CounterService struct {
+	store domain.CounterStore
+	repo  domain.PostCounterRepository
+}
+
+func NewCounterService(store domain.CounterStore, repo domain.PostCounterRepository) *CounterService {
+	return &CounterService{store: store, repo: repo}
+}
+
+// GetPostCounters returns the counters of the given posts. Counters that are
+// not cached are loaded from Postgres and cached; when the store fails,
+// Postgres answers alone.
+func (s *CounterService) GetPostCounters(postIDs []int) (map[int]domain.PostCounters, error) {
+	counters, err := s.store.Get(postIDs)
+	if err != nil {
+		log.Printf("failed to read cached post counters: %v", err)
+		counters = make(map[int]domain.PostCounters, len(postIDs))
+	}
+
+	var missing []int
+	for _, postID := range postIDs {
+		if _, ok := counters[postID]; !ok {
+			missing = append(missing, postID)
+		}
+	}
+	if len(missing) == 0 {
+		return counters, nil
+	}
+
+	loaded, err := s.repo.GetPostCounters(missing)
+	if err != nil {
+		return nil, err
+	}
+	for _, counter := range loaded {
+		counters[counter.PostID] = counter
+	}
+	if err := s.store.Prime(loaded); err != nil {
+		log.Printf("failed to cache post counters: %v", err)
+	}
+	return counters, nil
+}
+
+// Add changes a counter of a post by delta. Counters are best effort: a
+// failure is logged and left to reconciliation.
+func (s *CounterService) Add(postID int, field string, delta int) {
+	changed, err := s.store.Increment(postID, field, delta)
+	if err == nil && !changed {
+		// Not cached: start from the flushed counters.
+		var loaded []domain.PostCounters
+		if loaded, err = s.repo.GetPostCounters([]int{postID}); err == nil {
+			if err = s.store.Prime(loaded); err == nil {
+				_, err = s.store.Increment(postID, field, delta)
+			}
+		}
+	}
+	if err != nil {
+		log.Printf("failed to count %s of post %d: %v", field, postID, err)
+	}
+}
+
+// Flush writes the counters that changed since the last flush to Postgres.
+func (s *CounterService) Flush() error {
+	for {
+		counters, err := s.store.TakeDirty(counterFlushBatch)
+		if err != nil {
+			return err
+		}
+		if len(counters) == 0 {
+			return nil
+		}
+
+		if err := s.repo.SavePostCounters(counters); err != nil {
+			postIDs := make([]int, 0, len(counters))
+			for _, counter := range counters {
+				postIDs = append(postIDs, counter.PostID)
+			}
+			if markErr := s.store.MarkDirty(postIDs); markErr != nil {
+				log.Printf("failed to keep %d post counters dirty: %v", len(postIDs), markErr)
+			}
+			return err
+		}
+		if len(counters) < counterFlushBatch {
+			return nil
+		}
+	}
+}
+
+// Reconcile recounts the counters of every post from reactions and comments,
+// repairing the ones that drifted: cached counters in the store, which the
+// next flush writes back, and the columns of posts that are not cached. It
+// returns how many counters were repaired.
+//
+// Counters are read before the recount and only replaced if they still hold
+// the same values: a change made meanwhile may be missing from the recount,
+// so such counters are left for the next run.
+func (s *CounterService) Reconcile() (int, error) {
+	repaired := 0
+	afterID := 0
+	for {
+		postIDs, err := s.repo.ListPostIDs(afterID, counterReconcileBatch)
+		if err != nil {
+			return repaired, err
+		}
+		if len(postIDs) == 0 {
+			return repaired, nil
+		}
+		afterID = postIDs[len(postIDs)-1]
+
+		cached, err := s.store.Get(postIDs)
+		if err != nil {
+			return repaired, err
+		}
+		columns, err := s.repo.GetPostCounters(postIDs)
+		if err != nil {
+			return repaired, err
+		}
+		stored := make(map[int]domain.PostCounters, len(columns))
+		for _, counter := range columns {
+			stored[counter.PostID] = counter
+		}
+		counters, err := s.repo.RecountPosts(postIDs)
+		if err != nil {
+			return repaired, err
+		}
+
+		var driftedCache, driftedColumns []domain.PostCounters
+		for _, counter := range counters {
+			if current, ok := cached[counter.PostID]; ok {
+				if current != counter {
+					driftedCache = append(driftedCache, counter)
+				}
+			} else if stored[counter.PostID] != counter {
+				driftedColumns = append(driftedColumns, counter)
+			}
+		}
+
+		replaced, err := s.store.Overwrite(driftedCache, cached)
+		if err != nil {
+			return repaired, err
+		}
+		fixed, err := s.repo.RepairPostCounters(driftedColumns, stored)
+		if err != nil {
+			return repaired, err
+		}
+		repaired += replaced + fixed
+
+		if len(postIDs) < counterReconcileBatch {
+			return repaired, nil
+		}
+	}
+}
+
+// RunFlush flushes changed counters every interval until the context is
+// cancelled.
+func (s *CounterService) RunFlush(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.Flush(); err != nil {
+				log.Printf("failed to flush post counters: %v", err)
+			}
+		}
+	}
+}
+
+// RunReconciliation recounts post counters every interval until the context
+// is cancelled.
+func (s *CounterService) RunReconciliation(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			drifted, err := s.Reconcile()
+			if err != nil {
+				log.Printf("failed to reconcile post counters: %v", err)
+			}
+			if drifted > 0 {
+				log.Printf("repaired %d drifted post counters", drifted)
+			}
+		}
+	}
+}
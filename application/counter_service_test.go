@@ -0,0 +1,281 @@
+package application
+
+import (
+	"testing"
+
+	"github.com/bandvov/social-media-go/domain"
+)
+
+// stubCounters adds up the changes of post counters, by field and post.
+type stubCounters struct {
+	added map[string]map[int]int
+}
+
+func (c *stubCounters) GetPostCounters(postIDs []int) (map[int]domain.PostCounters, error) {
+	return map[int]domain.PostCounters{}, nil
+}
+
+func (c *stubCounters) Add(postID int, field string, delta int) {
+	if c.added == nil {
+		c.added = make(map[string]map[int]int)
+	}
+	if c.added[field] == nil {
+		c.added[field] = make(map[int]int)
+	}
+	c.added[field][postID] += delta
+}
+
+type memoryCounterStore struct {
+	counters map[int]domain.PostCounters
+	dirty    map[int]bool
+}
+
+func newMemoryCounterStore() *memoryCounterStore {
+	return &memoryCounterStore{counters: make(map[int]domain.PostCounters), dirty: make(map[int]bool)}
+}
+
+func (s *memoryCounterStore) Get(postIDs []int) (map[int]domain.PostCounters, error) {
+	counters := make(map[int]domain.PostCounters)
+	for _, postID := range postIDs {
+		if counter, ok := s.counters[postID]; ok {
+			counters[postID] = counter
+		}
+	}
+	return counters, nil
+}
+
+func (s *memoryCounterStore) Prime(counters []domain.PostCounters) error {
+	for _, counter := range counters {
+		if _, ok := s.counters[counter.PostID]; !ok {
+			s.counters[counter.PostID] = counter
+		}
+	}
+	return nil
+}
+
+func (s *memoryCounterStore) Increment(postID int, field string, delta int) (bool, error) {
+	counter, ok := s.counters[postID]
+	if !ok {
+		return false, nil
+	}
+	switch field {
+	case domain.CounterReactions:
+		counter.Reactions += delta
+	case domain.CounterComments:
+		counter.Comments += delta
+	case domain.CounterReplies:
+		counter.Replies += delta
+	}
+	s.counters[postID] = counter
+	s.dirty[postID] = true
+	return true, nil
+}
+
+func (s *memoryCounterStore) Overwrite(counters []domain.PostCounters, expected map[int]domain.PostCounters) (int, error) {
+	replaced := 0
+	for _, counter := range counters {
+		current, ok := s.counters[counter.PostID]
+		if !ok || s.dirty[counter.PostID] || current != expected[counter.PostID] {
+			continue
+		}
+		s.counters[counter.PostID] = counter
+		s.dirty[counter.PostID] = true
+		replaced++
+	}
+	return replaced, nil
+}
+
+func (s *memoryCounterStore) TakeDirty(limit int) ([]domain.PostCounters, error) {
+	var counters []domain.PostCounters
+	for postID := range s.dirty {
+		if len(counters) == limit {
+			break
+		}
+		counters = append(counters, s.counters[postID])
+		delete(s.dirty, postID)
+	}
+	return counters, nil
+}
+
+func (s *memoryCounterStore) MarkDirty(postIDs []int) error {
+	for _, postID := range postIDs {
+		s.dirty[postID] = true
+	}
+	return nil
+}
+
+// stubPostCounterRepository holds the flushed columns and the counts the
+// source tables would give. afterRecount runs once, right after the next
+// recount, like a change landing while reconciliation is running.
+type stubPostCounterRepository struct {
+	columns      map[int]domain.PostCounters
+	actual       []domain.PostCounters
+	afterRecount func()
+}
+
+func (r *stubPostCounterRepository) GetPostCounters(postIDs []int) ([]domain.PostCounters, error) {
+	var counters []domain.PostCounters
+	for _, postID := range postIDs {
+		if counter, ok := r.columns[postID]; ok {
+			counters = append(counters, counter)
+		}
+	}
+	return counters, nil
+}
+
+func (r *stubPostCounterRepository) SavePostCounters(counters []domain.PostCounters) error {
+	for _, counter := range counters {
+		r.columns[counter.PostID] = counter
+	}
+	return nil
+}
+
+func (r *stubPostCounterRepository) RepairPostCounters(counters []domain.PostCounters, expected map[int]domain.PostCounters) (int, error) {
+	repaired := 0
+	for _, counter := range counters {
+		if current, ok := r.columns[counter.PostID]; ok && current == expected[counter.PostID] {
+			r.columns[counter.PostID] = counter
+			repaired++
+		}
+	}
+	return repaired, nil
+}
+
+func (r *stubPostCounterRepository) ListPostIDs(afterID, limit int) ([]int, error) {
+	var postIDs []int
+	for _, counter := range r.actual {
+		if counter.PostID > afterID && len(postIDs) < limit {
+			postIDs = append(postIDs, counter.PostID)
+		}
+	}
+	return postIDs, nil
+}
+
+func (r *stubPostCounterRepository) RecountPosts(postIDs []int) ([]domain.PostCounters, error) {
+	var counters []domain.PostCounters
+	for _, counter := range r.actual {
+		for _, postID := range postIDs {
+			if counter.PostID == postID {
+				counters = append(counters, counter)
+			}
+		}
+	}
+	if hook := r.afterRecount; hook != nil {
+		r.afterRecount = nil
+		hook()
+	}
+	return counters, nil
+}
+
+func TestCounterServiceWriteBehind(t *testing.T) {
+	store := newMemoryCounterStore()
+	repo := &stubPostCounterRepository{columns: map[int]domain.PostCounters{
+		1: {PostID: 1, Reactions: 4, Comments: 2},
+		2: {PostID: 2, Replies: 1},
+	}}
+	service := NewCounterService(store, repo)
+
+	// The first change of a counter that is not cached starts from the
+	// flushed columns.
+	service.Add(1, domain.CounterReactions, 1)
+	service.Add(1, domain.CounterReplies, 1)
+	counters, err := service.GetPostCounters([]int{1, 2, 3})
+	if err != nil {
+		t.Fatalf("get counters: %v", err)
+	}
+	if want := (domain.PostCounters{PostID: 1, Reactions: 5, Comments: 2, Replies: 1}); counters[1] != want {
+		t.Errorf("expected %+v, got %+v", want, counters[1])
+	}
+	if counters[2].Replies != 1 {
+		t.Errorf("expected post 2 to be loaded from its columns, got %+v", counters[2])
+	}
+	if _, ok := counters[3]; ok {
+		t.Errorf("expected no counters for a missing post, got %+v", counters[3])
+	}
+	if repo.columns[1].Reactions != 4 {
+		t.Errorf("expected changes to stay cached until flushed, got %+v", repo.columns[1])
+	}
+
+	if err := service.Flush(); err != nil {
+		t.Fatalf("flush: %v", err)
+	}
+	if repo.columns[1] != counters[1] {
+		t.Errorf("expected the flush to write %+v, got %+v", counters[1], repo.columns[1])
+	}
+	if len(store.dirty) != 0 {
+		t.Errorf("expected no dirty counters after the flush, got %v", store.dirty)
+	}
+
+	// A reply removed by a moderator was never counted down.
+	repo.actual = []domain.PostCounters{
+		{PostID: 1, Reactions: 5, Comments: 2},
+		{PostID: 2, Replies: 1},
+	}
+	drifted, err := service.Reconcile()
+	if err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	if drifted != 1 {
+		t.Errorf("expected one drifted counter, got %d", drifted)
+	}
+	if err := service.Flush(); err != nil {
+		t.Fatalf("flush: %v", err)
+	}
+	if store.counters[1] != repo.actual[0] || repo.columns[1] != repo.actual[0] {
+		t.Errorf("expected post 1 to be repaired to %+v, got %+v cached and %+v stored", repo.actual[0], store.counters[1], repo.columns[1])
+	}
+}
+
+func TestCounterServiceReconcileKeepsConcurrentChanges(t *testing.T) {
+	store := newMemoryCounterStore()
+	repo := &stubPostCounterRepository{columns: map[int]domain.PostCounters{
+		1: {PostID: 1, Reactions: 7},
+		2: {PostID: 2, Reactions: 3},
+	}}
+	service := NewCounterService(store, repo)
+	if _, err := service.GetPostCounters([]int{1}); err != nil {
+		t.Fatalf("get counters: %v", err)
+	}
+
+	// Both counters drifted, and a reaction to each lands between the
+	// recount and the repair: post 1 is cached, post 2 is not yet, and the
+	// changes are flushed before reconciliation writes anything.
+	repo.actual = []domain.PostCounters{{PostID: 1, Reactions: 5}, {PostID: 2, Reactions: 2}}
+	repo.afterRecount = func() {
+		service.Add(1, domain.CounterReactions, 1)
+		service.Add(2, domain.CounterReactions, 1)
+		if err := service.Flush(); err != nil {
+			t.Fatalf("flush: %v", err)
+		}
+	}
+	repaired, err := service.Reconcile()
+	if err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	if repaired != 0 {
+		t.Errorf("expected counters that changed during the recount to be left alone, %d were repaired", repaired)
+	}
+	if store.counters[1].Reactions != 8 || repo.columns[1].Reactions != 8 {
+		t.Errorf("expected post 1 to keep the new reaction, got %+v cached and %+v stored", store.counters[1], repo.columns[1])
+	}
+	if store.counters[2].Reactions != 4 || repo.columns[2].Reactions != 4 {
+		t.Errorf("expected post 2 to keep the new reaction, got %+v cached and %+v stored", store.counters[2], repo.columns[2])
+	}
+
+	// The next run repairs them.
+	repo.actual = []domain.PostCounters{{PostID: 1, Reactions: 6}, {PostID: 2, Reactions: 3}}
+	if repaired, err = service.Reconcile(); err != nil {
+		t.Fatalf("reconcile again: %v", err)
+	}
+	if err := service.Flush(); err != nil {
+		t.Fatalf("flush: %v", err)
+	}
+	if repaired != 2 {
+		t.Errorf("expected both counters to be repaired, got %d", repaired)
+	}
+	for i, counter := range repo.actual {
+		if store.counters[counter.PostID] != counter || repo.columns[counter.PostID] != counter {
+			t.Errorf("post %d: expected %+v, got %+v cached and %+v stored", i+1, counter, store.counters[counter.PostID], repo.columns[counter.PostID])
+		}
+	}
+}
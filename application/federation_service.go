@@ -0,0 +1,718 @@
+package application
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/bandvov/social-media-go/domain"
+	"github.com/bandvov/social-media-go/utils"
+	"golang.org/x/sync/errgroup"
+)
+
+const (
+	// DefaultDeliveryInterval is how often the delivery queue is polled.
+	DefaultDeliveryInterval = 10 * time.Second
+
+	deliveryBatchSize     = 50
+	deliveryConcurrency   = 8
+	deliveryLease         = 5 * time.Minute
+	maxDeliveryAttempts   = 8
+	baseDeliveryBackoff   = time.Minute
+	maxDeliveryBackoff    = 12 * time.Hour
+	remoteActorRefresh    = 24 * time.Hour
+	actorKeyBits          = 2048
+	federatedLikeReaction = "Like"
+)
+
+// PostPublisher sends local posts to the remote followers of their author.
+type PostPublisher interface {
+	PublishPost(post *domain.Post) error
+	PublishDelete(post *domain.Post) error
+}
+
+// FederationServiceInterface defines the ActivityPub endpoints.
+type FederationServiceInterface interface {
+	ActorURI(username string) string
+	WebFinger(resource string) (*domain.WebFinger, error)
+	GetActor(username string) (*domain.Actor, error)
+	GetOutbox(username string, page domain.PageRequest) ([]domain.Activity, *domain.Cursor, error)
+	GetNote(postID int) (*domain.Note, error)
+	PublicKey(keyID string) (*rsa.PublicKey, error)
+	HandleInbox(keyID string, activity *domain.Activity) error
+}
+
+// FederationService makes local users followable from other ActivityPub
+// servers. Remote actors are stored as shadow users so that their follows,
+// posts and likes go through the same repositories as local ones.
+type FederationService struct {
+	repo         domain.FederationRepository
+	client       domain.FederationClient
+	userRepo     domain.UserRepository
+	followerRepo domain.FollowerRepository
+	blockRepo    domain.BlockRepository
+	postRepo     domain.PostRepository
+	reactionRepo domain.ReactionRepository
+	counters     CounterServiceInterface
+	baseURL      string
+	host         string
+	keyBits      int
+	now          func() time.Time
+}
+
+func NewFederationService(repo domain.FederationRepository, client domain.FederationClient, userRepo domain.UserRepository, followerRepo domain.FollowerRepository, blockRepo domain.BlockRepository, postRepo domain.PostRepository, reactionRepo domain.ReactionRepository, counters CounterServiceInterface, baseURL string) *FederationService {
+	baseURL = strings.TrimRight(baseURL, "/")
+	host := baseURL
+	if parsed, err := url.Parse(baseURL); err == nil {
+		host = parsed.Host
+	}
+	return &FederationService{
+		repo:         repo,
+		client:       client,
+		userRepo:     userRepo,
+		followerRepo: followerRepo,
+		blockRepo:    blockRepo,
+		postRepo:     postRepo,
+		reactionRepo: reactionRepo,
+		counters:     counters,
+		baseURL:      baseURL,
+		host:         host,
+		keyBits:      actorKeyBits,
+		now:          time.Now,
+	}
+}
+
+func (s *FederationService) ActorURI(username string) string {
+	return s.baseURL + "/users/" + username
+}
+
+func (s *FederationService) keyID(username string) string {
+	return s.ActorURI(username) + "#main-key"
+}
+
+func (s *FederationService) noteURI(postID int) string {
+	return fmt.Sprintf("%s/posts/%d", s.baseURL, postID)
+}
+
+func (s *FederationService) newActivityID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return s.baseURL + "/activities/" + hex.EncodeToString(b)
+}
+
+// localUsername returns the username of a local actor URI.
+func (s *FederationService) localUsername(actorURI string) (string, bool) {
+	username, ok := strings.CutPrefix(actorURI, s.baseURL+"/users/")
+	if !ok || username == "" || strings.ContainsAny(username, "/#?") {
+		return "", false
+	}
+	return username, true
+}
+
+// localPostID returns the id of a local post from its URI.
+func (s *FederationService) localPostID(objectURI string) (int, bool) {
+	id, ok := strings.CutPrefix(objectURI, s.baseURL+"/posts/")
+	if !ok {
+		return 0, false
+	}
+	postID, err := strconv.Atoi(id)
+	return postID, err == nil
+}
+
+// localUser returns a federated local user. Shadow users of remote actors
+// and banned users are not exposed.
+func (s *FederationService) localUser(username string) (*domain.User, error) {
+	user, err := s.userRepo.GetUserByUsername(username)
+	if err != nil {
+		return nil, err
+	}
+	if user.Status == domain.UserStatusRemote || user.Status == domain.UserStatusBanned {
+		return nil, sql.ErrNoRows
+	}
+	return user, nil
+}
+
+// actorKey returns the user's signing key, creating it on first use.
+func (s *FederationService) actorKey(userID int) (*domain.ActorKey, error) {
+	key, err := s.repo.GetActorKey(userID)
+	if !errors.Is(err, sql.ErrNoRows) {
+		return key, err
+	}
+
+	publicPEM, privatePEM, err := utils.GenerateRSAKeyPair(s.keyBits)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.SaveActorKey(&domain.ActorKey{UserID: userID, PublicKeyPEM: publicPEM, PrivateKeyPEM: privatePEM}); err != nil {
+		return nil, err
+	}
+	// Another request may have created the key first.
+	return s.repo.GetActorKey(userID)
+}
+
+// WebFinger resolves `acct:user@host` or an actor URI to the actor.
+func (s *FederationService) WebFinger(resource string) (*domain.WebFinger, error) {
+	var username string
+	if acct, ok := strings.CutPrefix(resource, "acct:"); ok {
+		at := strings.LastIndexByte(acct, '@')
+		if at <= 0 || !strings.EqualFold(acct[at+1:], s.host) {
+			return nil, sql.ErrNoRows
+		}
+		username = acct[:at]
+	} else if name, ok := s.localUsername(resource); ok {
+		username = name
+	} else {
+		return nil, sql.ErrNoRows
+	}
+
+	if _, err := s.localUser(username); err != nil {
+		return nil, err
+	}
+
+	actorURI := s.ActorURI(username)
+	return &domain.WebFinger{
+		Subject: "acct:" + username + "@" + s.host,
+		Aliases: []string{actorURI},
+		Links: []domain.WebFingerLink{
+			{Rel: "self", Type: domain.ActivityContentType, Href: actorURI},
+			{Rel: "http://webfinger.net/rel/profile-page", Type: "text/html", Href: actorURI},
+		},
+	}, nil
+}
+
+func (s *FederationService) GetActor(username string) (*domain.Actor, error) {
+	user, err := s.localUser(username)
+	if err != nil {
+		return nil, err
+	}
+	key, err := s.actorKey(user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	actorURI := s.ActorURI(username)
+	return &domain.Actor{
+		Context:           []string{domain.ActivityStreamsContext, domain.SecurityContext},
+		ID:                actorURI,
+		Type:              "Person",
+		PreferredUsername: username,
+		Name:              username,
+		URL:               actorURI,
+		Inbox:             actorURI + "/inbox",
+		Outbox:            actorURI + "/outbox",
+		Endpoints:         &domain.ActorEndpoints{SharedInbox: s.baseURL + "/inbox"},
+		PublicKey: domain.ActorPublicKey{
+			ID:           s.keyID(username),
+			Owner:        actorURI,
+			PublicKeyPem: key.PublicKeyPEM,
+		},
+	}, nil
+}
+
+// GetOutbox returns a page of Create activities for the user's public posts.
+func (s *FederationService) GetOutbox(username string, page domain.PageRequest) ([]domain.Activity, *domain.Cursor, error) {
+	user, err := s.localUser(username)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	posts, err := s.postRepo.FindByUserID(user.ID, 0, []domain.PostVisibility{domain.Public}, page)
+	if err != nil {
+		return nil, nil, err
+	}
+	posts, next := domain.NextPage(posts, page, postCursor)
+
+	activities := make([]domain.Activity, 0, len(posts))
+	for i := range posts {
+		activity, err := s.createActivity(username, &posts[i])
+		if err != nil {
+			return nil, nil, err
+		}
+		activity.Context = nil
+		activities = append(activities, *activity)
+	}
+	return activities, next, nil
+}
+
+// GetNote returns a local public post as an ActivityPub Note.
+func (s *FederationService) GetNote(postID int) (*domain.Note, error) {
+	post, err := s.postRepo.GetByID(postID)
+	if err != nil {
+		return nil, err
+	}
+	if post.Visibility == nil || *post.Visibility != domain.Public {
+		return nil, sql.ErrNoRows
+	}
+	author, err := s.userRepo.GetUserByID(post.AuthorID)
+	if err != nil {
+		return nil, err
+	}
+	if author.Status == domain.UserStatusRemote || author.Username == nil {
+		return nil, sql.ErrNoRows
+	}
+
+	note := s.note(*author.Username, post)
+	note.Context = domain.ActivityStreamsContext
+	return note, nil
+}
+
+// note renders a post as a Note. A thread continuation replies to the post
+// it continues, so remote servers show the thread in order.
+func (s *FederationService) note(username string, post *domain.Post) *domain.Note {
+	note := &domain.Note{
+		ID:           s.noteURI(post.ID),
+		Type:         "Note",
+		AttributedTo: s.ActorURI(username),
+		Content:      post.ContentHTML,
+		Summary:      post.ContentWarning,
+		Sensitive:    post.IsFlagged(),
+		URL:          s.noteURI(post.ID),
+		Published:    post.CreatedAt.UTC().Format(time.RFC3339),
+		To:           domain.Audience{domain.PublicAudience},
+	}
+	if post.ContinuesPostID != nil {
+		note.InReplyTo = s.noteURI(*post.ContinuesPostID)
+	}
+	return note
+}
+
+func (s *FederationService) createActivity(username string, post *domain.Post) (*domain.Activity, error) {
+	object, err := json.Marshal(s.note(username, post))
+	if err != nil {
+		return nil, err
+	}
+	return &domain.Activity{
+		Context:   domain.ActivityStreamsContext,
+		ID:        s.noteURI(post.ID) + "/activity",
+		Type:      domain.ActivityCreate,
+		Actor:     s.ActorURI(username),
+		Object:    object,
+		To:        domain.Audience{domain.PublicAudience},
+		Published: post.CreatedAt.UTC().Format(time.RFC3339),
+	}, nil
+}
+
+// PublicKey resolves the key id of an incoming signature. Remote actors are
+// fetched on first sight and refreshed once a day, so rotated keys are
+// picked up.
+func (s *FederationService) PublicKey(keyID string) (*rsa.PublicKey, error) {
+	actor, err := s.repo.GetRemoteActorByKeyID(keyID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	if err != nil || s.now().Sub(actor.FetchedAt) > remoteActorRefresh {
+		actorURI, _, _ := strings.Cut(keyID, "#")
+		actor, err = s.refreshActor(actorURI)
+		if err != nil {
+			return nil, err
+		}
+		if actor.PublicKeyID != keyID {
+			return nil, domain.ErrInvalidSignature
+		}
+	}
+	return utils.ParseRSAPublicKey(actor.PublicKeyPEM)
+}
+
+func (s *FederationService) refreshActor(actorURI string) (*domain.RemoteActor, error) {
+	parsed, err := url.Parse(actorURI)
+	if err != nil || parsed.Host == "" || strings.EqualFold(parsed.Host, s.host) {
+		return nil, domain.ErrInvalidSignature
+	}
+
+	document, err := s.client.FetchActor(actorURI)
+	if err != nil {
+		return nil, err
+	}
+	actor := &domain.RemoteActor{
+		ActorURI:     document.ID,
+		Username:     document.PreferredUsername,
+		Domain:       parsed.Host,
+		InboxURL:     document.Inbox,
+		PublicKeyID:  document.PublicKey.ID,
+		PublicKeyPEM: document.PublicKey.PublicKeyPem,
+		FetchedAt:    s.now(),
+	}
+	if document.Endpoints != nil {
+		actor.SharedInbox = document.Endpoints.SharedInbox
+	}
+	if actor.Username == "" {
+		actor.Username = strings.TrimPrefix(parsed.Path, "/")
+	}
+	if err := s.repo.SaveRemoteActor(actor); err != nil {
+		return nil, err
+	}
+	return actor, nil
+}
+
+// HandleInbox applies an activity whose signature was made with keyID.
+// Activities of unsupported types are ignored.
+func (s *FederationService) HandleInbox(keyID string, activity *domain.Activity) error {
+	signer, err := s.repo.GetRemoteActorByKeyID(keyID)
+	if err != nil {
+		return err
+	}
+	if activity.Actor != signer.ActorURI {
+		return ErrForbidden
+	}
+
+	switch activity.Type {
+	case domain.ActivityFollow:
+		return s.handleFollow(signer, activity)
+	case domain.ActivityUndo:
+		return s.handleUndo(signer, activity)
+	case domain.ActivityCreate:
+		return s.handleCreate(signer, activity)
+	case domain.ActivityLike:
+		return s.handleLike(signer, activity)
+	}
+	return nil
+}
+
+func (s *FederationService) handleFollow(signer *domain.RemoteActor, activity *domain.Activity) error {
+	username, ok := s.localUsername(activity.ObjectID())
+	if !ok {
+		return domain.ErrInvalidActivity
+	}
+	user, err := s.localUser(username)
+	if err != nil {
+		return err
+	}
+
+	blocked, err := s.blockRepo.IsBlocked(signer.UserID, user.ID)
+	if err != nil {
+		return err
+	}
+	if blocked {
+		return ErrForbidden
+	}
+
+	following, err := s.followerRepo.IsFollowing(signer.UserID, user.ID)
+	if err != nil {
+		return err
+	}
+	if !following {
+		if err := s.followerRepo.AddFollower(domain.NewFollower(signer.UserID, user.ID)); err != nil {
+			return err
+		}
+	}
+
+	// Follows are accepted right away, so resending a Follow gets another
+	// Accept.
+	follow := *activity
+	follow.Context = nil
+	object, err := json.Marshal(follow)
+	if err != nil {
+		return err
+	}
+	accept, err := json.Marshal(domain.Activity{
+		Context: domain.ActivityStreamsContext,
+		ID:      s.newActivityID(),
+		Type:    domain.ActivityAccept,
+		Actor:   s.ActorURI(username),
+		Object:  object,
+	})
+	if err != nil {
+		return err
+	}
+	return s.repo.EnqueueDeliveries([]domain.Delivery{{SenderID: user.ID, InboxURL: signer.InboxURL, Activity: accept}})
+}
+
+func (s *FederationService) handleUndo(signer *domain.RemoteActor, activity *domain.Activity) error {
+	undone, err := activity.EmbeddedActivity()
+	if err != nil {
+		// Undo of an activity given by URI only, which we cannot map back.
+		return nil
+	}
+	if undone.Actor != signer.ActorURI {
+		return ErrForbidden
+	}
+
+	switch undone.Type {
+	case domain.ActivityFollow:
+		username, ok := s.localUsername(undone.ObjectID())
+		if !ok {
+			return domain.ErrInvalidActivity
+		}
+		user, err := s.localUser(username)
+		if err != nil {
+			return err
+		}
+		return s.followerRepo.RemoveFollower(domain.NewFollower(signer.UserID, user.ID))
+	case domain.ActivityLike:
+		postID, ok := s.localPostID(undone.ObjectID())
+		if !ok {
+			return nil
+		}
+		removed, err := s.reactionRepo.RemoveReaction(strconv.Itoa(signer.UserID), domain.ReactionEntityPost, strconv.Itoa(postID))
+		if err != nil {
+			return err
+		}
+		if removed {
+			s.counters.Add(postID, domain.CounterReactions, -1)
+		}
+	}
+	return nil
+}
+
+// handleCreate stores a public note of the signer as a post of its shadow
+// user. Remote HTML is reduced to text and rendered like local content.
+func (s *FederationService) handleCreate(signer *domain.RemoteActor, activity *domain.Activity) error {
+	var note domain.Note
+	if err := json.Unmarshal(activity.Object, &note); err != nil || note.ID == "" {
+		return domain.ErrInvalidActivity
+	}
+	if note.Type != "Note" {
+		return nil
+	}
+	if note.AttributedTo != signer.ActorURI {
+		return ErrForbidden
+	}
+	if !note.To.Contains(domain.PublicAudience) {
+		return nil
+	}
+	if postID, ok := s.localPostID(note.InReplyTo); ok {
+		post, err := s.postRepo.GetByID(postID)
+		if err == nil {
+			err = s.checkNotBlocked(signer, post)
+		} else if errors.Is(err, sql.ErrNoRows) {
+			err = nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	if _, err := s.repo.GetRemotePostID(note.ID); err == nil {
+		return nil
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+
+	content := utils.HTMLToText(note.Content)
+	if content == "" {
+		return nil
+	}
+	warning := note.Summary
+	if utf8.RuneCountInString(warning) > domain.MaxContentWarningLength {
+		warning = string([]rune(warning)[:domain.MaxContentWarningLength])
+	}
+
+	created, err := s.postRepo.Create(&domain.CreatePostRequest{
+		AuthorID:       signer.UserID,
+		Content:        content,
+		ContentHTML:    utils.RenderMarkdown(content),
+		Visibility:     domain.Public,
+		ContentWarning: warning,
+		Sensitive:      note.Sensitive,
+	})
+	if err != nil {
+		return err
+	}
+	return s.repo.SaveRemotePost(note.ID, created.ID)
+}
+
+func (s *FederationService) handleLike(signer *domain.RemoteActor, activity *domain.Activity) error {
+	postID, ok := s.localPostID(activity.ObjectID())
+	if !ok {
+		return nil
+	}
+	post, err := s.postRepo.GetByID(postID)
+	if err != nil {
+		return err
+	}
+	if post.Visibility == nil || *post.Visibility != domain.Public {
+		return sql.ErrNoRows
+	}
+	if err := s.checkNotBlocked(signer, post); err != nil {
+		return err
+	}
+
+	reactionTypeID, err := s.repo.GetReactionTypeID(federatedLikeReaction)
+	if err != nil {
+		return err
+	}
+	created, err := s.reactionRepo.AddOrUpdateReaction(signer.UserID, domain.Reaction{
+		EntityId:   postID,
+		EntityType: domain.ReactionEntityPost,
+		Reaction:   strconv.Itoa(reactionTypeID),
+	})
+	if err != nil {
+		return err
+	}
+	if created {
+		s.counters.Add(postID, domain.CounterReactions, 1)
+	}
+	return nil
+}
+
+// checkNotBlocked refuses a remote actor's reply or like on a local post when
+// the actor and the post's author blocked each other.
+func (s *FederationService) checkNotBlocked(signer *domain.RemoteActor, post *domain.Post) error {
+	blocked, err := s.blockRepo.IsBlocked(post.AuthorID, signer.UserID)
+	if err != nil {
+		return err
+	}
+	if blocked {
+		return ErrForbidden
+	}
+	return nil
+}
+
+// PublishPost queues a Create of a new public post for the author's remote
+// followers.
+func (s *FederationService) PublishPost(post *domain.Post) error {
+	if post.Visibility == nil || *post.Visibility != domain.Public {
+		return nil
+	}
+	username, ok, err := s.federatedAuthor(post.AuthorID)
+	if err != nil || !ok {
+		return err
+	}
+
+	activity, err := s.createActivity(username, post)
+	if err != nil {
+		return err
+	}
+	return s.publish(post.AuthorID, activity)
+}
+
+// PublishDelete queues a Delete of a removed public post for the author's
+// remote followers.
+func (s *FederationService) PublishDelete(post *domain.Post) error {
+	if post.Visibility == nil || *post.Visibility != domain.Public {
+		return nil
+	}
+	username, ok, err := s.federatedAuthor(post.AuthorID)
+	if err != nil || !ok {
+		return err
+	}
+
+	object, err := json.Marshal(map[string]string{"id": s.noteURI(post.ID), "type": "Tombstone"})
+	if err != nil {
+		return err
+	}
+	return s.publish(post.AuthorID, &domain.Activity{
+		Context: domain.ActivityStreamsContext,
+		ID:      s.newActivityID(),
+		Type:    domain.ActivityDelete,
+		Actor:   s.ActorURI(username),
+		Object:  object,
+		To:      domain.Audience{domain.PublicAudience},
+	})
+}
+
+// federatedAuthor returns the username of a local author. Posts of shadow
+// users and of users without a username are not federated.
+func (s *FederationService) federatedAuthor(userID int) (string, bool, error) {
+	author, err := s.userRepo.GetUserByID(userID)
+	if err != nil {
+		return "", false, err
+	}
+	if author.Status == domain.UserStatusRemote || author.Username == nil {
+		return "", false, nil
+	}
+	return *author.Username, true, nil
+}
+
+func (s *FederationService) publish(senderID int, activity *domain.Activity) error {
+	inboxes, err := s.repo.GetFollowerInboxes(senderID)
+	if err != nil || len(inboxes) == 0 {
+		return err
+	}
+	body, err := json.Marshal(activity)
+	if err != nil {
+		return err
+	}
+
+	deliveries := make([]domain.Delivery, 0, len(inboxes))
+	for _, inbox := range inboxes {
+		deliveries = append(deliveries, domain.Delivery{SenderID: senderID, InboxURL: inbox, Activity: body})
+	}
+	return s.repo.EnqueueDeliveries(deliveries)
+}
+
+// DeliverDue sends the queued activities that are due. Failed deliveries
+// are retried with exponential backoff and given up after
+// maxDeliveryAttempts.
+func (s *FederationService) DeliverDue() error {
+	now := s.now()
+	deliveries, err := s.repo.ClaimDeliveries(now, deliveryLease, deliveryBatchSize)
+	if err != nil {
+		return err
+	}
+
+	var g errgroup.Group
+	slots := make(chan struct{}, deliveryConcurrency)
+	for _, delivery := range deliveries {
+		delivery := delivery
+		g.Go(func() error {
+			slots <- struct{}{}
+			defer func() { <-slots }()
+
+			err := s.deliver(delivery)
+			if err == nil {
+				return s.repo.CompleteDelivery(delivery.ID)
+			}
+
+			attempts := delivery.Attempts + 1
+			if attempts >= maxDeliveryAttempts {
+				log.Printf("giving up delivery %d to %s: %v", delivery.ID, delivery.InboxURL, err)
+				return s.repo.FailDelivery(delivery.ID, attempts, err.Error())
+			}
+			return s.repo.RetryDelivery(delivery.ID, attempts, now.Add(deliveryBackoff(attempts)), err.Error())
+		})
+	}
+	return g.Wait()
+}
+
+func (s *FederationService) deliver(delivery domain.Delivery) error {
+	username, ok, err := s.federatedAuthor(delivery.SenderID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("user %d cannot send activities", delivery.SenderID)
+	}
+	key, err := s.actorKey(delivery.SenderID)
+	if err != nil {
+		return err
+	}
+	return s.client.Deliver(delivery.InboxURL, delivery.Activity, s.keyID(username), key.PrivateKeyPEM)
+}
+
+func deliveryBackoff(attempts int) time.Duration {
+	backoff := baseDeliveryBackoff << (attempts - 1)
+	if backoff > maxDeliveryBackoff || backoff <= 0 {
+		return maxDeliveryBackoff
+	}
+	return backoff
+}
+
+// RunDeliveries works through the delivery queue every interval until the
+// context is cancelled.
+func (s *FederationService) RunDeliveries(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.DeliverDue(); err != nil {
+				log.Printf("failed to deliver activities: %v", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package application
+
+import (
+	"sort"
+	"time"
+
+	"github.com/bandvov/social-media-go/domain"
+)
+
+// DefaultFanOutThreshold is the follower count from which an author's posts
+// are no longer pushed into follower timelines but pulled at read time.
+const DefaultFanOutThreshold = 10000
+
+// FeedServiceInterface defines methods for home timeline operations.
+type FeedServiceInterface interface {
+	FanOutPost(post *domain.Post) error
+	GetFeed(userID int, before *domain.Cursor, limit int) ([]domain.Post, *domain.Cursor, error)
+}
+
+// FeedService builds home timelines with a hybrid fan-out strategy: posts of
+// regular accounts are written into every follower's timeline, posts of
+// accounts with many followers are merged in when the feed is read.
+type FeedService struct {
+	feedRepo        domain.FeedRepository
+	followerRepo    domain.FollowerRepository
+	postRepo        domain.PostRepository
+	fanOutThreshold int
+}
+
+func NewFeedService(feedRepo domain.FeedRepository, followerRepo domain.FollowerRepository, postRepo domain.PostRepository, fanOutThreshold int) *FeedService {
+	return &FeedService{
+		feedRepo:        feedRepo,
+		followerRepo:    followerRepo,
+		postRepo:        postRepo,
+		fanOutThreshold: fanOutThreshold,
+	}
+}
+
+// FanOutPost pushes a freshly created post into the timelines that should
+// contain it. The author always gets their own post; followers only get
+// public and followers-only posts of accounts below the fan-out threshold.
+func (s *FeedService) FanOutPost(post *domain.Post) error {
+	entry := domain.TimelineEntry{PostID: post.ID, CreatedAt: post.CreatedAt}
+	recipients := []int{post.AuthorID}
+
+	if post.Visibility != nil && (*post.Visibility == domain.Public || *post.Visibility == domain.Followers) {
+		count, err := s.followerRepo.CountFollowers(post.AuthorID)
+		if err != nil {
+			return err
+		}
+		if count < s.fanOutThreshold {
+			followerIDs, err := s.followerRepo.GetFollowerIDs(post.AuthorID)
+			if err != nil {
+				return err
+			}
+			recipients = append(recipients, followerIDs...)
+		}
+	}
+
+	return s.feedRepo.AddToTimelines(recipients, entry)
+}
+
+// GetFeed returns a page of the user's home timeline, newest first, together
+// with the cursor for the next page (nil when there are no more posts).
+func (s *FeedService) GetFeed(userID int, before *domain.Cursor, limit int) ([]domain.Post, *domain.Cursor, error) {
+	entries, err := s.feedRepo.GetTimeline(userID, before, limit)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	postIDs := make([]int, 0, len(entries))
+	for _, entry := range entries {
+		postIDs = append(postIDs, entry.PostID)
+	}
+
+	pushed, err := s.postRepo.GetFeedPostsByIDs(userID, postIDs)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	popularIDs, err := s.followerRepo.GetPopularFolloweeIDs(userID, s.fanOutThreshold)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	pulled, err := s.postRepo.GetFeedPostsByAuthors(userID, popularIDs, before, limit)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	posts := mergeFeedPosts(pushed, pulled)
+
+	// Each source is read up to limit posts. Where a full source stops, the
+	// other one may still hold posts between there and its own stop, so the
+	// page ends at the newer of the two stops and anything older is read
+	// again on the next page.
+	var end *domain.Cursor
+	if len(entries) == limit {
+		last := entries[len(entries)-1]
+		end = &domain.Cursor{CreatedAt: last.CreatedAt, ID: last.PostID}
+	}
+	if len(pulled) == limit {
+		last := pulled[len(pulled)-1]
+		if end == nil || olderThanCursor(end.CreatedAt, end.ID, &domain.Cursor{CreatedAt: last.CreatedAt, ID: last.ID}) {
+			end = &domain.Cursor{CreatedAt: last.CreatedAt, ID: last.ID}
+		}
+	}
+	if end != nil {
+		kept := posts[:0]
+		for _, post := range posts {
+			if !olderThanCursor(post.CreatedAt, post.ID, end) {
+				kept = append(kept, post)
+			}
+		}
+		posts = kept
+	}
+
+	if len(posts) > limit {
+		posts = posts[:limit]
+		last := posts[len(posts)-1]
+		return posts, &domain.Cursor{CreatedAt: last.CreatedAt, ID: last.ID}, nil
+	}
+	// Both sources are read up to the end of the page, or exhausted when
+	// there is no end.
+	return posts, end, nil
+}
+
+// olderThanCursor reports whether a post comes after the cursor in a feed
+// ordered by (created_at, id) descending.
+func olderThanCursor(createdAt time.Time, id int, cursor *domain.Cursor) bool {
+	return createdAt.Before(cursor.CreatedAt) || (createdAt.Equal(cursor.CreatedAt) && id < cursor.ID)
+}
+
+// mergeFeedPosts combines pushed and pulled posts, drops duplicates and
+// orders them by (created_at, id) descending.
+func mergeFeedPosts(pushed, pulled []domain.Post) []domain.Post {
+	seen := make(map[int]bool, len(pushed)+len(pulled))
+	posts := make([]domain.Post, 0, len(pushed)+len(pulled))
+
+	for _, post := range append(pushed, pulled...) {
+		if seen[post.ID] {
+			continue
+		}
+		seen[post.ID] = true
+		posts = append(posts, post)
+	}
+
+	sort.Slice(posts, func(i, j int) bool {
+		if posts[i].CreatedAt.Equal(posts[j].CreatedAt) {
+			return posts[i].ID > posts[j].ID
+		}
+		return posts[i].CreatedAt.After(posts[j].CreatedAt)
+	})
+
+	return posts
+}
@@ -0,0 +1,226 @@
+package application
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/bandvov/social-media-go/domain"
+)
+
+const (
+	testFeedReaderID    = 1
+	testRegularAuthorID = 10
+	testPopularAuthorID = 20
+)
+
+// memoryTimelines is a fake timeline store with the same paging as Redis:
+// entries strictly older than the cursor, newest first.
+type memoryTimelines map[int][]domain.TimelineEntry
+
+func (m memoryTimelines) AddToTimelines(userIDs []int, entry domain.TimelineEntry) error {
+	for _, userID := range userIDs {
+		m[userID] = append(m[userID], entry)
+	}
+	return nil
+}
+
+func (m memoryTimelines) GetTimeline(userID int, before *domain.Cursor, limit int) ([]domain.TimelineEntry, error) {
+	var entries []domain.TimelineEntry
+	for _, entry := range m[userID] {
+		if before == nil || olderThanCursor(entry.CreatedAt, entry.PostID, before) {
+			entries = append(entries, entry)
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return olderThanCursor(entries[j].CreatedAt, entries[j].PostID, &domain.Cursor{CreatedAt: entries[i].CreatedAt, ID: entries[i].PostID})
+	})
+	if len(entries) > limit {
+		entries = entries[:limit]
+	}
+	return entries, nil
+}
+
+// memoryFeedPosts serves feed posts from memory: posts by ID in the order
+// asked for, and the newest posts of authors before the cursor.
+type memoryFeedPosts struct {
+	domain.PostRepository
+	posts []domain.Post
+}
+
+func (r memoryFeedPosts) GetFeedPostsByIDs(viewerID int, postIDs []int) ([]domain.Post, error) {
+	var found []domain.Post
+	for _, id := range postIDs {
+		for _, post := range r.posts {
+			if post.ID == id {
+				found = append(found, post)
+			}
+		}
+	}
+	return found, nil
+}
+
+func (r memoryFeedPosts) GetFeedPostsByAuthors(viewerID int, authorIDs []int, before *domain.Cursor, limit int) ([]domain.Post, error) {
+	var found []domain.Post
+	for _, post := range mergeFeedPosts(r.posts, nil) {
+		if contains(authorIDs, post.AuthorID) && (before == nil || olderThanCursor(post.CreatedAt, post.ID, before)) {
+			found = append(found, post)
+		}
+	}
+	if len(found) > limit {
+		found = found[:limit]
+	}
+	return found, nil
+}
+
+// popularFollowees reports the same popular followees for every user.
+type popularFollowees struct {
+	domain.FollowerRepository
+	ids []int
+}
+
+func (f popularFollowees) GetPopularFolloweeIDs(userID, minFollowers int) ([]int, error) {
+	return f.ids, nil
+}
+
+func contains(ids []int, id int) bool {
+	for _, other := range ids {
+		if other == id {
+			return true
+		}
+	}
+	return false
+}
+
+func TestFeedServiceGetFeed(t *testing.T) {
+	base := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	post := func(id, authorID, minute int) domain.Post {
+		return domain.Post{ID: id, AuthorID: authorID, CreatedAt: base.Add(time.Duration(minute) * time.Minute)}
+	}
+
+	tests := []struct {
+		name     string
+		posts    []domain.Post
+		timeline []int
+		popular  []int
+		limit    int
+		expected [][]int
+	}{
+		{
+			name: "timeline only",
+			posts: []domain.Post{
+				post(1, testRegularAuthorID, 1), post(2, testRegularAuthorID, 2), post(3, testRegularAuthorID, 3),
+				post(4, testRegularAuthorID, 4), post(5, testRegularAuthorID, 5),
+			},
+			timeline: []int{1, 2, 3, 4, 5},
+			limit:    2,
+			expected: [][]int{{5, 4}, {3, 2}, {1}},
+		},
+		{
+			name: "pulls posts of popular authors",
+			posts: []domain.Post{
+				post(1, testPopularAuthorID, 1), post(2, testRegularAuthorID, 2), post(3, testPopularAuthorID, 3),
+				post(4, testRegularAuthorID, 4), post(5, testPopularAuthorID, 5), post(6, testRegularAuthorID, 6),
+			},
+			timeline: []int{2, 4, 6},
+			popular:  []int{testPopularAuthorID},
+			limit:    2,
+			expected: [][]int{{6, 5}, {4, 3}, {2, 1}},
+		},
+		{
+			name: "one source runs ahead of the other",
+			posts: []domain.Post{
+				post(1, testRegularAuthorID, 1), post(2, testRegularAuthorID, 2), post(3, testRegularAuthorID, 3),
+				post(4, testPopularAuthorID, 4), post(5, testPopularAuthorID, 5), post(6, testPopularAuthorID, 6),
+			},
+			timeline: []int{1, 2, 3},
+			popular:  []int{testPopularAuthorID},
+			limit:    2,
+			// A full page from either source may not be the last one.
+			expected: [][]int{{6, 5}, {4, 3}, {2, 1}, {}},
+		},
+		{
+			// Posts fanned out before their author became popular are both
+			// in the timeline and pulled.
+			name: "skips duplicates across page boundaries",
+			posts: []domain.Post{
+				post(1, testRegularAuthorID, 1), post(2, testPopularAuthorID, 2), post(3, testPopularAuthorID, 3),
+				post(4, testRegularAuthorID, 4), post(5, testPopularAuthorID, 5), post(6, testRegularAuthorID, 6),
+			},
+			timeline: []int{1, 2, 3, 4, 5, 6},
+			popular:  []int{testPopularAuthorID},
+			limit:    2,
+			expected: [][]int{{6, 5}, {4, 3}, {2, 1}, {}},
+		},
+		{
+			name: "same time orders by id",
+			posts: []domain.Post{
+				post(1, testPopularAuthorID, 1), post(2, testRegularAuthorID, 1), post(3, testPopularAuthorID, 1),
+				post(4, testRegularAuthorID, 1),
+			},
+			timeline: []int{2, 4},
+			popular:  []int{testPopularAuthorID},
+			limit:    3,
+			expected: [][]int{{4, 3, 2}, {1}},
+		},
+		{
+			name: "deleted timeline entries",
+			posts: []domain.Post{
+				post(1, testRegularAuthorID, 1), post(2, testRegularAuthorID, 2),
+			},
+			timeline: []int{1, 2, 3, 4},
+			limit:    2,
+			expected: [][]int{{}, {2, 1}, {}},
+		},
+		{
+			// Post 4 is not read with the deleted entries 6 and 5; the page
+			// may not end after it on the pulled posts.
+			name: "filtered entries do not skip unread ones",
+			posts: []domain.Post{
+				post(2, testPopularAuthorID, 2), post(3, testPopularAuthorID, 3), post(4, testRegularAuthorID, 4),
+			},
+			timeline: []int{4, 5, 6},
+			popular:  []int{testPopularAuthorID},
+			limit:    2,
+			expected: [][]int{{}, {4, 3}, {2}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			timelines := memoryTimelines{}
+			for _, id := range tt.timeline {
+				createdAt := base.Add(time.Duration(id) * time.Minute)
+				for _, post := range tt.posts {
+					if post.ID == id {
+						createdAt = post.CreatedAt
+					}
+				}
+				timelines.AddToTimelines([]int{testFeedReaderID}, domain.TimelineEntry{PostID: id, CreatedAt: createdAt})
+			}
+			service := NewFeedService(timelines, popularFollowees{ids: tt.popular}, memoryFeedPosts{posts: tt.posts}, DefaultFanOutThreshold)
+
+			var pages [][]int
+			var before *domain.Cursor
+			for len(pages) <= len(tt.expected) {
+				posts, next, err := service.GetFeed(testFeedReaderID, before, tt.limit)
+				if err != nil {
+					t.Fatalf("get feed: %v", err)
+				}
+				ids := []int{}
+				for _, post := range posts {
+					ids = append(ids, post.ID)
+				}
+				pages = append(pages, ids)
+				if next == nil {
+					break
+				}
+				before = next
+			}
+			if !reflect.DeepEqual(pages, tt.expected) {
+				t.Errorf("expected pages %v, got %v", tt.expected, pages)
+			}
+		})
+	}
+}
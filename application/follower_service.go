@@ -10,25 +10,35 @@ import (
 type FollowerServiceInterface interface {
 	AddFollower(followerID, followeeID int) error
 	RemoveFollower(followerID, followeeID int) error
-	GetFollowers(userID, otherUser, limit, offset int, sort, orderBy, search string) ([]domain.User, error)
-	GetFollowees(userID, otherUser, limit, offset int, sort, orderBy, search string) ([]domain.User, error)
+	GetFollowers(userID, otherUser int, page domain.PageRequest, sort, search string) ([]domain.User, *domain.Cursor, error)
+	GetFollowees(userID, otherUser int, page domain.PageRequest, sort, search string) ([]domain.User, *domain.Cursor, error)
 }
 
 type FollowerService struct {
 	repo domain.FollowerRepository
+	spam SpamServiceInterface
 }
 
-func NewFollowerService(repo domain.FollowerRepository) *FollowerService {
-	return &FollowerService{repo: repo}
+func NewFollowerService(repo domain.FollowerRepository, spam SpamServiceInterface) *FollowerService {
+	return &FollowerService{repo: repo, spam: spam}
 }
 
-// AddFollower adds a follower for a given user
+// AddFollower adds a follower for a given user. Follows by shadow-limited
+// accounts are silently dropped.
 func (s *FollowerService) AddFollower(followerID, followeeID int) error {
 	// Business logic to prevent self-following
 	if followerID == followeeID {
 		return errors.New("user cannot follow themselves")
 	}
 
+	verdict, err := s.spam.Check(followerID, domain.SpamActivityFollow, "")
+	if err != nil {
+		return err
+	}
+	if verdict.ShadowLimited() {
+		return nil
+	}
+
 	follower := domain.NewFollower(followerID, followeeID)
 	return s.repo.AddFollower(follower)
 }
@@ -40,11 +50,29 @@ func (s *FollowerService) RemoveFollower(followerID, followeeID int) error {
 }
 
 // GetFollowers retrieves all followers for a user
-func (s *FollowerService) GetFollowers(userID, otherUser, limit, offset int, sort, orderBy, search string) ([]domain.User, error) {
-	return s.repo.GetFollowers(userID,otherUser, limit, offset, sort, orderBy, search)
+func (s *FollowerService) GetFollowers(userID, otherUser int, page domain.PageRequest, sort, search string) ([]domain.User, *domain.Cursor, error) {
+	users, err := s.repo.GetFollowers(userID, otherUser, page, sort, search)
+	if err != nil {
+		return nil, nil, err
+	}
+	users, next := domain.NextPage(users, page, followCursor)
+	return users, next, nil
 }
 
 // GetFollowers retrieves all followers for a user
-func (s *FollowerService) GetFollowees(userID, otherUser, limit, offset int, sort, orderBy, search string) ([]domain.User, error) {
-	return s.repo.GetFollowees(userID, otherUser, limit, offset, sort, orderBy, search)
+func (s *FollowerService) GetFollowees(userID, otherUser int, page domain.PageRequest, sort, search string) ([]domain.User, *domain.Cursor, error) {
+	users, err := s.repo.GetFollowees(userID, otherUser, page, sort, search)
+	if err != nil {
+		return nil, nil, err
+	}
+	users, next := domain.NextPage(users, page, followCursor)
+	return users, next, nil
+}
+
+func followCursor(user domain.User) domain.Cursor {
+	cursor := domain.Cursor{ID: user.ID}
+	if user.FollowedAt != nil {
+		cursor.CreatedAt = *user.FollowedAt
+	}
+	return cursor
 }
@@ -0,0 +1,83 @@
+package application
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/bandvov/social-media-go/domain"
+	"github.com/bandvov/social-media-go/utils"
+)
+
+const (
+	// DefaultLinkPreviewTTL is how long an unfurled URL is served from cache.
+	DefaultLinkPreviewTTL = 24 * time.Hour
+
+	// maxLinksPerContent caps how many URLs of one post or comment are unfurled.
+	maxLinksPerContent = 4
+
+	linkPreviewFetchTimeout = 10 * time.Second
+)
+
+// LinkPreviewServiceInterface defines methods for link preview cards.
+type LinkPreviewServiceInterface interface {
+	Unfurl(entityType string, entityID int, content string) error
+	RemoveLinks(entityType string, entityID int) error
+	GetPreviews(entityType string, entityIDs []int) (map[int][]domain.LinkPreview, error)
+}
+
+type LinkPreviewService struct {
+	repo    domain.LinkPreviewRepository
+	fetcher domain.LinkPreviewFetcher
+	ttl     time.Duration
+}
+
+func NewLinkPreviewService(repo domain.LinkPreviewRepository, fetcher domain.LinkPreviewFetcher, ttl time.Duration) *LinkPreviewService {
+	return &LinkPreviewService{repo: repo, fetcher: fetcher, ttl: ttl}
+}
+
+// Unfurl records the URLs found in the content of a post or comment and
+// fetches previews for the ones that are not cached yet. It talks to remote
+// servers, so callers run it in the background.
+func (s *LinkPreviewService) Unfurl(entityType string, entityID int, content string) error {
+	urls := utils.ExtractURLs(content, maxLinksPerContent)
+	if err := s.repo.SetLinks(entityType, entityID, urls); err != nil {
+		return err
+	}
+	if len(urls) == 0 {
+		return nil
+	}
+
+	cached, err := s.repo.GetCachedURLs(urls, time.Now().Add(-s.ttl))
+	if err != nil {
+		return err
+	}
+
+	for _, url := range urls {
+		if cached[url] {
+			continue
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), linkPreviewFetchTimeout)
+		preview, err := s.fetcher.Fetch(ctx, url)
+		cancel()
+		if err != nil {
+			// Remember the failure so the URL is not fetched again on every post.
+			log.Printf("failed to unfurl %s: %v", url, err)
+			preview = &domain.LinkPreview{URL: url, Failed: true}
+		}
+
+		if err := s.repo.SavePreview(preview); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *LinkPreviewService) RemoveLinks(entityType string, entityID int) error {
+	return s.repo.DeleteLinks(entityType, entityID)
+}
+
+func (s *LinkPreviewService) GetPreviews(entityType string, entityIDs []int) (map[int][]domain.LinkPreview, error) {
+	return s.repo.GetPreviewsByEntityIDs(entityType, entityIDs)
+}
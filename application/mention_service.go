@@ -0,0 +1,124 @@
+package application
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+
+	"github.com/bandvov/social-media-go/domain"
+	"github.com/bandvov/social-media-go/utils"
+)
+
+// MentionServiceInterface defines methods for @mentions.
+type MentionServiceInterface interface {
+	// RecordMentions resolves the @handles in the content of a post or
+	// comment and stores the mentioned users. With notify set, users
+	// mentioned for the first time who can see the post are notified.
+	RecordMentions(entityType string, entityID, authorID int, content string, post *domain.Post, notify bool) error
+	RemoveMentions(entityType string, entityID int) error
+	// GetMentions returns the mentions of posts or comments, given their
+	// content keyed by ID.
+	GetMentions(entityType string, contents map[int]string) (map[int][]domain.Mention, error)
+}
+
+// MentionService links @handles in posts and comments to users. Handles of
+// unknown users, of the author and of users blocked by or blocking the
+// author are left as plain text.
+type MentionService struct {
+	repo       domain.MentionRepository
+	userRepo   domain.UserRepository
+	blockRepo  domain.BlockRepository
+	visibility *VisibilityPolicy
+	notifier   domain.Notifier
+}
+
+func NewMentionService(repo domain.MentionRepository, userRepo domain.UserRepository, blockRepo domain.BlockRepository, visibility *VisibilityPolicy, notifier domain.Notifier) *MentionService {
+	return &MentionService{repo: repo, userRepo: userRepo, blockRepo: blockRepo, visibility: visibility, notifier: notifier}
+}
+
+func (s *MentionService) RecordMentions(entityType string, entityID, authorID int, content string, post *domain.Post, notify bool) error {
+	var userIDs []int
+	for _, username := range utils.ExtractMentions(content) {
+		if len(userIDs) == domain.MaxMentionsPerContent {
+			break
+		}
+		user, err := s.userRepo.GetUserByUsername(username)
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if user.ID == authorID {
+			continue
+		}
+		blocked, err := s.blockRepo.IsBlocked(authorID, user.ID)
+		if err != nil {
+			return err
+		}
+		if !blocked {
+			userIDs = append(userIDs, user.ID)
+		}
+	}
+
+	added, err := s.repo.SetMentions(entityType, entityID, userIDs)
+	if err != nil || !notify {
+		return err
+	}
+
+	notificationEntity := domain.NotificationEntityPost
+	if entityType == domain.MentionEntityComment {
+		notificationEntity = domain.NotificationEntityComment
+	}
+	for _, userID := range added {
+		// Users who cannot open the post are not told about it.
+		canView, err := s.visibility.CanView(Viewer{ID: userID}, post)
+		if err != nil {
+			return err
+		}
+		if !canView {
+			continue
+		}
+
+		err = s.notifier.Notify(domain.Notification{
+			UserID:     userID,
+			Type:       domain.NotificationNewMention,
+			EntityType: notificationEntity,
+			EntityID:   entityID,
+			SenderID:   authorID,
+		})
+		if err != nil {
+			log.Printf("failed to notify user %d of a mention in %s %d: %v", userID, entityType, entityID, err)
+		}
+	}
+	return nil
+}
+
+func (s *MentionService) RemoveMentions(entityType string, entityID int) error {
+	return s.repo.DeleteMentions(entityType, entityID)
+}
+
+func (s *MentionService) GetMentions(entityType string, contents map[int]string) (map[int][]domain.Mention, error) {
+	ids := make([]int, 0, len(contents))
+	for id := range contents {
+		ids = append(ids, id)
+	}
+	stored, err := s.repo.GetMentionsByEntityIDs(entityType, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	mentions := make(map[int][]domain.Mention)
+	for id, users := range stored {
+		userIDs := make(map[string]int, len(users))
+		for _, user := range users {
+			userIDs[user.Username] = user.UserID
+		}
+		for _, span := range utils.FindMentions(contents[id]) {
+			if userID, ok := userIDs[span.Username]; ok {
+				mentions[id] = append(mentions[id], domain.Mention{UserID: userID, Username: span.Username, Start: span.Start, End: span.End})
+			}
+		}
+	}
+	return mentions, nil
+}
@@ -0,0 +1,101 @@
+package application
+
+import (
+	"database/sql"
+	"reflect"
+	"testing"
+
+	"github.com/bandvov/social-media-go/domain"
+	"github.com/bandvov/social-media-go/infrastructure"
+)
+
+type stubMentionRepository struct {
+	mentions map[int][]int
+}
+
+func (r *stubMentionRepository) SetMentions(entityType string, entityID int, userIDs []int) ([]int, error) {
+	var added []int
+	for _, userID := range userIDs {
+		if !contains(r.mentions[entityID], userID) {
+			added = append(added, userID)
+		}
+	}
+	r.mentions[entityID] = userIDs
+	return added, nil
+}
+
+func (r *stubMentionRepository) DeleteMentions(entityType string, entityID int) error {
+	delete(r.mentions, entityID)
+	return nil
+}
+
+func (r *stubMentionRepository) GetMentionsByEntityIDs(entityType string, entityIDs []int) (map[int][]domain.Mention, error) {
+	usernames := map[int]string{testFollowerID: "follower", testStrangerID: "stranger"}
+	mentions := make(map[int][]domain.Mention)
+	for _, id := range entityIDs {
+		for _, userID := range r.mentions[id] {
+			mentions[id] = append(mentions[id], domain.Mention{UserID: userID, Username: usernames[userID]})
+		}
+	}
+	return mentions, nil
+}
+
+func TestMentionServiceRecordMentions(t *testing.T) {
+	userIDs := map[string]int{"author": testAuthorID, "follower": testFollowerID, "stranger": testStrangerID, "admin": testAdminID}
+	users := &infrastructure.MockUserRepository{
+		GetUserByUsernameFunc: func(username string) (*domain.User, error) {
+			id, ok := userIDs[username]
+			if !ok {
+				return nil, sql.ErrNoRows
+			}
+			return &domain.User{ID: id, Username: &username}, nil
+		},
+	}
+	repo := &stubMentionRepository{mentions: make(map[int][]int)}
+	blocks := stubBlocks{{testAdminID, testAuthorID}: true}
+	var notified []int
+	notifier := notifierFunc(func(notification domain.Notification) error {
+		if notification.Type != domain.NotificationNewMention || notification.SenderID != testAuthorID {
+			t.Errorf("unexpected notification %+v", notification)
+		}
+		notified = append(notified, notification.UserID)
+		return nil
+	})
+	post := postWithVisibility(1, domain.Followers)
+	service := NewMentionService(repo, users, blocks, newTestVisibilityPolicy(map[int]*domain.Post{1: post}, nil), notifier)
+
+	// Unknown users, the author and blocked users are not mentioned, and the
+	// stranger cannot see a followers-only post.
+	content := "hi @follower, @stranger, @nobody, @author and @admin"
+	if err := service.RecordMentions(domain.MentionEntityPost, 1, testAuthorID, content, post, true); err != nil {
+		t.Fatalf("record: %v", err)
+	}
+	if want := []int{testFollowerID, testStrangerID}; !reflect.DeepEqual(repo.mentions[1], want) {
+		t.Errorf("expected mentions %v, got %v", want, repo.mentions[1])
+	}
+	if want := []int{testFollowerID}; !reflect.DeepEqual(notified, want) {
+		t.Errorf("expected %v to be notified, got %v", want, notified)
+	}
+
+	// Editing the post does not notify users mentioned before.
+	notified = nil
+	content = "héllo @stranger and @follower"
+	if err := service.RecordMentions(domain.MentionEntityPost, 1, testAuthorID, content, post, true); err != nil {
+		t.Fatalf("record edit: %v", err)
+	}
+	if len(notified) != 0 {
+		t.Errorf("expected no notifications on edit, got %v", notified)
+	}
+
+	mentions, err := service.GetMentions(domain.MentionEntityPost, map[int]string{1: content})
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	want := []domain.Mention{
+		{UserID: testStrangerID, Username: "stranger", Start: 6, End: 15},
+		{UserID: testFollowerID, Username: "follower", Start: 20, End: 29},
+	}
+	if !reflect.DeepEqual(mentions[1], want) {
+		t.Errorf("expected mentions %+v, got %+v", want, mentions[1])
+	}
+}
@@ -3,29 +3,49 @@ package application
 import "github.com/bandvov/social-media-go/domain"
 
 type MockPostService struct {
-	CreatePostFunc   func(post *domain.CreatePostRequest) error
-	DeletePostFunc   func(id int) error
-	UpdatePostFunc   func(id int, post *domain.Post) error
-	GetPostByIDFunc  func(id int) (*domain.Post, error)
-	FindByUserIDFunc func(userID, otherUserId, offset, limit int) ([]domain.Post, error)
+	CreatePostFunc        func(post *domain.CreatePostRequest) (*domain.Post, error)
+	DeletePostFunc        func(id int, viewer Viewer) error
+	UpdatePostFunc        func(id int, viewer Viewer, post *domain.Post) error
+	SetContentWarningFunc func(postID int, viewer Viewer, warning string, sensitive bool) error
+	ReleasePostFunc       func(id int) error
+	GetPostByIDFunc       func(id int, viewer Viewer) (*domain.Post, error)
+	GetThreadFunc         func(id int, viewer Viewer) ([]domain.Post, error)
+	FindByUserIDFunc      func(userID int, viewer Viewer, page domain.PageRequest) ([]domain.Post, *domain.Cursor, []int, error)
+	GetCountPostsFunc     func(userID int) (int, error)
 }
 
-func (s *MockPostService) CreatePost(post *domain.CreatePostRequest) error {
+func (s *MockPostService) CreatePost(post *domain.CreatePostRequest) (*domain.Post, error) {
 	return s.CreatePostFunc(post)
 }
 
-func (s *MockPostService) DeletePost(id int) error {
-	return s.DeletePostFunc(id)
+func (s *MockPostService) DeletePost(id int, viewer Viewer) error {
+	return s.DeletePostFunc(id, viewer)
 }
 
-func (s *MockPostService) UpdatePost(id int, post *domain.Post) error {
-	return s.UpdatePostFunc(id, post)
+func (s *MockPostService) UpdatePost(id int, viewer Viewer, post *domain.Post) error {
+	return s.UpdatePostFunc(id, viewer, post)
 }
 
-func (s *MockPostService) GetPostByID(id int) (*domain.Post, error) {
-	return s.GetPostByIDFunc(id)
+func (s *MockPostService) SetContentWarning(postID int, viewer Viewer, warning string, sensitive bool) error {
+	return s.SetContentWarningFunc(postID, viewer, warning, sensitive)
 }
 
-func (s *MockPostService) GetPostsByUser(userID, otherUserId, offset, limit int) ([]domain.Post, error) {
-	return s.FindByUserIDFunc(userID, otherUserId, offset, limit)
+func (s *MockPostService) ReleasePost(id int) error {
+	return s.ReleasePostFunc(id)
+}
+
+func (s *MockPostService) GetPostByID(id int, viewer Viewer) (*domain.Post, error) {
+	return s.GetPostByIDFunc(id, viewer)
+}
+
+func (s *MockPostService) GetThread(id int, viewer Viewer) ([]domain.Post, error) {
+	return s.GetThreadFunc(id, viewer)
+}
+
+func (s *MockPostService) GetPostsByUser(userID int, viewer Viewer, page domain.PageRequest) ([]domain.Post, *domain.Cursor, []int, error) {
+	return s.FindByUserIDFunc(userID, viewer, page)
+}
+
+func (s *MockPostService) GetCountPostsByUser(userID int) (int, error) {
+	return s.GetCountPostsFunc(userID)
 }
@@ -5,15 +5,18 @@ import (
 )
 
 type MockUserService struct {
-	AuthenticateFunc       func(email, password string) (*domain.User, error)
-	RegisterUserFunc       func(user domain.CreateUserRequest) error
-	UpdateUserDataFunc     func(user *domain.User) error
-	ChangeUserRoleFunc     func(userID int, newRole string, isAdmin bool) error
-	FindByEmailFunc        func(email string) (*domain.User, error)
-	GetUserByIDFunc        func(id int) (*domain.User, error)
-	GetPublicProfilesFunc  func(limit, offset int) ([]domain.User, error)
-	GetAdminProfilesFunc   func(limit, offset int) ([]domain.User, error)
-	GetUserProfileInfoFunc func(id, otherUser int) (*domain.User, error)
+	AuthenticateFunc           func(email, password string) (*domain.User, error)
+	RegisterUserFunc           func(user domain.CreateUserRequest) error
+	UpdateUserDataFunc         func(user *domain.User) error
+	ChangeUserRoleFunc         func(userID int, newRole string, isAdmin bool) error
+	FindByEmailFunc            func(email string) (*domain.User, error)
+	GetUserByIDFunc            func(id int) (*domain.User, error)
+	GetPublicProfilesFunc      func(page domain.PageRequest) ([]domain.User, *domain.Cursor, error)
+	GetAdminProfilesFunc       func(limit, offset int) ([]domain.User, error)
+	GetUserProfileInfoFunc     func(id, otherUser int) (*domain.User, error)
+	GetUsersByIDsFunc          func(userIDs []int) (map[int]domain.User, error)
+	UpdateSensitiveContentFunc func(userID int, preference string) error
+	UpdatePrivacyFunc          func(userID int, private bool) error
 }
 
 func (m *MockUserService) Authenticate(email, password string) (*domain.User, error) {
@@ -35,8 +38,8 @@ func (m *MockUserService) FindByEmail(email string) (*domain.User, error) {
 func (m *MockUserService) GetUserByID(id int) (*domain.User, error) {
 	return m.GetUserByIDFunc(id)
 }
-func (m *MockUserService) GetPublicProfiles(limit, offset int) ([]domain.User, error) {
-	return m.GetPublicProfilesFunc(limit, offset)
+func (m *MockUserService) GetPublicProfiles(page domain.PageRequest) ([]domain.User, *domain.Cursor, error) {
+	return m.GetPublicProfilesFunc(page)
 }
 func (m *MockUserService) GetAdminProfiles(limit, offset int) ([]domain.User, error) {
 	return m.GetAdminProfilesFunc(limit, offset)
@@ -45,3 +48,15 @@ func (m *MockUserService) GetAdminProfiles(limit, offset int) ([]domain.User, er
 func (m *MockUserService) GetUserProfileInfo(id, otherUser int) (*domain.User, error) {
 	return m.GetUserProfileInfoFunc(id, otherUser)
 }
+
+func (m *MockUserService) GetUsersByIDs(userIDs []int) (map[int]domain.User, error) {
+	return m.GetUsersByIDsFunc(userIDs)
+}
+
+func (m *MockUserService) UpdateSensitiveContent(userID int, preference string) error {
+	return m.UpdateSensitiveContentFunc(userID, preference)
+}
+
+func (m *MockUserService) UpdatePrivacy(userID int, private bool) error {
+	return m.UpdatePrivacyFunc(userID, private)
+}
@@ -1,52 +1,331 @@
 package application
 
 import (
+	"database/sql"
+	"errors"
+	"log"
+
 	"github.com/bandvov/social-media-go/domain"
+	"github.com/bandvov/social-media-go/utils"
 )
 
 type PostServiceInterface interface {
-	CreatePost(post *domain.CreatePostRequest) error
-	DeletePost(id int) error
-	UpdatePost(id int, post *domain.Post) error
-	GetPostByID(id int) (*domain.Post, error)
-	GetPostsByUser(userID, offset, limit int) ([]domain.Post, []int, error)
+	CreatePost(post *domain.CreatePostRequest) (*domain.Post, error)
+	DeletePost(id int, viewer Viewer) error
+	UpdatePost(id int, viewer Viewer, post *domain.Post) error
+	SetContentWarning(postID int, viewer Viewer, warning string, sensitive bool) error
+	ReleasePost(id int) error
+	GetPostByID(id int, viewer Viewer) (*domain.Post, error)
+	GetThread(id int, viewer Viewer) ([]domain.Post, error)
+	GetPostsByUser(userID int, viewer Viewer, page domain.PageRequest) ([]domain.Post, *domain.Cursor, []int, error)
 	GetCountPostsByUser(userID int) (int, error)
 }
 
+var ErrForbidden = errors.New("access forbidden")
+
 type PostService struct {
-	postRepo domain.PostRepository
+	postRepo     domain.PostRepository
+	feedService  FeedServiceInterface
+	visibility   *VisibilityPolicy
+	linkPreviews LinkPreviewServiceInterface
+	publisher    PostPublisher
+	automod      AutomodServiceInterface
+	spam         SpamServiceInterface
+	mentions     MentionServiceInterface
+}
+
+func NewPostService(repo domain.PostRepository, feedService FeedServiceInterface, visibility *VisibilityPolicy, linkPreviews LinkPreviewServiceInterface, publisher PostPublisher, automod AutomodServiceInterface, spam SpamServiceInterface, mentions MentionServiceInterface) *PostService {
+	return &PostService{postRepo: repo, feedService: feedService, visibility: visibility, linkPreviews: linkPreviews, publisher: publisher, automod: automod, spam: spam, mentions: mentions}
+}
+
+func (s *PostService) CreatePost(post *domain.CreatePostRequest) (*domain.Post, error) {
+	if err := domain.ValidateContentWarning(post.ContentWarning); err != nil {
+		return nil, err
+	}
+	if post.CommentPolicy == "" {
+		post.CommentPolicy = domain.CommentPolicyEveryone
+	}
+	if err := post.CommentPolicy.Validate(); err != nil {
+		return nil, err
+	}
+	post.ContentHTML = utils.RenderMarkdown(post.Content)
+
+	if post.ContinuesPostID != nil {
+		previous, err := s.postRepo.GetByID(*post.ContinuesPostID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, domain.ErrInvalidThread
+		}
+		if err != nil {
+			return nil, err
+		}
+		if previous.AuthorID != post.AuthorID {
+			return nil, domain.ErrInvalidThread
+		}
+		// A thread is read as a whole, so every post shares the visibility
+		// of the first one.
+		if previous.Visibility != nil {
+			post.Visibility = *previous.Visibility
+		}
+	}
+
+	spamVerdict, err := s.spam.Check(post.AuthorID, domain.SpamActivityPost, post.Content)
+	if err != nil {
+		return nil, err
+	}
+
+	verdict, err := s.automod.Evaluate(domain.AutomodScopePosts, post.Content)
+	if err != nil {
+		return nil, err
+	}
+	if verdict.Action == domain.AutomodReject {
+		return nil, domain.ErrContentRejected
+	}
+	if verdict.ContentWarning != "" {
+		if post.ContentWarning == "" {
+			post.ContentWarning = verdict.ContentWarning
+		}
+		post.WarningForced = true
+	}
+	post.HoldForReview = verdict.Held() || spamVerdict.Held()
+
+	created, err := s.postRepo.Create(post)
+	if err != nil {
+		return nil, err
+	}
+
+	if created.HeldForReview {
+		// Held posts are hidden, they reach timelines and remote servers
+		// only if a moderator releases them.
+		if verdict.Held() {
+			err = s.automod.HoldForReview(domain.ReportTargetPost, created.ID, created.AuthorID, verdict)
+		} else {
+			err = s.spam.HoldForReview(domain.ReportTargetPost, created.ID, created.AuthorID, spamVerdict)
+		}
+		if err != nil {
+			log.Printf("failed to queue held post %d for review: %v", created.ID, err)
+		}
+		go s.unfurlLinks(created.ID, created.Content)
+		go s.recordMentions(created, false)
+		return created, nil
+	}
+	if spamVerdict.ShadowLimited() {
+		// Posts of shadow-limited accounts stay on their profile but are
+		// not pushed to timelines or remote servers.
+		go s.unfurlLinks(created.ID, created.Content)
+		go s.recordMentions(created, false)
+		return created, nil
+	}
+
+	go s.unfurlLinks(created.ID, created.Content)
+	s.distribute(created)
+	return created, nil
+}
+
+// distribute sends a visible post to follower timelines, mentioned users and
+// remote servers. Fan-out can touch thousands of timelines and federation
+// talks to remote servers, so the caller does not wait for either.
+func (s *PostService) distribute(post *domain.Post) {
+	go func() {
+		if err := s.feedService.FanOutPost(post); err != nil {
+			log.Printf("failed to fan out post %d: %v", post.ID, err)
+		}
+	}()
+	go s.recordMentions(post, true)
+	go func() {
+		if err := s.publisher.PublishPost(post); err != nil {
+			log.Printf("failed to federate post %d: %v", post.ID, err)
+		}
+	}()
+}
+
+// DeletePost removes a post together with its bookmarks, links and mentions.
+// Only the author or a moderator may delete a post.
+func (s *PostService) DeletePost(id int, viewer Viewer) error {
+	post, err := s.postRepo.GetByID(id)
+	if err != nil {
+		return err
+	}
+	if post.AuthorID != viewer.ID && !viewer.IsModerator {
+		return ErrForbidden
+	}
+
+	if err := s.postRepo.Delete(id); err != nil {
+		return err
+	}
+
+	if err := s.publisher.PublishDelete(post); err != nil {
+		log.Printf("failed to federate deletion of post %d: %v", id, err)
+	}
+	return nil
+}
+
+// UpdatePost edits a post. Only the author or an admin may edit a post, and a
+// content warning forced by a moderator can only be changed by a moderator,
+// as can the visibility of a hidden post. A thread shares the visibility of
+// its first post, so it is changed there for the whole thread. Edits go
+// through auto-moderation like new posts.
+func (s *PostService) UpdatePost(id int, viewer Viewer, post *domain.Post) error {
+	if err := domain.ValidateContentWarning(post.ContentWarning); err != nil {
+		return err
+	}
+
+	existing, err := s.postRepo.GetByID(id)
+	if err != nil {
+		return err
+	}
+	if existing.AuthorID != viewer.ID && !viewer.IsAdmin {
+		return ErrForbidden
+	}
+	if existing.WarningForced && !viewer.IsModerator {
+		post.ContentWarning = existing.ContentWarning
+		post.Sensitive = existing.Sensitive
+	}
+	if existing.Visibility != nil && *existing.Visibility == domain.Hidden && !viewer.IsModerator {
+		post.Visibility = existing.Visibility
+	}
+	visibilityChanged := post.Visibility != nil && existing.Visibility != nil && *post.Visibility != *existing.Visibility
+	if visibilityChanged && existing.ContinuesPostID != nil {
+		return domain.ErrThreadVisibility
+	}
+	post.ContentHTML = utils.RenderMarkdown(post.Content)
+
+	verdict, err := s.automod.Evaluate(domain.AutomodScopePosts, post.Content)
+	if err != nil {
+		return err
+	}
+	if verdict.Action == domain.AutomodReject {
+		return domain.ErrContentRejected
+	}
+	if verdict.ContentWarning != "" && post.ContentWarning == "" {
+		post.ContentWarning = verdict.ContentWarning
+	}
+
+	if err := s.postRepo.Update(id, post); err != nil {
+		return err
+	}
+	if visibilityChanged {
+		if err := s.postRepo.SetThreadVisibility(id, *post.Visibility); err != nil {
+			return err
+		}
+	}
+	if verdict.ContentWarning != "" {
+		if err := s.postRepo.SetContentWarning(id, post.ContentWarning, post.Sensitive); err != nil {
+			return err
+		}
+	}
+	if verdict.Held() {
+		if err := s.postRepo.Hold(id); err != nil {
+			return err
+		}
+		if err := s.automod.HoldForReview(domain.ReportTargetPost, id, existing.AuthorID, verdict); err != nil {
+			log.Printf("failed to queue held post %d for review: %v", id, err)
+		}
+	}
+
+	go s.unfurlLinks(id, post.Content)
+
+	// Users mentioned by the edit are notified, unless the post is held.
+	edited := *existing
+	edited.Content = post.Content
+	if post.Visibility != nil {
+		edited.Visibility = post.Visibility
+	}
+	go s.recordMentions(&edited, !verdict.Held() && !existing.HeldForReview)
+	return nil
 }
 
-func NewPostService(repo domain.PostRepository) *PostService {
-	return &PostService{postRepo: repo}
+// SetContentWarning lets a moderator put a content warning on someone else's
+// post. The author cannot remove it afterwards.
+func (s *PostService) SetContentWarning(postID int, viewer Viewer, warning string, sensitive bool) error {
+	if !viewer.IsModerator {
+		return ErrForbidden
+	}
+	if err := domain.ValidateContentWarning(warning); err != nil {
+		return err
+	}
+	return s.postRepo.SetContentWarning(postID, warning, sensitive)
 }
 
-func (s *PostService) CreatePost(post *domain.CreatePostRequest) error {
-	return s.postRepo.Create(post)
+// ReleasePost gives a post held for review its visibility back and sends it
+// where it would have gone had it not been held. It returns sql.ErrNoRows
+// when the post is not held.
+func (s *PostService) ReleasePost(id int) error {
+	if err := s.postRepo.Release(id); err != nil {
+		return err
+	}
+	post, err := s.postRepo.GetByID(id)
+	if err != nil {
+		return err
+	}
+	s.distribute(post)
+	return nil
+}
+
+func (s *PostService) unfurlLinks(postID int, content string) {
+	if err := s.linkPreviews.Unfurl(domain.LinkEntityPost, postID, content); err != nil {
+		log.Printf("failed to unfurl links of post %d: %v", postID, err)
+	}
 }
 
-func (s *PostService) DeletePost(id int) error {
-	return s.postRepo.Delete(id)
+func (s *PostService) recordMentions(post *domain.Post, notify bool) {
+	if err := s.mentions.RecordMentions(domain.MentionEntityPost, post.ID, post.AuthorID, post.Content, post, notify); err != nil {
+		log.Printf("failed to record mentions of post %d: %v", post.ID, err)
+	}
 }
 
-func (s *PostService) UpdatePost(id int, post *domain.Post) error {
-	return s.postRepo.Update(id, post)
+// GetPostByID returns the post if the viewer is allowed to see it.
+func (s *PostService) GetPostByID(id int, viewer Viewer) (*domain.Post, error) {
+	return s.visibility.CheckPost(viewer, id)
 }
 
-func (s *PostService) GetPostByID(id int) (*domain.Post, error) {
-	return s.postRepo.GetByID(id)
+// GetThread returns the thread the post belongs to, first post first,
+// leaving out the posts the viewer is not allowed to see.
+func (s *PostService) GetThread(id int, viewer Viewer) ([]domain.Post, error) {
+	post, err := s.visibility.CheckPost(viewer, id)
+	if err != nil {
+		return nil, err
+	}
+
+	posts, err := s.postRepo.GetThread(post.ThreadRoot())
+	if err != nil {
+		return nil, err
+	}
+
+	visible := posts[:0]
+	for _, p := range posts {
+		ok, err := s.visibility.CanView(viewer, &p)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			visible = append(visible, p)
+		}
+	}
+	return visible, nil
 }
 
-func (s *PostService) GetPostsByUser(authorID, offset, limit int) ([]domain.Post, []int, error) {
-	posts, err := s.postRepo.GetPosts(authorID, offset, limit)
+// GetPostsByUser lists the author's posts that the viewer is allowed to see.
+func (s *PostService) GetPostsByUser(authorID int, viewer Viewer, page domain.PageRequest) ([]domain.Post, *domain.Cursor, []int, error) {
+	visibilities, err := s.visibility.ListableVisibilities(viewer, authorID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	posts, err := s.postRepo.GetPosts(authorID, visibilities, page)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
-	postIDs := make([]int, len(posts))
+	posts, next := domain.NextPage(posts, page, postCursor)
+
+	postIDs := make([]int, 0, len(posts))
 	for _, post := range posts {
 		postIDs = append(postIDs, post.ID)
 	}
-	return posts, postIDs, err
+	return posts, next, postIDs, nil
+}
+
+func postCursor(post domain.Post) domain.Cursor {
+	return domain.Cursor{CreatedAt: post.CreatedAt, ID: post.ID}
 }
 
 func (s *PostService) GetCountPostsByUser(userID int) (int, error) {
@@ -0,0 +1,323 @@
+package application
+
+import (
+	"database/sql"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/bandvov/social-media-go/domain"
+	"github.com/bandvov/social-media-go/infrastructure"
+)
+
+type stubFeed struct{}
+
+func (stubFeed) FanOutPost(post *domain.Post) error { return nil }
+
+func (stubFeed) GetFeed(userID int, before *domain.Cursor, limit int) ([]domain.Post, *domain.Cursor, error) {
+	return nil, nil, nil
+}
+
+type stubPostPublisher struct{}
+
+func (stubPostPublisher) PublishPost(post *domain.Post) error   { return nil }
+func (stubPostPublisher) PublishDelete(post *domain.Post) error { return nil }
+
+type stubLinkPreviews struct{}
+
+func (stubLinkPreviews) Unfurl(entityType string, entityID int, content string) error { return nil }
+
+func (stubLinkPreviews) RemoveLinks(entityType string, entityID int) error { return nil }
+
+func (stubLinkPreviews) GetPreviews(entityType string, entityIDs []int) (map[int][]domain.LinkPreview, error) {
+	return nil, nil
+}
+
+func intPtr(i int) *int { return &i }
+
+func TestPostServiceCreateContinuation(t *testing.T) {
+	posts := map[int]*domain.Post{
+		1: postWithVisibility(1, domain.Followers),
+		2: {ID: 2, AuthorID: testStrangerID},
+	}
+	var stored []domain.CreatePostRequest
+	postRepo := &infrastructure.MockPostRepository{
+		GetByIDFunc: func(id int) (*domain.Post, error) {
+			if post, ok := posts[id]; ok {
+				return post, nil
+			}
+			return nil, sql.ErrNoRows
+		},
+		CreateFunc: func(post *domain.CreatePostRequest) (*domain.Post, error) {
+			stored = append(stored, *post)
+			visibility := post.Visibility
+			return &domain.Post{ID: 10, AuthorID: post.AuthorID, Visibility: &visibility, ContinuesPostID: post.ContinuesPostID}, nil
+		},
+	}
+	spam, _ := newTestSpamService()
+	automod := NewAutomodService(&stubAutomodRepository{}, nil, DefaultAutomodRefreshInterval)
+	service := NewPostService(postRepo, stubFeed{}, nil, stubLinkPreviews{}, stubPostPublisher{}, automod, spam, stubMentions{})
+
+	tests := []struct {
+		name       string
+		continues  int
+		expected   error
+		visibility domain.PostVisibility
+	}{
+		// A thread is read as a whole: the continuation is as visible as
+		// the post it continues, whatever was asked for.
+		{name: "own post", continues: 1, visibility: domain.Followers},
+		{name: "someone else's post", continues: 2, expected: domain.ErrInvalidThread},
+		{name: "missing post", continues: 3, expected: domain.ErrInvalidThread},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stored = nil
+			_, err := service.CreatePost(&domain.CreatePostRequest{
+				AuthorID:        testAuthorID,
+				Content:         "and another thing",
+				Visibility:      domain.Public,
+				ContinuesPostID: intPtr(tt.continues),
+			})
+			if !errors.Is(err, tt.expected) {
+				t.Fatalf("expected error %v, got %v", tt.expected, err)
+			}
+			if tt.expected != nil {
+				if len(stored) != 0 {
+					t.Errorf("expected nothing to be stored, got %+v", stored)
+				}
+				return
+			}
+			if len(stored) != 1 || stored[0].Visibility != tt.visibility {
+				t.Errorf("expected the continuation to be stored as %q, got %+v", tt.visibility, stored)
+			}
+		})
+	}
+}
+
+func TestPostServiceGetThread(t *testing.T) {
+	thread := []domain.Post{
+		*postWithVisibility(1, domain.Public),
+		*postWithVisibility(2, domain.Public),
+		*postWithVisibility(3, domain.Private),
+	}
+	for i := 1; i < len(thread); i++ {
+		thread[i].ContinuesPostID = intPtr(thread[i-1].ID)
+		thread[i].ThreadRootID = intPtr(1)
+	}
+	posts := map[int]*domain.Post{}
+	for i := range thread {
+		posts[thread[i].ID] = &thread[i]
+	}
+
+	var rootIDs []int
+	postRepo := &infrastructure.MockPostRepository{
+		GetThreadFunc: func(rootID int) ([]domain.Post, error) {
+			rootIDs = append(rootIDs, rootID)
+			return append([]domain.Post(nil), thread...), nil
+		},
+	}
+	service := NewPostService(postRepo, nil, newTestVisibilityPolicy(posts, nil), nil, nil, nil, nil, nil)
+
+	got, err := service.GetThread(2, Viewer{ID: testStrangerID})
+	if err != nil {
+		t.Fatalf("get thread: %v", err)
+	}
+	ids := []int{}
+	for _, post := range got {
+		ids = append(ids, post.ID)
+	}
+	if !reflect.DeepEqual(ids, []int{1, 2}) || !reflect.DeepEqual(rootIDs, []int{1}) {
+		t.Errorf("expected the visible posts of thread 1, got %v from %v", ids, rootIDs)
+	}
+
+	if _, err := service.GetThread(3, Viewer{ID: testStrangerID}); !errors.Is(err, ErrForbidden) {
+		t.Errorf("expected a private post to be refused, got %v", err)
+	}
+}
+
+func TestPostServiceUpdateThreadVisibility(t *testing.T) {
+	// Thread 1 <- 2, both for followers.
+	posts := map[int]*domain.Post{
+		1: postWithVisibility(1, domain.Followers),
+		2: postWithVisibility(2, domain.Followers),
+	}
+	posts[2].ContinuesPostID = intPtr(1)
+	posts[2].ThreadRootID = intPtr(1)
+
+	var updated []int
+	var threads []domain.PostVisibility
+	postRepo := &infrastructure.MockPostRepository{
+		GetByIDFunc: func(id int) (*domain.Post, error) {
+			return posts[id], nil
+		},
+		UpdateFunc: func(id int, post *domain.Post) error {
+			updated = append(updated, id)
+			return nil
+		},
+		SetThreadVisibilityFunc: func(rootID int, visibility domain.PostVisibility) error {
+			threads = append(threads, visibility)
+			return nil
+		},
+	}
+	automod := NewAutomodService(&stubAutomodRepository{}, nil, DefaultAutomodRefreshInterval)
+	service := NewPostService(postRepo, stubFeed{}, nil, stubLinkPreviews{}, stubPostPublisher{}, automod, nil, stubMentions{})
+
+	tests := []struct {
+		name       string
+		postID     int
+		visibility domain.PostVisibility
+		expected   error
+		threads    []domain.PostVisibility
+	}{
+		{name: "continuation keeps its visibility", postID: 2, visibility: domain.Followers},
+		{name: "continuation", postID: 2, visibility: domain.Public, expected: domain.ErrThreadVisibility},
+		{name: "first post keeps its visibility", postID: 1, visibility: domain.Followers},
+		{name: "first post", postID: 1, visibility: domain.Private, threads: []domain.PostVisibility{domain.Private}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			updated, threads = nil, nil
+			visibility := tt.visibility
+			err := service.UpdatePost(tt.postID, Viewer{ID: testAuthorID}, &domain.Post{Content: "edited", Visibility: &visibility})
+			if !errors.Is(err, tt.expected) {
+				t.Fatalf("expected error %v, got %v", tt.expected, err)
+			}
+			if (tt.expected == nil) != (len(updated) == 1) {
+				t.Errorf("expected the post to be updated only without an error, got %v", updated)
+			}
+			if !reflect.DeepEqual(threads, tt.threads) {
+				t.Errorf("expected the thread to be set to %v, got %v", tt.threads, threads)
+			}
+		})
+	}
+}
+
+// distributedPosts records where a post was sent to.
+type distributedPosts struct {
+	stubFeed
+	stubMentions
+	sent chan string
+}
+
+func (d distributedPosts) FanOutPost(post *domain.Post) error {
+	d.sent <- "timelines"
+	return nil
+}
+
+func (d distributedPosts) PublishPost(post *domain.Post) error {
+	d.sent <- "remote servers"
+	return nil
+}
+
+func (d distributedPosts) PublishDelete(post *domain.Post) error { return nil }
+
+func (d distributedPosts) RecordMentions(entityType string, entityID, authorID int, content string, post *domain.Post, notify bool) error {
+	if notify {
+		d.sent <- "mentioned users"
+	}
+	return nil
+}
+
+func TestPostServiceReleasePost(t *testing.T) {
+	held := map[int]bool{1: true}
+	postRepo := &infrastructure.MockPostRepository{
+		ReleaseFunc: func(postID int) error {
+			if !held[postID] {
+				return sql.ErrNoRows
+			}
+			held[postID] = false
+			return nil
+		},
+		GetByIDFunc: func(id int) (*domain.Post, error) {
+			return postWithVisibility(id, domain.Public), nil
+		},
+	}
+	distributed := distributedPosts{sent: make(chan string, 3)}
+	service := NewPostService(postRepo, distributed, nil, stubLinkPreviews{}, distributed, nil, nil, distributed)
+
+	if err := service.ReleasePost(2); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("expected a post that is not held to be left alone, got %v", err)
+	}
+	if err := service.ReleasePost(1); err != nil {
+		t.Fatalf("release post: %v", err)
+	}
+
+	sent := map[string]bool{}
+	for i := 0; i < 3; i++ {
+		select {
+		case to := <-distributed.sent:
+			sent[to] = true
+		case <-time.After(time.Second):
+			t.Fatalf("expected the released post to be sent on, got %v", sent)
+		}
+	}
+	select {
+	case to := <-distributed.sent:
+		t.Errorf("expected the post to be sent once, also got %s", to)
+	case <-time.After(10 * time.Millisecond):
+	}
+}
+
+func TestUnlinkFromThread(t *testing.T) {
+	// Thread 1 <- 2 <- 3 <- 4.
+	thread := func() []domain.Post {
+		return []domain.Post{
+			{ID: 1},
+			{ID: 2, ContinuesPostID: intPtr(1), ThreadRootID: intPtr(1)},
+			{ID: 3, ContinuesPostID: intPtr(2), ThreadRootID: intPtr(1)},
+			{ID: 4, ContinuesPostID: intPtr(3), ThreadRootID: intPtr(1)},
+		}
+	}
+
+	tests := []struct {
+		name     string
+		removed  int
+		expected []domain.Post
+	}{
+		{
+			name:     "middle post",
+			removed:  2,
+			expected: []domain.Post{{ID: 3, ContinuesPostID: intPtr(1), ThreadRootID: intPtr(1)}},
+		},
+		{
+			name:    "first post",
+			removed: 1,
+			expected: []domain.Post{
+				{ID: 2},
+				{ID: 3, ContinuesPostID: intPtr(2), ThreadRootID: intPtr(2)},
+				{ID: 4, ContinuesPostID: intPtr(3), ThreadRootID: intPtr(2)},
+			},
+		},
+		{name: "last post", removed: 4},
+		{name: "not in the thread", removed: 5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := domain.UnlinkFromThread(thread(), tt.removed)
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("expected %v, got %v", describeLinks(tt.expected), describeLinks(got))
+			}
+		})
+	}
+}
+
+// describeLinks prints thread links without pointer addresses.
+func describeLinks(posts []domain.Post) [][3]int {
+	links := [][3]int{}
+	for _, post := range posts {
+		link := [3]int{post.ID}
+		if post.ContinuesPostID != nil {
+			link[1] = *post.ContinuesPostID
+		}
+		if post.ThreadRootID != nil {
+			link[2] = *post.ThreadRootID
+		}
+		links = append(links, link)
+	}
+	return links
+}
@@ -1,33 +1,152 @@
 package application
 
-import "github.com/bandvov/social-media-go/domain"
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+
+	"github.com/bandvov/social-media-go/domain"
+)
 
 type ReactionServiceInterface interface {
-	AddOrUpdateReaction(userID int, reaction domain.Reaction) error
-	RemoveReaction(userID, contentID string) error
-	GetReactions(entityIDs []int) (map[int][]domain.Reaction, error)
-	GetReactionsCount(entityIDs []int) ([]domain.Reaction, error)
+	AddOrUpdateReaction(viewer Viewer, reaction domain.Reaction) error
+	RemoveReaction(userID, entityType, contentID string) error
+	GetReactions(entityType string, entityIDs []int) (map[int][]domain.Reaction, error)
+	GetReactionsCount(entityType string, entityIDs []int) ([]domain.Reaction, error)
+	GetReactors(viewer Viewer, entityType string, entityID int, reactionType string, page domain.PageRequest) ([]domain.Reactor, *domain.Cursor, error)
 }
 type ReactionService struct {
 	reactionRepo domain.ReactionRepository
+	visibility   *VisibilityPolicy
+	userRepo     domain.UserRepository
+	typeRepo     domain.ReactionTypeRepository
+	counters     CounterServiceInterface
+}
+
+func NewReactionService(reactionRepo domain.ReactionRepository, visibility *VisibilityPolicy, userRepo domain.UserRepository, typeRepo domain.ReactionTypeRepository, counters CounterServiceInterface) *ReactionService {
+	return &ReactionService{reactionRepo: reactionRepo, visibility: visibility, userRepo: userRepo, typeRepo: typeRepo, counters: counters}
+}
+
+// AddOrUpdateReaction reacts to a post or comment the viewer is allowed to
+// see, with one of the enabled reaction types.
+func (s *ReactionService) AddOrUpdateReaction(viewer Viewer, reaction domain.Reaction) error {
+	if err := domain.ValidateReactionEntityType(reaction.EntityType); err != nil {
+		return err
+	}
+	if err := s.checkReactionType(reaction.Reaction); err != nil {
+		return err
+	}
+
+	if err := s.checkEntity(viewer, reaction.EntityType, reaction.EntityId); err != nil {
+		return err
+	}
+
+	created, err := s.reactionRepo.AddOrUpdateReaction(viewer.ID, reaction)
+	if err != nil {
+		return err
+	}
+	if created && reaction.EntityType != domain.ReactionEntityComment {
+		s.counters.Add(reaction.EntityId, domain.CounterReactions, 1)
+	}
+	return nil
+}
+
+// GetReactors lists who reacted to a post or comment the viewer is allowed to
+// see, leaving out blocked users and private accounts the viewer does not
+// follow.
+func (s *ReactionService) GetReactors(viewer Viewer, entityType string, entityID int, reactionType string, page domain.PageRequest) ([]domain.Reactor, *domain.Cursor, error) {
+	if err := domain.ValidateReactionEntityType(entityType); err != nil {
+		return nil, nil, err
+	}
+	if err := s.checkEntity(viewer, entityType, entityID); err != nil {
+		return nil, nil, err
+	}
+
+	reactors, err := s.reactionRepo.GetReactors(entityType, entityID, viewer.ID, reactionType, page)
+	if err != nil {
+		return nil, nil, err
+	}
+	reactors, next := domain.NextPage(reactors, page, reactorCursor)
+
+	userIDs := make([]int, 0, len(reactors))
+	for _, reactor := range reactors {
+		userIDs = append(userIDs, reactor.UserID)
+	}
+	users, err := s.userRepo.GetUsersByID(context.Background(), userIDs)
+	if err != nil {
+		return nil, nil, err
+	}
+	byID := make(map[int]domain.User, len(users))
+	for _, user := range users {
+		byID[user.ID] = user
+	}
+	for i, reactor := range reactors {
+		user := byID[reactor.UserID]
+		if user.Username != nil {
+			reactors[i].Username = *user.Username
+		}
+		if user.ProfilePic != nil {
+			reactors[i].ProfilePic = *user.ProfilePic
+		}
+	}
+	return reactors, next, nil
 }
 
-func NewReactionService(reactionRepo domain.ReactionRepository) *ReactionService {
-	return &ReactionService{reactionRepo: reactionRepo}
+func reactorCursor(reactor domain.Reactor) domain.Cursor {
+	return domain.Cursor{CreatedAt: reactor.ReactedAt, ID: reactor.ReactionID}
 }
 
-func (s *ReactionService) AddOrUpdateReaction(userID int, reaction domain.Reaction) error {
-	return s.reactionRepo.AddOrUpdateReaction(userID, reaction)
+// checkReactionType fails with ErrUnknownReactionType unless the ID is the
+// one of an enabled reaction type.
+func (s *ReactionService) checkReactionType(reactionTypeID string) error {
+	id, err := strconv.Atoi(reactionTypeID)
+	if err != nil {
+		return domain.ErrUnknownReactionType
+	}
+	reactionType, err := s.typeRepo.GetByID(id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.ErrUnknownReactionType
+	}
+	if err != nil {
+		return err
+	}
+	if !reactionType.Enabled {
+		return domain.ErrUnknownReactionType
+	}
+	return nil
 }
 
-func (s *ReactionService) RemoveReaction(userID, contentID string) error {
-	return s.reactionRepo.RemoveReaction(userID, contentID)
+// checkEntity fails unless the viewer may see the post or comment.
+func (s *ReactionService) checkEntity(viewer Viewer, entityType string, entityID int) error {
+	if entityType == domain.ReactionEntityComment {
+		return s.visibility.CheckComment(viewer, entityID)
+	}
+	_, err := s.visibility.CheckPost(viewer, entityID)
+	return err
 }
 
-func (s *ReactionService) GetReactions(entityIDs []int) (map[int][]domain.Reaction, error) {
+func (s *ReactionService) RemoveReaction(userID, entityType, contentID string) error {
+	if err := domain.ValidateReactionEntityType(entityType); err != nil {
+		return err
+	}
+	removed, err := s.reactionRepo.RemoveReaction(userID, entityType, contentID)
+	if err != nil {
+		return err
+	}
+	if !removed || entityType == domain.ReactionEntityComment {
+		return nil
+	}
+	if postID, err := strconv.Atoi(contentID); err == nil {
+		s.counters.Add(postID, domain.CounterReactions, -1)
+	}
+	return nil
+}
+
+func (s *ReactionService) GetReactions(entityType string, entityIDs []int) (map[int][]domain.Reaction, error) {
 	reactionMap := make(map[int][]domain.Reaction)
 
-	reactions, err := s.reactionRepo.GetReactionsByEntityIDs(entityIDs)
+	reactions, err := s.reactionRepo.GetReactionsByEntityIDs(entityType, entityIDs)
 	if err != nil {
 		return nil, err
 	}
@@ -38,6 +157,22 @@ func (s *ReactionService) GetReactions(entityIDs []int) (map[int][]domain.Reacti
 	return reactionMap, nil
 }
 
-func (s *ReactionService) GetReactionsCount(entityIDs []int) ([]domain.Reaction, error) {
-	return s.reactionRepo.CountByEntityIDs(entityIDs)
+// GetReactionsCount counts reactions to posts from the post counters, and
+// reactions to comments from the reactions themselves.
+func (s *ReactionService) GetReactionsCount(entityType string, entityIDs []int) ([]domain.Reaction, error) {
+	if entityType == domain.ReactionEntityComment {
+		return s.reactionRepo.CountByEntityIDs(entityType, entityIDs)
+	}
+
+	counters, err := s.counters.GetPostCounters(entityIDs)
+	if err != nil {
+		return nil, err
+	}
+	counts := make([]domain.Reaction, 0, len(counters))
+	for _, postID := range entityIDs {
+		if counter, ok := counters[postID]; ok {
+			counts = append(counts, domain.Reaction{EntityId: postID, EntityType: domain.ReactionEntityPost, Count: counter.Reactions})
+		}
+	}
+	return counts, nil
 }
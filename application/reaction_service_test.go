@@ -0,0 +1,106 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/bandvov/social-media-go/domain"
+	"github.com/bandvov/social-media-go/infrastructure"
+)
+
+type stubReactorRepository struct {
+	domain.ReactionRepository
+	reactors []domain.Reactor
+}
+
+func (r *stubReactorRepository) GetReactors(entityType string, entityID, viewerID int, reactionType string, page domain.PageRequest) ([]domain.Reactor, error) {
+	return r.reactors[:min(len(r.reactors), page.Limit+1)], nil
+}
+
+func TestReactionServiceGetReactors(t *testing.T) {
+	reactedAt := time.Now()
+	repo := &stubReactorRepository{reactors: []domain.Reactor{
+		{ReactionID: 7, UserID: testFollowerID, Reaction: "Love", ReactedAt: reactedAt},
+		{ReactionID: 5, UserID: testStrangerID, Reaction: "Like", ReactedAt: reactedAt.Add(-time.Minute)},
+		{ReactionID: 2, UserID: testAdminID, Reaction: "Like", ReactedAt: reactedAt.Add(-time.Hour)},
+	}}
+	users := &infrastructure.MockUserRepository{
+		GetUsersByIDFunc: func(ctx context.Context, userIDs []int) ([]domain.User, error) {
+			var found []domain.User
+			for _, id := range userIDs {
+				username := map[int]string{testFollowerID: "follower", testStrangerID: "stranger"}[id]
+				found = append(found, domain.User{ID: id, Username: &username})
+			}
+			return found, nil
+		},
+	}
+	posts := map[int]*domain.Post{1: postWithVisibility(1, domain.Followers)}
+	service := NewReactionService(repo, newTestVisibilityPolicy(posts, nil), users, nil, &stubCounters{})
+
+	reactors, next, err := service.GetReactors(Viewer{ID: testFollowerID}, domain.ReactionEntityPost, 1, "", domain.PageRequest{Limit: 2})
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	var usernames []string
+	for _, reactor := range reactors {
+		usernames = append(usernames, reactor.Username)
+	}
+	if !reflect.DeepEqual(usernames, []string{"follower", "stranger"}) {
+		t.Errorf("expected follower and stranger, got %v", usernames)
+	}
+	if next == nil || next.ID != 5 || !next.CreatedAt.Equal(reactors[1].ReactedAt) {
+		t.Errorf("expected the cursor to point at reaction 5, got %+v", next)
+	}
+
+	if _, _, err := service.GetReactors(Viewer{ID: testStrangerID}, domain.ReactionEntityPost, 1, "", domain.PageRequest{Limit: 2}); !errors.Is(err, ErrForbidden) {
+		t.Errorf("expected reactions on posts the viewer cannot see to be forbidden, got %v", err)
+	}
+	if _, _, err := service.GetReactors(Viewer{ID: testFollowerID}, "story", 1, "", domain.PageRequest{Limit: 2}); !errors.Is(err, domain.ErrInvalidReactionEntity) {
+		t.Errorf("expected an invalid entity type to be refused, got %v", err)
+	}
+}
+
+type stubAddReactionRepository struct {
+	domain.ReactionRepository
+	added []domain.Reaction
+}
+
+func (r *stubAddReactionRepository) AddOrUpdateReaction(userID int, reaction domain.Reaction) (bool, error) {
+	r.added = append(r.added, reaction)
+	return len(r.added) == 1, nil
+}
+
+func TestReactionServiceRequiresEnabledReactionType(t *testing.T) {
+	types := &stubReactionTypeRepository{types: map[int]domain.ReactionType{
+		1: {ID: 1, Name: "Like", Enabled: true},
+		2: {ID: 2, Name: "Meh", Enabled: false},
+	}}
+	repo := &stubAddReactionRepository{}
+	posts := map[int]*domain.Post{1: postWithVisibility(1, domain.Public)}
+	counters := &stubCounters{}
+	service := NewReactionService(repo, newTestVisibilityPolicy(posts, nil), nil, types, counters)
+
+	for _, reactionTypeID := range []string{"2", "3", "like"} {
+		err := service.AddOrUpdateReaction(Viewer{ID: testStrangerID}, domain.Reaction{EntityId: 1, Reaction: reactionTypeID})
+		if !errors.Is(err, domain.ErrUnknownReactionType) {
+			t.Errorf("reaction type %q: expected it to be refused, got %v", reactionTypeID, err)
+		}
+	}
+	if err := service.AddOrUpdateReaction(Viewer{ID: testStrangerID}, domain.Reaction{EntityId: 1, Reaction: "1"}); err != nil {
+		t.Fatalf("react: %v", err)
+	}
+	if len(repo.added) != 1 {
+		t.Errorf("expected one reaction to be stored, got %v", repo.added)
+	}
+
+	// Changing the reaction type does not count the reaction again.
+	if err := service.AddOrUpdateReaction(Viewer{ID: testStrangerID}, domain.Reaction{EntityId: 1, Reaction: "1"}); err != nil {
+		t.Fatalf("react again: %v", err)
+	}
+	if got := counters.added[domain.CounterReactions][1]; got != 1 {
+		t.Errorf("expected the post to count one reaction, got %d", got)
+	}
+}
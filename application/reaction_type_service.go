@@ -0,0 +1,84 @@
+package application
+
+import "github.com/bandvov/social-media-go/domain"
+
+// ReactionTypeServiceInterface defines methods for the catalog of reaction
+// types.
+type ReactionTypeServiceInterface interface {
+	// ListReactionTypes returns the types users can react with, in order.
+	ListReactionTypes() ([]domain.ReactionType, error)
+	// ListAllReactionTypes also returns disabled types, for admins.
+	ListAllReactionTypes() ([]domain.ReactionType, error)
+	GetReactionType(id int) (*domain.ReactionType, error)
+	CreateReactionType(req *domain.ReactionTypeRequest) (*domain.ReactionType, error)
+	UpdateReactionType(id int, req *domain.ReactionTypeRequest) (*domain.ReactionType, error)
+	DeleteReactionType(id int) error
+}
+
+// ReactionTypeService manages the admin-defined reaction types.
+type ReactionTypeService struct {
+	repo domain.ReactionTypeRepository
+}
+
+func NewReactionTypeService(repo domain.ReactionTypeRepository) *ReactionTypeService {
+	return &ReactionTypeService{repo: repo}
+}
+
+func (s *ReactionTypeService) ListReactionTypes() ([]domain.ReactionType, error) {
+	return s.repo.List(false)
+}
+
+func (s *ReactionTypeService) ListAllReactionTypes() ([]domain.ReactionType, error) {
+	return s.repo.List(true)
+}
+
+func (s *ReactionTypeService) GetReactionType(id int) (*domain.ReactionType, error) {
+	return s.repo.GetByID(id)
+}
+
+func (s *ReactionTypeService) CreateReactionType(req *domain.ReactionTypeRequest) (*domain.ReactionType, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+
+	reactionType := reactionTypeFromRequest(req)
+	if err := s.repo.Create(reactionType); err != nil {
+		return nil, err
+	}
+	return reactionType, nil
+}
+
+func (s *ReactionTypeService) UpdateReactionType(id int, req *domain.ReactionTypeRequest) (*domain.ReactionType, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+
+	reactionType := reactionTypeFromRequest(req)
+	reactionType.ID = id
+	if err := s.repo.Update(reactionType); err != nil {
+		return nil, err
+	}
+	return reactionType, nil
+}
+
+// DeleteReactionType removes a reaction type nobody reacted with. Types in
+// use can only be disabled.
+func (s *ReactionTypeService) DeleteReactionType(id int) error {
+	return s.repo.Delete(id)
+}
+
+func reactionTypeFromRequest(req *domain.ReactionTypeRequest) *domain.ReactionType {
+	enabled := true
+	if req.Enabled != nil {
+		enabled = *req.Enabled
+	}
+	return &domain.ReactionType{
+		Name:     req.Name,
+		Emoji:    req.Emoji,
+		ImageURL: req.ImageURL,
+		Verb:     req.Verb,
+		Polarity: req.Polarity,
+		Position: req.Position,
+		Enabled:  enabled,
+	}
+}
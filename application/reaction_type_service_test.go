@@ -0,0 +1,53 @@
+package application
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/bandvov/social-media-go/domain"
+)
+
+type stubReactionTypeRepository struct {
+	domain.ReactionTypeRepository
+	types map[int]domain.ReactionType
+}
+
+func (r *stubReactionTypeRepository) GetByID(id int) (*domain.ReactionType, error) {
+	reactionType, ok := r.types[id]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return &reactionType, nil
+}
+
+func TestReactionTypeRequestValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		req  domain.ReactionTypeRequest
+	}{
+		{"name with spaces", domain.ReactionTypeRequest{Name: "Party Parrot", Emoji: "🦜"}},
+		{"name starting with a digit", domain.ReactionTypeRequest{Name: "100", Emoji: "💯"}},
+		{"no emoji or image", domain.ReactionTypeRequest{Name: "Like"}},
+		{"emoji and image", domain.ReactionTypeRequest{Name: "Like", Emoji: "👍", ImageURL: "https://cdn.example/like.png"}},
+		{"image with another scheme", domain.ReactionTypeRequest{Name: "Like", ImageURL: "javascript:alert(1)"}},
+		{"bad polarity", domain.ReactionTypeRequest{Name: "Like", Emoji: "👍", Polarity: 2}},
+		{"negative position", domain.ReactionTypeRequest{Name: "Like", Emoji: "👍", Position: -1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.req.Validate(); !errors.Is(err, domain.ErrInvalidReactionType) {
+				t.Errorf("expected the reaction type to be invalid, got %v", err)
+			}
+		})
+	}
+
+	req := domain.ReactionTypeRequest{Name: " party_parrot ", ImageURL: "https://cdn.example/parrot.gif"}
+	if err := req.Validate(); err != nil {
+		t.Fatalf("expected a custom emoji to be valid, got %v", err)
+	}
+	if req.Name != "party_parrot" || req.Verb != domain.DefaultReactionVerb {
+		t.Errorf("expected the name to be trimmed and the verb defaulted, got %+v", req)
+	}
+}
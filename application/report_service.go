@@ -0,0 +1,288 @@
+package application
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+
+	"github.com/bandvov/social-media-go/domain"
+)
+
+var ErrInvalidAssignee = errors.New("reports can only be assigned to moderators")
+
+// ReportServiceInterface defines methods for user reports and the
+// moderation queue.
+type ReportServiceInterface interface {
+	CreateReport(viewer Viewer, req *domain.CreateReportRequest) (*domain.Report, error)
+	GetReport(viewer Viewer, id int) (*domain.Report, error)
+	ListQueue(viewer Viewer, filter domain.ReportQueueFilter, page domain.PageRequest) ([]domain.Report, *domain.Cursor, error)
+	AssignReport(viewer Viewer, id int, assigneeID *int) error
+	ResolveReport(viewer Viewer, id int, req *domain.ResolveReportRequest) ([]domain.Report, error)
+}
+
+// ReportService lets users report posts, comments and other users, and
+// moderators work through the reports. Resolving a report resolves every
+// open report on the same target and notifies all of their reporters.
+type ReportService struct {
+	reportRepo   domain.ReportRepository
+	postRepo     domain.PostRepository
+	commentRepo  domain.CommentRepository
+	userRepo     domain.UserRepository
+	visibility   *VisibilityPolicy
+	postService  PostServiceInterface
+	linkPreviews LinkPreviewServiceInterface
+	notifier     domain.Notifier
+	counters     CounterServiceInterface
+}
+
+func NewReportService(reportRepo domain.ReportRepository, postRepo domain.PostRepository, commentRepo domain.CommentRepository, userRepo domain.UserRepository, visibility *VisibilityPolicy, postService PostServiceInterface, linkPreviews LinkPreviewServiceInterface, notifier domain.Notifier, counters CounterServiceInterface) *ReportService {
+	return &ReportService{
+		reportRepo:   reportRepo,
+		postRepo:     postRepo,
+		commentRepo:  commentRepo,
+		userRepo:     userRepo,
+		visibility:   visibility,
+		postService:  postService,
+		linkPreviews: linkPreviews,
+		notifier:     notifier,
+		counters:     counters,
+	}
+}
+
+// CreateReport files a report on something the viewer can see. A user can
+// have one open report per target.
+func (s *ReportService) CreateReport(viewer Viewer, req *domain.CreateReportRequest) (*domain.Report, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+
+	ownerID, err := s.targetOwner(viewer, req.TargetType, req.TargetID)
+	if err != nil {
+		return nil, err
+	}
+	if ownerID == viewer.ID {
+		return nil, domain.ErrCannotReportOwnContent
+	}
+
+	report := &domain.Report{
+		ReporterID:    viewer.ID,
+		TargetType:    req.TargetType,
+		TargetID:      req.TargetID,
+		TargetOwnerID: ownerID,
+		Reason:        req.Reason,
+		Details:       req.Details,
+	}
+	if err := s.reportRepo.Create(report); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// targetOwner returns the author of the reported content, or the reported
+// user, after checking that the viewer can see it.
+func (s *ReportService) targetOwner(viewer Viewer, targetType domain.ReportTargetType, targetID int) (int, error) {
+	switch targetType {
+	case domain.ReportTargetPost:
+		post, err := s.visibility.CheckPost(viewer, targetID)
+		if err != nil {
+			return 0, err
+		}
+		return post.AuthorID, nil
+	case domain.ReportTargetComment:
+		if err := s.visibility.CheckComment(viewer, targetID); err != nil {
+			return 0, err
+		}
+		comment, err := s.commentRepo.GetCommentByID(targetID)
+		if err != nil {
+			return 0, err
+		}
+		return comment.AuthorID, nil
+	case domain.ReportTargetUser:
+		user, err := s.userRepo.GetUserByID(targetID)
+		if err != nil {
+			return 0, err
+		}
+		return user.ID, nil
+	default:
+		return 0, domain.ErrInvalidReportTarget
+	}
+}
+
+func (s *ReportService) GetReport(viewer Viewer, id int) (*domain.Report, error) {
+	if !viewer.IsModerator {
+		return nil, ErrForbidden
+	}
+	return s.reportRepo.GetByID(id)
+}
+
+func (s *ReportService) ListQueue(viewer Viewer, filter domain.ReportQueueFilter, page domain.PageRequest) ([]domain.Report, *domain.Cursor, error) {
+	if !viewer.IsModerator {
+		return nil, nil, ErrForbidden
+	}
+
+	reports, err := s.reportRepo.ListQueue(filter, page)
+	if err != nil {
+		return nil, nil, err
+	}
+	reports, next := domain.NextPage(reports, page, func(report domain.Report) domain.Cursor {
+		return domain.Cursor{CreatedAt: report.CreatedAt, ID: report.ID}
+	})
+	return reports, next, nil
+}
+
+// AssignReport hands an open report to a moderator, or returns it to the
+// queue when assigneeID is nil.
+func (s *ReportService) AssignReport(viewer Viewer, id int, assigneeID *int) error {
+	if !viewer.IsModerator {
+		return ErrForbidden
+	}
+	if assigneeID != nil && *assigneeID != viewer.ID {
+		assignee, err := s.userRepo.GetUserByID(*assigneeID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrInvalidAssignee
+		}
+		if err != nil {
+			return err
+		}
+		if !isStaff(assignee) {
+			return ErrInvalidAssignee
+		}
+	}
+	return s.reportRepo.Assign(id, assigneeID)
+}
+
+// ResolveReport applies the moderator's action to the reported target and
+// closes every open report on it.
+func (s *ReportService) ResolveReport(viewer Viewer, id int, req *domain.ResolveReportRequest) ([]domain.Report, error) {
+	if !viewer.IsModerator {
+		return nil, ErrForbidden
+	}
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+
+	report, err := s.reportRepo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if report.Status != domain.ReportStatusOpen {
+		return nil, domain.ErrReportAlreadyResolved
+	}
+
+	if err := s.applyResolution(viewer, report, req.Resolution); err != nil {
+		return nil, err
+	}
+
+	resolved, err := s.reportRepo.Resolve(id, viewer.ID, req.Resolution, req.Note)
+	if err != nil {
+		return nil, err
+	}
+
+	go s.notifyReporters(resolved)
+	return resolved, nil
+}
+
+// applyResolution carries out the action. Dismissing a report releases
+// content held by auto-moderation or spam checks; released posts reach
+// timelines and remote servers then. Content that is already gone counts as
+// hidden or deleted.
+func (s *ReportService) applyResolution(viewer Viewer, report *domain.Report, resolution domain.ReportResolution) error {
+	var err error
+	switch resolution {
+	case domain.ResolutionDismiss:
+		switch report.TargetType {
+		case domain.ReportTargetPost:
+			err = s.postService.ReleasePost(report.TargetID)
+		case domain.ReportTargetComment:
+			err = s.moderateComment(report.TargetID, domain.CommentActive, s.commentRepo.Release)
+		}
+	case domain.ResolutionHide:
+		switch report.TargetType {
+		case domain.ReportTargetPost:
+			err = s.postRepo.SetVisibility(report.TargetID, domain.Hidden)
+		case domain.ReportTargetComment:
+			err = s.moderateComment(report.TargetID, domain.CommentHidden, s.commentRepo.Hide)
+		default:
+			return domain.ErrResolutionNotApplicable
+		}
+	case domain.ResolutionDelete:
+		switch report.TargetType {
+		case domain.ReportTargetPost:
+			err = s.postService.DeletePost(report.TargetID, viewer)
+		case domain.ReportTargetComment:
+			if err = s.linkPreviews.RemoveLinks(domain.LinkEntityComment, report.TargetID); err == nil {
+				err = s.moderateComment(report.TargetID, domain.CommentDeleted, s.commentRepo.Delete)
+			}
+		default:
+			return domain.ErrResolutionNotApplicable
+		}
+	case domain.ResolutionBan:
+		return s.banUser(viewer, report.TargetOwnerID)
+	default:
+		return domain.ErrInvalidResolution
+	}
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	return err
+}
+
+// moderateComment changes the status of a comment with apply and updates
+// the counters of its post. Only flagged comments can be released.
+func (s *ReportService) moderateComment(id int, status domain.CommentStatus, apply func(id int) error) error {
+	comment, err := s.commentRepo.GetCommentByID(id)
+	if err != nil {
+		return err
+	}
+	if err := apply(id); err != nil {
+		return err
+	}
+	if status == domain.CommentActive && comment.Status != domain.CommentFlagged {
+		return nil
+	}
+	countCommentStatus(s.counters, comment.PostID, comment.ParentID != nil, comment.Status, status)
+	return nil
+}
+
+// banUser bans the reported user or the author of the reported content.
+// Only admins can ban moderators and other admins.
+func (s *ReportService) banUser(viewer Viewer, userID int) error {
+	user, err := s.userRepo.GetUserByID(userID)
+	if err != nil {
+		return err
+	}
+	if isStaff(user) && !viewer.IsAdmin {
+		return ErrForbidden
+	}
+	if user.Status == domain.UserStatusBanned {
+		return nil
+	}
+	return s.userRepo.UpdateStatus(userID, domain.UserStatusBanned)
+}
+
+func (s *ReportService) notifyReporters(reports []domain.Report) {
+	for _, report := range reports {
+		if report.ReporterID == 0 {
+			continue
+		}
+		notificationType := domain.NotificationReportActioned
+		if report.Resolution == domain.ResolutionDismiss {
+			notificationType = domain.NotificationReportDismissed
+		}
+
+		err := s.notifier.Notify(domain.Notification{
+			UserID:     report.ReporterID,
+			Type:       notificationType,
+			EntityType: domain.NotificationEntityReport,
+			EntityID:   report.ID,
+		})
+		if err != nil {
+			log.Printf("failed to notify reporter of report %d: %v", report.ID, err)
+		}
+	}
+}
+
+func isStaff(user *domain.User) bool {
+	return user.Role == "admin" || user.Role == "moderator"
+}
@@ -0,0 +1,166 @@
+package application
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/bandvov/social-media-go/domain"
+	"github.com/bandvov/social-media-go/infrastructure"
+)
+
+type stubReportRepository struct {
+	reports []domain.Report
+}
+
+func (r *stubReportRepository) Create(report *domain.Report) error {
+	for _, existing := range r.reports {
+		if existing.Status == domain.ReportStatusOpen && existing.ReporterID == report.ReporterID &&
+			existing.TargetType == report.TargetType && existing.TargetID == report.TargetID {
+			return domain.ErrDuplicateReport
+		}
+	}
+	report.ID = len(r.reports) + 1
+	report.Status = domain.ReportStatusOpen
+	report.CreatedAt = time.Now()
+	r.reports = append(r.reports, *report)
+	return nil
+}
+
+func (r *stubReportRepository) GetByID(id int) (*domain.Report, error) {
+	report := r.reports[id-1]
+	return &report, nil
+}
+
+func (r *stubReportRepository) ListQueue(filter domain.ReportQueueFilter, page domain.PageRequest) ([]domain.Report, error) {
+	return r.reports, nil
+}
+
+func (r *stubReportRepository) Assign(id int, assigneeID *int) error {
+	r.reports[id-1].AssigneeID = assigneeID
+	return nil
+}
+
+func (r *stubReportRepository) Resolve(id, moderatorID int, resolution domain.ReportResolution, note string) ([]domain.Report, error) {
+	target := r.reports[id-1]
+	var resolved []domain.Report
+	for i, report := range r.reports {
+		if report.Status == domain.ReportStatusOpen && report.TargetType == target.TargetType && report.TargetID == target.TargetID {
+			r.reports[i].Status = domain.ReportStatusResolved
+			r.reports[i].Resolution = resolution
+			resolved = append(resolved, r.reports[i])
+		}
+	}
+	return resolved, nil
+}
+
+type notifierFunc func(notification domain.Notification) error
+
+func (f notifierFunc) Notify(notification domain.Notification) error {
+	return f(notification)
+}
+
+func TestReportServiceCreateReport(t *testing.T) {
+	posts := map[int]*domain.Post{
+		1: postWithVisibility(1, domain.Public),
+		2: postWithVisibility(2, domain.Private),
+	}
+	service := NewReportService(&stubReportRepository{}, nil, nil, nil, newTestVisibilityPolicy(posts, nil), nil, nil, nil, &stubCounters{})
+	stranger := Viewer{ID: testStrangerID}
+
+	tests := []struct {
+		name     string
+		viewer   Viewer
+		req      domain.CreateReportRequest
+		expected error
+	}{
+		{"report public post", stranger, domain.CreateReportRequest{TargetType: domain.ReportTargetPost, TargetID: 1, Reason: domain.ReportReasonSpam}, nil},
+		{"report twice", stranger, domain.CreateReportRequest{TargetType: domain.ReportTargetPost, TargetID: 1, Reason: domain.ReportReasonHate}, domain.ErrDuplicateReport},
+		{"report private post", stranger, domain.CreateReportRequest{TargetType: domain.ReportTargetPost, TargetID: 2, Reason: domain.ReportReasonSpam}, ErrForbidden},
+		{"report own post", Viewer{ID: testAuthorID}, domain.CreateReportRequest{TargetType: domain.ReportTargetPost, TargetID: 1, Reason: domain.ReportReasonSpam}, domain.ErrCannotReportOwnContent},
+		{"other without details", stranger, domain.CreateReportRequest{TargetType: domain.ReportTargetPost, TargetID: 1, Reason: domain.ReportReasonOther}, domain.ErrReportDetailsRequired},
+		{"unknown reason", stranger, domain.CreateReportRequest{TargetType: domain.ReportTargetPost, TargetID: 1, Reason: "boring"}, domain.ErrInvalidReportReason},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := service.CreateReport(tt.viewer, &tt.req)
+			if !errors.Is(err, tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, err)
+			}
+		})
+	}
+}
+
+func TestReportServiceResolveReport(t *testing.T) {
+	posts := map[int]*domain.Post{1: postWithVisibility(1, domain.Public)}
+
+	var hidden []int
+	postRepo := &infrastructure.MockPostRepository{
+		SetVisibilityFunc: func(postID int, visibility domain.PostVisibility) error {
+			if visibility == domain.Hidden {
+				hidden = append(hidden, postID)
+			}
+			return nil
+		},
+	}
+	userRepo := &infrastructure.MockUserRepository{
+		GetUserByIDFunc: func(id int) (*domain.User, error) {
+			return &domain.User{ID: id, Role: "moderator"}, nil
+		},
+	}
+	notified := make(chan domain.Notification, 2)
+	notifier := notifierFunc(func(notification domain.Notification) error {
+		notified <- notification
+		return nil
+	})
+
+	reportRepo := &stubReportRepository{}
+	service := NewReportService(reportRepo, postRepo, nil, userRepo, newTestVisibilityPolicy(posts, nil), nil, nil, notifier, &stubCounters{})
+	for _, reporterID := range []int{testFollowerID, testStrangerID} {
+		req := domain.CreateReportRequest{TargetType: domain.ReportTargetPost, TargetID: 1, Reason: domain.ReportReasonSpam}
+		if _, err := service.CreateReport(Viewer{ID: reporterID}, &req); err != nil {
+			t.Fatalf("create report: %v", err)
+		}
+	}
+
+	if _, err := service.ResolveReport(Viewer{ID: testStrangerID}, 1, &domain.ResolveReportRequest{Resolution: domain.ResolutionHide}); !errors.Is(err, ErrForbidden) {
+		t.Errorf("expected users to be forbidden from resolving, got %v", err)
+	}
+
+	moderator := Viewer{ID: testAdminID, IsModerator: true}
+	if _, err := service.ResolveReport(moderator, 1, &domain.ResolveReportRequest{Resolution: domain.ResolutionBan}); !errors.Is(err, ErrForbidden) {
+		t.Errorf("expected moderators to be forbidden from banning moderators, got %v", err)
+	}
+
+	resolved, err := service.ResolveReport(moderator, 1, &domain.ResolveReportRequest{Resolution: domain.ResolutionHide})
+	if err != nil {
+		t.Fatalf("resolve report: %v", err)
+	}
+	if len(resolved) != 2 {
+		t.Errorf("expected both reports on the post to be resolved, got %d", len(resolved))
+	}
+	if len(hidden) != 1 || hidden[0] != 1 {
+		t.Errorf("expected post 1 to be hidden, got %v", hidden)
+	}
+
+	reporters := map[int]bool{}
+	for i := 0; i < 2; i++ {
+		select {
+		case notification := <-notified:
+			if notification.Type != domain.NotificationReportActioned {
+				t.Errorf("expected %s notification, got %s", domain.NotificationReportActioned, notification.Type)
+			}
+			reporters[notification.UserID] = true
+		case <-time.After(time.Second):
+			t.Fatal("reporters were not notified")
+		}
+	}
+	if !reporters[testFollowerID] || !reporters[testStrangerID] {
+		t.Errorf("expected both reporters to be notified, got %v", reporters)
+	}
+
+	if _, err := service.ResolveReport(moderator, 2, &domain.ResolveReportRequest{Resolution: domain.ResolutionDismiss}); !errors.Is(err, domain.ErrReportAlreadyResolved) {
+		t.Errorf("expected resolved reports to stay resolved, got %v", err)
+	}
+}
@@ -0,0 +1,66 @@
+package application
+
+import "github.com/bandvov/social-media-go/domain"
+
+// SearchServiceInterface defines methods for searching posts, users and tags.
+// Every method also reports whether more results follow the page.
+type SearchServiceInterface interface {
+	SearchPosts(viewer Viewer, query domain.SearchQuery) ([]domain.PostSearchResult, bool, error)
+	SearchUsers(viewerID int, query domain.SearchQuery) ([]domain.UserSearchResult, bool, error)
+	SearchTags(query domain.SearchQuery) ([]domain.TagSearchResult, bool, error)
+}
+
+type SearchService struct {
+	repo domain.SearchRepository
+}
+
+func NewSearchService(repo domain.SearchRepository) *SearchService {
+	return &SearchService{repo: repo}
+}
+
+// SearchPosts ranks the posts the viewer may see, applying their preference
+// for posts with a content warning.
+func (s *SearchService) SearchPosts(viewer Viewer, query domain.SearchQuery) ([]domain.PostSearchResult, bool, error) {
+	results, err := s.repo.SearchPosts(viewer.ID, query)
+	if err != nil {
+		return nil, false, err
+	}
+	results, hasMore := trimSearchResults(results, query.Limit)
+
+	shown := results[:0]
+	for _, result := range results {
+		if result.IsFlagged() && result.AuthorID != viewer.ID && viewer.SensitiveContent == domain.SensitiveContentHide {
+			continue
+		}
+		ApplyContentPreferenceToPost(viewer, &result.Post)
+		shown = append(shown, result)
+	}
+	return shown, hasMore, nil
+}
+
+func (s *SearchService) SearchUsers(viewerID int, query domain.SearchQuery) ([]domain.UserSearchResult, bool, error) {
+	results, err := s.repo.SearchUsers(viewerID, query)
+	if err != nil {
+		return nil, false, err
+	}
+	results, hasMore := trimSearchResults(results, query.Limit)
+	return results, hasMore, nil
+}
+
+func (s *SearchService) SearchTags(query domain.SearchQuery) ([]domain.TagSearchResult, bool, error) {
+	results, err := s.repo.SearchTags(query)
+	if err != nil {
+		return nil, false, err
+	}
+	results, hasMore := trimSearchResults(results, query.Limit)
+	return results, hasMore, nil
+}
+
+// trimSearchResults drops the extra row repositories fetch to detect
+// whether another page exists.
+func trimSearchResults[T any](results []T, limit int) ([]T, bool) {
+	if len(results) > limit {
+		return results[:limit], true
+	}
+	return results, false
+}
@@ -0,0 +1,257 @@
+package application
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bandvov/social-media-go/domain"
+	"github.com/bandvov/social-media-go/utils"
+)
+
+// minDuplicateLength is the shortest content checked for duplicates.
+const minDuplicateLength = 20
+
+// SpamPolicy holds the limits and thresholds of the spam filter.
+type SpamPolicy struct {
+	// Window is the period activity limits are counted over.
+	Window time.Duration
+	// Limits is how many times an account can do each activity per window
+	// before it is throttled.
+	Limits map[domain.SpamActivity]int
+	// DuplicateWindow is how long identical content is remembered.
+	DuplicateWindow time.Duration
+	// HoldScore and ShadowLimitScore are the scores at which content is
+	// held for review and the account is shadow-limited.
+	HoldScore        int
+	ShadowLimitScore int
+}
+
+// DefaultSpamPolicy is tuned so that ordinary use never trips it, while an
+// account posting the same link over and over is stopped within a few
+// attempts.
+var DefaultSpamPolicy = SpamPolicy{
+	Window: 10 * time.Minute,
+	Limits: map[domain.SpamActivity]int{
+		domain.SpamActivityPost:    20,
+		domain.SpamActivityComment: 60,
+		domain.SpamActivityFollow:  100,
+	},
+	DuplicateWindow:  24 * time.Hour,
+	HoldScore:        60,
+	ShadowLimitScore: 100,
+}
+
+// SpamServiceInterface defines methods for the spam filter.
+type SpamServiceInterface interface {
+	// Check scores an action before it is stored. Accounts acting too fast
+	// get a *domain.RateLimitError.
+	Check(userID int, activity domain.SpamActivity, content string) (*domain.SpamVerdict, error)
+	// HoldForReview puts held content in the moderation queue.
+	HoldForReview(targetType domain.ReportTargetType, targetID, ownerID int, verdict *domain.SpamVerdict) error
+	GetStanding(viewer Viewer, userID int) (*domain.AccountLimit, error)
+	SetStanding(viewer Viewer, userID int, req *domain.SetStandingRequest) (*domain.AccountLimit, error)
+	ListLimits(viewer Viewer, standing domain.AccountStanding, page domain.PageRequest) ([]domain.AccountLimit, *domain.Cursor, error)
+}
+
+// SpamService scores new posts, comments and follows on account age,
+// activity velocity, duplicate content and link density. Staff and trusted
+// accounts are never checked; moderators can trust, limit or clear any
+// other account.
+type SpamService struct {
+	store     domain.SpamActivityStore
+	limitRepo domain.AccountLimitRepository
+	userRepo  domain.UserRepository
+	reports   domain.ReportRepository
+	policy    SpamPolicy
+	now       func() time.Time
+}
+
+func NewSpamService(store domain.SpamActivityStore, limitRepo domain.AccountLimitRepository, userRepo domain.UserRepository, reports domain.ReportRepository, policy SpamPolicy) *SpamService {
+	return &SpamService{store: store, limitRepo: limitRepo, userRepo: userRepo, reports: reports, policy: policy, now: time.Now}
+}
+
+func (s *SpamService) Check(userID int, activity domain.SpamActivity, content string) (*domain.SpamVerdict, error) {
+	user, err := s.userRepo.GetUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if isStaff(user) {
+		return &domain.SpamVerdict{}, nil
+	}
+
+	standing := domain.StandingNormal
+	limit, err := s.limitRepo.GetLimit(userID)
+	if err == nil {
+		standing = limit.Standing
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	if standing == domain.StandingTrusted {
+		return &domain.SpamVerdict{}, nil
+	}
+
+	count, err := s.store.RecordActivity(userID, activity, s.policy.Window)
+	if err != nil {
+		return nil, err
+	}
+	if max := s.policy.Limits[activity]; max > 0 && count > max {
+		return nil, &domain.RateLimitError{RetryAfter: s.policy.Window}
+	}
+
+	verdict := &domain.SpamVerdict{}
+	verdict.Add(s.ageScore(user))
+	if max := s.policy.Limits[activity]; max > 0 && count > max/2 {
+		verdict.Add(20, "high activity")
+	}
+	if activity != domain.SpamActivityFollow {
+		if err := s.scoreContent(verdict, userID, content); err != nil {
+			return nil, err
+		}
+	}
+
+	switch {
+	case standing == domain.StandingShadowLimited:
+		verdict.Action = domain.SpamShadowLimit
+	case verdict.Score >= s.policy.ShadowLimitScore:
+		verdict.Action = domain.SpamShadowLimit
+		err := s.limitRepo.SetLimit(&domain.AccountLimit{
+			UserID:    userID,
+			Standing:  domain.StandingShadowLimited,
+			Automatic: true,
+			Reason:    verdict.Summary(),
+		})
+		if err != nil {
+			return nil, err
+		}
+	case verdict.Score >= s.policy.HoldScore && activity != domain.SpamActivityFollow:
+		verdict.Action = domain.SpamHold
+	}
+	return verdict, nil
+}
+
+// ageScore returns the points for a young account.
+func (s *SpamService) ageScore(user *domain.User) (int, string) {
+	age := s.now().Sub(user.CreatedAt)
+	switch {
+	case age < time.Hour:
+		return 30, "account younger than an hour"
+	case age < 24*time.Hour:
+		return 15, "account younger than a day"
+	case age < 7*24*time.Hour:
+		return 5, "account younger than a week"
+	}
+	return 0, ""
+}
+
+// scoreContent adds the points for repeated content and for content that
+// is mostly links.
+func (s *SpamService) scoreContent(verdict *domain.SpamVerdict, userID int, content string) error {
+	if links := len(utils.ExtractURLs(content, maxAutomodLinks)); links > 0 {
+		density := float64(links) / float64(len(strings.Fields(content)))
+		switch {
+		case links >= 5 || density >= 0.5:
+			verdict.Add(25, "mostly links")
+		case density >= 0.2:
+			verdict.Add(10, "many links")
+		}
+	}
+
+	// Short replies like "thanks" are repeated all the time.
+	normalized := strings.Join(strings.Fields(strings.ToLower(content)), " ")
+	if len(normalized) < minDuplicateLength {
+		return nil
+	}
+
+	sum := sha256.Sum256([]byte(normalized))
+	byUser, byEveryone, err := s.store.RecordContent(userID, hex.EncodeToString(sum[:16]), s.policy.DuplicateWindow)
+	if err != nil {
+		return err
+	}
+	if byUser > 1 {
+		verdict.Add(min(30*(byUser-1), 60), fmt.Sprintf("posted the same content %d times", byUser))
+	}
+	if byEveryone-byUser >= 5 {
+		verdict.Add(30, "content posted by many accounts")
+	}
+	return nil
+}
+
+func (s *SpamService) HoldForReview(targetType domain.ReportTargetType, targetID, ownerID int, verdict *domain.SpamVerdict) error {
+	return s.reports.Create(&domain.Report{
+		TargetType:    targetType,
+		TargetID:      targetID,
+		TargetOwnerID: ownerID,
+		Reason:        domain.ReportReasonSpam,
+		Details:       "Held for review by the spam filter: " + verdict.Summary(),
+	})
+}
+
+// GetStanding returns the stored standing of an account, or normal standing
+// when there is none.
+func (s *SpamService) GetStanding(viewer Viewer, userID int) (*domain.AccountLimit, error) {
+	if !viewer.IsModerator {
+		return nil, ErrForbidden
+	}
+	if _, err := s.userRepo.GetUserByID(userID); err != nil {
+		return nil, err
+	}
+
+	limit, err := s.limitRepo.GetLimit(userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return &domain.AccountLimit{UserID: userID, Standing: domain.StandingNormal}, nil
+	}
+	return limit, err
+}
+
+// SetStanding overrides the spam filter for an account. Setting normal
+// standing lifts a limit; the filter may limit the account again later.
+func (s *SpamService) SetStanding(viewer Viewer, userID int, req *domain.SetStandingRequest) (*domain.AccountLimit, error) {
+	if !viewer.IsModerator {
+		return nil, ErrForbidden
+	}
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	if _, err := s.userRepo.GetUserByID(userID); err != nil {
+		return nil, err
+	}
+
+	if req.Standing == domain.StandingNormal {
+		if err := s.limitRepo.ClearLimit(userID); err != nil {
+			return nil, err
+		}
+		return &domain.AccountLimit{UserID: userID, Standing: domain.StandingNormal}, nil
+	}
+
+	moderatorID := viewer.ID
+	limit := &domain.AccountLimit{
+		UserID:   userID,
+		Standing: req.Standing,
+		Reason:   req.Reason,
+		SetBy:    &moderatorID,
+	}
+	if err := s.limitRepo.SetLimit(limit); err != nil {
+		return nil, err
+	}
+	return limit, nil
+}
+
+func (s *SpamService) ListLimits(viewer Viewer, standing domain.AccountStanding, page domain.PageRequest) ([]domain.AccountLimit, *domain.Cursor, error) {
+	if !viewer.IsModerator {
+		return nil, nil, ErrForbidden
+	}
+
+	limits, err := s.limitRepo.ListLimits(standing, page)
+	if err != nil {
+		return nil, nil, err
+	}
+	limits, next := domain.NextPage(limits, page, func(limit domain.AccountLimit) domain.Cursor {
+		return domain.Cursor{CreatedAt: limit.UpdatedAt, ID: limit.UserID}
+	})
+	return limits, next, nil
+}
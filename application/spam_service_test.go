@@ -0,0 +1,179 @@
+package application
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/bandvov/social-media-go/domain"
+	"github.com/bandvov/social-media-go/infrastructure"
+)
+
+type stubSpamStore struct {
+	activity map[string]int
+	content  map[string]int
+}
+
+func newStubSpamStore() *stubSpamStore {
+	return &stubSpamStore{activity: map[string]int{}, content: map[string]int{}}
+}
+
+func (s *stubSpamStore) RecordActivity(userID int, activity domain.SpamActivity, window time.Duration) (int, error) {
+	key := fmt.Sprintf("%s:%d", activity, userID)
+	s.activity[key]++
+	return s.activity[key], nil
+}
+
+func (s *stubSpamStore) RecordContent(userID int, hash string, window time.Duration) (int, int, error) {
+	s.content[fmt.Sprintf("%s:%d", hash, userID)]++
+	s.content[hash]++
+	return s.content[fmt.Sprintf("%s:%d", hash, userID)], s.content[hash], nil
+}
+
+type stubAccountLimitRepository struct {
+	limits map[int]domain.AccountLimit
+}
+
+func (r *stubAccountLimitRepository) GetLimit(userID int) (*domain.AccountLimit, error) {
+	limit, ok := r.limits[userID]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return &limit, nil
+}
+
+func (r *stubAccountLimitRepository) SetLimit(limit *domain.AccountLimit) error {
+	r.limits[limit.UserID] = *limit
+	return nil
+}
+
+func (r *stubAccountLimitRepository) ClearLimit(userID int) error {
+	delete(r.limits, userID)
+	return nil
+}
+
+func (r *stubAccountLimitRepository) ListLimits(standing domain.AccountStanding, page domain.PageRequest) ([]domain.AccountLimit, error) {
+	return nil, nil
+}
+
+const (
+	spamNewUserID     = 1
+	spamOldUserID     = 2
+	spamTrustedUserID = 3
+)
+
+func newTestSpamService() (*SpamService, *stubAccountLimitRepository) {
+	now := time.Now()
+	users := &infrastructure.MockUserRepository{
+		GetUserByIDFunc: func(id int) (*domain.User, error) {
+			user := &domain.User{ID: id, Role: "user", CreatedAt: now.Add(-365 * 24 * time.Hour)}
+			switch id {
+			case spamNewUserID:
+				user.CreatedAt = now.Add(-10 * time.Minute)
+			case testAdminID:
+				user.Role = "admin"
+			}
+			return user, nil
+		},
+	}
+	limits := &stubAccountLimitRepository{limits: map[int]domain.AccountLimit{
+		spamTrustedUserID: {UserID: spamTrustedUserID, Standing: domain.StandingTrusted},
+	}}
+	policy := DefaultSpamPolicy
+	policy.Limits = map[domain.SpamActivity]int{domain.SpamActivityComment: 10}
+	return NewSpamService(newStubSpamStore(), limits, users, &stubReportRepository{}, policy), limits
+}
+
+func TestSpamServiceEscalates(t *testing.T) {
+	service, limits := newTestSpamService()
+	content := "Cheap followers at https://spam.example"
+
+	expected := []domain.SpamAction{domain.SpamAllow, domain.SpamHold, domain.SpamShadowLimit, domain.SpamShadowLimit}
+	for i, action := range expected {
+		verdict, err := service.Check(spamNewUserID, domain.SpamActivityComment, content)
+		if err != nil {
+			t.Fatalf("check %d: %v", i+1, err)
+		}
+		if verdict.Action != action {
+			t.Errorf("check %d: expected %q, got %q (%s)", i+1, action, verdict.Action, verdict.Summary())
+		}
+	}
+
+	limit, ok := limits.limits[spamNewUserID]
+	if !ok || limit.Standing != domain.StandingShadowLimited || !limit.Automatic {
+		t.Errorf("expected an automatic shadow limit, got %+v", limit)
+	}
+
+	// Once limited, even unrelated content is limited.
+	verdict, err := service.Check(spamNewUserID, domain.SpamActivityComment, "hello")
+	if err != nil || !verdict.ShadowLimited() {
+		t.Errorf("expected the account to stay limited, got %+v, %v", verdict, err)
+	}
+}
+
+func TestSpamServiceLeavesOrdinaryUseAlone(t *testing.T) {
+	service, _ := newTestSpamService()
+
+	for _, userID := range []int{spamOldUserID, spamTrustedUserID, testAdminID} {
+		// Saying the same thing twice happens.
+		for i := 0; i < 2; i++ {
+			verdict, err := service.Check(userID, domain.SpamActivityComment, "Great write-up, thanks for sharing!")
+			if err != nil {
+				t.Fatalf("user %d: %v", userID, err)
+			}
+			if verdict.Action != domain.SpamAllow {
+				t.Errorf("user %d check %d: expected no action, got %q (%s)", userID, i+1, verdict.Action, verdict.Summary())
+			}
+		}
+	}
+}
+
+func TestSpamServiceThrottles(t *testing.T) {
+	service, _ := newTestSpamService()
+
+	var err error
+	for i := 0; i < 11 && err == nil; i++ {
+		_, err = service.Check(spamOldUserID, domain.SpamActivityComment, fmt.Sprintf("comment %d", i))
+	}
+	var rateLimited *domain.RateLimitError
+	if !errors.As(err, &rateLimited) || rateLimited.RetryAfter != DefaultSpamPolicy.Window {
+		t.Fatalf("expected the 11th comment to be throttled, got %v", err)
+	}
+
+	// Trusted accounts are never throttled.
+	for i := 0; i < 11; i++ {
+		if _, err := service.Check(spamTrustedUserID, domain.SpamActivityComment, fmt.Sprintf("comment %d", i)); err != nil {
+			t.Fatalf("trusted comment %d: %v", i+1, err)
+		}
+	}
+}
+
+func TestSpamServiceSetStanding(t *testing.T) {
+	service, limits := newTestSpamService()
+	moderator := Viewer{ID: testAdminID, IsModerator: true}
+
+	if _, err := service.SetStanding(Viewer{ID: spamOldUserID}, spamNewUserID, &domain.SetStandingRequest{Standing: domain.StandingTrusted}); !errors.Is(err, ErrForbidden) {
+		t.Errorf("expected non-moderators to be forbidden, got %v", err)
+	}
+	if _, err := service.SetStanding(moderator, spamNewUserID, &domain.SetStandingRequest{Standing: "banned"}); !errors.Is(err, domain.ErrInvalidStanding) {
+		t.Errorf("expected an invalid standing error, got %v", err)
+	}
+
+	if _, err := service.SetStanding(moderator, spamNewUserID, &domain.SetStandingRequest{Standing: domain.StandingShadowLimited, Reason: " spam wave "}); err != nil {
+		t.Fatalf("limit: %v", err)
+	}
+	limit := limits.limits[spamNewUserID]
+	if limit.Automatic || limit.SetBy == nil || *limit.SetBy != testAdminID || limit.Reason != "spam wave" {
+		t.Errorf("unexpected limit %+v", limit)
+	}
+
+	if _, err := service.SetStanding(moderator, spamNewUserID, &domain.SetStandingRequest{Standing: domain.StandingNormal}); err != nil {
+		t.Fatalf("clear: %v", err)
+	}
+	standing, err := service.GetStanding(moderator, spamNewUserID)
+	if err != nil || standing.Standing != domain.StandingNormal {
+		t.Errorf("expected normal standing, got %+v, %v", standing, err)
+	}
+}
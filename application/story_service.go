@@ -0,0 +1,216 @@
+package application
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/bandvov/social-media-go/domain"
+)
+
+const (
+	// DefaultStoryTTL is how long a story stays up when STORY_TTL is not set.
+	DefaultStoryTTL = 24 * time.Hour
+
+	// DefaultStorySweepInterval is how often expired stories are deleted.
+	DefaultStorySweepInterval = 10 * time.Minute
+)
+
+// StoryServiceInterface defines methods for ephemeral stories.
+type StoryServiceInterface interface {
+	CreateStory(authorID int, story *domain.CreateStoryRequest) (*domain.Story, error)
+	DeleteStory(viewer Viewer, storyID int) error
+	GetTray(viewerID int) ([]domain.StoryTrayItem, error)
+	MarkSeen(viewerID, storyID int) error
+	GetViewers(viewer Viewer, storyID int, page domain.PageRequest) ([]domain.StoryViewer, *domain.Cursor, error)
+}
+
+// StoryService manages stories, which are shown to the author and their
+// followers until they expire.
+type StoryService struct {
+	storyRepo    domain.StoryRepository
+	followerRepo domain.FollowerRepository
+	blockRepo    domain.BlockRepository
+	userRepo     domain.UserRepository
+	ttl          time.Duration
+}
+
+func NewStoryService(storyRepo domain.StoryRepository, followerRepo domain.FollowerRepository, blockRepo domain.BlockRepository, userRepo domain.UserRepository, ttl time.Duration) *StoryService {
+	return &StoryService{
+		storyRepo:    storyRepo,
+		followerRepo: followerRepo,
+		blockRepo:    blockRepo,
+		userRepo:     userRepo,
+		ttl:          ttl,
+	}
+}
+
+func (s *StoryService) CreateStory(authorID int, story *domain.CreateStoryRequest) (*domain.Story, error) {
+	if err := story.Validate(); err != nil {
+		return nil, err
+	}
+	return s.storyRepo.Create(authorID, story, time.Now().Add(s.ttl))
+}
+
+// DeleteStory takes a story down before it expires. Only the author or an
+// admin may delete a story.
+func (s *StoryService) DeleteStory(viewer Viewer, storyID int) error {
+	story, err := s.storyRepo.GetByID(storyID)
+	if err != nil {
+		return err
+	}
+	if story.AuthorID != viewer.ID && !viewer.IsAdmin {
+		return ErrForbidden
+	}
+	return s.storyRepo.Delete(storyID)
+}
+
+// GetTray returns the active stories of the viewer and the users they follow,
+// grouped by author. The viewer's own stories come first, then authors with
+// stories the viewer has not seen, most recent first.
+func (s *StoryService) GetTray(viewerID int) ([]domain.StoryTrayItem, error) {
+	authorIDs, err := s.followerRepo.GetFolloweeIDs(viewerID)
+	if err != nil {
+		return nil, err
+	}
+	authorIDs = append(authorIDs, viewerID)
+
+	stories, err := s.storyRepo.GetActiveByAuthors(viewerID, authorIDs, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if len(stories) == 0 {
+		return []domain.StoryTrayItem{}, nil
+	}
+
+	byAuthor := make(map[int]*domain.StoryTrayItem)
+	var order []int
+	for _, story := range stories {
+		item, ok := byAuthor[story.AuthorID]
+		if !ok {
+			item = &domain.StoryTrayItem{AuthorID: story.AuthorID}
+			byAuthor[story.AuthorID] = item
+			order = append(order, story.AuthorID)
+		}
+		item.Stories = append(item.Stories, story)
+		if !story.Seen && story.AuthorID != viewerID {
+			item.HasUnseen = true
+		}
+	}
+
+	users, err := s.userRepo.GetUsersByID(context.Background(), order)
+	if err != nil {
+		return nil, err
+	}
+	for _, user := range users {
+		item := byAuthor[user.ID]
+		if item == nil {
+			continue
+		}
+		if user.Username != nil {
+			item.Username = *user.Username
+		}
+		if user.ProfilePic != nil {
+			item.ProfilePic = *user.ProfilePic
+		}
+	}
+
+	tray := make([]domain.StoryTrayItem, 0, len(order))
+	for _, authorID := range order {
+		tray = append(tray, *byAuthor[authorID])
+	}
+	sort.SliceStable(tray, func(i, j int) bool {
+		a, b := tray[i], tray[j]
+		if (a.AuthorID == viewerID) != (b.AuthorID == viewerID) {
+			return a.AuthorID == viewerID
+		}
+		if a.HasUnseen != b.HasUnseen {
+			return a.HasUnseen
+		}
+		return latestStory(a).After(latestStory(b))
+	})
+	return tray, nil
+}
+
+func latestStory(item domain.StoryTrayItem) time.Time {
+	return item.Stories[len(item.Stories)-1].CreatedAt
+}
+
+// MarkSeen records that the viewer watched the story. Only the author's
+// followers can see a story, and expired stories are gone.
+func (s *StoryService) MarkSeen(viewerID, storyID int) error {
+	story, err := s.storyRepo.GetByID(storyID)
+	if err != nil {
+		return err
+	}
+	if !story.ExpiresAt.After(time.Now()) {
+		return sql.ErrNoRows
+	}
+	if story.AuthorID == viewerID {
+		return nil
+	}
+
+	following, err := s.followerRepo.IsFollowing(viewerID, story.AuthorID)
+	if err != nil {
+		return err
+	}
+	if !following {
+		return ErrForbidden
+	}
+	blocked, err := s.blockRepo.IsBlocked(viewerID, story.AuthorID)
+	if err != nil {
+		return err
+	}
+	if blocked {
+		return ErrForbidden
+	}
+
+	return s.storyRepo.MarkSeen(storyID, viewerID)
+}
+
+// GetViewers lists who has seen the story. Only the author may see it.
+func (s *StoryService) GetViewers(viewer Viewer, storyID int, page domain.PageRequest) ([]domain.StoryViewer, *domain.Cursor, error) {
+	story, err := s.storyRepo.GetByID(storyID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if story.AuthorID != viewer.ID && !viewer.IsAdmin {
+		return nil, nil, ErrForbidden
+	}
+
+	viewers, err := s.storyRepo.GetViewers(storyID, page)
+	if err != nil {
+		return nil, nil, err
+	}
+	viewers, next := domain.NextPage(viewers, page, storyViewerCursor)
+	return viewers, next, nil
+}
+
+func storyViewerCursor(viewer domain.StoryViewer) domain.Cursor {
+	return domain.Cursor{CreatedAt: viewer.ViewedAt, ID: viewer.UserID}
+}
+
+// RunExpirySweeper deletes expired stories every interval until the context
+// is cancelled.
+func (s *StoryService) RunExpirySweeper(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			deleted, err := s.storyRepo.DeleteExpired(now)
+			if err != nil {
+				log.Printf("failed to delete expired stories: %v", err)
+				continue
+			}
+			if deleted > 0 {
+				log.Printf("deleted %d expired stories", deleted)
+			}
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package application
+
+import (
+	"database/sql"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/bandvov/social-media-go/domain"
+	"github.com/bandvov/social-media-go/infrastructure"
+)
+
+// memoryStoryRepository keeps stories in memory and, like Postgres, only
+// returns the ones that expire after now as active.
+type memoryStoryRepository struct {
+	domain.StoryRepository
+	stories []domain.Story
+	seen    map[[2]int]bool
+}
+
+func (r *memoryStoryRepository) Create(authorID int, story *domain.CreateStoryRequest, expiresAt time.Time) (*domain.Story, error) {
+	created := domain.Story{ID: len(r.stories) + 1, AuthorID: authorID, Content: story.Content, CreatedAt: time.Now(), ExpiresAt: expiresAt}
+	r.stories = append(r.stories, created)
+	return &created, nil
+}
+
+func (r *memoryStoryRepository) GetByID(id int) (*domain.Story, error) {
+	for _, story := range r.stories {
+		if story.ID == id {
+			return &story, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (r *memoryStoryRepository) GetActiveByAuthors(viewerID int, authorIDs []int, now time.Time) ([]domain.Story, error) {
+	var active []domain.Story
+	for _, story := range r.stories {
+		if contains(authorIDs, story.AuthorID) && story.ExpiresAt.After(now) {
+			story.Seen = r.seen[[2]int{story.ID, viewerID}]
+			active = append(active, story)
+		}
+	}
+	return active, nil
+}
+
+func (r *memoryStoryRepository) MarkSeen(storyID, viewerID int) error {
+	r.seen[[2]int{storyID, viewerID}] = true
+	return nil
+}
+
+func (r *memoryStoryRepository) GetViewers(storyID int, page domain.PageRequest) ([]domain.StoryViewer, error) {
+	return []domain.StoryViewer{}, nil
+}
+
+// stubBlocks holds blocks as pairs of blocker and blocked user.
+type stubBlocks map[[2]int]bool
+
+func (b stubBlocks) AddBlock(block *domain.Block) error    { return nil }
+func (b stubBlocks) RemoveBlock(block *domain.Block) error { return nil }
+
+func (b stubBlocks) IsBlocked(userID, otherUserID int) (bool, error) {
+	return b[[2]int{userID, otherUserID}] || b[[2]int{otherUserID, userID}], nil
+}
+
+func newTestStoryService(repo *memoryStoryRepository, blocks stubBlocks) *StoryService {
+	followerRepo := &infrastructure.MockFollowerRepository{
+		IsFollowingFunc: func(followerID, followeeID int) (bool, error) {
+			return followerID == testFollowerID && followeeID == testAuthorID, nil
+		},
+		GetFolloweeIDsFunc: func(userID int) ([]int, error) {
+			if userID == testFollowerID {
+				return []int{testAuthorID}, nil
+			}
+			return nil, nil
+		},
+	}
+	userRepo := &infrastructure.MockUserRepository{}
+	return NewStoryService(repo, followerRepo, blocks, userRepo, time.Hour)
+}
+
+func TestStoryServiceExpiry(t *testing.T) {
+	repo := &memoryStoryRepository{seen: make(map[[2]int]bool)}
+	service := newTestStoryService(repo, stubBlocks{})
+
+	before := time.Now()
+	story, err := service.CreateStory(testAuthorID, &domain.CreateStoryRequest{Content: "up for an hour"})
+	if err != nil {
+		t.Fatalf("create story: %v", err)
+	}
+	if story.ExpiresAt.Before(before.Add(time.Hour)) || story.ExpiresAt.After(time.Now().Add(time.Hour)) {
+		t.Errorf("expected the story to expire after the TTL, got %v", story.ExpiresAt)
+	}
+
+	// A story that expired a moment ago is gone everywhere.
+	repo.stories = append(repo.stories, domain.Story{ID: 2, AuthorID: testAuthorID, CreatedAt: before.Add(-time.Hour), ExpiresAt: time.Now().Add(-time.Second)})
+
+	tray, err := service.GetTray(testFollowerID)
+	if err != nil {
+		t.Fatalf("get tray: %v", err)
+	}
+	if len(tray) != 1 || len(tray[0].Stories) != 1 || tray[0].Stories[0].ID != story.ID || !tray[0].HasUnseen {
+		t.Fatalf("expected only the active story in the tray, got %+v", tray)
+	}
+
+	if err := service.MarkSeen(testFollowerID, 2); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("expected an expired story to be missing, got %v", err)
+	}
+	if err := service.MarkSeen(testFollowerID, story.ID); err != nil {
+		t.Fatalf("mark seen: %v", err)
+	}
+	tray, _ = service.GetTray(testFollowerID)
+	if tray[0].HasUnseen {
+		t.Errorf("expected the story to be seen, got %+v", tray[0])
+	}
+}
+
+func TestStoryServiceAccess(t *testing.T) {
+	repo := &memoryStoryRepository{
+		stories: []domain.Story{{ID: 1, AuthorID: testAuthorID, ExpiresAt: time.Now().Add(time.Hour)}},
+		seen:    make(map[[2]int]bool),
+	}
+
+	tests := []struct {
+		name    string
+		viewer  Viewer
+		blocks  stubBlocks
+		seen    error
+		viewers error
+	}{
+		{name: "author", viewer: Viewer{ID: testAuthorID}},
+		{name: "admin", viewer: Viewer{ID: testAdminID, IsAdmin: true}, seen: ErrForbidden},
+		{name: "follower", viewer: Viewer{ID: testFollowerID}, viewers: ErrForbidden},
+		{name: "blocked follower", viewer: Viewer{ID: testFollowerID}, blocks: stubBlocks{{testAuthorID, testFollowerID}: true}, seen: ErrForbidden, viewers: ErrForbidden},
+		{name: "stranger", viewer: Viewer{ID: testStrangerID}, seen: ErrForbidden, viewers: ErrForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := newTestStoryService(repo, tt.blocks)
+			if err := service.MarkSeen(tt.viewer.ID, 1); !errors.Is(err, tt.seen) {
+				t.Errorf("mark seen: expected %v, got %v", tt.seen, err)
+			}
+			if _, _, err := service.GetViewers(tt.viewer, 1, domain.PageRequest{Limit: 10}); !errors.Is(err, tt.viewers) {
+				t.Errorf("get viewers: expected %v, got %v", tt.viewers, err)
+			}
+		})
+	}
+
+	// The author watching their own story is not a view.
+	if !reflect.DeepEqual(repo.seen, map[[2]int]bool{{1, testFollowerID}: true}) {
+		t.Errorf("expected only the follower's view to be recorded, got %v", repo.seen)
+	}
+}
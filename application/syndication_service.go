@@ -0,0 +1,58 @@
+package application
+
+import (
+	"database/sql"
+
+	"github.com/bandvov/social-media-go/domain"
+)
+
+// DefaultSyndicationFeedSize is how many posts a user's RSS or Atom feed
+// contains.
+const DefaultSyndicationFeedSize = 20
+
+// SyndicationServiceInterface defines methods for public per-user feeds.
+type SyndicationServiceInterface interface {
+	GetUserFeed(username string) (*domain.UserFeed, error)
+}
+
+type SyndicationService struct {
+	userRepo domain.UserRepository
+	postRepo domain.PostRepository
+}
+
+func NewSyndicationService(userRepo domain.UserRepository, postRepo domain.PostRepository) *SyndicationService {
+	return &SyndicationService{
+		userRepo: userRepo,
+		postRepo: postRepo,
+	}
+}
+
+// GetUserFeed returns the latest public posts of a user for subscribers
+// outside the app. Banned users have no feed.
+func (s *SyndicationService) GetUserFeed(username string) (*domain.UserFeed, error) {
+	author, err := s.userRepo.GetUserByUsername(username)
+	if err != nil {
+		return nil, err
+	}
+	if author.Status == domain.UserStatusBanned {
+		return nil, sql.ErrNoRows
+	}
+
+	page := domain.PageRequest{Limit: DefaultSyndicationFeedSize}
+	posts, err := s.postRepo.FindByUserID(author.ID, 0, []domain.PostVisibility{domain.Public}, page)
+	if err != nil {
+		return nil, err
+	}
+	posts, _ = domain.NextPage(posts, page, postCursor)
+
+	feed := &domain.UserFeed{Author: *author, Posts: posts}
+	for _, post := range posts {
+		if post.CreatedAt.After(feed.Updated) {
+			feed.Updated = post.CreatedAt
+		}
+		if post.UpdatedAt.After(feed.Updated) {
+			feed.Updated = post.UpdatedAt
+		}
+	}
+	return feed, nil
+}
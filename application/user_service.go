@@ -15,10 +15,12 @@ type UserServiceInterface interface {
 	UpdateUserData(*domain.User) error
 	ChangeUserRole(userID int, newRole string, isAdmin bool) error
 	GetUserByID(id int) (*domain.User, error)
-	GetPublicProfiles(limit, offset int) ([]domain.User, error)
+	GetPublicProfiles(page domain.PageRequest) ([]domain.User, *domain.Cursor, error)
 	GetAdminProfiles(limit, offset int) ([]domain.User, error)
 	GetUserProfileInfo(id, otherUser int) (*domain.User, error)
 	GetUsersByIDs(userIDs []int) (map[int]domain.User, error)
+	UpdateSensitiveContent(userID int, preference string) error
+	UpdatePrivacy(userID int, private bool) error
 }
 type UserService struct {
 	userRepo domain.UserRepository
@@ -55,6 +57,9 @@ func (s *UserService) Authenticate(email, password string) (*domain.User, error)
 	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
 		return nil, errors.New("invalid email or password")
 	}
+	if user.Status == domain.UserStatusBanned {
+		return nil, ErrUserBanned
+	}
 	user.Password = ""
 
 	return user, nil
@@ -84,13 +89,41 @@ func (s *UserService) ChangeUserRole(userID int, newRole string, isAdmin bool) e
 	})
 }
 
+var ErrUserBanned = errors.New("user is banned")
+
+var ErrInvalidSensitiveContent = errors.New("invalid sensitive content preference")
+
+// UpdateSensitiveContent sets whether posts with a content warning are shown
+// expanded, collapsed behind the warning or hidden from the user's lists.
+func (s *UserService) UpdateSensitiveContent(userID int, preference string) error {
+	if !domain.IsValidSensitiveContent(preference) {
+		return ErrInvalidSensitiveContent
+	}
+	return s.userRepo.UpdateSensitiveContent(userID, preference)
+}
+
+// UpdatePrivacy makes the user's account private or public. Private accounts
+// are only shown among the users reacting to a post to their followers.
+func (s *UserService) UpdatePrivacy(userID int, private bool) error {
+	return s.userRepo.UpdatePrivacy(userID, private)
+}
+
 func (s *UserService) GetUserByID(id int) (*domain.User, error) {
 	return s.userRepo.GetUserByID(id)
 }
 
 // GetPublicProfiles retrieves public profiles with pagination
-func (s *UserService) GetPublicProfiles(limit, offset int) ([]domain.User, error) {
-	return s.userRepo.GetPublicProfiles(limit, offset)
+func (s *UserService) GetPublicProfiles(page domain.PageRequest) ([]domain.User, *domain.Cursor, error) {
+	users, err := s.userRepo.GetPublicProfiles(page)
+	if err != nil {
+		return nil, nil, err
+	}
+	users, next := domain.NextPage(users, page, userCursor)
+	return users, next, nil
+}
+
+func userCursor(user domain.User) domain.Cursor {
+	return domain.Cursor{CreatedAt: user.CreatedAt, ID: user.ID}
 }
 
 // GetAdminProfiles retrieves admin profiles with pagination
@@ -0,0 +1,136 @@
+package application
+
+import (
+	"github.com/bandvov/social-media-go/domain"
+)
+
+// maxReplyDepth bounds how far a reply chain is walked up to find its post
+// when the post is not stored with the reply.
+const maxReplyDepth = 32
+
+// Viewer identifies who is reading content.
+type Viewer struct {
+	ID          int
+	IsAdmin     bool
+	IsModerator bool // Admins and moderators
+	// SensitiveContent is the viewer's preference for flagged posts, see
+	// ApplyContentPreference.
+	SensitiveContent string
+}
+
+// VisibilityPolicy decides which posts, and therefore which comments and
+// reactions, a viewer may see:
+//
+//   - admins see everything, including hidden posts;
+//   - public and unlisted posts can be opened by anyone, but unlisted posts
+//     only show up in the author's own listings;
+//   - followers-only posts are shown to the author and their followers;
+//   - private posts are shown to the author only;
+//   - hidden posts are shown to admins and moderators, and to their author
+//     while they are held for review.
+type VisibilityPolicy struct {
+	followerRepo domain.FollowerRepository
+	postRepo     domain.PostRepository
+	commentRepo  domain.CommentRepository
+}
+
+func NewVisibilityPolicy(followerRepo domain.FollowerRepository, postRepo domain.PostRepository, commentRepo domain.CommentRepository) *VisibilityPolicy {
+	return &VisibilityPolicy{
+		followerRepo: followerRepo,
+		postRepo:     postRepo,
+		commentRepo:  commentRepo,
+	}
+}
+
+// CanView reports whether the viewer may open the post.
+func (p *VisibilityPolicy) CanView(viewer Viewer, post *domain.Post) (bool, error) {
+	if viewer.IsAdmin {
+		return true, nil
+	}
+
+	visibility := domain.Public
+	if post.Visibility != nil {
+		visibility = *post.Visibility
+	}
+
+	switch visibility {
+	case domain.Public, domain.Unlisted:
+		return true, nil
+	case domain.Private:
+		return post.AuthorID == viewer.ID, nil
+	case domain.Followers:
+		if post.AuthorID == viewer.ID {
+			return true, nil
+		}
+		return p.followerRepo.IsFollowing(viewer.ID, post.AuthorID)
+	case domain.Hidden:
+		return viewer.IsModerator || (post.HeldForReview && post.AuthorID == viewer.ID), nil
+	default:
+		return false, nil
+	}
+}
+
+// Follows reports whether the viewer follows the author.
+func (p *VisibilityPolicy) Follows(viewer Viewer, authorID int) (bool, error) {
+	return p.followerRepo.IsFollowing(viewer.ID, authorID)
+}
+
+// ListableVisibilities returns the visibilities of authorID's posts that may
+// appear when the viewer lists that author's posts.
+func (p *VisibilityPolicy) ListableVisibilities(viewer Viewer, authorID int) ([]domain.PostVisibility, error) {
+	if viewer.IsAdmin {
+		return []domain.PostVisibility{domain.Public, domain.Private, domain.Unlisted, domain.Followers, domain.Hidden}, nil
+	}
+	if viewer.ID == authorID {
+		return []domain.PostVisibility{domain.Public, domain.Private, domain.Unlisted, domain.Followers}, nil
+	}
+
+	following, err := p.followerRepo.IsFollowing(viewer.ID, authorID)
+	if err != nil {
+		return nil, err
+	}
+	if following {
+		return []domain.PostVisibility{domain.Public, domain.Followers}, nil
+	}
+	return []domain.PostVisibility{domain.Public}, nil
+}
+
+// CheckPost loads the post and returns ErrForbidden when the viewer may not
+// see it. A missing post is reported with the repository error.
+func (p *VisibilityPolicy) CheckPost(viewer Viewer, postID int) (*domain.Post, error) {
+	post, err := p.postRepo.GetByID(postID)
+	if err != nil {
+		return nil, err
+	}
+
+	ok, err := p.CanView(viewer, post)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrForbidden
+	}
+	return post, nil
+}
+
+// CheckComment finds the post a comment or reply belongs to and checks that
+// post.
+func (p *VisibilityPolicy) CheckComment(viewer Viewer, commentID int) error {
+	id := commentID
+	for i := 0; i < maxReplyDepth; i++ {
+		comment, err := p.commentRepo.GetCommentByID(id)
+		if err != nil {
+			return err
+		}
+		if comment.PostID != 0 {
+			_, err := p.CheckPost(viewer, comment.PostID)
+			return err
+		}
+		if comment.EntityType != domain.CommentTypeReply {
+			_, err := p.CheckPost(viewer, comment.EntityID)
+			return err
+		}
+		id = comment.EntityID
+	}
+	return ErrForbidden
+}
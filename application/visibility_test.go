@@ -0,0 +1,200 @@
+package application
+
+import (
+	"database/sql"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/bandvov/social-media-go/domain"
+	"github.com/bandvov/social-media-go/infrastructure"
+)
+
+const (
+	testAuthorID   = 1
+	testFollowerID = 2
+	testStrangerID = 3
+	testAdminID    = 4
+)
+
+func newTestVisibilityPolicy(posts map[int]*domain.Post, comments map[int]*domain.Comment) *VisibilityPolicy {
+	followerRepo := &infrastructure.MockFollowerRepository{
+		IsFollowingFunc: func(followerID, followeeID int) (bool, error) {
+			return followerID == testFollowerID && followeeID == testAuthorID, nil
+		},
+	}
+	postRepo := &infrastructure.MockPostRepository{
+		GetByIDFunc: func(id int) (*domain.Post, error) {
+			if post, ok := posts[id]; ok {
+				return post, nil
+			}
+			return nil, sql.ErrNoRows
+		},
+	}
+	commentRepo := &infrastructure.MockCommentRepository{
+		GetCommentByIDFunc: func(id int) (*domain.Comment, error) {
+			if comment, ok := comments[id]; ok {
+				return comment, nil
+			}
+			return nil, sql.ErrNoRows
+		},
+	}
+	return NewVisibilityPolicy(followerRepo, postRepo, commentRepo)
+}
+
+func postWithVisibility(id int, visibility domain.PostVisibility) *domain.Post {
+	return &domain.Post{ID: id, AuthorID: testAuthorID, Visibility: &visibility}
+}
+
+func TestVisibilityPolicyCanView(t *testing.T) {
+	author := Viewer{ID: testAuthorID}
+	follower := Viewer{ID: testFollowerID}
+	stranger := Viewer{ID: testStrangerID}
+	admin := Viewer{ID: testAdminID, IsAdmin: true}
+
+	tests := []struct {
+		name       string
+		visibility domain.PostVisibility
+		expected   map[Viewer]bool
+	}{
+		{
+			name:       "public",
+			visibility: domain.Public,
+			expected:   map[Viewer]bool{author: true, follower: true, stranger: true, admin: true},
+		},
+		{
+			name:       "unlisted",
+			visibility: domain.Unlisted,
+			expected:   map[Viewer]bool{author: true, follower: true, stranger: true, admin: true},
+		},
+		{
+			name:       "followers",
+			visibility: domain.Followers,
+			expected:   map[Viewer]bool{author: true, follower: true, stranger: false, admin: true},
+		},
+		{
+			name:       "private",
+			visibility: domain.Private,
+			expected:   map[Viewer]bool{author: true, follower: false, stranger: false, admin: true},
+		},
+		{
+			name:       "hidden",
+			visibility: domain.Hidden,
+			expected:   map[Viewer]bool{author: false, follower: false, stranger: false, admin: true},
+		},
+	}
+
+	policy := newTestVisibilityPolicy(nil, nil)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			post := postWithVisibility(10, tt.visibility)
+			for viewer, want := range tt.expected {
+				got, err := policy.CanView(viewer, post)
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if got != want {
+					t.Errorf("viewer %+v: expected %v, got %v", viewer, want, got)
+				}
+			}
+		})
+	}
+}
+
+func TestVisibilityPolicyListableVisibilities(t *testing.T) {
+	tests := []struct {
+		name     string
+		viewer   Viewer
+		expected []domain.PostVisibility
+	}{
+		{
+			name:     "admin sees every post",
+			viewer:   Viewer{ID: testAdminID, IsAdmin: true},
+			expected: []domain.PostVisibility{domain.Public, domain.Private, domain.Unlisted, domain.Followers, domain.Hidden},
+		},
+		{
+			name:     "author sees everything but hidden posts",
+			viewer:   Viewer{ID: testAuthorID},
+			expected: []domain.PostVisibility{domain.Public, domain.Private, domain.Unlisted, domain.Followers},
+		},
+		{
+			name:     "follower sees public and followers-only posts",
+			viewer:   Viewer{ID: testFollowerID},
+			expected: []domain.PostVisibility{domain.Public, domain.Followers},
+		},
+		{
+			name:     "stranger sees public posts only",
+			viewer:   Viewer{ID: testStrangerID},
+			expected: []domain.PostVisibility{domain.Public},
+		},
+	}
+
+	policy := newTestVisibilityPolicy(nil, nil)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := policy.ListableVisibilities(tt.viewer, testAuthorID)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestVisibilityPolicyCheckComment(t *testing.T) {
+	posts := map[int]*domain.Post{
+		10: postWithVisibility(10, domain.Public),
+		11: postWithVisibility(11, domain.Private),
+	}
+	comments := map[int]*domain.Comment{
+		100: {ID: 100, EntityID: 10, EntityType: domain.CommentTypeComment},
+		101: {ID: 101, EntityID: 100, EntityType: domain.CommentTypeReply},
+		110: {ID: 110, EntityID: 11, EntityType: domain.CommentTypeComment},
+		111: {ID: 111, EntityID: 110, EntityType: domain.CommentTypeReply},
+	}
+
+	tests := []struct {
+		name        string
+		viewer      Viewer
+		commentID   int
+		expectedErr error
+	}{
+		{"comment on public post", Viewer{ID: testStrangerID}, 100, nil},
+		{"reply on public post", Viewer{ID: testStrangerID}, 101, nil},
+		{"reply on private post by stranger", Viewer{ID: testStrangerID}, 111, ErrForbidden},
+		{"reply on private post by author", Viewer{ID: testAuthorID}, 111, nil},
+		{"missing comment", Viewer{ID: testAuthorID}, 999, sql.ErrNoRows},
+	}
+
+	policy := newTestVisibilityPolicy(posts, comments)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := policy.CheckComment(tt.viewer, tt.commentID)
+			if !errors.Is(err, tt.expectedErr) {
+				t.Errorf("expected error %v, got %v", tt.expectedErr, err)
+			}
+		})
+	}
+}
+
+func TestPostServiceGetPostsByUserAppliesVisibility(t *testing.T) {
+	var gotVisibilities []domain.PostVisibility
+	postRepo := &infrastructure.MockPostRepository{
+		GetPostsFunc: func(authorID int, visibilities []domain.PostVisibility, page domain.PageRequest) ([]domain.Post, error) {
+			gotVisibilities = visibilities
+			return nil, nil
+		},
+	}
+	followerRepo := &infrastructure.MockFollowerRepository{}
+	service := NewPostService(postRepo, nil, NewVisibilityPolicy(followerRepo, postRepo, nil), nil, nil, nil, nil, nil)
+
+	_, _, _, err := service.GetPostsByUser(testAuthorID, Viewer{ID: testStrangerID}, domain.PageRequest{Limit: 10})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(gotVisibilities, []domain.PostVisibility{domain.Public}) {
+		t.Errorf("expected only public posts to be listed, got %v", gotVisibilities)
+	}
+}
@@ -0,0 +1,233 @@
+package domain
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// ActivityPub constants used in federated documents.
+const (
+	ActivityStreamsContext = "https://www.w3.org/ns/activitystreams"
+	SecurityContext        = "https://w3id.org/security/v1"
+	PublicAudience         = "https://www.w3.org/ns/activitystreams#Public"
+	ActivityContentType    = "application/activity+json"
+)
+
+// Activity types handled by the inbox or sent to remote servers.
+const (
+	ActivityFollow = "Follow"
+	ActivityUndo   = "Undo"
+	ActivityCreate = "Create"
+	ActivityLike   = "Like"
+	ActivityAccept = "Accept"
+	ActivityDelete = "Delete"
+)
+
+// UserStatusRemote marks the local shadow account of a remote actor. Shadow
+// accounts cannot log in and exist so that remote follows, posts and likes
+// can be stored like local ones.
+const UserStatusRemote = "remote"
+
+var (
+	ErrInvalidActivity  = errors.New("invalid activity")
+	ErrInvalidSignature = errors.New("invalid http signature")
+)
+
+// Audience is an ActivityStreams address list. Remote servers send it
+// either as a single string or as an array.
+type Audience []string
+
+func (a *Audience) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*a = Audience{single}
+		return nil
+	}
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return err
+	}
+	*a = list
+	return nil
+}
+
+func (a Audience) Contains(address string) bool {
+	for _, v := range a {
+		if v == address {
+			return true
+		}
+	}
+	return false
+}
+
+type Activity struct {
+	Context   interface{}     `json:"@context,omitempty"`
+	ID        string          `json:"id"`
+	Type      string          `json:"type"`
+	Actor     string          `json:"actor"`
+	Object    json.RawMessage `json:"object"`
+	To        Audience        `json:"to,omitempty"`
+	Cc        Audience        `json:"cc,omitempty"`
+	Published string          `json:"published,omitempty"`
+}
+
+// ObjectID returns the id of the activity's object, which may be given as a
+// plain URI or as an embedded object.
+func (a *Activity) ObjectID() string {
+	var id string
+	if err := json.Unmarshal(a.Object, &id); err == nil {
+		return id
+	}
+	var object struct {
+		ID string `json:"id"`
+	}
+	if err := json.Unmarshal(a.Object, &object); err == nil {
+		return object.ID
+	}
+	return ""
+}
+
+// EmbeddedActivity decodes an object that is itself an activity, such as
+// the Follow inside an Undo.
+func (a *Activity) EmbeddedActivity() (*Activity, error) {
+	var embedded Activity
+	if err := json.Unmarshal(a.Object, &embedded); err != nil || embedded.Type == "" {
+		return nil, ErrInvalidActivity
+	}
+	return &embedded, nil
+}
+
+type Note struct {
+	Context      interface{} `json:"@context,omitempty"`
+	ID           string      `json:"id"`
+	Type         string      `json:"type"`
+	AttributedTo string      `json:"attributedTo"`
+	Content      string      `json:"content"`
+	Summary      string      `json:"summary,omitempty"` // Content warning
+	InReplyTo    string      `json:"inReplyTo,omitempty"`
+	Sensitive    bool        `json:"sensitive,omitempty"`
+	URL          string      `json:"url,omitempty"`
+	Published    string      `json:"published,omitempty"`
+	To           Audience    `json:"to,omitempty"`
+	Cc           Audience    `json:"cc,omitempty"`
+}
+
+type ActorPublicKey struct {
+	ID           string `json:"id"`
+	Owner        string `json:"owner"`
+	PublicKeyPem string `json:"publicKeyPem"`
+}
+
+type ActorEndpoints struct {
+	SharedInbox string `json:"sharedInbox,omitempty"`
+}
+
+type Actor struct {
+	Context           interface{}     `json:"@context,omitempty"`
+	ID                string          `json:"id"`
+	Type              string          `json:"type"`
+	PreferredUsername string          `json:"preferredUsername"`
+	Name              string          `json:"name,omitempty"`
+	Summary           string          `json:"summary,omitempty"`
+	URL               string          `json:"url,omitempty"`
+	Inbox             string          `json:"inbox"`
+	Outbox            string          `json:"outbox,omitempty"`
+	Endpoints         *ActorEndpoints `json:"endpoints,omitempty"`
+	PublicKey         ActorPublicKey  `json:"publicKey"`
+}
+
+type OrderedCollection struct {
+	Context      interface{}   `json:"@context,omitempty"`
+	ID           string        `json:"id"`
+	Type         string        `json:"type"`
+	TotalItems   int           `json:"totalItems,omitempty"`
+	First        string        `json:"first,omitempty"`
+	PartOf       string        `json:"partOf,omitempty"`
+	Next         string        `json:"next,omitempty"`
+	OrderedItems []interface{} `json:"orderedItems,omitempty"`
+}
+
+type WebFingerLink struct {
+	Rel  string `json:"rel"`
+	Type string `json:"type,omitempty"`
+	Href string `json:"href,omitempty"`
+}
+
+type WebFinger struct {
+	Subject string          `json:"subject"`
+	Aliases []string        `json:"aliases,omitempty"`
+	Links   []WebFingerLink `json:"links"`
+}
+
+// ActorKey is the RSA key pair a local user signs outgoing activities with.
+type ActorKey struct {
+	UserID        int
+	PublicKeyPEM  string
+	PrivateKeyPEM string
+}
+
+// RemoteActor is a user on another server, known locally through a shadow
+// account with the id UserID.
+type RemoteActor struct {
+	UserID       int
+	ActorURI     string
+	Username     string
+	Domain       string
+	InboxURL     string
+	SharedInbox  string
+	PublicKeyID  string
+	PublicKeyPEM string
+	FetchedAt    time.Time
+}
+
+// DeliveryInbox is where activities for the actor are sent, preferring the
+// shared inbox of the actor's server.
+func (a *RemoteActor) DeliveryInbox() string {
+	if a.SharedInbox != "" {
+		return a.SharedInbox
+	}
+	return a.InboxURL
+}
+
+// Delivery is an activity queued for sending to a remote inbox.
+type Delivery struct {
+	ID        int64
+	SenderID  int
+	InboxURL  string
+	Activity  []byte
+	Attempts  int
+	LastError string
+}
+
+type FederationRepository interface {
+	GetActorKey(userID int) (*ActorKey, error)
+	// SaveActorKey stores the key unless the user already has one.
+	SaveActorKey(key *ActorKey) error
+	GetRemoteActorByURI(actorURI string) (*RemoteActor, error)
+	GetRemoteActorByKeyID(keyID string) (*RemoteActor, error)
+	// SaveRemoteActor inserts or refreshes a remote actor, creating its
+	// shadow account on first sight, and sets actor.UserID.
+	SaveRemoteActor(actor *RemoteActor) error
+	// GetFollowerInboxes returns the distinct inboxes of the user's remote
+	// followers.
+	GetFollowerInboxes(userID int) ([]string, error)
+	GetRemotePostID(objectURI string) (int, error)
+	SaveRemotePost(objectURI string, postID int) error
+	GetReactionTypeID(name string) (int, error)
+
+	EnqueueDeliveries(deliveries []Delivery) error
+	// ClaimDeliveries returns up to limit deliveries that are due and hides
+	// them from other workers for the lease.
+	ClaimDeliveries(now time.Time, lease time.Duration, limit int) ([]Delivery, error)
+	CompleteDelivery(id int64) error
+	RetryDelivery(id int64, attempts int, nextAttemptAt time.Time, lastError string) error
+	FailDelivery(id int64, attempts int, lastError string) error
+}
+
+// FederationClient talks to remote ActivityPub servers.
+type FederationClient interface {
+	FetchActor(actorURI string) (*Actor, error)
+	// Deliver posts a signed activity to a remote inbox.
+	Deliver(inboxURL string, activity []byte, keyID, privateKeyPEM string) error
+}
@@ -0,0 +1,106 @@
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+// ViewSource tells where a post was seen.
+type ViewSource string
+
+const (
+	ViewSourcePost ViewSource = "post" // the post was opened
+	ViewSourceFeed ViewSource = "feed" // the post was shown in the home timeline
+)
+
+// AnalyticsGranularity is the size of the buckets of an analytics series.
+type AnalyticsGranularity string
+
+const (
+	GranularityHour AnalyticsGranularity = "hour"
+	GranularityDay  AnalyticsGranularity = "day"
+)
+
+// Longest ranges that can be requested for each granularity.
+const (
+	MaxHourlyAnalyticsRange = 14 * 24 * time.Hour
+	MaxDailyAnalyticsRange  = 366 * 24 * time.Hour
+)
+
+var (
+	ErrInvalidGranularity    = errors.New("granularity must be hour or day")
+	ErrInvalidAnalyticsRange = errors.New("from must be before to")
+	ErrAnalyticsRangeTooLong = errors.New("analytics range is too long")
+)
+
+// AnalyticsQuery selects the time range and bucket size of a series.
+type AnalyticsQuery struct {
+	Granularity AnalyticsGranularity
+	From        time.Time
+	To          time.Time
+}
+
+func (q AnalyticsQuery) Validate() error {
+	var max time.Duration
+	switch q.Granularity {
+	case GranularityHour:
+		max = MaxHourlyAnalyticsRange
+	case GranularityDay:
+		max = MaxDailyAnalyticsRange
+	default:
+		return ErrInvalidGranularity
+	}
+	if !q.From.Before(q.To) {
+		return ErrInvalidAnalyticsRange
+	}
+	if q.To.Sub(q.From) > max {
+		return ErrAnalyticsRangeTooLong
+	}
+	return nil
+}
+
+// AnalyticsPoint holds the activity of one bucket. UniqueViewers is only
+// filled in for a single post, it cannot be added up across posts.
+type AnalyticsPoint struct {
+	Bucket        time.Time `json:"bucket"`
+	Views         int       `json:"views"`
+	UniqueViewers int       `json:"unique_viewers,omitempty"`
+	Reactions     int       `json:"reactions"`
+	Comments      int       `json:"comments"`
+}
+
+// Add sums the counters of another bucket into the point.
+func (p *AnalyticsPoint) Add(other AnalyticsPoint) {
+	p.Views += other.Views
+	p.UniqueViewers += other.UniqueViewers
+	p.Reactions += other.Reactions
+	p.Comments += other.Comments
+}
+
+// Analytics is a time series together with its totals.
+type Analytics struct {
+	Granularity AnalyticsGranularity `json:"granularity"`
+	From        time.Time            `json:"from"`
+	To          time.Time            `json:"to"`
+	Totals      AnalyticsPoint       `json:"totals"`
+	Series      []AnalyticsPoint     `json:"series"`
+}
+
+// AnalyticsRepository stores post views and their rollups.
+type AnalyticsRepository interface {
+	// RecordViews stores one, already deduplicated, view of every post.
+	RecordViews(viewerID int, postIDs []int, source ViewSource) error
+	// Rollup recomputes the buckets of the given granularity starting with
+	// the one that contains since.
+	Rollup(granularity AnalyticsGranularity, since time.Time) error
+	DeleteViewsBefore(before time.Time) error
+	GetPostSeries(postID int, query AnalyticsQuery) ([]AnalyticsPoint, error)
+	GetAuthorSeries(authorID int, query AnalyticsQuery) ([]AnalyticsPoint, error)
+}
+
+// ViewDeduplicator remembers which posts a viewer has seen recently.
+type ViewDeduplicator interface {
+	// MarkViewed returns the posts the viewer has not seen within the window
+	// and marks them as seen.
+	MarkViewed(viewerID int, postIDs []int, window time.Duration) ([]int, error)
+}
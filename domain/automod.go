@@ -0,0 +1,175 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// MaxAutomodPatternLength limits the pattern of an auto-moderation rule.
+const MaxAutomodPatternLength = 500
+
+type AutomodRuleType string
+
+const (
+	AutomodKeyword    AutomodRuleType = "keyword"     // Case-insensitive whole word or phrase
+	AutomodRegex      AutomodRuleType = "regex"       // RE2 regular expression
+	AutomodLinkDomain AutomodRuleType = "link_domain" // Links to the domain or its subdomains
+)
+
+// AutomodAction is what happens to content matching a rule. When several
+// rules match, the strictest action wins: reject, then flag, then
+// content_warning.
+type AutomodAction string
+
+const (
+	AutomodReject         AutomodAction = "reject"          // Refuse the content
+	AutomodFlag           AutomodAction = "flag"            // Hold the content for review
+	AutomodContentWarning AutomodAction = "content_warning" // Force a content warning onto a post
+)
+
+// Severity orders actions from the most lenient to the strictest.
+func (a AutomodAction) Severity() int {
+	switch a {
+	case AutomodContentWarning:
+		return 1
+	case AutomodFlag:
+		return 2
+	case AutomodReject:
+		return 3
+	default:
+		return 0
+	}
+}
+
+// AutomodScope is the kind of content a rule is evaluated on.
+type AutomodScope string
+
+const (
+	AutomodScopeAll      AutomodScope = "all"
+	AutomodScopePosts    AutomodScope = "posts"
+	AutomodScopeComments AutomodScope = "comments"
+)
+
+var (
+	ErrInvalidAutomodRule = errors.New("invalid auto-moderation rule")
+	ErrContentRejected    = errors.New("content was rejected by auto-moderation")
+)
+
+type AutomodRule struct {
+	ID             int             `json:"id"`
+	Type           AutomodRuleType `json:"type"`
+	Pattern        string          `json:"pattern"`
+	Action         AutomodAction   `json:"action"`
+	Scope          AutomodScope    `json:"scope"`
+	ContentWarning string          `json:"content_warning,omitempty"` // Warning put on posts by content_warning rules
+	DryRun         bool            `json:"dry_run"`                   // Only count matches, don't act on them
+	Enabled        bool            `json:"enabled"`
+	Hits           int             `json:"hits"`         // Matches that were acted on
+	DryRunHits     int             `json:"dry_run_hits"` // Matches while the rule was in dry-run mode
+	LastHitAt      *time.Time      `json:"last_hit_at,omitempty"`
+	CreatedBy      int             `json:"created_by"`
+	CreatedAt      time.Time       `json:"created_at"`
+	UpdatedAt      time.Time       `json:"updated_at"`
+}
+
+// AppliesTo reports whether the rule is evaluated on content of the scope.
+func (r *AutomodRule) AppliesTo(scope AutomodScope) bool {
+	return r.Scope == AutomodScopeAll || r.Scope == scope
+}
+
+type AutomodRuleRequest struct {
+	Type           AutomodRuleType `json:"type"`
+	Pattern        string          `json:"pattern"`
+	Action         AutomodAction   `json:"action"`
+	Scope          AutomodScope    `json:"scope,omitempty"`
+	ContentWarning string          `json:"content_warning,omitempty"`
+	DryRun         bool            `json:"dry_run"`
+	Enabled        *bool           `json:"enabled,omitempty"` // Defaults to true
+}
+
+func (r *AutomodRuleRequest) Validate() error {
+	r.Pattern = strings.TrimSpace(r.Pattern)
+	r.ContentWarning = strings.TrimSpace(r.ContentWarning)
+	if r.Scope == "" {
+		r.Scope = AutomodScopeAll
+	}
+
+	if r.Pattern == "" || utf8.RuneCountInString(r.Pattern) > MaxAutomodPatternLength {
+		return invalidAutomodRule("pattern must be between 1 and 500 characters")
+	}
+	switch r.Type {
+	case AutomodKeyword:
+	case AutomodRegex:
+		if _, err := regexp.Compile(r.Pattern); err != nil {
+			return invalidAutomodRule("invalid regex: " + err.Error())
+		}
+	case AutomodLinkDomain:
+		r.Pattern = strings.Trim(strings.ToLower(r.Pattern), ".")
+		if strings.ContainsAny(r.Pattern, "/:@ \t") || !strings.Contains(r.Pattern, ".") {
+			return invalidAutomodRule("link_domain pattern must be a domain name such as example.com")
+		}
+	default:
+		return invalidAutomodRule("type must be keyword, regex or link_domain")
+	}
+
+	switch r.Scope {
+	case AutomodScopeAll, AutomodScopePosts, AutomodScopeComments:
+	default:
+		return invalidAutomodRule("scope must be all, posts or comments")
+	}
+
+	switch r.Action {
+	case AutomodReject, AutomodFlag:
+	case AutomodContentWarning:
+		if r.Scope != AutomodScopePosts {
+			return invalidAutomodRule("content_warning rules only apply to posts")
+		}
+		if r.ContentWarning == "" {
+			return invalidAutomodRule("content_warning rules need a content warning")
+		}
+		if err := ValidateContentWarning(r.ContentWarning); err != nil {
+			return invalidAutomodRule(err.Error())
+		}
+	default:
+		return invalidAutomodRule("action must be reject, flag or content_warning")
+	}
+	return nil
+}
+
+func invalidAutomodRule(reason string) error {
+	return fmt.Errorf("%w: %s", ErrInvalidAutomodRule, reason)
+}
+
+// AutomodMatch is a rule that matched a piece of content.
+type AutomodMatch struct {
+	RuleID int           `json:"rule_id"`
+	Action AutomodAction `json:"action"`
+	DryRun bool          `json:"dry_run"`
+}
+
+// AutomodVerdict is the outcome of evaluating content against the rules.
+// Action and ContentWarning only reflect rules that are not in dry-run mode.
+type AutomodVerdict struct {
+	Action         AutomodAction  `json:"action,omitempty"`
+	ContentWarning string         `json:"content_warning,omitempty"`
+	Matches        []AutomodMatch `json:"matches"`
+}
+
+// Held reports whether the content must wait for a moderator.
+func (v *AutomodVerdict) Held() bool {
+	return v != nil && v.Action == AutomodFlag
+}
+
+type AutomodRepository interface {
+	CreateRule(rule *AutomodRule) error
+	UpdateRule(rule *AutomodRule) error
+	DeleteRule(id int) error
+	GetRule(id int) (*AutomodRule, error)
+	ListRules() ([]AutomodRule, error)
+	// RecordHits bumps the hit counters of the matched rules.
+	RecordHits(matches []AutomodMatch) error
+}
@@ -0,0 +1,22 @@
+package domain
+
+// Block is a user hiding another user: neither of them sees the other in
+// search results.
+type Block struct {
+	BlockerID int
+	BlockedID int
+}
+
+func NewBlock(blockerID, blockedID int) *Block {
+	return &Block{
+		BlockerID: blockerID,
+		BlockedID: blockedID,
+	}
+}
+
+type BlockRepository interface {
+	AddBlock(block *Block) error
+	RemoveBlock(block *Block) error
+	// IsBlocked reports whether either user blocked the other.
+	IsBlocked(userID, otherUserID int) (bool, error)
+}
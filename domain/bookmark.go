@@ -0,0 +1,36 @@
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+// Bookmark is a post privately saved by a user, optionally into a collection.
+type Bookmark struct {
+	ID           int       `json:"id,omitempty"`
+	UserID       int       `json:"user_id,omitempty"`
+	PostID       int       `json:"post_id,omitempty"`
+	CollectionID *int      `json:"collection_id,omitempty"`
+	CreatedAt    time.Time `json:"created_at,omitempty"`
+	Post         *Post     `json:"post,omitempty"`
+}
+
+// BookmarkCollection is a named folder of bookmarks owned by a user.
+type BookmarkCollection struct {
+	ID             int       `json:"id,omitempty"`
+	UserID         int       `json:"user_id,omitempty"`
+	Name           string    `json:"name,omitempty"`
+	BookmarksCount int       `json:"bookmarks_count,omitempty"`
+	CreatedAt      time.Time `json:"created_at,omitempty"`
+}
+
+// Validate checks if the collection is valid.
+func (c *BookmarkCollection) Validate() error {
+	if c.Name == "" {
+		return errors.New("collection name cannot be empty")
+	}
+	if len(c.Name) > 100 {
+		return errors.New("collection name is too long")
+	}
+	return nil
+}
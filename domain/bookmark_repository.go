@@ -0,0 +1,12 @@
+package domain
+
+type BookmarkRepository interface {
+	AddBookmark(bookmark *Bookmark) error
+	RemoveBookmark(userID, postID int) error
+	GetBookmarks(userID int, collectionID *int, page PageRequest) ([]Bookmark, error)
+	GetBookmarkedPostIDs(userID int, postIDs []int) ([]int, error)
+	CreateCollection(collection *BookmarkCollection) error
+	GetCollections(userID int) ([]BookmarkCollection, error)
+	GetCollectionByID(id int) (*BookmarkCollection, error)
+	DeleteCollection(userID, id int) error
+}
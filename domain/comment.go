@@ -2,6 +2,7 @@ package domain
 
 import (
 	"encoding/json"
+	"errors"
 	"time"
 )
 
@@ -12,44 +13,89 @@ const (
 	CommentTypeReply   CommentType = "reply"
 )
 
-// PostVisibility represents the visibility of a post
-type CommentStatus int
+// CommentStatus is the state of a comment, as stored in the status column.
+type CommentStatus string
 
 const (
-	// Visibility constants
-	Active  CommentStatus = iota // status = "active"
-	Flagged                      // status = "flagged"
-	Deleted                      // status = "deleted"
+	CommentActive  CommentStatus = "active"
+	CommentFlagged CommentStatus = "flagged" // Held for review, only listed to its author
+	CommentHidden  CommentStatus = "hidden"  // Taken down by a moderator, or hidden by the author of the post
+	CommentDeleted CommentStatus = "deleted" // Kept as a tombstone so its replies stay in place
 )
 
-func (c CommentStatus) String() string {
-	switch c {
-	case Active:
-		return "active"
-	case Flagged:
-		return "flagged"
-	case Deleted:
-		return "deleted"
-	default:
-		return "Unknown"
+// DeletedCommentContent replaces the content of deleted comments.
+const DeletedCommentContent = "[deleted]"
+
+// CommentSort is the order top-level comments are listed in.
+type CommentSort string
+
+const (
+	CommentSortNewest        CommentSort = "newest"
+	CommentSortOldest        CommentSort = "oldest"
+	CommentSortTop           CommentSort = "top"           // Most reactions first
+	CommentSortControversial CommentSort = "controversial" // Most evenly split positive and negative reactions first
+)
+
+var (
+	ErrCommentContentRequired = errors.New("comment content is required")
+	ErrCommentNotEditable     = errors.New("deleted or hidden comments cannot be edited")
+	ErrInvalidCommentSort     = errors.New("sort must be top, newest, oldest or controversial")
+	ErrCommentSortMismatch    = errors.New("cursor belongs to a different sort")
+)
+
+func (s CommentSort) Validate() error {
+	switch s {
+	case CommentSortNewest, CommentSortOldest, CommentSortTop, CommentSortControversial:
+		return nil
 	}
+	return ErrInvalidCommentSort
+}
+
+// Scored reports whether comments are ordered by a score, in which case
+// their cursors carry it.
+func (s CommentSort) Scored() bool {
+	return s == CommentSortTop || s == CommentSortControversial
+}
+
+// CursorOf returns the position of a comment in this order.
+func (s CommentSort) CursorOf(comment Comment) Cursor {
+	cursor := Cursor{CreatedAt: comment.CreatedAt, ID: comment.ID}
+	switch s {
+	case CommentSortTop:
+		score := float64(comment.TotaReactionslCount)
+		cursor.Score = &score
+	case CommentSortControversial:
+		score := comment.Controversy
+		cursor.Score = &score
+	}
+	return cursor
 }
 
 type Comment struct {
 	ID                  int             `json:"id,omitempty"`
 	EntityID            int             `json:"entity_id,omitempty"`
 	EntityType          CommentType     `json:"entity_type,omitempty"`
+	ParentID            *int            `json:"parent_id,omitempty"` // The comment replied to, unset for top-level comments
+	PostID              int             `json:"post_id,omitempty"`
+	Depth               int             `json:"depth"` // 0 for top-level comments
 	Content             string          `json:"content,omitempty"`
+	ContentHTML         string          `json:"content_html,omitempty"` // Sanitized HTML rendered from the Markdown in Content
 	AuthorID            int             `json:"author_id,omitempty"`
 	Username            string          `json:"username,omitempty"`
 	ProfilePic          string          `json:"profile_pic,omitempty"`
 	Status              CommentStatus   `json:"status,omitempty"`
-	RepliesCount        int             `json:"replies_count,omitempty"`
+	RepliesCount        int             `json:"replies_count,omitempty"`     // Direct replies
+	DescendantsCount    int             `json:"descendants_count,omitempty"` // Replies at any depth below the comment
 	Reactions           json.RawMessage `json:"reactions,omitempty"`
 	TotaReactionslCount int             `json:"total_reactions_count,omitempty"`
 	UserReaction        string          `json:"user_reaction,omitempty"`
+	Controversy         float64         `json:"-"`                // Sort score of the controversial order
+	Pinned              bool            `json:"pinned,omitempty"` // Pinned by the author of the post
+	LinkPreviews        []LinkPreview   `json:"link_previews,omitempty"`
+	Mentions            []Mention       `json:"mentions,omitempty"`
 	CreatedAt           time.Time       `json:"created_at,omitempty"`
 	UpdatedAt           time.Time       `json:"updated_at,omitempty"`
+	EditedAt            *time.Time      `json:"edited_at,omitempty"` // Set once the author edits the comment
 }
 
 func (c *Comment) IsValidEntityId() bool {
@@ -65,6 +111,23 @@ func (c *Comment) IsValidContent() bool {
 	return c.Content != ""
 }
 
+const (
+	DefaultCommentTreeDepth = 3
+	MaxCommentTreeDepth     = 10
+	DefaultRepliesPerBranch = 3
+	MaxRepliesPerBranch     = 50
+)
+
+// CommentNode is a comment with the first replies of its branch.
+// MoreReplies is set when the branch has replies that were not loaded;
+// RepliesAfter is where to continue, or nil to start from the first reply.
+type CommentNode struct {
+	Comment
+	Replies      []CommentNode `json:"replies,omitempty"`
+	MoreReplies  bool          `json:"more_replies"`
+	RepliesAfter *Cursor       `json:"-"`
+}
+
 type CommentCount struct {
 	EntityID     int `json:"entity_id"`
 	CommentCount int `json:"comment_count"`
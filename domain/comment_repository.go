@@ -1,8 +1,28 @@
 package domain
 
 type CommentRepository interface {
-	AddComment(comment Comment) error
-	FetchCommentsByEntityID(entityID, userID, offset, limit int) ([]Comment, error)
+	AddComment(comment Comment) (int, error)
+	GetCommentByID(id int) (*Comment, error)
+	// FetchCommentsByEntityID returns up to limit+1 top-level comments of a
+	// post. The first page starts with the pinned comment, if any, marked
+	// Pinned and not counted towards the limit.
+	FetchCommentsByEntityID(entityID, userID int, sort CommentSort, page PageRequest) ([]Comment, error)
+	// FetchReplies returns up to limit+1 direct replies of each parent,
+	// oldest first.
+	FetchReplies(parentIDs []int, userID int, after *Cursor, limit int) ([]Comment, error)
 	GetCommentsByEntityIDs(entityIDs []int) ([]Comment, error)
 	CountByEntityIDs(entityIDs []int) ([]CommentCount, error)
+	GetCommentsWithoutHTML(limit int) ([]Comment, error)
+	SetContentHTML(id int, html string) error
+	Hide(id int) error
+	Release(id int) error
+	// SetHiddenByAuthor hides an active comment on behalf of the author of
+	// the post, or lists it again when hidden is false. It returns
+	// sql.ErrNoRows when the comment is in any other state.
+	SetHiddenByAuthor(id int, hidden bool) error
+	// UpdateContent stores edited content and sets EditedAt. It returns
+	// sql.ErrNoRows for deleted and hidden comments.
+	UpdateContent(comment *Comment) error
+	// Delete replaces the comment with a tombstone, keeping its replies.
+	Delete(id int) error
 }
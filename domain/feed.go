@@ -0,0 +1,15 @@
+package domain
+
+import "time"
+
+// TimelineEntry is a reference to a post stored in a user's home timeline.
+type TimelineEntry struct {
+	PostID    int
+	CreatedAt time.Time
+}
+
+// FeedRepository stores precomputed home timelines (fan-out-on-write).
+type FeedRepository interface {
+	AddToTimelines(userIDs []int, entry TimelineEntry) error
+	GetTimeline(userID int, before *Cursor, limit int) ([]TimelineEntry, error)
+}
@@ -3,6 +3,11 @@ package domain
 type FollowerRepository interface {
 	AddFollower(follower *Follower) error
 	RemoveFollower(follower *Follower) error
-	GetFollowers(userID, otherUser, limit, offset int, sort, orderBy, search string) ([]User, error)
-	GetFollowees(userID, otherUser, limit, offset int, sort, orderBy, search string) ([]User, error)
+	GetFollowers(userID, otherUser int, page PageRequest, sort, search string) ([]User, error)
+	GetFollowees(userID, otherUser int, page PageRequest, sort, search string) ([]User, error)
+	GetFollowerIDs(userID int) ([]int, error)
+	GetFolloweeIDs(userID int) ([]int, error)
+	IsFollowing(followerID, followeeID int) (bool, error)
+	CountFollowers(userID int) (int, error)
+	GetPopularFolloweeIDs(userID, minFollowers int) ([]int, error)
 }
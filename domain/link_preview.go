@@ -0,0 +1,39 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// Kinds of content link previews are attached to.
+const (
+	LinkEntityPost    = "post"
+	LinkEntityComment = "comment"
+)
+
+// LinkPreview is the OpenGraph/Twitter-card metadata of a URL, rendered as a
+// card under the post or comment that links to it.
+type LinkPreview struct {
+	URL         string    `json:"url"`
+	Title       string    `json:"title,omitempty"`
+	Description string    `json:"description,omitempty"`
+	ImageURL    string    `json:"image_url,omitempty"`
+	SiteName    string    `json:"site_name,omitempty"`
+	Failed      bool      `json:"-"` // The URL could not be unfurled, kept so it is not refetched right away
+	FetchedAt   time.Time `json:"fetched_at,omitempty"`
+}
+
+// LinkPreviewFetcher loads preview metadata from the web.
+type LinkPreviewFetcher interface {
+	Fetch(ctx context.Context, url string) (*LinkPreview, error)
+}
+
+// LinkPreviewRepository caches previews keyed by URL and remembers which
+// URLs every post or comment links to.
+type LinkPreviewRepository interface {
+	SetLinks(entityType string, entityID int, urls []string) error
+	DeleteLinks(entityType string, entityID int) error
+	GetCachedURLs(urls []string, fetchedAfter time.Time) (map[string]bool, error)
+	SavePreview(preview *LinkPreview) error
+	GetPreviewsByEntityIDs(entityType string, entityIDs []int) (map[int][]LinkPreview, error)
+}
@@ -0,0 +1,30 @@
+package domain
+
+// Kinds of content users can be mentioned in.
+const (
+	MentionEntityPost    = "post"
+	MentionEntityComment = "comment"
+)
+
+// MaxMentionsPerContent caps how many users one post or comment can mention.
+const MaxMentionsPerContent = 20
+
+// Mention is an @handle in a post or comment that links to a user. Start
+// and End are the character offsets of the handle in the content.
+type Mention struct {
+	UserID   int    `json:"user_id"`
+	Username string `json:"username"`
+	Start    int    `json:"start"`
+	End      int    `json:"end"`
+}
+
+// MentionRepository remembers which users every post or comment mentions.
+type MentionRepository interface {
+	// SetMentions replaces the users mentioned by a post or comment and
+	// returns the ones that were not mentioned before.
+	SetMentions(entityType string, entityID int, userIDs []int) ([]int, error)
+	DeleteMentions(entityType string, entityID int) error
+	// GetMentionsByEntityIDs returns the mentioned users of each entity,
+	// without offsets.
+	GetMentionsByEntityIDs(entityType string, entityIDs []int) (map[int][]Mention, error)
+}
@@ -0,0 +1,27 @@
+package domain
+
+// Notification types and entity types understood by the notifications
+// service, see notifications/domain/notification.go.
+const (
+	NotificationReportActioned  = "report_actioned"
+	NotificationReportDismissed = "report_dismissed"
+	NotificationNewMention      = "new_mention"
+
+	NotificationEntityReport  = "report"
+	NotificationEntityPost    = "post"
+	NotificationEntityComment = "comment"
+)
+
+// Notification is the payload accepted by the notifications service.
+type Notification struct {
+	UserID     int    `json:"user_id"`
+	Type       string `json:"type"`
+	EntityType string `json:"entity_type"`
+	EntityID   int    `json:"entity_id"`
+	SenderID   int    `json:"sender_id"`
+}
+
+// Notifier sends in-app notifications through the notifications service.
+type Notifier interface {
+	Notify(notification Notification) error
+}
@@ -0,0 +1,35 @@
+package domain
+
+import "time"
+
+// Cursor marks the position of the last item of a page in
+// (created_at, id) keyset order, or (score, id) order for lists sorted by a
+// score.
+type Cursor struct {
+	CreatedAt time.Time
+	ID        int
+	Score     *float64
+}
+
+// PageRequest describes which slice of a list should be returned.
+//
+// Repositories fetch up to Limit+1 rows; the extra row only tells whether a
+// next page exists and is dropped by NextPage.
+type PageRequest struct {
+	Limit int
+	After *Cursor
+	// Deprecated: Offset is only honoured for clients still sending
+	// page/offset parameters. Use After instead.
+	Offset int
+}
+
+// NextPage trims items to the requested limit and returns the cursor of the
+// following page, or nil when the list is exhausted.
+func NextPage[T any](items []T, page PageRequest, cursorOf func(T) Cursor) ([]T, *Cursor) {
+	if len(items) <= page.Limit {
+		return items, nil
+	}
+	items = items[:page.Limit]
+	next := cursorOf(items[len(items)-1])
+	return items, &next
+}
@@ -1,21 +1,31 @@
 package domain
 
 import (
+	"errors"
 	"time"
+	"unicode/utf8"
 )
 
 type CreatePostRequest struct {
-	AuthorID   int            `json:"author_id,omitempty"` // ID of the user who created the post
-	Content    string         `json:"content,omitempty"`
-	Pinned     bool           `json:"pinned,omitempty"`
-	Tags       string         `json:"tags,omitempty"`
-	Visibility PostVisibility `json:"visibility,omitempty"`
+	AuthorID        int            `json:"author_id,omitempty"` // ID of the user who created the post
+	Content         string         `json:"content,omitempty"`
+	ContentHTML     string         `json:"-"` // Rendered by the post service
+	Pinned          bool           `json:"pinned,omitempty"`
+	Tags            string         `json:"tags,omitempty"`
+	Visibility      PostVisibility `json:"visibility,omitempty"`
+	ContentWarning  string         `json:"content_warning,omitempty"`   // Shown in place of the content until the reader expands it
+	Sensitive       bool           `json:"sensitive,omitempty"`         // Marks attached media as sensitive
+	ContinuesPostID *int           `json:"continues_post_id,omitempty"` // Previous post of the author's thread
+	CommentPolicy   CommentPolicy  `json:"comment_policy,omitempty"`    // Everyone when unset
+	WarningForced   bool           `json:"-"`                           // Set when auto-moderation put the warning on the post
+	HoldForReview   bool           `json:"-"`                           // Set when auto-moderation holds the post for a moderator
 }
 
 type Post struct {
 	ID                  int             `json:"id,omitempty"`
 	AuthorID            int             `json:"author_id,omitempty"` // ID of the user who created the post
 	Content             string          `json:"content,omitempty"`
+	ContentHTML         string          `json:"content_html,omitempty"` // Sanitized HTML rendered from the Markdown in Content
 	AuthorName          string          `json:"author_name,omitempty"`
 	Pinned              bool            `json:"pinned,omitempty"`
 	Tags                string          `json:"tags,omitempty"`
@@ -27,6 +37,111 @@ type Post struct {
 	TotaReactionslCount int             `json:"total_reactions_count,omitempty"`
 	TotalCommentsCount  int             `json:"total_comments_count,omitempty"`
 	UserReaction        string          `json:"user_reaction,omitempty"`
+	Bookmarked          bool            `json:"bookmarked,omitempty"` // Whether the requesting user saved the post
+	LinkPreviews        []LinkPreview   `json:"link_previews,omitempty"`
+	Mentions            []Mention       `json:"mentions,omitempty"`
+	ContentWarning      string          `json:"content_warning,omitempty"`
+	Sensitive           bool            `json:"sensitive,omitempty"`
+	WarningForced       bool            `json:"warning_forced,omitempty"`       // Set when a moderator put the warning on the post
+	Collapsed           bool            `json:"collapsed,omitempty"`            // Whether the requesting user's preferences collapse the post
+	ContinuesPostID     *int            `json:"continues_post_id,omitempty"`    // Previous post of the thread
+	ThreadRootID        *int            `json:"thread_root_id,omitempty"`       // First post of the thread, unset on the first post itself
+	ThreadContinuations int             `json:"thread_continuations,omitempty"` // Posts collapsed under the first post of a thread in listings
+	HeldForReview       bool            `json:"held_for_review,omitempty"`      // Hidden until a moderator reviews it
+	CommentPolicy       CommentPolicy   `json:"comment_policy,omitempty"`
+	PinnedCommentID     *int            `json:"pinned_comment_id,omitempty"` // Listed above the other comments
+}
+
+var (
+	ErrInvalidThread        = errors.New("a post can only continue one of the author's own posts")
+	ErrPostAlreadyContinued = errors.New("post is already continued by another post")
+	ErrThreadVisibility     = errors.New("the visibility of a thread can only be changed on its first post")
+)
+
+// ThreadRoot returns the ID of the first post of the thread the post is in.
+func (p *Post) ThreadRoot() int {
+	if p.ThreadRootID != nil {
+		return *p.ThreadRootID
+	}
+	return p.ID
+}
+
+// UnlinkFromThread returns the posts of a thread whose links change when the
+// post with postID is removed from it: the next post continues the previous
+// one instead, and when the first post goes away the next one starts the
+// thread.
+func UnlinkFromThread(thread []Post, postID int) []Post {
+	var removed, next *Post
+	for i := range thread {
+		if thread[i].ID == postID {
+			removed = &thread[i]
+		}
+		if thread[i].ContinuesPostID != nil && *thread[i].ContinuesPostID == postID {
+			next = &thread[i]
+		}
+	}
+	if removed == nil || next == nil {
+		return nil
+	}
+
+	relinked := *next
+	relinked.ContinuesPostID = removed.ContinuesPostID
+	if removed.ThreadRootID != nil {
+		return []Post{relinked}
+	}
+
+	relinked.ThreadRootID = nil
+	changed := []Post{relinked}
+	for _, post := range thread {
+		if post.ID != next.ID && post.ThreadRootID != nil && *post.ThreadRootID == postID {
+			post.ThreadRootID = &relinked.ID
+			changed = append(changed, post)
+		}
+	}
+	return changed
+}
+
+// CommentPolicy is who may comment on a post, besides its author.
+type CommentPolicy string
+
+const (
+	CommentPolicyEveryone  CommentPolicy = "everyone"
+	CommentPolicyFollowers CommentPolicy = "followers" // The author's followers
+	CommentPolicyMentioned CommentPolicy = "mentioned" // Users @mentioned in the post
+	CommentPolicyDisabled  CommentPolicy = "disabled"
+)
+
+var (
+	ErrInvalidCommentPolicy = errors.New("comment policy must be everyone, followers, mentioned or disabled")
+	ErrCommentsDisabled     = errors.New("comments are disabled on this post")
+	ErrCommentsRestricted   = errors.New("the author limited who can comment on this post")
+	ErrInvalidPinnedComment = errors.New("only an active top-level comment of the post can be pinned")
+)
+
+func (p CommentPolicy) Validate() error {
+	switch p {
+	case CommentPolicyEveryone, CommentPolicyFollowers, CommentPolicyMentioned, CommentPolicyDisabled:
+		return nil
+	}
+	return ErrInvalidCommentPolicy
+}
+
+// MaxContentWarningLength limits the length of a content warning.
+const MaxContentWarningLength = 200
+
+var ErrContentWarningTooLong = errors.New("content warning is too long")
+
+// IsFlagged reports whether the post carries a content warning or sensitive media.
+func (p *Post) IsFlagged() bool {
+	return p.Sensitive || p.ContentWarning != ""
+}
+
+// ValidateContentWarning checks the length of a content warning.
+func ValidateContentWarning(warning string) error {
+	if utf8.RuneCountInString(warning) > MaxContentWarningLength {
+		return ErrContentWarningTooLong
+	}
+	return nil
 }
 
 // PostVisibility represents the visibility of a post
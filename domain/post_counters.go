@@ -0,0 +1,52 @@
+package domain
+
+// Fields of the post counters.
+const (
+	CounterReactions = "reactions"
+	CounterComments  = "comments" // Active top-level comments
+	CounterReplies   = "replies"  // Active replies at any depth
+)
+
+// PostCounters are the totals shown with a post.
+type PostCounters struct {
+	PostID    int
+	Reactions int
+	Comments  int
+	Replies   int
+}
+
+// CounterStore keeps post counters where they are cheap to read and update.
+// Counters that changed since they were last flushed are marked dirty.
+type CounterStore interface {
+	// Get returns the cached counters of the given posts. Posts whose
+	// counters are not cached are left out.
+	Get(postIDs []int) (map[int]PostCounters, error)
+	// Prime caches counters of posts that are not cached yet.
+	Prime(counters []PostCounters) error
+	// Increment changes a counter of a cached post and marks it dirty. It
+	// reports false, without changing anything, when the post is not cached.
+	Increment(postID int, field string, delta int) (bool, error)
+	// Overwrite replaces cached counters that still hold the expected
+	// values and are not dirty, marks them dirty so the new values get
+	// flushed, and returns how many it replaced. Counters that changed since
+	// expected was read are left alone, so no change is lost.
+	Overwrite(counters []PostCounters, expected map[int]PostCounters) (int, error)
+	// TakeDirty unmarks up to limit dirty posts and returns their counters.
+	TakeDirty(limit int) ([]PostCounters, error)
+	// MarkDirty marks posts dirty again, when flushing them failed.
+	MarkDirty(postIDs []int) error
+}
+
+// PostCounterRepository stores post counters in denormalized columns and
+// recounts them from reactions and comments.
+type PostCounterRepository interface {
+	GetPostCounters(postIDs []int) ([]PostCounters, error)
+	SavePostCounters(counters []PostCounters) error
+	// RepairPostCounters saves counters of posts whose columns still hold
+	// the expected values, and returns how many it saved.
+	RepairPostCounters(counters []PostCounters, expected map[int]PostCounters) (int, error)
+	// ListPostIDs returns up to limit post IDs above afterID, in order.
+	ListPostIDs(afterID, limit int) ([]int, error)
+	// RecountPosts counts the reactions and comments of the given posts.
+	RecountPosts(postIDs []int) ([]PostCounters, error)
+}
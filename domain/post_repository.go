@@ -1,11 +1,26 @@
 package domain
 
 type PostRepository interface {
-	Create(post *CreatePostRequest) error
+	Create(post *CreatePostRequest) (*Post, error)
 	GetByID(id int) (*Post, error)
 	Update(id int, post *Post) error
+	SetContentWarning(postID int, warning string, sensitive bool) error
+	SetVisibility(postID int, visibility PostVisibility) error
+	// SetThreadVisibility gives the continuations of a thread the visibility
+	// of its first post, leaving hidden ones hidden.
+	SetThreadVisibility(rootID int, visibility PostVisibility) error
+	SetCommentPolicy(postID int, policy CommentPolicy) error
+	// SetPinnedComment pins a comment of the post, or unpins with nil.
+	SetPinnedComment(postID int, commentID *int) error
+	Hold(postID int) error
+	Release(postID int) error
+	GetPostsWithoutHTML(limit int) ([]Post, error)
+	SetContentHTML(id int, html string) error
 	Delete(id int) error
-	FindByUserID(userID, otherUserId, offset, limit int) ([]Post, error)
+	GetThread(rootID int) ([]Post, error)
+	FindByUserID(userID, otherUserId int, visibilities []PostVisibility, page PageRequest) ([]Post, error)
 	GetCountPostsByUser(userId int) (int, error)
-	GetPosts(authorID, offset, limit int) ([]Post, error)
+	GetPosts(authorID int, visibilities []PostVisibility, page PageRequest) ([]Post, error)
+	GetFeedPostsByIDs(viewerID int, postIDs []int) ([]Post, error)
+	GetFeedPostsByAuthors(viewerID int, authorIDs []int, before *Cursor, limit int) ([]Post, error)
 }
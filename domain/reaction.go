@@ -1,14 +1,58 @@
 package domain
 
+import (
+	"errors"
+	"time"
+)
+
+// Kinds of content a reaction can be attached to.
+const (
+	ReactionEntityPost    = "post"
+	ReactionEntityComment = "comment"
+)
+
+var ErrInvalidReactionEntity = errors.New("entity_type must be post or comment")
+
 type Reaction struct {
-	EntityId int    `json:"entity_id"`
-	Reaction string `json:"reaction_type_id"`
-	Count    int    `json:"count"`
+	EntityId   int    `json:"entity_id"`
+	EntityType string `json:"entity_type,omitempty"` // "post" (default) or "comment"
+	Reaction   string `json:"reaction_type_id"`
+	Count      int    `json:"count"`
+}
+
+// ValidateReactionEntityType checks the kind of content a reaction is for.
+// An empty type stands for a post.
+func ValidateReactionEntityType(entityType string) error {
+	switch entityType {
+	case "", ReactionEntityPost, ReactionEntityComment:
+		return nil
+	}
+	return ErrInvalidReactionEntity
+}
+
+// Reactor is a user who reacted to a post or comment.
+type Reactor struct {
+	ReactionID int       `json:"-"`
+	UserID     int       `json:"user_id"`
+	Username   string    `json:"username"`
+	ProfilePic string    `json:"profile_pic,omitempty"`
+	Reaction   string    `json:"reaction_type"`
+	ReactedAt  time.Time `json:"reacted_at"`
 }
 
+// ReactionRepository stores reactions keyed by entity type and ID, so post 5
+// and comment 5 have reactions of their own.
 type ReactionRepository interface {
-	AddOrUpdateReaction(userId int, reaction Reaction) error
-	RemoveReaction(userID, contentID string) error
-	GetReactionsByEntityIDs(entityIDs []int) ([]Reaction, error)
-	CountByEntityIDs(entityIDs []int) ([]Reaction, error)
+	// AddOrUpdateReaction reports whether the reaction is new rather than
+	// a change of the user's reaction type.
+	AddOrUpdateReaction(userId int, reaction Reaction) (bool, error)
+	// RemoveReaction reports whether there was a reaction to remove.
+	RemoveReaction(userID, entityType, contentID string) (bool, error)
+	GetReactionsByEntityIDs(entityType string, entityIDs []int) ([]Reaction, error)
+	CountByEntityIDs(entityType string, entityIDs []int) ([]Reaction, error)
+	// GetReactors lists who reacted to a post or comment, most recent first,
+	// optionally with one reaction type only. Users blocked by or blocking
+	// the viewer are left out, as are private accounts the viewer does not
+	// follow. Only IDs, reaction types and times are filled in.
+	GetReactors(entityType string, entityID, viewerID int, reactionType string, page PageRequest) ([]Reactor, error)
 }
@@ -0,0 +1,112 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	// DefaultReactionVerb is used in notifications about reactions of types
+	// without a verb of their own.
+	DefaultReactionVerb = "reacted to"
+
+	MaxReactionVerbLength  = 50
+	MaxReactionEmojiLength = 8 // In characters, enough for emoji sequences with modifiers
+	MaxReactionImageURL    = 255
+)
+
+// reactionTypeNamePattern keeps names usable in notification types, which
+// are new_reaction_ followed by the lowercased name.
+var reactionTypeNamePattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_]{0,49}$`)
+
+var (
+	ErrInvalidReactionType = errors.New("invalid reaction type")
+	ErrUnknownReactionType = errors.New("reaction type does not exist or is disabled")
+	ErrReactionTypeExists  = errors.New("a reaction type with this name already exists")
+	ErrReactionTypeInUse   = errors.New("reaction type is in use, disable it instead")
+)
+
+// ReactionType is a reaction users can pick, shown as a unicode emoji or as
+// a custom image. Disabled types are kept for existing reactions but cannot
+// be picked anymore.
+type ReactionType struct {
+	ID        int       `json:"id"`
+	Name      string    `json:"name"`
+	Emoji     string    `json:"emoji,omitempty"`
+	ImageURL  string    `json:"image_url,omitempty"`
+	Verb      string    `json:"verb"`     // Used in notifications, as in "Ann liked your post"
+	Polarity  int       `json:"polarity"` // 1 for positive, -1 for negative reactions, used to sort comments
+	Position  int       `json:"position"` // Types are listed by position, then by ID
+	Enabled   bool      `json:"enabled"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type ReactionTypeRequest struct {
+	Name     string `json:"name"`
+	Emoji    string `json:"emoji,omitempty"`
+	ImageURL string `json:"image_url,omitempty"`
+	Verb     string `json:"verb,omitempty"`
+	Polarity int    `json:"polarity"`
+	Position int    `json:"position"`
+	Enabled  *bool  `json:"enabled,omitempty"` // Defaults to true
+}
+
+func (r *ReactionTypeRequest) Validate() error {
+	r.Name = strings.TrimSpace(r.Name)
+	r.Emoji = strings.TrimSpace(r.Emoji)
+	r.ImageURL = strings.TrimSpace(r.ImageURL)
+	r.Verb = strings.TrimSpace(r.Verb)
+	if r.Verb == "" {
+		r.Verb = DefaultReactionVerb
+	}
+
+	if !reactionTypeNamePattern.MatchString(r.Name) {
+		return invalidReactionType("name must start with a letter and have up to 50 letters, digits or underscores")
+	}
+	if (r.Emoji == "") == (r.ImageURL == "") {
+		return invalidReactionType("either emoji or image_url must be set")
+	}
+	if utf8.RuneCountInString(r.Emoji) > MaxReactionEmojiLength {
+		return invalidReactionType(fmt.Sprintf("emoji must be at most %d characters", MaxReactionEmojiLength))
+	}
+	if r.ImageURL != "" {
+		parsed, err := url.Parse(r.ImageURL)
+		if err != nil || (parsed.Scheme != "https" && parsed.Scheme != "http") || parsed.Host == "" || len(r.ImageURL) > MaxReactionImageURL {
+			return invalidReactionType("image_url must be an http or https URL of up to 255 characters")
+		}
+	}
+	if utf8.RuneCountInString(r.Verb) > MaxReactionVerbLength {
+		return invalidReactionType(fmt.Sprintf("verb must be at most %d characters", MaxReactionVerbLength))
+	}
+	if r.Polarity < -1 || r.Polarity > 1 {
+		return invalidReactionType("polarity must be -1, 0 or 1")
+	}
+	if r.Position < 0 {
+		return invalidReactionType("position must not be negative")
+	}
+	return nil
+}
+
+func invalidReactionType(reason string) error {
+	return fmt.Errorf("%w: %s", ErrInvalidReactionType, reason)
+}
+
+type ReactionTypeRepository interface {
+	// List returns reaction types ordered by position, only the enabled
+	// ones unless all is set.
+	List(all bool) ([]ReactionType, error)
+	GetByID(id int) (*ReactionType, error)
+	// Create and Update fail with ErrReactionTypeExists when the name is
+	// taken, regardless of case.
+	Create(reactionType *ReactionType) error
+	Update(reactionType *ReactionType) error
+	// Delete fails with ErrReactionTypeInUse while reactions of the type
+	// exist.
+	Delete(id int) error
+}
@@ -0,0 +1,163 @@
+package domain
+
+import (
+	"errors"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// MaxReportDetailsLength limits the free text a reporter can add.
+const MaxReportDetailsLength = 1000
+
+type ReportTargetType string
+
+const (
+	ReportTargetPost    ReportTargetType = "post"
+	ReportTargetComment ReportTargetType = "comment"
+	ReportTargetUser    ReportTargetType = "user"
+)
+
+type ReportReason string
+
+const (
+	ReportReasonSpam           ReportReason = "spam"
+	ReportReasonHarassment     ReportReason = "harassment"
+	ReportReasonHate           ReportReason = "hate"
+	ReportReasonViolence       ReportReason = "violence"
+	ReportReasonNudity         ReportReason = "nudity"
+	ReportReasonMisinformation ReportReason = "misinformation"
+	ReportReasonSelfHarm       ReportReason = "self_harm"
+	ReportReasonOther          ReportReason = "other"
+
+	// ReportReasonAutomod marks the reports auto-moderation files for the
+	// content it holds. They have no reporter.
+	ReportReasonAutomod ReportReason = "automod"
+)
+
+type ReportStatus string
+
+const (
+	ReportStatusOpen     ReportStatus = "open"
+	ReportStatusResolved ReportStatus = "resolved"
+)
+
+// ReportResolution is the action a moderator took on a report.
+type ReportResolution string
+
+const (
+	ResolutionDismiss ReportResolution = "dismiss" // Nothing wrong, held content is released
+	ResolutionHide    ReportResolution = "hide"    // Hide the post or comment
+	ResolutionDelete  ReportResolution = "delete"  // Delete the post or comment
+	ResolutionBan     ReportResolution = "ban"     // Ban the reported user or the author of the content
+)
+
+var (
+	ErrInvalidReportTarget     = errors.New("invalid report target")
+	ErrInvalidReportReason     = errors.New("invalid report reason")
+	ErrReportDetailsTooLong    = errors.New("report details are too long")
+	ErrReportDetailsRequired   = errors.New("report details are required when the reason is other")
+	ErrInvalidResolution       = errors.New("invalid resolution")
+	ErrDuplicateReport         = errors.New("you already reported this")
+	ErrReportAlreadyResolved   = errors.New("report is already resolved")
+	ErrCannotReportOwnContent  = errors.New("you cannot report yourself or your own content")
+	ErrResolutionNotApplicable = errors.New("resolution does not apply to this report")
+)
+
+func IsValidReportReason(reason ReportReason) bool {
+	switch reason {
+	case ReportReasonSpam, ReportReasonHarassment, ReportReasonHate, ReportReasonViolence,
+		ReportReasonNudity, ReportReasonMisinformation, ReportReasonSelfHarm, ReportReasonOther:
+		return true
+	}
+	return false
+}
+
+func IsValidReportTarget(target ReportTargetType) bool {
+	switch target {
+	case ReportTargetPost, ReportTargetComment, ReportTargetUser:
+		return true
+	}
+	return false
+}
+
+type Report struct {
+	ID             int              `json:"id"`
+	ReporterID     int              `json:"reporter_id,omitempty"` // Unset on reports filed by auto-moderation
+	TargetType     ReportTargetType `json:"target_type"`
+	TargetID       int              `json:"target_id"`
+	TargetOwnerID  int              `json:"target_owner_id"` // Author of the reported content, or the reported user
+	Reason         ReportReason     `json:"reason"`
+	Details        string           `json:"details,omitempty"`
+	Status         ReportStatus     `json:"status"`
+	AssigneeID     *int             `json:"assignee_id,omitempty"`
+	Resolution     ReportResolution `json:"resolution,omitempty"`
+	ResolutionNote string           `json:"resolution_note,omitempty"`
+	ResolvedBy     *int             `json:"resolved_by,omitempty"`
+	CreatedAt      time.Time        `json:"created_at"`
+	ResolvedAt     *time.Time       `json:"resolved_at,omitempty"`
+}
+
+type CreateReportRequest struct {
+	TargetType ReportTargetType `json:"target_type"`
+	TargetID   int              `json:"target_id"`
+	Reason     ReportReason     `json:"reason"`
+	Details    string           `json:"details,omitempty"`
+}
+
+func (r *CreateReportRequest) Validate() error {
+	r.Details = strings.TrimSpace(r.Details)
+
+	if !IsValidReportTarget(r.TargetType) || r.TargetID <= 0 {
+		return ErrInvalidReportTarget
+	}
+	if !IsValidReportReason(r.Reason) {
+		return ErrInvalidReportReason
+	}
+	if r.Reason == ReportReasonOther && r.Details == "" {
+		return ErrReportDetailsRequired
+	}
+	if utf8.RuneCountInString(r.Details) > MaxReportDetailsLength {
+		return ErrReportDetailsTooLong
+	}
+	return nil
+}
+
+type ResolveReportRequest struct {
+	Resolution ReportResolution `json:"resolution"`
+	Note       string           `json:"note,omitempty"`
+}
+
+func (r *ResolveReportRequest) Validate() error {
+	r.Note = strings.TrimSpace(r.Note)
+
+	switch r.Resolution {
+	case ResolutionDismiss, ResolutionHide, ResolutionDelete, ResolutionBan:
+	default:
+		return ErrInvalidResolution
+	}
+	if utf8.RuneCountInString(r.Note) > MaxReportDetailsLength {
+		return ErrReportDetailsTooLong
+	}
+	return nil
+}
+
+// ReportQueueFilter narrows the moderation queue. Zero values match
+// everything; AssigneeID -1 matches unassigned reports.
+type ReportQueueFilter struct {
+	Status     ReportStatus
+	TargetType ReportTargetType
+	AssigneeID int
+}
+
+type ReportRepository interface {
+	Create(report *Report) error
+	GetByID(id int) (*Report, error)
+	// ListQueue returns reports oldest first, so the queue is worked in
+	// the order reports came in.
+	ListQueue(filter ReportQueueFilter, page PageRequest) ([]Report, error)
+	Assign(id int, assigneeID *int) error
+	// Resolve closes every open report on the same target as the given
+	// report and returns them.
+	Resolve(id, moderatorID int, resolution ReportResolution, note string) ([]Report, error)
+}
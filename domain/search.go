@@ -0,0 +1,70 @@
+package domain
+
+import "errors"
+
+// SearchType selects what GET /api/search looks for.
+type SearchType string
+
+const (
+	SearchPosts SearchType = "posts"
+	SearchUsers SearchType = "users"
+	SearchTags  SearchType = "tags"
+)
+
+const maxSearchQueryLength = 200
+
+// SearchQuery is a ranked, offset-paginated search request.
+type SearchQuery struct {
+	Query  string
+	Type   SearchType
+	Limit  int
+	Offset int
+}
+
+// Validate checks if the search request is valid.
+func (q *SearchQuery) Validate() error {
+	if q.Query == "" {
+		return errors.New("search query cannot be empty")
+	}
+	if len(q.Query) > maxSearchQueryLength {
+		return errors.New("search query is too long")
+	}
+	switch q.Type {
+	case SearchPosts, SearchUsers, SearchTags:
+		return nil
+	default:
+		return errors.New("invalid search type")
+	}
+}
+
+// PostSearchResult is a post matching a search, with the matching fragments
+// of its content wrapped in <mark> tags.
+type PostSearchResult struct {
+	Post
+	Rank      float64 `json:"rank"`
+	Highlight string  `json:"highlight,omitempty"`
+}
+
+// UserSearchResult is a user matching a search by handle, name or bio.
+type UserSearchResult struct {
+	ID         int     `json:"id"`
+	Username   *string `json:"username,omitempty"`
+	FirstName  *string `json:"first_name,omitempty"`
+	LastName   *string `json:"last_name,omitempty"`
+	ProfilePic *string `json:"profile_pic,omitempty"`
+	Rank       float64 `json:"rank"`
+	Highlight  string  `json:"highlight,omitempty"`
+}
+
+// TagSearchResult is a tag whose name is similar to the search.
+type TagSearchResult struct {
+	ID   int     `json:"id"`
+	Name string  `json:"name"`
+	Rank float64 `json:"rank"`
+}
+
+type SearchRepository interface {
+	SearchPosts(viewerID int, query SearchQuery) ([]PostSearchResult, error)
+	SearchUsers(viewerID int, query SearchQuery) ([]UserSearchResult, error)
+	SearchTags(query SearchQuery) ([]TagSearchResult, error)
+}
@@ -0,0 +1,126 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// SpamActivity is a kind of action checked by the spam filter.
+type SpamActivity string
+
+const (
+	SpamActivityPost    SpamActivity = "post"
+	SpamActivityComment SpamActivity = "comment"
+	SpamActivityFollow  SpamActivity = "follow"
+)
+
+// SpamAction is what the spam filter does with an action.
+type SpamAction string
+
+const (
+	SpamAllow       SpamAction = ""
+	SpamThrottle    SpamAction = "throttle"     // Refuse the action for now
+	SpamHold        SpamAction = "hold"         // Hold the content for review
+	SpamShadowLimit SpamAction = "shadow_limit" // Accept the action but keep it from reaching others
+)
+
+// AccountStanding is how the spam filter treats an account. Accounts without
+// a stored standing are scored normally.
+type AccountStanding string
+
+const (
+	StandingNormal        AccountStanding = "normal"
+	StandingTrusted       AccountStanding = "trusted"        // Never checked
+	StandingShadowLimited AccountStanding = "shadow_limited" // Posts are not spread, comments and follows are only visible to the account
+)
+
+var ErrInvalidStanding = errors.New("standing must be normal, trusted or shadow_limited")
+
+// RateLimitError is returned when an account acts faster than the spam
+// filter allows.
+type RateLimitError struct {
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("too many requests, try again in %s", e.RetryAfter.Round(time.Second))
+}
+
+// SpamVerdict is the outcome of checking an action.
+type SpamVerdict struct {
+	Score   int        `json:"score"`
+	Reasons []string   `json:"reasons,omitempty"`
+	Action  SpamAction `json:"action,omitempty"`
+}
+
+// Add adds points to the score for a reason.
+func (v *SpamVerdict) Add(points int, reason string) {
+	if points == 0 {
+		return
+	}
+	v.Score += points
+	v.Reasons = append(v.Reasons, reason)
+}
+
+// Summary describes the verdict for moderators.
+func (v *SpamVerdict) Summary() string {
+	return fmt.Sprintf("score %d (%s)", v.Score, strings.Join(v.Reasons, ", "))
+}
+
+func (v *SpamVerdict) Held() bool {
+	return v != nil && v.Action == SpamHold
+}
+
+func (v *SpamVerdict) ShadowLimited() bool {
+	return v != nil && v.Action == SpamShadowLimit
+}
+
+// AccountLimit is the stored standing of an account, either set by a
+// moderator or applied automatically by the spam filter.
+type AccountLimit struct {
+	UserID    int             `json:"user_id"`
+	Standing  AccountStanding `json:"standing"`
+	Automatic bool            `json:"automatic"`
+	Reason    string          `json:"reason,omitempty"`
+	SetBy     *int            `json:"set_by,omitempty"`
+	UpdatedAt time.Time       `json:"updated_at"`
+}
+
+type SetStandingRequest struct {
+	Standing AccountStanding `json:"standing"`
+	Reason   string          `json:"reason,omitempty"`
+}
+
+func (r *SetStandingRequest) Validate() error {
+	r.Reason = strings.TrimSpace(r.Reason)
+	switch r.Standing {
+	case StandingNormal, StandingTrusted, StandingShadowLimited:
+	default:
+		return ErrInvalidStanding
+	}
+	if utf8.RuneCountInString(r.Reason) > MaxReportDetailsLength {
+		return ErrReportDetailsTooLong
+	}
+	return nil
+}
+
+// SpamActivityStore keeps short-lived counters of what accounts do.
+type SpamActivityStore interface {
+	// RecordActivity counts an action and returns how many times the user
+	// did it within the current window, this one included.
+	RecordActivity(userID int, activity SpamActivity, window time.Duration) (int, error)
+	// RecordContent counts a content hash and returns how many times the
+	// user, and everyone, posted it within the window, this one included.
+	RecordContent(userID int, hash string, window time.Duration) (byUser int, byEveryone int, err error)
+}
+
+type AccountLimitRepository interface {
+	// GetLimit returns sql.ErrNoRows for accounts in normal standing.
+	GetLimit(userID int) (*AccountLimit, error)
+	SetLimit(limit *AccountLimit) error
+	ClearLimit(userID int) error
+	ListLimits(standing AccountStanding, page PageRequest) ([]AccountLimit, error)
+}
@@ -0,0 +1,86 @@
+package domain
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// MaxStoryContentLength limits the text of a story.
+const MaxStoryContentLength = 500
+
+var (
+	ErrEmptyStory        = errors.New("story needs text or an image")
+	ErrStoryTooLong      = errors.New("story text is too long")
+	ErrInvalidStoryMedia = errors.New("story image must be an http or https URL")
+)
+
+// Story is a short-lived text or image post shown to the author's followers
+// until it expires.
+type Story struct {
+	ID         int       `json:"id"`
+	AuthorID   int       `json:"author_id"`
+	Content    string    `json:"content,omitempty"`
+	MediaURL   string    `json:"media_url,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	Seen       bool      `json:"seen"`                  // Whether the requesting user has seen the story
+	ViewsCount int       `json:"views_count,omitempty"` // Only filled in for the author
+}
+
+type CreateStoryRequest struct {
+	Content  string `json:"content,omitempty"`
+	MediaURL string `json:"media_url,omitempty"`
+}
+
+func (r *CreateStoryRequest) Validate() error {
+	r.Content = strings.TrimSpace(r.Content)
+	r.MediaURL = strings.TrimSpace(r.MediaURL)
+
+	if r.Content == "" && r.MediaURL == "" {
+		return ErrEmptyStory
+	}
+	if utf8.RuneCountInString(r.Content) > MaxStoryContentLength {
+		return ErrStoryTooLong
+	}
+	if r.MediaURL != "" {
+		parsed, err := url.Parse(r.MediaURL)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			return ErrInvalidStoryMedia
+		}
+	}
+	return nil
+}
+
+// StoryTrayItem groups the active stories of one author, oldest first, the
+// way they are played.
+type StoryTrayItem struct {
+	AuthorID   int     `json:"author_id"`
+	Username   string  `json:"username"`
+	ProfilePic string  `json:"profile_pic,omitempty"`
+	HasUnseen  bool    `json:"has_unseen"`
+	Stories    []Story `json:"stories"`
+}
+
+// StoryViewer is a user who has seen a story.
+type StoryViewer struct {
+	UserID     int       `json:"user_id"`
+	Username   string    `json:"username"`
+	ProfilePic string    `json:"profile_pic,omitempty"`
+	ViewedAt   time.Time `json:"viewed_at"`
+}
+
+type StoryRepository interface {
+	Create(authorID int, story *CreateStoryRequest, expiresAt time.Time) (*Story, error)
+	GetByID(id int) (*Story, error)
+	Delete(id int) error
+	// GetActiveByAuthors returns the unexpired stories of the authors that
+	// the viewer has not blocked and is not blocked by, with the viewer's
+	// seen state and, for the viewer's own stories, the number of views.
+	GetActiveByAuthors(viewerID int, authorIDs []int, now time.Time) ([]Story, error)
+	MarkSeen(storyID, viewerID int) error
+	GetViewers(storyID int, page PageRequest) ([]StoryViewer, error)
+	DeleteExpired(before time.Time) (int64, error)
+}
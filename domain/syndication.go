@@ -0,0 +1,11 @@
+package domain
+
+import "time"
+
+// UserFeed is what the public RSS and Atom feeds of a user are built from:
+// the user's most recent public posts.
+type UserFeed struct {
+	Author  User
+	Posts   []Post
+	Updated time.Time // Latest creation or edit time among the posts
+}
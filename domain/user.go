@@ -6,25 +6,47 @@ import (
 )
 
 type User struct {
-	ID                 int       `json:"id"`
-	Username           *string   `json:"username,omitempty"`
-	Password           string    `json:"password,omitempty"`
-	Email              string    `json:"email,omitempty"`
-	Status             string    `json:"status,omitempty"` // "active", "inactive", "banned"
-	Role               string    `json:"role,omitempty"`   // "user", "admin", "moderator"
-	FirstName          *string   `json:"first_name,omitempty"`
-	LastName           *string   `json:"last_name,omitempty"`
-	ProfilePic         *string   `json:"profile_pic,omitempty"` // URL to profile picture
-	Bio                *string   `json:"bio,omitempty"`         // Short biography
-	CreatedAt          time.Time `json:"created_at,omitempty"`  // Account creation timestamp
-	UpdatedAt          time.Time `json:"updated_at,omitempty"`  // Last update timestamp
-	PostsCount         int       `json:"posts_count,omitempty"`
-	FollowersCount     int       `json:"followers_count,omitempty"`
-	FolloweesCount     int       `json:"followees_count,omitempty"`
-	FollowsFollower    bool      `json:"follows_follower,omitempty"`
-	FollowedByFollower bool      `json:"followed_by_follower,omitempty"`
-	IsFollowee         bool      `json:"is_followee,omitempty"`
-	IsFollower         bool      `json:"is_follower,omitempty"`
+	ID                 int        `json:"id"`
+	Username           *string    `json:"username,omitempty"`
+	Password           string     `json:"password,omitempty"`
+	Email              string     `json:"email,omitempty"`
+	Status             string     `json:"status,omitempty"` // "active", "inactive", "banned"
+	Role               string     `json:"role,omitempty"`   // "user", "admin", "moderator"
+	FirstName          *string    `json:"first_name,omitempty"`
+	LastName           *string    `json:"last_name,omitempty"`
+	ProfilePic         *string    `json:"profile_pic,omitempty"` // URL to profile picture
+	Bio                *string    `json:"bio,omitempty"`         // Short biography
+	CreatedAt          time.Time  `json:"created_at,omitempty"`  // Account creation timestamp
+	UpdatedAt          time.Time  `json:"updated_at,omitempty"`  // Last update timestamp
+	PostsCount         int        `json:"posts_count,omitempty"`
+	FollowersCount     int        `json:"followers_count,omitempty"`
+	FolloweesCount     int        `json:"followees_count,omitempty"`
+	FollowsFollower    bool       `json:"follows_follower,omitempty"`
+	FollowedByFollower bool       `json:"followed_by_follower,omitempty"`
+	IsFollowee         bool       `json:"is_followee,omitempty"`
+	IsFollower         bool       `json:"is_follower,omitempty"`
+	FollowedAt         *time.Time `json:"followed_at,omitempty"`       // When the follow relation was created, set on follower lists
+	SensitiveContent   string     `json:"sensitive_content,omitempty"` // How posts with a content warning are shown to the user
+	IsPrivate          bool       `json:"is_private,omitempty"`        // Private accounts are only listed among reactors to their followers
+}
+
+// UserStatusBanned locks a user out; their content stays up unless a
+// moderator removes it.
+const UserStatusBanned = "banned"
+
+// Preferences for posts with a content warning or sensitive media.
+const (
+	SensitiveContentShow = "show" // expand flagged posts
+	SensitiveContentBlur = "blur" // collapse flagged posts behind their warning
+	SensitiveContentHide = "hide" // leave flagged posts out of lists
+)
+
+func IsValidSensitiveContent(preference string) bool {
+	switch preference {
+	case SensitiveContentShow, SensitiveContentBlur, SensitiveContentHide:
+		return true
+	}
+	return false
 }
 
 type CreateUserRequest struct {
@@ -7,9 +7,12 @@ type UserRepository interface {
 	GetUserByUsername(username string) (*User, error)
 	GetUserByEmail(email string) (*User, error)
 	GetUserByID(id int) (*User, error)
-	GetPublicProfiles(offset, limit int) ([]User, error)
+	GetPublicProfiles(page PageRequest) ([]User, error)
 	GetAdminProfiles(limit, offset int) ([]User, error)
 	GetUserProfileInfo(id, otherUser int) (*User, error)
 	UpdateUser(user *User) error
+	UpdateSensitiveContent(userID int, preference string) error
+	UpdateStatus(userID int, status string) error
+	UpdatePrivacy(userID int, private bool) error
 	GetUsersByID(ctx context.Context, userIDs []int) ([]User, error)
 }
@@ -0,0 +1,85 @@
+package infrastructure
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/bandvov/social-media-go/domain"
+)
+
+type AccountLimitRepository struct {
+	db *sql.DB
+}
+
+func NewAccountLimitRepository(db *sql.DB) *AccountLimitRepository {
+	return &AccountLimitRepository{db: db}
+}
+
+const accountLimitColumns = `user_id, standing, automatic, COALESCE(reason, ''), set_by, updated_at`
+
+func scanAccountLimit(row rowScanner) (*domain.AccountLimit, error) {
+	var limit domain.AccountLimit
+	var setBy sql.NullInt64
+	if err := row.Scan(&limit.UserID, &limit.Standing, &limit.Automatic, &limit.Reason, &setBy, &limit.UpdatedAt); err != nil {
+		return nil, err
+	}
+	if setBy.Valid {
+		id := int(setBy.Int64)
+		limit.SetBy = &id
+	}
+	return &limit, nil
+}
+
+func (r *AccountLimitRepository) GetLimit(userID int) (*domain.AccountLimit, error) {
+	return scanAccountLimit(r.db.QueryRow("SELECT "+accountLimitColumns+" FROM account_limits WHERE user_id = $1", userID))
+}
+
+func (r *AccountLimitRepository) SetLimit(limit *domain.AccountLimit) error {
+	err := r.db.QueryRow(`
+		INSERT INTO account_limits (user_id, standing, automatic, reason, set_by)
+		VALUES ($1, $2, $3, NULLIF($4, ''), $5)
+		ON CONFLICT (user_id) DO UPDATE
+		SET standing = EXCLUDED.standing, automatic = EXCLUDED.automatic, reason = EXCLUDED.reason,
+			set_by = EXCLUDED.set_by, updated_at = CURRENT_TIMESTAMP
+		RETURNING updated_at`,
+		limit.UserID, limit.Standing, limit.Automatic, limit.Reason, limit.SetBy).
+		Scan(&limit.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to set account limit: %v", err)
+	}
+	return nil
+}
+
+func (r *AccountLimitRepository) ClearLimit(userID int) error {
+	if _, err := r.db.Exec("DELETE FROM account_limits WHERE user_id = $1", userID); err != nil {
+		return fmt.Errorf("failed to clear account limit: %v", err)
+	}
+	return nil
+}
+
+// ListLimits lists stored standings, most recently changed first.
+func (r *AccountLimitRepository) ListLimits(standing domain.AccountStanding, page domain.PageRequest) ([]domain.AccountLimit, error) {
+	afterTime, afterID := keysetArgs(page.After)
+	rows, err := r.db.Query(`
+	SELECT `+accountLimitColumns+`
+	FROM account_limits
+	WHERE ($1 = '' OR standing = $1)
+		AND ($2::timestamp IS NULL OR (updated_at, user_id) < ($2::timestamp, $3))
+	ORDER BY updated_at DESC, user_id DESC
+	OFFSET $4 LIMIT $5`,
+		string(standing), afterTime, afterID, page.Offset, page.Limit+1)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list account limits: %v", err)
+	}
+	defer rows.Close()
+
+	var limits []domain.AccountLimit
+	for rows.Next() {
+		limit, err := scanAccountLimit(rows)
+		if err != nil {
+			return nil, err
+		}
+		limits = append(limits, *limit)
+	}
+	return limits, rows.Err()
+}
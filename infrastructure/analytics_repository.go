@@ -0,0 +1,175 @@
+package infrastructure
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/bandvov/social-media-go/domain"
+	"github.com/lib/pq"
+)
+
+// statsTables maps a granularity to its rollup table.
+var statsTables = map[domain.AnalyticsGranularity]string{
+	domain.GranularityHour: "post_stats_hourly",
+	domain.GranularityDay:  "post_stats_daily",
+}
+
+type AnalyticsRepository struct {
+	db *sql.DB
+}
+
+func NewAnalyticsRepository(db *sql.DB) *AnalyticsRepository {
+	return &AnalyticsRepository{db: db}
+}
+
+// RecordViews stores a view of every post that still exists.
+func (r *AnalyticsRepository) RecordViews(viewerID int, postIDs []int, source domain.ViewSource) error {
+	if len(postIDs) == 0 {
+		return nil
+	}
+
+	_, err := r.db.Exec(`
+	INSERT INTO post_views (post_id, viewer_id, source, viewed_at)
+	SELECT p.id, $2, $3, CURRENT_TIMESTAMP
+	FROM posts p
+	WHERE p.id = ANY($1)`, pq.Array(postIDs), viewerID, source)
+	if err != nil {
+		return fmt.Errorf("failed to record views: %v", err)
+	}
+	return nil
+}
+
+// Rollup recomputes the views, unique viewers, new reactions and new comments
+// of every post in the buckets from the one containing since onwards, and
+// drops the buckets in that range with nothing left to count (e.g. after the
+// reactions in them were removed). Recomputing whole buckets keeps the rollup
+// idempotent, so it can run as often as needed.
+func (r *AnalyticsRepository) Rollup(granularity domain.AnalyticsGranularity, since time.Time) error {
+	table, ok := statsTables[granularity]
+	if !ok {
+		return domain.ErrInvalidGranularity
+	}
+
+	_, err := r.db.Exec(fmt.Sprintf(`
+	WITH
+	views AS (
+		SELECT post_id, date_trunc('%[2]s', viewed_at) AS bucket,
+			COUNT(*) AS views,
+			COUNT(DISTINCT viewer_id) AS unique_viewers
+		FROM post_views
+		WHERE viewed_at >= date_trunc('%[2]s', $1::timestamp)
+		GROUP BY 1, 2
+	),
+	reactions AS (
+		SELECT entity_id AS post_id, date_trunc('%[2]s', created_at) AS bucket, COUNT(*) AS reactions
+		FROM reactions
+		WHERE entity_type = 'post' AND created_at >= date_trunc('%[2]s', $1::timestamp)
+		GROUP BY 1, 2
+	),
+	comments AS (
+		SELECT entity_id AS post_id, date_trunc('%[2]s', created_at) AS bucket, COUNT(*) AS comments
+		FROM comments
+		WHERE entity_type = 'comment' AND created_at >= date_trunc('%[2]s', $1::timestamp)
+		GROUP BY 1, 2
+	),
+	buckets AS (
+		SELECT post_id, bucket FROM views
+		UNION SELECT post_id, bucket FROM reactions
+		UNION SELECT post_id, bucket FROM comments
+	),
+	emptied AS (
+		DELETE FROM %[1]s s
+		WHERE s.bucket >= date_trunc('%[2]s', $1::timestamp)
+			AND NOT EXISTS (SELECT 1 FROM buckets b WHERE b.post_id = s.post_id AND b.bucket = s.bucket)
+	)
+	INSERT INTO %[1]s (post_id, author_id, bucket, views, unique_viewers, reactions, comments)
+	SELECT
+		b.post_id,
+		p.author_id,
+		b.bucket,
+		COALESCE(v.views, 0),
+		COALESCE(v.unique_viewers, 0),
+		COALESCE(re.reactions, 0),
+		COALESCE(c.comments, 0)
+	FROM buckets b
+	JOIN posts p ON p.id = b.post_id
+	LEFT JOIN views v ON v.post_id = b.post_id AND v.bucket = b.bucket
+	LEFT JOIN reactions re ON re.post_id = b.post_id AND re.bucket = b.bucket
+	LEFT JOIN comments c ON c.post_id = b.post_id AND c.bucket = b.bucket
+	ON CONFLICT (post_id, bucket)
+	DO UPDATE SET
+		views = EXCLUDED.views,
+		unique_viewers = EXCLUDED.unique_viewers,
+		reactions = EXCLUDED.reactions,
+		comments = EXCLUDED.comments`, table, granularity), since)
+	if err != nil {
+		return fmt.Errorf("failed to roll up %s stats: %v", granularity, err)
+	}
+	return nil
+}
+
+// DeleteViewsBefore drops raw views that have already been rolled up.
+func (r *AnalyticsRepository) DeleteViewsBefore(before time.Time) error {
+	_, err := r.db.Exec("DELETE FROM post_views WHERE viewed_at < $1", before)
+	if err != nil {
+		return fmt.Errorf("failed to delete views: %v", err)
+	}
+	return nil
+}
+
+func (r *AnalyticsRepository) GetPostSeries(postID int, query domain.AnalyticsQuery) ([]domain.AnalyticsPoint, error) {
+	table, ok := statsTables[query.Granularity]
+	if !ok {
+		return nil, domain.ErrInvalidGranularity
+	}
+
+	rows, err := r.db.Query(fmt.Sprintf(`
+	SELECT bucket, views, unique_viewers, reactions, comments
+	FROM %s
+	WHERE post_id = $1 AND bucket >= $2 AND bucket < $3
+	ORDER BY bucket`, table), postID, query.From, query.To)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get post analytics: %v", err)
+	}
+	defer rows.Close()
+
+	var points []domain.AnalyticsPoint
+	for rows.Next() {
+		var point domain.AnalyticsPoint
+		if err := rows.Scan(&point.Bucket, &point.Views, &point.UniqueViewers, &point.Reactions, &point.Comments); err != nil {
+			return nil, fmt.Errorf("failed to scan analytics: %v", err)
+		}
+		points = append(points, point)
+	}
+	return points, rows.Err()
+}
+
+// GetAuthorSeries sums the stats of all posts of the author per bucket.
+func (r *AnalyticsRepository) GetAuthorSeries(authorID int, query domain.AnalyticsQuery) ([]domain.AnalyticsPoint, error) {
+	table, ok := statsTables[query.Granularity]
+	if !ok {
+		return nil, domain.ErrInvalidGranularity
+	}
+
+	rows, err := r.db.Query(fmt.Sprintf(`
+	SELECT bucket, SUM(views), SUM(reactions), SUM(comments)
+	FROM %s
+	WHERE author_id = $1 AND bucket >= $2 AND bucket < $3
+	GROUP BY bucket
+	ORDER BY bucket`, table), authorID, query.From, query.To)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get author analytics: %v", err)
+	}
+	defer rows.Close()
+
+	var points []domain.AnalyticsPoint
+	for rows.Next() {
+		var point domain.AnalyticsPoint
+		if err := rows.Scan(&point.Bucket, &point.Views, &point.Reactions, &point.Comments); err != nil {
+			return nil, fmt.Errorf("failed to scan analytics: %v", err)
+		}
+		points = append(points, point)
+	}
+	return points, rows.Err()
+}
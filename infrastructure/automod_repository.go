@@ -0,0 +1,117 @@
+package infrastructure
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/bandvov/social-media-go/domain"
+	"github.com/lib/pq"
+)
+
+type AutomodRepository struct {
+	db *sql.DB
+}
+
+func NewAutomodRepository(db *sql.DB) *AutomodRepository {
+	return &AutomodRepository{db: db}
+}
+
+const automodRuleColumns = `id, type, pattern, action, scope, COALESCE(content_warning, ''), dry_run, enabled,
+	hits, dry_run_hits, last_hit_at, COALESCE(created_by, 0), created_at, updated_at`
+
+func scanAutomodRule(row rowScanner) (*domain.AutomodRule, error) {
+	var rule domain.AutomodRule
+	var lastHitAt sql.NullTime
+	err := row.Scan(&rule.ID, &rule.Type, &rule.Pattern, &rule.Action, &rule.Scope, &rule.ContentWarning, &rule.DryRun, &rule.Enabled,
+		&rule.Hits, &rule.DryRunHits, &lastHitAt, &rule.CreatedBy, &rule.CreatedAt, &rule.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if lastHitAt.Valid {
+		rule.LastHitAt = &lastHitAt.Time
+	}
+	return &rule, nil
+}
+
+func (r *AutomodRepository) CreateRule(rule *domain.AutomodRule) error {
+	err := r.db.QueryRow(`
+		INSERT INTO automod_rules (type, pattern, action, scope, content_warning, dry_run, enabled, created_by)
+		VALUES ($1, $2, $3, $4, NULLIF($5, ''), $6, $7, $8)
+		RETURNING id, created_at, updated_at`,
+		rule.Type, rule.Pattern, rule.Action, rule.Scope, rule.ContentWarning, rule.DryRun, rule.Enabled, rule.CreatedBy).
+		Scan(&rule.ID, &rule.CreatedAt, &rule.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create automod rule: %v", err)
+	}
+	return nil
+}
+
+// UpdateRule replaces the definition of a rule and keeps its statistics.
+func (r *AutomodRepository) UpdateRule(rule *domain.AutomodRule) error {
+	updated, err := scanAutomodRule(r.db.QueryRow(`
+		UPDATE automod_rules
+		SET type = $2, pattern = $3, action = $4, scope = $5, content_warning = NULLIF($6, ''), dry_run = $7, enabled = $8, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1
+		RETURNING `+automodRuleColumns,
+		rule.ID, rule.Type, rule.Pattern, rule.Action, rule.Scope, rule.ContentWarning, rule.DryRun, rule.Enabled))
+	if err != nil {
+		return err
+	}
+	*rule = *updated
+	return nil
+}
+
+func (r *AutomodRepository) DeleteRule(id int) error {
+	result, err := r.db.Exec("DELETE FROM automod_rules WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (r *AutomodRepository) GetRule(id int) (*domain.AutomodRule, error) {
+	return scanAutomodRule(r.db.QueryRow("SELECT "+automodRuleColumns+" FROM automod_rules WHERE id = $1", id))
+}
+
+func (r *AutomodRepository) ListRules() ([]domain.AutomodRule, error) {
+	rows, err := r.db.Query("SELECT " + automodRuleColumns + " FROM automod_rules ORDER BY id")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list automod rules: %v", err)
+	}
+	defer rows.Close()
+
+	var rules []domain.AutomodRule
+	for rows.Next() {
+		rule, err := scanAutomodRule(rows)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, *rule)
+	}
+	return rules, rows.Err()
+}
+
+func (r *AutomodRepository) RecordHits(matches []domain.AutomodMatch) error {
+	var hits, dryRunHits []int64
+	for _, match := range matches {
+		if match.DryRun {
+			dryRunHits = append(dryRunHits, int64(match.RuleID))
+		} else {
+			hits = append(hits, int64(match.RuleID))
+		}
+	}
+
+	_, err := r.db.Exec(`
+		UPDATE automod_rules
+		SET hits = hits + CASE WHEN id = ANY($1) THEN 1 ELSE 0 END,
+			dry_run_hits = dry_run_hits + CASE WHEN id = ANY($2) THEN 1 ELSE 0 END,
+			last_hit_at = CURRENT_TIMESTAMP
+		WHERE id = ANY($1) OR id = ANY($2)`, pq.Array(hits), pq.Array(dryRunHits))
+	if err != nil {
+		return fmt.Errorf("failed to record automod hits: %v", err)
+	}
+	return nil
+}
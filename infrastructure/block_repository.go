@@ -0,0 +1,47 @@
+package infrastructure
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/bandvov/social-media-go/domain"
+)
+
+type BlockRepository struct {
+	db *sql.DB
+}
+
+func NewBlockRepository(db *sql.DB) *BlockRepository {
+	return &BlockRepository{db: db}
+}
+
+func (r *BlockRepository) AddBlock(block *domain.Block) error {
+	query := "INSERT INTO blocks (blocker_id, blocked_id) VALUES ($1, $2) ON CONFLICT DO NOTHING"
+	_, err := r.db.Exec(query, block.BlockerID, block.BlockedID)
+	if err != nil {
+		return fmt.Errorf("failed to add block: %v", err)
+	}
+	return nil
+}
+
+func (r *BlockRepository) RemoveBlock(block *domain.Block) error {
+	query := "DELETE FROM blocks WHERE blocker_id = $1 AND blocked_id = $2"
+	_, err := r.db.Exec(query, block.BlockerID, block.BlockedID)
+	if err != nil {
+		return fmt.Errorf("failed to remove block: %v", err)
+	}
+	return nil
+}
+
+func (r *BlockRepository) IsBlocked(userID, otherUserID int) (bool, error) {
+	var blocked bool
+	err := r.db.QueryRow(`
+		SELECT EXISTS (
+			SELECT 1 FROM blocks
+			WHERE (blocker_id = $1 AND blocked_id = $2) OR (blocker_id = $2 AND blocked_id = $1)
+		)`, userID, otherUserID).Scan(&blocked)
+	if err != nil {
+		return false, fmt.Errorf("failed to check block: %v", err)
+	}
+	return blocked, nil
+}
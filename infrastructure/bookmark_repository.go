@@ -0,0 +1,190 @@
+package infrastructure
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/bandvov/social-media-go/domain"
+	"github.com/lib/pq"
+)
+
+type BookmarkRepository struct {
+	db *sql.DB
+}
+
+func NewBookmarkRepository(db *sql.DB) *BookmarkRepository {
+	return &BookmarkRepository{db: db}
+}
+
+// AddBookmark saves a post for the user. Saving an already bookmarked post
+// moves it into the given collection instead of failing.
+func (r *BookmarkRepository) AddBookmark(bookmark *domain.Bookmark) error {
+	err := r.db.QueryRow(`
+		INSERT INTO bookmarks (user_id, post_id, collection_id)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (user_id, post_id)
+		DO UPDATE SET collection_id = $3
+		RETURNING id, created_at`,
+		bookmark.UserID, bookmark.PostID, bookmark.CollectionID).
+		Scan(&bookmark.ID, &bookmark.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to add bookmark: %v", err)
+	}
+	return nil
+}
+
+func (r *BookmarkRepository) RemoveBookmark(userID, postID int) error {
+	_, err := r.db.Exec("DELETE FROM bookmarks WHERE user_id = $1 AND post_id = $2", userID, postID)
+	if err != nil {
+		return fmt.Errorf("failed to remove bookmark: %v", err)
+	}
+	return nil
+}
+
+// GetBookmarks lists the user's bookmarks, newest first, with the saved posts.
+// A nil collectionID lists bookmarks from every collection. Posts the user can
+// no longer see are left out.
+func (r *BookmarkRepository) GetBookmarks(userID int, collectionID *int, page domain.PageRequest) ([]domain.Bookmark, error) {
+	afterTime, afterID := keysetArgs(page.After)
+	rows, err := r.db.Query(`
+	SELECT
+		b.id,
+		b.post_id,
+		b.collection_id,
+		b.created_at,
+		p.author_id,
+		COALESCE(u.username, ''),
+		p.content,
+		COALESCE(p.content_html, ''),
+		p.visibility,
+		p.pinned,
+		COALESCE(p.content_warning, ''),
+		p.sensitive,
+		p.warning_forced,
+		p.created_at,
+		p.updated_at
+	FROM bookmarks b
+	JOIN posts p ON p.id = b.post_id
+	LEFT JOIN users u ON u.id = p.author_id
+	WHERE b.user_id = $1
+		AND ($2::int IS NULL OR b.collection_id = $2)
+		AND ($3::timestamp IS NULL OR (b.created_at, b.id) < ($3::timestamp, $4))
+		AND (
+			(p.author_id = $1 AND p.visibility <> $7)
+			OR p.visibility IN ($8, $9)
+			OR (p.visibility = $10 AND EXISTS (
+				SELECT 1 FROM followers f WHERE f.follower_id = $1 AND f.followee_id = p.author_id
+			))
+		)
+	ORDER BY b.created_at DESC, b.id DESC
+	OFFSET $5 LIMIT $6`, userID, collectionID, afterTime, afterID, page.Offset, page.Limit+1,
+		domain.Hidden, domain.Public, domain.Unlisted, domain.Followers)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get bookmarks: %v", err)
+	}
+	defer rows.Close()
+
+	var bookmarks []domain.Bookmark
+	for rows.Next() {
+		bookmark := domain.Bookmark{UserID: userID, Post: &domain.Post{Bookmarked: true}}
+		if err := rows.Scan(
+			&bookmark.ID,
+			&bookmark.PostID,
+			&bookmark.CollectionID,
+			&bookmark.CreatedAt,
+			&bookmark.Post.AuthorID,
+			&bookmark.Post.AuthorName,
+			&bookmark.Post.Content,
+			&bookmark.Post.ContentHTML,
+			&bookmark.Post.Visibility,
+			&bookmark.Post.Pinned,
+			&bookmark.Post.ContentWarning,
+			&bookmark.Post.Sensitive,
+			&bookmark.Post.WarningForced,
+			&bookmark.Post.CreatedAt,
+			&bookmark.Post.UpdatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan bookmark: %v", err)
+		}
+		bookmark.Post.ID = bookmark.PostID
+		bookmarks = append(bookmarks, bookmark)
+	}
+	return bookmarks, rows.Err()
+}
+
+// GetBookmarkedPostIDs returns which of the given posts the user has saved.
+func (r *BookmarkRepository) GetBookmarkedPostIDs(userID int, postIDs []int) ([]int, error) {
+	if len(postIDs) == 0 {
+		return nil, nil
+	}
+
+	rows, err := r.db.Query("SELECT post_id FROM bookmarks WHERE user_id = $1 AND post_id = ANY($2)", userID, pq.Array(postIDs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get bookmarked posts: %v", err)
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan post id: %v", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+func (r *BookmarkRepository) CreateCollection(collection *domain.BookmarkCollection) error {
+	err := r.db.QueryRow("INSERT INTO bookmark_collections (user_id, name) VALUES ($1, $2) RETURNING id, created_at",
+		collection.UserID, collection.Name).
+		Scan(&collection.ID, &collection.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create collection: %v", err)
+	}
+	return nil
+}
+
+func (r *BookmarkRepository) GetCollections(userID int) ([]domain.BookmarkCollection, error) {
+	rows, err := r.db.Query(`
+	SELECT c.id, c.user_id, c.name, c.created_at, COUNT(b.id) AS bookmarks_count
+	FROM bookmark_collections c
+	LEFT JOIN bookmarks b ON b.collection_id = c.id
+	WHERE c.user_id = $1
+	GROUP BY c.id
+	ORDER BY c.name`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get collections: %v", err)
+	}
+	defer rows.Close()
+
+	var collections []domain.BookmarkCollection
+	for rows.Next() {
+		var collection domain.BookmarkCollection
+		if err := rows.Scan(&collection.ID, &collection.UserID, &collection.Name, &collection.CreatedAt, &collection.BookmarksCount); err != nil {
+			return nil, fmt.Errorf("failed to scan collection: %v", err)
+		}
+		collections = append(collections, collection)
+	}
+	return collections, rows.Err()
+}
+
+func (r *BookmarkRepository) GetCollectionByID(id int) (*domain.BookmarkCollection, error) {
+	collection := &domain.BookmarkCollection{}
+	err := r.db.QueryRow("SELECT id, user_id, name, created_at FROM bookmark_collections WHERE id = $1", id).
+		Scan(&collection.ID, &collection.UserID, &collection.Name, &collection.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return collection, nil
+}
+
+// DeleteCollection removes a collection; its bookmarks are kept and become
+// uncategorised.
+func (r *BookmarkRepository) DeleteCollection(userID, id int) error {
+	_, err := r.db.Exec("DELETE FROM bookmark_collections WHERE id = $1 AND user_id = $2", id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete collection: %v", err)
+	}
+	return nil
+}
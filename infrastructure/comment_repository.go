@@ -3,9 +3,11 @@ package infrastructure
 import (
 	"database/sql"
 	"fmt"
+	"time"
 
 	"github.com/bandvov/social-media-go/domain"
 	"github.com/bandvov/social-media-go/utils"
+	"github.com/lib/pq"
 )
 
 type PostgresCommentRepository struct {
@@ -16,105 +18,260 @@ func NewPostgresCommentRepository(db *sql.DB) *PostgresCommentRepository {
 	return &PostgresCommentRepository{db: db}
 }
 
-func (r *PostgresCommentRepository) AddComment(comment domain.Comment) error {
-	_, err := r.db.Exec(
-		"INSERT INTO comments (entity_id, entity_type, content, author_id) VALUES ($1, $2, $3, $4)",
-		comment.EntityID, comment.EntityType, comment.Content, comment.AuthorID,
-	)
-	return err
-}
+// AddComment stores a comment. Flagged comments wait for a moderator before
+// they are listed.
+func (r *PostgresCommentRepository) AddComment(comment domain.Comment) (int, error) {
+	status := comment.Status
+	if status == "" {
+		status = domain.CommentActive
+	}
 
-func (r *PostgresCommentRepository) FetchCommentsByEntityID(entityID, userID, offset, limit int) ([]domain.Comment, error) {
-
-	// Prepare the SQL query
-	stmt, err := r.db.Prepare(`
-	SELECT 
-    c.id, 
-    c.entity_id, 
-    c.content, 
-    c.author_id, 
-    u.username, 
-    u.profile_pic, 
-    c.created_at,   
-    COALESCE((
-        SELECT rt.name
-        FROM reactions rct2
-        JOIN reaction_types rt ON rct2.reaction_type_id = rt.id
-        WHERE rct2.entity_id = c.id AND rct2.user_id = $2
-        LIMIT 1
-    ),'') AS user_reaction,
-    COALESCE(SUM(grouped_reactions.reaction_count), 0) AS total_reactions_count,
-    COALESCE(
-        json_agg(
-            json_build_object(
-                'reaction_type', grouped_reactions.reaction_type,
-                'count', grouped_reactions.reaction_count
-            )
-        ) FILTER (WHERE grouped_reactions.reaction_type IS NOT NULL),
-        '[]'
-    ) AS reactions,
-    COALESCE(r.reply_count, 0) AS replies_count  -- Added this line
-FROM comments c
-LEFT JOIN (
-    SELECT 
-        r.entity_id,
-        rt.name AS reaction_type,
-        COUNT(r.id) AS reaction_count
-    FROM 
-        reactions r
-    LEFT JOIN 
-        reaction_types rt ON r.reaction_type_id = rt.id
-    GROUP BY 
-        r.entity_id, rt.name
-	) grouped_reactions ON c.id = grouped_reactions.entity_id
-	LEFT JOIN (
-    SELECT 
-        entity_id, 
-        COUNT(*) AS reply_count
-    FROM comments
-    WHERE entity_type = 'reply'
-    GROUP BY entity_id
-	) r ON c.id = r.entity_id
-	LEFT JOIN users u ON c.author_id = u.id
-	WHERE c.entity_id = $1 AND c.entity_type = 'comment'
-	GROUP BY c.id, u.username, u.profile_pic, r.reply_count
-	ORDER BY c.created_at DESC
-	OFFSET $3 LIMIT $4;
+	var id int
+	err := r.db.QueryRow(
+		"INSERT INTO comments (entity_id, entity_type, content, content_html, author_id, status) VALUES ($1, $2, $3, $4, $5, $6) RETURNING id",
+		comment.EntityID, comment.EntityType, comment.Content, comment.ContentHTML, comment.AuthorID, status,
+	).Scan(&id)
+	return id, err
+}
 
-	`)
+func (r *PostgresCommentRepository) GetCommentByID(id int) (*domain.Comment, error) {
+	var comment domain.Comment
+	var parentID sql.NullInt64
+	var editedAt sql.NullTime
+	err := r.db.QueryRow(`
+		SELECT id, entity_id, entity_type, parent_id, COALESCE(post_id, 0), depth, content, COALESCE(content_html, ''), author_id, status, created_at, edited_at
+		FROM comments WHERE id = $1`, id).
+		Scan(&comment.ID, &comment.EntityID, &comment.EntityType, &parentID, &comment.PostID, &comment.Depth, &comment.Content, &comment.ContentHTML, &comment.AuthorID, &comment.Status, &comment.CreatedAt, &editedAt)
 	if err != nil {
 		return nil, err
 	}
-	defer stmt.Close()
-
-	// Execute the prepared statement
-	rows, err := stmt.Query(entityID, userID, offset, limit)
-	if err != nil {
-		return nil, err
+	if editedAt.Valid {
+		comment.EditedAt = &editedAt.Time
 	}
-	defer rows.Close()
+	if parentID.Valid {
+		id := int(parentID.Int64)
+		comment.ParentID = &id
+	}
+	return &comment, nil
+}
+
+// commentColumns selects a listed comment for the viewer in $2, with its
+// reactions and the number of visible replies directly below it and in its
+// whole branch.
+const commentColumns = `
+	c.id,
+	c.entity_id,
+	c.parent_id,
+	COALESCE(c.post_id, 0),
+	c.depth,
+	c.content,
+	COALESCE(c.content_html, ''),
+	c.author_id,
+	COALESCE(u.username, ''),
+	COALESCE(u.profile_pic, ''),
+	c.status,
+	c.created_at,
+	c.edited_at,
+	COALESCE((
+		SELECT rt.name
+		FROM reactions rct
+		JOIN reaction_types rt ON rct.reaction_type_id = rt.id
+		WHERE rct.entity_type = 'comment' AND rct.entity_id = c.id AND rct.user_id = $2
+		LIMIT 1
+	), '') AS user_reaction,
+	c.reactions_count,
+	c.controversy,
+	COALESCE((
+		SELECT json_agg(json_build_object('reaction_type', g.name, 'count', g.count))
+		FROM (
+			SELECT rt.name, COUNT(*) AS count
+			FROM reactions rct
+			JOIN reaction_types rt ON rct.reaction_type_id = rt.id
+			WHERE rct.entity_type = 'comment' AND rct.entity_id = c.id
+			GROUP BY rt.name
+		) g
+	), '[]') AS reactions,
+	(SELECT COUNT(*) FROM comments r WHERE r.parent_id = c.id AND r.status = 'active') AS replies_count,
+	(
+		SELECT COUNT(*) FROM comments d
+		WHERE d.post_id = c.post_id AND d.path > c.path || '.' AND d.path < c.path || '/' AND d.status = 'active'
+	) AS descendants_count`
 
+// visibleComment lists active comments, flagged ones to their author in $2,
+// ones hidden by the author of the post to both authors, and deleted ones
+// while replies below them are still up.
+const visibleComment = `(c.status = 'active' OR (c.status = 'flagged' AND c.author_id = $2) OR (c.status = 'hidden' AND c.hidden_by_author AND (
+	c.author_id = $2 OR EXISTS (SELECT 1 FROM posts cp WHERE cp.id = c.post_id AND cp.author_id = $2)
+)) OR (c.status = 'deleted' AND EXISTS (
+	SELECT 1 FROM comments d
+	WHERE d.post_id = c.post_id AND d.path > c.path || '.' AND d.path < c.path || '/' AND d.status = 'active'
+)))`
+
+func scanComments(rows *sql.Rows) ([]domain.Comment, error) {
 	var comments []domain.Comment
 	for rows.Next() {
 		var comment domain.Comment
+		var parentID sql.NullInt64
+		var editedAt sql.NullTime
 		if err := rows.Scan(
 			&comment.ID,
 			&comment.EntityID,
+			&parentID,
+			&comment.PostID,
+			&comment.Depth,
 			&comment.Content,
+			&comment.ContentHTML,
 			&comment.AuthorID,
 			&comment.Username,
 			&comment.ProfilePic,
+			&comment.Status,
 			&comment.CreatedAt,
+			&editedAt,
 			&comment.UserReaction,
 			&comment.TotaReactionslCount,
+			&comment.Controversy,
 			&comment.Reactions,
 			&comment.RepliesCount,
+			&comment.DescendantsCount,
 		); err != nil {
 			return nil, err
 		}
+		if editedAt.Valid {
+			comment.EditedAt = &editedAt.Time
+		}
+		// Tombstones keep their place in the tree but not their author.
+		if comment.Status == domain.CommentDeleted {
+			comment.AuthorID, comment.Username, comment.ProfilePic = 0, "", ""
+		}
+		comment.EntityType = domain.CommentTypeComment
+		if parentID.Valid {
+			id := int(parentID.Int64)
+			comment.ParentID = &id
+			comment.EntityType = domain.CommentTypeReply
+		}
 		comments = append(comments, comment)
 	}
-	return comments, nil
+	return comments, rows.Err()
+}
+
+// commentOrders holds the ORDER BY clause of each comment sort and the
+// keyset condition continuing after the cursor in $3 and $4. $3 is the
+// cursor's creation time, or its score for scored sorts.
+var commentOrders = map[domain.CommentSort]struct{ orderBy, after string }{
+	domain.CommentSortNewest: {
+		orderBy: "c.created_at DESC, c.id DESC",
+		after:   "($3::timestamp IS NULL OR (c.created_at, c.id) < ($3::timestamp, $4))",
+	},
+	domain.CommentSortOldest: {
+		orderBy: "c.created_at, c.id",
+		after:   "($3::timestamp IS NULL OR (c.created_at, c.id) > ($3::timestamp, $4))",
+	},
+	domain.CommentSortTop: {
+		orderBy: "c.reactions_count DESC, c.id DESC",
+		after:   "($3::float8 IS NULL OR (c.reactions_count, c.id) < ($3::float8, $4))",
+	},
+	domain.CommentSortControversial: {
+		orderBy: "c.controversy DESC, c.id DESC",
+		after:   "($3::float8 IS NULL OR (c.controversy, c.id) < ($3::float8, $4))",
+	},
+}
+
+// FetchCommentsByEntityID lists the top-level comments of a post in the
+// given order, after the pinned comment on the first page.
+func (r *PostgresCommentRepository) FetchCommentsByEntityID(entityID, userID int, sort domain.CommentSort, page domain.PageRequest) ([]domain.Comment, error) {
+	order, ok := commentOrders[sort]
+	if !ok {
+		return nil, domain.ErrInvalidCommentSort
+	}
+
+	var comments []domain.Comment
+	if page.After == nil && page.Offset == 0 {
+		pinned, err := r.fetchPinnedComment(entityID, userID)
+		if err != nil {
+			return nil, err
+		}
+		comments = pinned
+	}
+
+	afterKey, afterID := keysetArgs(page.After)
+	if sort.Scored() {
+		afterKey = nil
+		if page.After != nil && page.After.Score != nil {
+			afterKey = *page.After.Score
+		}
+	}
+	rows, err := r.db.Query(`
+	SELECT `+commentColumns+`
+	FROM comments c
+	LEFT JOIN users u ON c.author_id = u.id
+	WHERE c.entity_id = $1 AND c.entity_type = 'comment' AND `+visibleComment+`
+		AND c.id IS DISTINCT FROM (SELECT pinned_comment_id FROM posts WHERE id = $1)
+		AND `+order.after+`
+	ORDER BY `+order.orderBy+`
+	OFFSET $5 LIMIT $6`,
+		entityID, userID, afterKey, afterID, page.Offset, page.Limit+1)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	listed, err := scanComments(rows)
+	if err != nil {
+		return nil, err
+	}
+	return append(comments, listed...), nil
+}
+
+// fetchPinnedComment returns the pinned comment of a post, if the viewer in
+// userID can see it.
+func (r *PostgresCommentRepository) fetchPinnedComment(postID, userID int) ([]domain.Comment, error) {
+	rows, err := r.db.Query(`
+	SELECT `+commentColumns+`
+	FROM posts p
+	JOIN comments c ON c.id = p.pinned_comment_id
+	LEFT JOIN users u ON c.author_id = u.id
+	WHERE p.id = $1 AND `+visibleComment,
+		postID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch pinned comment: %v", err)
+	}
+	defer rows.Close()
+
+	pinned, err := scanComments(rows)
+	for i := range pinned {
+		pinned[i].Pinned = true
+	}
+	return pinned, err
+}
+
+// FetchReplies lists the direct replies of each parent, oldest first, at
+// most limit+1 per parent so callers can tell which branches have more.
+func (r *PostgresCommentRepository) FetchReplies(parentIDs []int, userID int, after *domain.Cursor, limit int) ([]domain.Comment, error) {
+	if len(parentIDs) == 0 {
+		return nil, nil
+	}
+
+	afterTime, afterID := keysetArgs(after)
+	rows, err := r.db.Query(`
+	WITH branch AS (
+		SELECT c.id, ROW_NUMBER() OVER (PARTITION BY c.parent_id ORDER BY c.created_at, c.id) AS position
+		FROM comments c
+		WHERE c.parent_id = ANY($1) AND `+visibleComment+`
+			AND ($3::timestamp IS NULL OR (c.created_at, c.id) > ($3::timestamp, $4))
+	)
+	SELECT `+commentColumns+`
+	FROM branch b
+	JOIN comments c ON c.id = b.id
+	LEFT JOIN users u ON c.author_id = u.id
+	WHERE b.position <= $5
+	ORDER BY c.created_at, c.id`,
+		pq.Array(parentIDs), userID, afterTime, afterID, limit+1)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch replies: %v", err)
+	}
+	defer rows.Close()
+
+	return scanComments(rows)
 }
 
 // Fetch comments by post IDs
@@ -125,9 +282,9 @@ func (r *PostgresCommentRepository) GetCommentsByEntityIDs(entityIDs []int) ([]d
 
 	// Prepare query with IN clause
 	query := fmt.Sprintf(`
-	SELECT id, entity_id, content, author_id, created_at
+	SELECT id, entity_id, content, COALESCE(content_html, ''), author_id, created_at
 	FROM comments
-	WHERE entity_id IN (%s)`, utils.Placeholders(len(entityIDs)))
+	WHERE entity_id IN (%s) AND status = 'active'`, utils.Placeholders(len(entityIDs)))
 
 	rows, err := r.db.Query(query, utils.ToInterface(entityIDs)...)
 	if err != nil {
@@ -138,7 +295,7 @@ func (r *PostgresCommentRepository) GetCommentsByEntityIDs(entityIDs []int) ([]d
 	var comments []domain.Comment
 	for rows.Next() {
 		var comment domain.Comment
-		if err := rows.Scan(&comment.ID, &comment.EntityID, &comment.Content, &comment.AuthorID, &comment.CreatedAt); err != nil {
+		if err := rows.Scan(&comment.ID, &comment.EntityID, &comment.Content, &comment.ContentHTML, &comment.AuthorID, &comment.CreatedAt); err != nil {
 			return nil, err
 		}
 		comments = append(comments, comment)
@@ -155,7 +312,7 @@ func (r *PostgresCommentRepository) CountByEntityIDs(entityIDs []int) ([]domain.
 			COALESCE(COUNT(CASE WHEN entity_type = 'comment' THEN 1 END), 0) AS comment_count,
             COALESCE(COUNT(CASE WHEN entity_type = 'reply' THEN 1 END), 0) AS reply_count
         FROM comments
-        WHERE entity_id IN (%s)
+        WHERE entity_id IN (%s) AND status = 'active'
 		GROUP BY entity_id`, utils.Placeholders(len(entityIDs)))
 
 	rows, err := r.db.Query(query, utils.ToInterface(entityIDs)...)
@@ -175,3 +332,94 @@ func (r *PostgresCommentRepository) CountByEntityIDs(entityIDs []int) ([]domain.
 
 	return counts, nil
 }
+
+// GetCommentsWithoutHTML returns comments whose Markdown has not been
+// rendered yet.
+func (r *PostgresCommentRepository) GetCommentsWithoutHTML(limit int) ([]domain.Comment, error) {
+	rows, err := r.db.Query("SELECT id, content FROM comments WHERE content_html IS NULL ORDER BY id LIMIT $1", limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var comments []domain.Comment
+	for rows.Next() {
+		var comment domain.Comment
+		if err := rows.Scan(&comment.ID, &comment.Content); err != nil {
+			return nil, err
+		}
+		comments = append(comments, comment)
+	}
+	return comments, rows.Err()
+}
+
+func (r *PostgresCommentRepository) SetContentHTML(id int, html string) error {
+	_, err := r.db.Exec("UPDATE comments SET content_html = $1 WHERE id = $2", html, id)
+	return err
+}
+
+// Hide takes a comment out of listings without deleting it.
+func (r *PostgresCommentRepository) Hide(id int) error {
+	result, err := r.db.Exec("UPDATE comments SET status = 'hidden', hidden_by_author = FALSE WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// Release lists a flagged comment again.
+func (r *PostgresCommentRepository) Release(id int) error {
+	_, err := r.db.Exec("UPDATE comments SET status = 'active' WHERE id = $1 AND status = 'flagged'", id)
+	return err
+}
+
+func (r *PostgresCommentRepository) SetHiddenByAuthor(id int, hidden bool) error {
+	query := "UPDATE comments SET status = 'hidden', hidden_by_author = TRUE WHERE id = $1 AND status = 'active'"
+	if !hidden {
+		query = "UPDATE comments SET status = 'active', hidden_by_author = FALSE WHERE id = $1 AND status = 'hidden' AND hidden_by_author"
+	}
+	result, err := r.db.Exec(query, id)
+	if err != nil {
+		return err
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// UpdateContent replaces the content of a comment that is neither deleted
+// nor hidden and marks it as edited.
+func (r *PostgresCommentRepository) UpdateContent(comment *domain.Comment) error {
+	var editedAt time.Time
+	err := r.db.QueryRow(`
+		UPDATE comments
+		SET content = $2, content_html = $3, status = $4, edited_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND status IN ('active', 'flagged')
+		RETURNING edited_at`,
+		comment.ID, comment.Content, comment.ContentHTML, comment.Status).Scan(&editedAt)
+	if err != nil {
+		return err
+	}
+	comment.EditedAt = &editedAt
+	return nil
+}
+
+// Delete replaces a comment with a tombstone. Its replies stay where they
+// are.
+func (r *PostgresCommentRepository) Delete(id int) error {
+	result, err := r.db.Exec(`
+		UPDATE comments
+		SET status = 'deleted', content = $2, content_html = $2
+		WHERE id = $1`, id, domain.DeletedCommentContent)
+	if err != nil {
+		return err
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
@@ -0,0 +1,219 @@
+package infrastructure
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/bandvov/social-media-go/domain"
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	postCountersDirtyKey = "post_counters:dirty"
+
+	// Counters that were flushed expire after a while without changes.
+	// Dirty counters never expire, or their changes would be lost.
+	postCountersTTL = 24 * time.Hour
+)
+
+var (
+	// incrementPostCounter only changes cached counters: a counter that is
+	// not cached has no base to add to.
+	incrementPostCounter = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 0 then return 0 end
+redis.call('HINCRBY', KEYS[1], ARGV[1], ARGV[2])
+redis.call('PERSIST', KEYS[1])
+redis.call('SADD', KEYS[2], ARGV[3])
+return 1`)
+
+	primePostCounters = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 1 then return 0 end
+redis.call('HSET', KEYS[1], 'reactions', ARGV[1], 'comments', ARGV[2], 'replies', ARGV[3])
+redis.call('EXPIRE', KEYS[1], ARGV[4])
+return 1`)
+
+	// overwritePostCounters compares and sets: a counter that is dirty, or
+	// no longer holds the expected values, has changes the new values may
+	// not include.
+	overwritePostCounters = redis.NewScript(`
+if redis.call('SISMEMBER', KEYS[2], ARGV[7]) == 1 then return 0 end
+local current = redis.call('HMGET', KEYS[1], 'reactions', 'comments', 'replies')
+if current[1] ~= ARGV[4] or current[2] ~= ARGV[5] or current[3] ~= ARGV[6] then return 0 end
+redis.call('HSET', KEYS[1], 'reactions', ARGV[1], 'comments', ARGV[2], 'replies', ARGV[3])
+redis.call('PERSIST', KEYS[1])
+redis.call('SADD', KEYS[2], ARGV[7])
+return 1`)
+)
+
+// RedisCounterStore keeps the reaction, comment and reply counts of posts in
+// one hash per post, and the IDs of posts whose counts changed since the
+// last flush in a set.
+type RedisCounterStore struct {
+	client *redis.Client
+}
+
+func NewRedisCounterStore(client *redis.Client) *RedisCounterStore {
+	return &RedisCounterStore{client: client}
+}
+
+func postCountersKey(postID int) string {
+	return fmt.Sprintf("post_counters:%d", postID)
+}
+
+func (s *RedisCounterStore) Get(postIDs []int) (map[int]domain.PostCounters, error) {
+	counters := make(map[int]domain.PostCounters, len(postIDs))
+	if len(postIDs) == 0 {
+		return counters, nil
+	}
+
+	ctx := context.Background()
+	pipe := s.client.Pipeline()
+	results := make([]*redis.SliceCmd, len(postIDs))
+	for i, postID := range postIDs {
+		results[i] = pipe.HMGet(ctx, postCountersKey(postID), domain.CounterReactions, domain.CounterComments, domain.CounterReplies)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, fmt.Errorf("failed to get post counters: %v", err)
+	}
+
+	for i, result := range results {
+		if counter, ok := parsePostCounters(postIDs[i], result.Val()); ok {
+			counters[postIDs[i]] = counter
+		}
+	}
+	return counters, nil
+}
+
+func (s *RedisCounterStore) Prime(counters []domain.PostCounters) error {
+	if len(counters) == 0 {
+		return nil
+	}
+
+	ctx := context.Background()
+	pipe := s.client.Pipeline()
+	for _, counter := range counters {
+		primePostCounters.Eval(ctx, pipe, []string{postCountersKey(counter.PostID)},
+			counter.Reactions, counter.Comments, counter.Replies, int(postCountersTTL.Seconds()))
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to cache post counters: %v", err)
+	}
+	return nil
+}
+
+func (s *RedisCounterStore) Overwrite(counters []domain.PostCounters, expected map[int]domain.PostCounters) (int, error) {
+	ctx := context.Background()
+	pipe := s.client.Pipeline()
+	var results []*redis.Cmd
+	for _, counter := range counters {
+		old, ok := expected[counter.PostID]
+		if !ok {
+			continue
+		}
+		results = append(results, overwritePostCounters.Eval(ctx, pipe,
+			[]string{postCountersKey(counter.PostID), postCountersDirtyKey},
+			counter.Reactions, counter.Comments, counter.Replies,
+			old.Reactions, old.Comments, old.Replies, counter.PostID))
+	}
+	if len(results) == 0 {
+		return 0, nil
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, fmt.Errorf("failed to overwrite post counters: %v", err)
+	}
+
+	replaced := 0
+	for _, result := range results {
+		if changed, _ := result.Int(); changed == 1 {
+			replaced++
+		}
+	}
+	return replaced, nil
+}
+
+func (s *RedisCounterStore) Increment(postID int, field string, delta int) (bool, error) {
+	changed, err := incrementPostCounter.Run(context.Background(), s.client,
+		[]string{postCountersKey(postID), postCountersDirtyKey}, field, delta, postID).Int()
+	if err != nil {
+		return false, fmt.Errorf("failed to increment post counter: %v", err)
+	}
+	return changed == 1, nil
+}
+
+func (s *RedisCounterStore) TakeDirty(limit int) ([]domain.PostCounters, error) {
+	ctx := context.Background()
+	members, err := s.client.SPopN(ctx, postCountersDirtyKey, int64(limit)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to take dirty post counters: %v", err)
+	}
+	if len(members) == 0 {
+		return nil, nil
+	}
+
+	postIDs := make([]int, 0, len(members))
+	for _, member := range members {
+		if postID, err := strconv.Atoi(member); err == nil {
+			postIDs = append(postIDs, postID)
+		}
+	}
+
+	pipe := s.client.Pipeline()
+	results := make([]*redis.SliceCmd, len(postIDs))
+	for i, postID := range postIDs {
+		results[i] = pipe.HMGet(ctx, postCountersKey(postID), domain.CounterReactions, domain.CounterComments, domain.CounterReplies)
+		pipe.Expire(ctx, postCountersKey(postID), postCountersTTL)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		// Put them back, so the next flush tries again.
+		if markErr := s.MarkDirty(postIDs); markErr != nil {
+			return nil, markErr
+		}
+		return nil, fmt.Errorf("failed to read dirty post counters: %v", err)
+	}
+
+	var counters []domain.PostCounters
+	for i, result := range results {
+		if counter, ok := parsePostCounters(postIDs[i], result.Val()); ok {
+			counters = append(counters, counter)
+		}
+	}
+	return counters, nil
+}
+
+func (s *RedisCounterStore) MarkDirty(postIDs []int) error {
+	if len(postIDs) == 0 {
+		return nil
+	}
+
+	ctx := context.Background()
+	pipe := s.client.Pipeline()
+	members := make([]interface{}, 0, len(postIDs))
+	for _, postID := range postIDs {
+		members = append(members, postID)
+		pipe.Persist(ctx, postCountersKey(postID))
+	}
+	pipe.SAdd(ctx, postCountersDirtyKey, members...)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to mark post counters dirty: %v", err)
+	}
+	return nil
+}
+
+// parsePostCounters reads the fields of a post counters hash, reporting
+// false when the hash does not exist.
+func parsePostCounters(postID int, values []interface{}) (domain.PostCounters, bool) {
+	counter := domain.PostCounters{PostID: postID}
+	fields := []*int{&counter.Reactions, &counter.Comments, &counter.Replies}
+	found := false
+	for i, value := range values {
+		text, ok := value.(string)
+		if !ok || i >= len(fields) {
+			continue
+		}
+		found = true
+		*fields[i], _ = strconv.Atoi(text)
+	}
+	return counter, found
+}
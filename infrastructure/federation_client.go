@@ -0,0 +1,144 @@
+package infrastructure
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"syscall"
+	"time"
+
+	"github.com/bandvov/social-media-go/domain"
+	"github.com/bandvov/social-media-go/utils"
+)
+
+const (
+	maxActorDocumentBytes  = 1 << 20
+	maxFederationRedirects = 3
+	federationUserAgent    = "social-media-go federation"
+)
+
+// HTTPFederationClient fetches actors from and delivers activities to remote
+// servers. Like link previews, it refuses to connect to private addresses
+// unless allowPrivate is set, which is meant for local development against a
+// fake remote server.
+type HTTPFederationClient struct {
+	client  *http.Client
+	allowIP func(ip net.IP) bool
+}
+
+func NewHTTPFederationClient(timeout time.Duration, allowPrivate bool) *HTTPFederationClient {
+	c := &HTTPFederationClient{allowIP: isPublicIP}
+	if allowPrivate {
+		c.allowIP = func(net.IP) bool { return true }
+	}
+
+	dialer := &net.Dialer{Timeout: timeout, Control: c.checkAddress}
+	c.client = &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			DialContext:           dialer.DialContext,
+			TLSHandshakeTimeout:   timeout,
+			ResponseHeaderTimeout: timeout,
+			MaxIdleConns:          50,
+			IdleConnTimeout:       90 * time.Second,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= maxFederationRedirects {
+				return errors.New("too many redirects")
+			}
+			return nil
+		},
+	}
+	return c
+}
+
+func (c *HTTPFederationClient) checkAddress(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || !c.allowIP(ip) {
+		return ErrBlockedAddress
+	}
+	return nil
+}
+
+// FetchActor downloads an actor document. The document must describe the
+// requested actor and carry a public key owned by it.
+func (c *HTTPFederationClient) FetchActor(actorURI string) (*domain.Actor, error) {
+	if err := checkFederationURL(actorURI); err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest(http.MethodGet, actorURI, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", domain.ActivityContentType+`, application/ld+json; profile="https://www.w3.org/ns/activitystreams"`)
+	req.Header.Set("User-Agent", federationUserAgent)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d fetching actor %s", resp.StatusCode, actorURI)
+	}
+
+	var actor domain.Actor
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxActorDocumentBytes)).Decode(&actor); err != nil {
+		return nil, fmt.Errorf("invalid actor document: %v", err)
+	}
+	if actor.ID != actorURI || actor.Inbox == "" || actor.PublicKey.Owner != actor.ID || actor.PublicKey.PublicKeyPem == "" {
+		return nil, fmt.Errorf("invalid actor document for %s", actorURI)
+	}
+	return &actor, nil
+}
+
+func (c *HTTPFederationClient) Deliver(inboxURL string, activity []byte, keyID, privateKeyPEM string) error {
+	if err := checkFederationURL(inboxURL); err != nil {
+		return err
+	}
+	key, err := utils.ParseRSAPrivateKey(privateKeyPEM)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, inboxURL, bytes.NewReader(activity))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", domain.ActivityContentType)
+	req.Header.Set("User-Agent", federationUserAgent)
+	if err := utils.SignRequest(req, activity, keyID, key); err != nil {
+		return err
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("inbox %s responded with status %d", inboxURL, resp.StatusCode)
+	}
+	return nil
+}
+
+func checkFederationURL(rawURL string) error {
+	parsed, err := url.Parse(rawURL)
+	if err != nil || parsed.Host == "" || (parsed.Scheme != "https" && parsed.Scheme != "http") {
+		return fmt.Errorf("unsupported url %q", rawURL)
+	}
+	return nil
+}
@@ -0,0 +1,229 @@
+package infrastructure
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/bandvov/social-media-go/domain"
+)
+
+// maxUsernameLength matches users.username.
+const maxUsernameLength = 50
+
+type FederationRepository struct {
+	db *sql.DB
+}
+
+func NewFederationRepository(db *sql.DB) *FederationRepository {
+	return &FederationRepository{db: db}
+}
+
+func (r *FederationRepository) GetActorKey(userID int) (*domain.ActorKey, error) {
+	key := domain.ActorKey{UserID: userID}
+	err := r.db.QueryRow("SELECT public_key_pem, private_key_pem FROM actor_keys WHERE user_id = $1", userID).
+		Scan(&key.PublicKeyPEM, &key.PrivateKeyPEM)
+	if err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+func (r *FederationRepository) SaveActorKey(key *domain.ActorKey) error {
+	_, err := r.db.Exec(`
+		INSERT INTO actor_keys (user_id, public_key_pem, private_key_pem)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (user_id) DO NOTHING`, key.UserID, key.PublicKeyPEM, key.PrivateKeyPEM)
+	if err != nil {
+		return fmt.Errorf("failed to save actor key: %v", err)
+	}
+	return nil
+}
+
+const remoteActorColumns = `user_id, actor_uri, username, domain, inbox_url, COALESCE(shared_inbox_url, ''), public_key_id, public_key_pem, fetched_at`
+
+func scanRemoteActor(row *sql.Row) (*domain.RemoteActor, error) {
+	var actor domain.RemoteActor
+	err := row.Scan(&actor.UserID, &actor.ActorURI, &actor.Username, &actor.Domain, &actor.InboxURL, &actor.SharedInbox, &actor.PublicKeyID, &actor.PublicKeyPEM, &actor.FetchedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &actor, nil
+}
+
+func (r *FederationRepository) GetRemoteActorByURI(actorURI string) (*domain.RemoteActor, error) {
+	return scanRemoteActor(r.db.QueryRow("SELECT "+remoteActorColumns+" FROM remote_actors WHERE actor_uri = $1", actorURI))
+}
+
+func (r *FederationRepository) GetRemoteActorByKeyID(keyID string) (*domain.RemoteActor, error) {
+	return scanRemoteActor(r.db.QueryRow("SELECT "+remoteActorColumns+" FROM remote_actors WHERE public_key_id = $1", keyID))
+}
+
+// SaveRemoteActor creates the shadow account of a new remote actor, or
+// refreshes the inbox and key of a known one. Shadow accounts get an
+// address at the reserved .invalid domain and a password no hash matches.
+func (r *FederationRepository) SaveRemoteActor(actor *domain.RemoteActor) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRow("SELECT user_id FROM remote_actors WHERE actor_uri = $1 FOR UPDATE", actor.ActorURI).Scan(&actor.UserID)
+	switch {
+	case err == sql.ErrNoRows:
+		sum := sha256.Sum256([]byte(actor.ActorURI))
+		email := hex.EncodeToString(sum[:8]) + "@remote.invalid"
+
+		var username *string
+		if handle := actor.Username + "@" + actor.Domain; len(handle) <= maxUsernameLength {
+			username = &handle
+		}
+
+		err = tx.QueryRow(`
+			INSERT INTO users (username, password, email, status, role)
+			VALUES ($1, '!', $2, $3, 'user')
+			ON CONFLICT (email) DO UPDATE SET email = EXCLUDED.email
+			RETURNING id`, username, email, domain.UserStatusRemote).Scan(&actor.UserID)
+		if err != nil {
+			return fmt.Errorf("failed to create shadow user: %v", err)
+		}
+
+		_, err = tx.Exec(`
+			INSERT INTO remote_actors (user_id, actor_uri, username, domain, inbox_url, shared_inbox_url, public_key_id, public_key_pem, fetched_at)
+			VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''), $7, $8, $9)`,
+			actor.UserID, actor.ActorURI, actor.Username, actor.Domain, actor.InboxURL, actor.SharedInbox, actor.PublicKeyID, actor.PublicKeyPEM, actor.FetchedAt)
+		if err != nil {
+			return fmt.Errorf("failed to save remote actor: %v", err)
+		}
+	case err != nil:
+		return err
+	default:
+		_, err = tx.Exec(`
+			UPDATE remote_actors
+			SET username = $2, domain = $3, inbox_url = $4, shared_inbox_url = NULLIF($5, ''), public_key_id = $6, public_key_pem = $7, fetched_at = $8
+			WHERE user_id = $1`,
+			actor.UserID, actor.Username, actor.Domain, actor.InboxURL, actor.SharedInbox, actor.PublicKeyID, actor.PublicKeyPEM, actor.FetchedAt)
+		if err != nil {
+			return fmt.Errorf("failed to update remote actor: %v", err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (r *FederationRepository) GetFollowerInboxes(userID int) ([]string, error) {
+	rows, err := r.db.Query(`
+		SELECT DISTINCT COALESCE(ra.shared_inbox_url, ra.inbox_url)
+		FROM followers f
+		JOIN remote_actors ra ON ra.user_id = f.follower_id
+		WHERE f.followee_id = $1`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get follower inboxes: %v", err)
+	}
+	defer rows.Close()
+
+	var inboxes []string
+	for rows.Next() {
+		var inbox string
+		if err := rows.Scan(&inbox); err != nil {
+			return nil, err
+		}
+		inboxes = append(inboxes, inbox)
+	}
+	return inboxes, rows.Err()
+}
+
+func (r *FederationRepository) GetRemotePostID(objectURI string) (int, error) {
+	var postID int
+	err := r.db.QueryRow("SELECT post_id FROM remote_posts WHERE object_uri = $1", objectURI).Scan(&postID)
+	return postID, err
+}
+
+func (r *FederationRepository) SaveRemotePost(objectURI string, postID int) error {
+	_, err := r.db.Exec("INSERT INTO remote_posts (object_uri, post_id) VALUES ($1, $2) ON CONFLICT (object_uri) DO NOTHING", objectURI, postID)
+	if err != nil {
+		return fmt.Errorf("failed to save remote post: %v", err)
+	}
+	return nil
+}
+
+func (r *FederationRepository) GetReactionTypeID(name string) (int, error) {
+	var id int
+	err := r.db.QueryRow("SELECT id FROM reaction_types WHERE LOWER(name) = LOWER($1)", name).Scan(&id)
+	return id, err
+}
+
+func (r *FederationRepository) EnqueueDeliveries(deliveries []domain.Delivery) error {
+	if len(deliveries) == 0 {
+		return nil
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare("INSERT INTO deliveries (sender_id, inbox_url, activity) VALUES ($1, $2, $3)")
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, delivery := range deliveries {
+		if _, err := stmt.Exec(delivery.SenderID, delivery.InboxURL, string(delivery.Activity)); err != nil {
+			return fmt.Errorf("failed to enqueue delivery: %v", err)
+		}
+	}
+	return tx.Commit()
+}
+
+// ClaimDeliveries pushes the next attempt of the claimed deliveries past the
+// lease, so a worker that dies mid-delivery only delays them.
+func (r *FederationRepository) ClaimDeliveries(now time.Time, lease time.Duration, limit int) ([]domain.Delivery, error) {
+	rows, err := r.db.Query(`
+		UPDATE deliveries
+		SET next_attempt_at = $2
+		WHERE id IN (
+			SELECT id FROM deliveries
+			WHERE status = 'pending' AND next_attempt_at <= $1
+			ORDER BY next_attempt_at
+			LIMIT $3
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, sender_id, inbox_url, activity, attempts`, now, now.Add(lease), limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim deliveries: %v", err)
+	}
+	defer rows.Close()
+
+	var deliveries []domain.Delivery
+	for rows.Next() {
+		var delivery domain.Delivery
+		var activity string
+		if err := rows.Scan(&delivery.ID, &delivery.SenderID, &delivery.InboxURL, &activity, &delivery.Attempts); err != nil {
+			return nil, err
+		}
+		delivery.Activity = []byte(activity)
+		deliveries = append(deliveries, delivery)
+	}
+	return deliveries, rows.Err()
+}
+
+func (r *FederationRepository) CompleteDelivery(id int64) error {
+	_, err := r.db.Exec("DELETE FROM deliveries WHERE id = $1", id)
+	return err
+}
+
+func (r *FederationRepository) RetryDelivery(id int64, attempts int, nextAttemptAt time.Time, lastError string) error {
+	_, err := r.db.Exec("UPDATE deliveries SET attempts = $2, next_attempt_at = $3, last_error = $4 WHERE id = $1", id, attempts, nextAttemptAt, lastError)
+	return err
+}
+
+func (r *FederationRepository) FailDelivery(id int64, attempts int, lastError string) error {
+	_, err := r.db.Exec("UPDATE deliveries SET status = 'failed', attempts = $2, last_error = $3 WHERE id = $1", id, attempts, lastError)
+	return err
+}
@@ -0,0 +1,111 @@
+package infrastructure
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/bandvov/social-media-go/domain"
+	"github.com/go-redis/redis/v8"
+)
+
+// maxTimelineSize caps how many entries are kept in every home timeline.
+const maxTimelineSize = 800
+
+type RedisFeedRepository struct {
+	client *redis.Client
+}
+
+func NewRedisFeedRepository(client *redis.Client) *RedisFeedRepository {
+	return &RedisFeedRepository{client: client}
+}
+
+func timelineKey(userID int) string {
+	return fmt.Sprintf("timeline:%d", userID)
+}
+
+// AddToTimelines pushes a post into the timelines of the given users.
+// Entries are scored by creation time in microseconds so they stay ordered
+// the same way as posts read straight from Postgres.
+func (r *RedisFeedRepository) AddToTimelines(userIDs []int, entry domain.TimelineEntry) error {
+	if len(userIDs) == 0 {
+		return nil
+	}
+
+	ctx := context.Background()
+	member := &redis.Z{
+		Score:  float64(entry.CreatedAt.UnixMicro()),
+		Member: strconv.Itoa(entry.PostID),
+	}
+
+	pipe := r.client.Pipeline()
+	for _, userID := range userIDs {
+		key := timelineKey(userID)
+		pipe.ZAdd(ctx, key, member)
+		pipe.ZRemRangeByRank(ctx, key, 0, -maxTimelineSize-1)
+	}
+	_, err := pipe.Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to fan out post %d: %v", entry.PostID, err)
+	}
+	return nil
+}
+
+// GetTimeline returns up to limit entries older than the cursor, newest first.
+func (r *RedisFeedRepository) GetTimeline(userID int, before *domain.Cursor, limit int) ([]domain.TimelineEntry, error) {
+	max := "+inf"
+	if before != nil {
+		max = strconv.FormatInt(before.CreatedAt.UnixMicro(), 10)
+	}
+
+	// The cursor bound is inclusive, so fetch one extra entry to make up for
+	// the post the cursor itself points at.
+	result, err := r.client.ZRevRangeByScoreWithScores(context.Background(), timelineKey(userID), &redis.ZRangeBy{
+		Min:   "-inf",
+		Max:   max,
+		Count: int64(limit + 1),
+	}).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read timeline: %v", err)
+	}
+
+	return timelinePage(result, before, limit), nil
+}
+
+// timelinePage turns timeline members read up to the cursor's score into
+// entries strictly older than the cursor, newest first.
+func timelinePage(result []redis.Z, before *domain.Cursor, limit int) []domain.TimelineEntry {
+	entries := make([]domain.TimelineEntry, 0, len(result))
+	for _, z := range result {
+		member, ok := z.Member.(string)
+		if !ok {
+			continue
+		}
+		postID, err := strconv.Atoi(member)
+		if err != nil {
+			continue
+		}
+		entry := domain.TimelineEntry{
+			PostID:    postID,
+			CreatedAt: time.UnixMicro(int64(z.Score)).UTC(),
+		}
+		if before != nil && entry.CreatedAt.Equal(before.CreatedAt) && entry.PostID >= before.ID {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].CreatedAt.Equal(entries[j].CreatedAt) {
+			return entries[i].PostID > entries[j].PostID
+		}
+		return entries[i].CreatedAt.After(entries[j].CreatedAt)
+	})
+	if len(entries) > limit {
+		entries = entries[:limit]
+	}
+
+	return entries
+}
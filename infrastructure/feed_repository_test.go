@@ -0,0 +1,76 @@
+package infrastructure
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/bandvov/social-media-go/domain"
+	"github.com/go-redis/redis/v8"
+)
+
+func TestTimelinePage(t *testing.T) {
+	base := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	member := func(postID int, minute int) redis.Z {
+		return redis.Z{Score: float64(base.Add(time.Duration(minute) * time.Minute).UnixMicro()), Member: strconv.Itoa(postID)}
+	}
+	cursor := func(postID int, minute int) *domain.Cursor {
+		return &domain.Cursor{CreatedAt: base.Add(time.Duration(minute) * time.Minute), ID: postID}
+	}
+
+	tests := []struct {
+		name     string
+		result   []redis.Z
+		before   *domain.Cursor
+		limit    int
+		expected []int
+	}{
+		{
+			name:     "first page",
+			result:   []redis.Z{member(3, 3), member(2, 2), member(1, 1)},
+			limit:    2,
+			expected: []int{3, 2},
+		},
+		{
+			// The score bound includes the cursor's own entry, which is read
+			// as the extra entry and dropped.
+			name:     "inclusive cursor",
+			result:   []redis.Z{member(3, 3), member(2, 2), member(1, 1)},
+			before:   cursor(3, 3),
+			limit:    2,
+			expected: []int{2, 1},
+		},
+		{
+			name:     "same time as the cursor",
+			result:   []redis.Z{member(5, 2), member(4, 2), member(3, 2)},
+			before:   cursor(4, 2),
+			limit:    2,
+			expected: []int{3},
+		},
+		{
+			name:     "orders ties by id",
+			result:   []redis.Z{member(2, 1), member(7, 1), member(4, 1)},
+			limit:    3,
+			expected: []int{7, 4, 2},
+		},
+		{
+			name:     "skips foreign members",
+			result:   []redis.Z{member(2, 2), {Score: 1, Member: "not-a-post"}},
+			limit:    2,
+			expected: []int{2},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ids := []int{}
+			for _, entry := range timelinePage(tt.result, tt.before, tt.limit) {
+				ids = append(ids, entry.PostID)
+			}
+			if !reflect.DeepEqual(ids, tt.expected) {
+				t.Errorf("expected posts %v, got %v", tt.expected, ids)
+			}
+		})
+	}
+}
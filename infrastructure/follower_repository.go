@@ -33,19 +33,7 @@ func (r *FollowerRepository) RemoveFollower(follower *domain.Follower) error {
 	return nil
 }
 
-func (r *FollowerRepository) GetFollowers(userID, otherUser, limit, offset int, sort string, orderBy string, searchTerm string) ([]domain.User, error) {
-	// Validate and set default sorting
-	if sort == "" || sort == "desc" {
-		sort = "DESC"
-	}
-	if sort == "asc" {
-		sort = "ASC"
-	}
-
-	if limit == 0 {
-		limit = 24
-	}
-
+func (r *FollowerRepository) GetFollowers(userID, otherUser int, page domain.PageRequest, sort string, searchTerm string) ([]domain.User, error) {
 	query := `
 	SELECT 
     u.id,
@@ -60,50 +48,25 @@ func (r *FollowerRepository) GetFollowers(userID, otherUser, limit, offset int,
 	 CASE 
         WHEN f.followee_id = $2 THEN TRUE      
         ELSE FALSE                            
-    END AS followed_by_follower
+    END AS followed_by_follower,
+	f.created_at
 	FROM followers f
 	LEFT JOIN users u ON u.id = f.follower_id
 	WHERE f.followee_id = $1                          
 `
 
-	if searchTerm != "" {
-		query += fmt.Sprintf("\nWHERE position('%v' IN id) > 0 \n", searchTerm)
-	}
+	query += followSearchFilter
 
-	query += "\nLIMIT $3 OFFSET $4"
+	query += keysetFollowClause("f.follower_id", sort)
 
-	rows, err := r.db.Query(query, userID, otherUser, limit, offset)
+	users, err := r.queryFollowUsers(query, userID, otherUser, page, searchTerm)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get followers: %v", err)
 	}
-	defer rows.Close()
-
-	var users []domain.User
-	for rows.Next() {
-		var user domain.User
-		if err := rows.Scan(&user.ID, &user.Username, &user.Email, &user.Bio, &user.ProfilePic, &user.FollowsFollower, &user.FollowedByFollower); err != nil {
-			return nil, fmt.Errorf("failed to scan user: %v", err)
-		}
-		users = append(users, user)
-	}
 	return users, nil
 }
 
-func (r *FollowerRepository) GetFollowees(userID, otherUser, limit, offset int, sort string, orderBy string, searchTerm string) ([]domain.User, error) {
-	// Validate and set default sorting
-	if sort == "" || sort == "desc" {
-		sort = "DESC"
-	}
-	if sort == "asc" {
-		sort = "ASC"
-	}
-	if orderBy == "" {
-		orderBy = "created_at"
-	}
-	if limit == 0 {
-		limit = 24
-	}
-
+func (r *FollowerRepository) GetFollowees(userID, otherUser int, page domain.PageRequest, sort string, searchTerm string) ([]domain.User, error) {
 	query := `
 	SELECT 
     u.id,
@@ -118,31 +81,139 @@ func (r *FollowerRepository) GetFollowees(userID, otherUser, limit, offset int,
 	 CASE 
         WHEN f.followee_id = $2 THEN TRUE      
         ELSE FALSE                            
-    END AS followed_by_follower
+    END AS followed_by_follower,
+	f.created_at
 	FROM followers f
 	LEFT JOIN users u ON u.id = f.followee_id
 	WHERE f.follower_id = $1                         
 `
 
-	if searchTerm != "" {
-		query += fmt.Sprintf("\nWHERE position('%v' IN id) > 0 \n", searchTerm)
-	}
+	query += followSearchFilter
 
-	query += fmt.Sprintf("\nORDER BY %s %s\nLIMIT $3 OFFSET $4", orderBy, sort)
+	query += keysetFollowClause("f.followee_id", sort)
 
-	rows, err := r.db.Query(query, userID, otherUser, limit, offset)
+	users, err := r.queryFollowUsers(query, userID, otherUser, page, searchTerm)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get followees: %v", err)
 	}
+	return users, nil
+}
+
+// followSearchFilter narrows a follow list to handles containing the search
+// term ($7). The term is passed as a parameter, never spliced into the SQL.
+const followSearchFilter = `
+	AND ($7 = '' OR position(lower($7) IN lower(COALESCE(u.username, ''))) > 0)`
+
+// keysetFollowClause pages a follow list by (followed at, user id), newest
+// follows first unless sort is "asc".
+func keysetFollowClause(userColumn, sort string) string {
+	comparison, direction := "<", "DESC"
+	if sort == "asc" {
+		comparison, direction = ">", "ASC"
+	}
+	return fmt.Sprintf(`
+	AND ($3::timestamp IS NULL OR (f.created_at, %[1]s) %[2]s ($3::timestamp, $4))
+	ORDER BY f.created_at %[3]s, %[1]s %[3]s
+	OFFSET $5 LIMIT $6`, userColumn, comparison, direction)
+}
+
+func (r *FollowerRepository) queryFollowUsers(query string, userID, otherUser int, page domain.PageRequest, searchTerm string) ([]domain.User, error) {
+	afterTime, afterID := keysetArgs(page.After)
+	rows, err := r.db.Query(query, userID, otherUser, afterTime, afterID, page.Offset, page.Limit+1, searchTerm)
+	if err != nil {
+		return nil, err
+	}
 	defer rows.Close()
 
 	var users []domain.User
 	for rows.Next() {
 		var user domain.User
-		if err := rows.Scan(&user.ID, &user.Username, &user.Email, &user.Bio, &user.ProfilePic, &user.FollowsFollower, &user.FollowedByFollower); err != nil {
-			return nil, fmt.Errorf("failed to scan user: %v", err)
+		if err := rows.Scan(&user.ID, &user.Username, &user.Email, &user.Bio, &user.ProfilePic, &user.FollowsFollower, &user.FollowedByFollower, &user.FollowedAt); err != nil {
+			return nil, err
 		}
 		users = append(users, user)
 	}
-	return users, nil
+	return users, rows.Err()
+}
+
+// GetFollowerIDs returns the IDs of every user following userID.
+func (r *FollowerRepository) GetFollowerIDs(userID int) ([]int, error) {
+	rows, err := r.db.Query("SELECT follower_id FROM followers WHERE followee_id = $1", userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get follower ids: %v", err)
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan follower id: %v", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// GetFolloweeIDs returns the IDs of the users the user follows.
+func (r *FollowerRepository) GetFolloweeIDs(userID int) ([]int, error) {
+	rows, err := r.db.Query("SELECT followee_id FROM followers WHERE follower_id = $1", userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get followee ids: %v", err)
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan followee id: %v", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// IsFollowing reports whether followerID currently follows followeeID.
+func (r *FollowerRepository) IsFollowing(followerID, followeeID int) (bool, error) {
+	var exists bool
+	err := r.db.QueryRow("SELECT EXISTS (SELECT 1 FROM followers WHERE follower_id = $1 AND followee_id = $2)", followerID, followeeID).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("failed to check follower: %v", err)
+	}
+	return exists, nil
+}
+
+// CountFollowers returns how many users follow userID.
+func (r *FollowerRepository) CountFollowers(userID int) (int, error) {
+	var count int
+	err := r.db.QueryRow("SELECT followers_count FROM users WHERE id = $1", userID).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count followers: %v", err)
+	}
+	return count, nil
+}
+
+// GetPopularFolloweeIDs returns the users followed by userID that have at
+// least minFollowers followers themselves.
+func (r *FollowerRepository) GetPopularFolloweeIDs(userID, minFollowers int) ([]int, error) {
+	rows, err := r.db.Query(`
+	SELECT f.followee_id
+	FROM followers f
+	JOIN users u ON u.id = f.followee_id
+	WHERE f.follower_id = $1 AND u.followers_count >= $2`, userID, minFollowers)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get popular followees: %v", err)
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan followee id: %v", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
 }
@@ -0,0 +1,229 @@
+package infrastructure
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/bandvov/social-media-go/domain"
+)
+
+const (
+	maxPreviewRedirects   = 3
+	maxPreviewTitle       = 300
+	maxPreviewDescription = 1000
+)
+
+// ErrBlockedAddress is returned when a URL resolves to an address that must
+// not be reached from the server, such as loopback or private networks.
+var ErrBlockedAddress = errors.New("address is not publicly routable")
+
+// blockedNetworks lists special-purpose ranges not covered by the net.IP
+// helpers used in isPublicIP.
+var blockedNetworks = mustParseCIDRs(
+	"0.0.0.0/8",
+	"100.64.0.0/10",
+	"192.0.0.0/24",
+	"192.0.2.0/24",
+	"198.18.0.0/15",
+	"198.51.100.0/24",
+	"203.0.113.0/24",
+	"240.0.0.0/4",
+	"64:ff9b::/96",
+	"2001:db8::/32",
+)
+
+var (
+	metaTagPattern   = regexp.MustCompile(`(?is)<meta\s[^>]*>`)
+	attributePattern = regexp.MustCompile(`(?is)([a-z_:-]+)\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s"'>]+))`)
+	titlePattern     = regexp.MustCompile(`(?is)<title[^>]*>(.*?)</title>`)
+)
+
+// HTTPLinkPreviewFetcher unfurls URLs by reading their OpenGraph and
+// Twitter-card tags. Every connection, including the ones made for
+// redirects, is checked against the resolved IP so that previews cannot be
+// used to reach internal services.
+type HTTPLinkPreviewFetcher struct {
+	client   *http.Client
+	maxBytes int64
+	allowIP  func(ip net.IP) bool
+}
+
+func NewHTTPLinkPreviewFetcher(timeout time.Duration, maxBytes int64) *HTTPLinkPreviewFetcher {
+	f := &HTTPLinkPreviewFetcher{maxBytes: maxBytes, allowIP: isPublicIP}
+
+	dialer := &net.Dialer{Timeout: timeout, Control: f.checkAddress}
+	transport := &http.Transport{
+		DialContext:           dialer.DialContext,
+		TLSHandshakeTimeout:   timeout,
+		ResponseHeaderTimeout: timeout,
+		MaxIdleConns:          10,
+		IdleConnTimeout:       30 * time.Second,
+	}
+	f.client = &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= maxPreviewRedirects {
+				return errors.New("too many redirects")
+			}
+			if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+				return fmt.Errorf("unsupported redirect scheme %q", req.URL.Scheme)
+			}
+			return nil
+		},
+	}
+	return f
+}
+
+// checkAddress runs right before a connection is made, after DNS resolution,
+// so a hostname cannot be rebound to a private address between the check and
+// the request.
+func (f *HTTPLinkPreviewFetcher) checkAddress(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || !f.allowIP(ip) {
+		return ErrBlockedAddress
+	}
+	return nil
+}
+
+func (f *HTTPLinkPreviewFetcher) Fetch(ctx context.Context, rawURL string) (*domain.LinkPreview, error) {
+	pageURL, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if pageURL.Scheme != "http" && pageURL.Scheme != "https" || pageURL.Host == "" {
+		return nil, fmt.Errorf("unsupported url %q", rawURL)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "social-media-go link preview")
+	req.Header.Set("Accept", "text/html")
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	mediaType, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if err != nil || mediaType != "text/html" {
+		return nil, fmt.Errorf("unsupported content type %q", resp.Header.Get("Content-Type"))
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, f.maxBytes))
+	if err != nil {
+		return nil, err
+	}
+
+	preview := parseLinkPreview(resp.Request.URL, string(body))
+	preview.URL = rawURL
+	preview.FetchedAt = time.Now()
+	return preview, nil
+}
+
+// parseLinkPreview reads preview metadata from an HTML document, preferring
+// OpenGraph over Twitter-card tags over plain HTML.
+func parseLinkPreview(pageURL *url.URL, document string) *domain.LinkPreview {
+	meta := make(map[string]string)
+	for _, tag := range metaTagPattern.FindAllString(document, -1) {
+		attributes := make(map[string]string)
+		for _, match := range attributePattern.FindAllStringSubmatch(tag, -1) {
+			attributes[strings.ToLower(match[1])] = match[2] + match[3] + match[4]
+		}
+
+		key := attributes["property"]
+		if key == "" {
+			key = attributes["name"]
+		}
+		key = strings.ToLower(key)
+		if key != "" && meta[key] == "" {
+			meta[key] = strings.TrimSpace(html.UnescapeString(attributes["content"]))
+		}
+	}
+
+	title := firstNonEmpty(meta["og:title"], meta["twitter:title"])
+	if title == "" {
+		if match := titlePattern.FindStringSubmatch(document); match != nil {
+			title = strings.TrimSpace(html.UnescapeString(match[1]))
+		}
+	}
+
+	preview := &domain.LinkPreview{
+		Title:       truncate(title, maxPreviewTitle),
+		Description: truncate(firstNonEmpty(meta["og:description"], meta["twitter:description"], meta["description"]), maxPreviewDescription),
+		SiteName:    truncate(meta["og:site_name"], maxPreviewTitle),
+	}
+
+	image := firstNonEmpty(meta["og:image:secure_url"], meta["og:image"], meta["twitter:image"], meta["twitter:image:src"])
+	if image != "" {
+		if imageURL, err := pageURL.Parse(image); err == nil && (imageURL.Scheme == "http" || imageURL.Scheme == "https") {
+			preview.ImageURL = imageURL.String()
+		}
+	}
+
+	return preview
+}
+
+// isPublicIP reports whether the address is routable on the public internet.
+func isPublicIP(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() || ip.IsMulticast() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() {
+		return false
+	}
+	for _, network := range blockedNetworks {
+		if network.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+func mustParseCIDRs(cidrs ...string) []*net.IPNet {
+	networks := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		networks = append(networks, network)
+	}
+	return networks
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+func truncate(s string, max int) string {
+	runes := []rune(s)
+	if len(runes) <= max {
+		return s
+	}
+	return string(runes[:max])
+}
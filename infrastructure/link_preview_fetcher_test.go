@@ -0,0 +1,202 @@
+package infrastructure
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const previewPage = `<!DOCTYPE html>
+<html>
+<head>
+	<title>Fallback title</title>
+	<meta property="og:title" content="Go &amp; Postgres">
+	<meta name="twitter:title" content="Twitter title">
+	<meta name="description" content="Plain description">
+	<meta property="og:description" content='Building a social network'>
+	<meta property="og:image" content="/images/cover.png">
+	<meta property="og:site_name" content="Example Blog">
+</head>
+<body>Hello</body>
+</html>`
+
+// newTestFetcher returns a fetcher that may reach the loopback httptest
+// servers used in these tests.
+func newTestFetcher(timeout time.Duration, maxBytes int64) *HTTPLinkPreviewFetcher {
+	f := NewHTTPLinkPreviewFetcher(timeout, maxBytes)
+	f.allowIP = func(ip net.IP) bool { return true }
+	return f
+}
+
+func TestLinkPreviewFetcherParsesOpenGraph(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(previewPage))
+	}))
+	defer server.Close()
+
+	preview, err := newTestFetcher(time.Second, 64*1024).Fetch(context.Background(), server.URL+"/post")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if preview.URL != server.URL+"/post" {
+		t.Errorf("expected url %s, got %s", server.URL+"/post", preview.URL)
+	}
+	if preview.Title != "Go & Postgres" {
+		t.Errorf("expected og:title, got %q", preview.Title)
+	}
+	if preview.Description != "Building a social network" {
+		t.Errorf("expected og:description, got %q", preview.Description)
+	}
+	if preview.ImageURL != server.URL+"/images/cover.png" {
+		t.Errorf("expected image resolved against the page, got %q", preview.ImageURL)
+	}
+	if preview.SiteName != "Example Blog" {
+		t.Errorf("expected site name, got %q", preview.SiteName)
+	}
+}
+
+func TestLinkPreviewFetcherFallsBackToTitle(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><head><title> Just a title </title></head></html>`))
+	}))
+	defer server.Close()
+
+	preview, err := newTestFetcher(time.Second, 64*1024).Fetch(context.Background(), server.URL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if preview.Title != "Just a title" {
+		t.Errorf("expected <title> fallback, got %q", preview.Title)
+	}
+}
+
+func TestLinkPreviewFetcherBlocksPrivateAddresses(t *testing.T) {
+	requested := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = true
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(previewPage))
+	}))
+	defer server.Close()
+
+	// The default fetcher must refuse to connect to the loopback server.
+	_, err := NewHTTPLinkPreviewFetcher(time.Second, 64*1024).Fetch(context.Background(), server.URL)
+	if !errors.Is(err, ErrBlockedAddress) {
+		t.Fatalf("expected ErrBlockedAddress, got %v", err)
+	}
+	if requested {
+		t.Error("request reached a loopback server")
+	}
+}
+
+func TestLinkPreviewFetcherBlocksRedirectToPrivateAddress(t *testing.T) {
+	internal := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("redirect reached the internal server")
+	}))
+	defer internal.Close()
+
+	public := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, internal.URL, http.StatusFound)
+	}))
+	defer public.Close()
+
+	// Both servers listen on loopback: allow the first connection only, so
+	// the redirect target has to pass the address check on its own.
+	f := NewHTTPLinkPreviewFetcher(time.Second, 64*1024)
+	dials := 0
+	f.allowIP = func(ip net.IP) bool {
+		dials++
+		return dials == 1
+	}
+
+	_, err := f.Fetch(context.Background(), public.URL)
+	if !errors.Is(err, ErrBlockedAddress) {
+		t.Fatalf("expected ErrBlockedAddress, got %v", err)
+	}
+}
+
+func TestLinkPreviewFetcherRejectsNonHTML(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Write([]byte("binary"))
+	}))
+	defer server.Close()
+
+	if _, err := newTestFetcher(time.Second, 64*1024).Fetch(context.Background(), server.URL); err == nil {
+		t.Fatal("expected an error for non-HTML content")
+	}
+}
+
+func TestLinkPreviewFetcherLimitsBodySize(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte("<html><head>" + strings.Repeat(" ", 4096)))
+		w.Write([]byte(`<meta property="og:title" content="Too far"></head></html>`))
+	}))
+	defer server.Close()
+
+	preview, err := newTestFetcher(time.Second, 1024).Fetch(context.Background(), server.URL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if preview.Title != "" {
+		t.Errorf("expected metadata past the size limit to be ignored, got %q", preview.Title)
+	}
+}
+
+func TestLinkPreviewFetcherTimesOut(t *testing.T) {
+	done := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-done:
+		case <-time.After(2 * time.Second):
+		}
+	}))
+	defer server.Close()
+	defer close(done)
+
+	start := time.Now()
+	if _, err := newTestFetcher(100*time.Millisecond, 1024).Fetch(context.Background(), server.URL); err == nil {
+		t.Fatal("expected a timeout error")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("fetch took %v, expected it to give up after the timeout", elapsed)
+	}
+}
+
+func TestIsPublicIP(t *testing.T) {
+	tests := []struct {
+		ip       string
+		expected bool
+	}{
+		{"93.184.216.34", true},
+		{"2606:2800:220:1:248:1893:25c8:1946", true},
+		{"127.0.0.1", false},
+		{"10.1.2.3", false},
+		{"172.16.0.1", false},
+		{"192.168.1.1", false},
+		{"169.254.169.254", false},
+		{"100.64.0.1", false},
+		{"0.0.0.0", false},
+		{"::1", false},
+		{"fc00::1", false},
+		{"fe80::1", false},
+		{"::ffff:127.0.0.1", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.ip, func(t *testing.T) {
+			if got := isPublicIP(net.ParseIP(tt.ip)); got != tt.expected {
+				t.Errorf("isPublicIP(%s) = %v, expected %v", tt.ip, got, tt.expected)
+			}
+		})
+	}
+}
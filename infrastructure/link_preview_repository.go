@@ -0,0 +1,116 @@
+package infrastructure
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/bandvov/social-media-go/domain"
+	"github.com/lib/pq"
+)
+
+type LinkPreviewRepository struct {
+	db *sql.DB
+}
+
+func NewLinkPreviewRepository(db *sql.DB) *LinkPreviewRepository {
+	return &LinkPreviewRepository{db: db}
+}
+
+// SetLinks replaces the URLs a post or comment links to.
+func (r *LinkPreviewRepository) SetLinks(entityType string, entityID int, urls []string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM content_links WHERE entity_type = $1 AND entity_id = $2", entityType, entityID); err != nil {
+		return fmt.Errorf("failed to clear links: %v", err)
+	}
+	for i, url := range urls {
+		_, err := tx.Exec("INSERT INTO content_links (entity_type, entity_id, url, position) VALUES ($1, $2, $3, $4)",
+			entityType, entityID, url, i)
+		if err != nil {
+			return fmt.Errorf("failed to save link: %v", err)
+		}
+	}
+	return tx.Commit()
+}
+
+func (r *LinkPreviewRepository) DeleteLinks(entityType string, entityID int) error {
+	_, err := r.db.Exec("DELETE FROM content_links WHERE entity_type = $1 AND entity_id = $2", entityType, entityID)
+	if err != nil {
+		return fmt.Errorf("failed to delete links: %v", err)
+	}
+	return nil
+}
+
+// GetCachedURLs returns which of the URLs were fetched, successfully or not,
+// after the given time.
+func (r *LinkPreviewRepository) GetCachedURLs(urls []string, fetchedAfter time.Time) (map[string]bool, error) {
+	cached := make(map[string]bool)
+	if len(urls) == 0 {
+		return cached, nil
+	}
+
+	rows, err := r.db.Query("SELECT url FROM link_previews WHERE url = ANY($1) AND fetched_at > $2", pq.Array(urls), fetchedAfter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cached previews: %v", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var url string
+		if err := rows.Scan(&url); err != nil {
+			return nil, fmt.Errorf("failed to scan url: %v", err)
+		}
+		cached[url] = true
+	}
+	return cached, rows.Err()
+}
+
+func (r *LinkPreviewRepository) SavePreview(preview *domain.LinkPreview) error {
+	_, err := r.db.Exec(`
+		INSERT INTO link_previews (url, title, description, image_url, site_name, failed, fetched_at)
+		VALUES ($1, $2, $3, $4, $5, $6, CURRENT_TIMESTAMP)
+		ON CONFLICT (url)
+		DO UPDATE SET title = $2, description = $3, image_url = $4, site_name = $5, failed = $6, fetched_at = CURRENT_TIMESTAMP`,
+		preview.URL, preview.Title, preview.Description, preview.ImageURL, preview.SiteName, preview.Failed)
+	if err != nil {
+		return fmt.Errorf("failed to save preview: %v", err)
+	}
+	return nil
+}
+
+// GetPreviewsByEntityIDs returns the previews of every successfully unfurled
+// link, grouped by post or comment and in the order the links appear.
+func (r *LinkPreviewRepository) GetPreviewsByEntityIDs(entityType string, entityIDs []int) (map[int][]domain.LinkPreview, error) {
+	previews := make(map[int][]domain.LinkPreview)
+	if len(entityIDs) == 0 {
+		return previews, nil
+	}
+
+	rows, err := r.db.Query(`
+	SELECT cl.entity_id, lp.url, COALESCE(lp.title, ''), COALESCE(lp.description, ''), COALESCE(lp.image_url, ''), COALESCE(lp.site_name, ''), lp.fetched_at
+	FROM content_links cl
+	JOIN link_previews lp ON lp.url = cl.url
+	WHERE cl.entity_type = $1 AND cl.entity_id = ANY($2) AND NOT lp.failed
+	ORDER BY cl.entity_id, cl.position`, entityType, pq.Array(entityIDs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get previews: %v", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			entityID int
+			preview  domain.LinkPreview
+		)
+		if err := rows.Scan(&entityID, &preview.URL, &preview.Title, &preview.Description, &preview.ImageURL, &preview.SiteName, &preview.FetchedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan preview: %v", err)
+		}
+		previews[entityID] = append(previews[entityID], preview)
+	}
+	return previews, rows.Err()
+}
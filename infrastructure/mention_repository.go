@@ -0,0 +1,96 @@
+package infrastructure
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/bandvov/social-media-go/domain"
+	"github.com/lib/pq"
+)
+
+type MentionRepository struct {
+	db *sql.DB
+}
+
+func NewMentionRepository(db *sql.DB) *MentionRepository {
+	return &MentionRepository{db: db}
+}
+
+func (r *MentionRepository) SetMentions(entityType string, entityID int, userIDs []int) ([]int, error) {
+	if userIDs == nil {
+		// A NULL array would keep every old mention.
+		userIDs = []int{}
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec("DELETE FROM mentions WHERE entity_type = $1 AND entity_id = $2 AND NOT user_id = ANY($3)",
+		entityType, entityID, pq.Array(userIDs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to clear mentions: %v", err)
+	}
+
+	rows, err := tx.Query(`
+	INSERT INTO mentions (entity_type, entity_id, user_id)
+	SELECT $1, $2, unnest($3::int[])
+	ON CONFLICT DO NOTHING
+	RETURNING user_id`,
+		entityType, entityID, pq.Array(userIDs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to save mentions: %v", err)
+	}
+	defer rows.Close()
+
+	var added []int
+	for rows.Next() {
+		var userID int
+		if err := rows.Scan(&userID); err != nil {
+			return nil, err
+		}
+		added = append(added, userID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return added, tx.Commit()
+}
+
+func (r *MentionRepository) DeleteMentions(entityType string, entityID int) error {
+	_, err := r.db.Exec("DELETE FROM mentions WHERE entity_type = $1 AND entity_id = $2", entityType, entityID)
+	if err != nil {
+		return fmt.Errorf("failed to delete mentions: %v", err)
+	}
+	return nil
+}
+
+func (r *MentionRepository) GetMentionsByEntityIDs(entityType string, entityIDs []int) (map[int][]domain.Mention, error) {
+	mentions := make(map[int][]domain.Mention)
+	if len(entityIDs) == 0 {
+		return mentions, nil
+	}
+
+	rows, err := r.db.Query(`
+	SELECT m.entity_id, m.user_id, u.username
+	FROM mentions m
+	JOIN users u ON u.id = m.user_id
+	WHERE m.entity_type = $1 AND m.entity_id = ANY($2)`,
+		entityType, pq.Array(entityIDs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get mentions: %v", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var entityID int
+		var mention domain.Mention
+		if err := rows.Scan(&entityID, &mention.UserID, &mention.Username); err != nil {
+			return nil, err
+		}
+		mentions[entityID] = append(mentions[entityID], mention)
+	}
+	return mentions, rows.Err()
+}
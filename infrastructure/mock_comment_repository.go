@@ -0,0 +1,110 @@
+package infrastructure
+
+import "github.com/bandvov/social-media-go/domain"
+
+type MockCommentRepository struct {
+	AddCommentFunc              func(comment domain.Comment) (int, error)
+	GetCommentByIDFunc          func(id int) (*domain.Comment, error)
+	FetchCommentsByEntityIDFunc func(entityID, userID int, sort domain.CommentSort, page domain.PageRequest) ([]domain.Comment, error)
+	FetchRepliesFunc            func(parentIDs []int, userID int, after *domain.Cursor, limit int) ([]domain.Comment, error)
+	GetCommentsByEntityIDsFunc  func(entityIDs []int) ([]domain.Comment, error)
+	CountByEntityIDsFunc        func(entityIDs []int) ([]domain.CommentCount, error)
+	GetCommentsWithoutHTMLFunc  func(limit int) ([]domain.Comment, error)
+	SetContentHTMLFunc          func(id int, html string) error
+	HideFunc                    func(id int) error
+	ReleaseFunc                 func(id int) error
+	UpdateContentFunc           func(comment *domain.Comment) error
+	DeleteFunc                  func(id int) error
+	SetHiddenByAuthorFunc       func(id int, hidden bool) error
+}
+
+func (m *MockCommentRepository) AddComment(comment domain.Comment) (int, error) {
+	if m.AddCommentFunc != nil {
+		return m.AddCommentFunc(comment)
+	}
+	return 0, nil
+}
+
+func (m *MockCommentRepository) GetCommentByID(id int) (*domain.Comment, error) {
+	if m.GetCommentByIDFunc != nil {
+		return m.GetCommentByIDFunc(id)
+	}
+	return nil, nil
+}
+
+func (m *MockCommentRepository) FetchCommentsByEntityID(entityID, userID int, sort domain.CommentSort, page domain.PageRequest) ([]domain.Comment, error) {
+	if m.FetchCommentsByEntityIDFunc != nil {
+		return m.FetchCommentsByEntityIDFunc(entityID, userID, sort, page)
+	}
+	return nil, nil
+}
+
+func (m *MockCommentRepository) FetchReplies(parentIDs []int, userID int, after *domain.Cursor, limit int) ([]domain.Comment, error) {
+	if m.FetchRepliesFunc != nil {
+		return m.FetchRepliesFunc(parentIDs, userID, after, limit)
+	}
+	return nil, nil
+}
+
+func (m *MockCommentRepository) GetCommentsByEntityIDs(entityIDs []int) ([]domain.Comment, error) {
+	if m.GetCommentsByEntityIDsFunc != nil {
+		return m.GetCommentsByEntityIDsFunc(entityIDs)
+	}
+	return nil, nil
+}
+
+func (m *MockCommentRepository) CountByEntityIDs(entityIDs []int) ([]domain.CommentCount, error) {
+	if m.CountByEntityIDsFunc != nil {
+		return m.CountByEntityIDsFunc(entityIDs)
+	}
+	return nil, nil
+}
+
+func (m *MockCommentRepository) GetCommentsWithoutHTML(limit int) ([]domain.Comment, error) {
+	if m.GetCommentsWithoutHTMLFunc != nil {
+		return m.GetCommentsWithoutHTMLFunc(limit)
+	}
+	return nil, nil
+}
+
+func (m *MockCommentRepository) SetContentHTML(id int, html string) error {
+	if m.SetContentHTMLFunc != nil {
+		return m.SetContentHTMLFunc(id, html)
+	}
+	return nil
+}
+
+func (m *MockCommentRepository) Hide(id int) error {
+	if m.HideFunc != nil {
+		return m.HideFunc(id)
+	}
+	return nil
+}
+
+func (m *MockCommentRepository) UpdateContent(comment *domain.Comment) error {
+	if m.UpdateContentFunc != nil {
+		return m.UpdateContentFunc(comment)
+	}
+	return nil
+}
+
+func (m *MockCommentRepository) Delete(id int) error {
+	if m.DeleteFunc != nil {
+		return m.DeleteFunc(id)
+	}
+	return nil
+}
+
+func (m *MockCommentRepository) Release(id int) error {
+	if m.ReleaseFunc != nil {
+		return m.ReleaseFunc(id)
+	}
+	return nil
+}
+
+func (m *MockCommentRepository) SetHiddenByAuthor(id int, hidden bool) error {
+	if m.SetHiddenByAuthorFunc != nil {
+		return m.SetHiddenByAuthorFunc(id, hidden)
+	}
+	return nil
+}
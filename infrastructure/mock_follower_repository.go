@@ -0,0 +1,78 @@
+package infrastructure
+
+import "github.com/bandvov/social-media-go/domain"
+
+type MockFollowerRepository struct {
+	AddFollowerFunc           func(follower *domain.Follower) error
+	RemoveFollowerFunc        func(follower *domain.Follower) error
+	GetFollowersFunc          func(userID, otherUser int, page domain.PageRequest, sort, search string) ([]domain.User, error)
+	GetFolloweesFunc          func(userID, otherUser int, page domain.PageRequest, sort, search string) ([]domain.User, error)
+	GetFollowerIDsFunc        func(userID int) ([]int, error)
+	IsFollowingFunc           func(followerID, followeeID int) (bool, error)
+	CountFollowersFunc        func(userID int) (int, error)
+	GetPopularFolloweeIDsFunc func(userID, minFollowers int) ([]int, error)
+	GetFolloweeIDsFunc        func(userID int) ([]int, error)
+}
+
+func (m *MockFollowerRepository) AddFollower(follower *domain.Follower) error {
+	if m.AddFollowerFunc != nil {
+		return m.AddFollowerFunc(follower)
+	}
+	return nil
+}
+
+func (m *MockFollowerRepository) RemoveFollower(follower *domain.Follower) error {
+	if m.RemoveFollowerFunc != nil {
+		return m.RemoveFollowerFunc(follower)
+	}
+	return nil
+}
+
+func (m *MockFollowerRepository) GetFollowers(userID, otherUser int, page domain.PageRequest, sort, search string) ([]domain.User, error) {
+	if m.GetFollowersFunc != nil {
+		return m.GetFollowersFunc(userID, otherUser, page, sort, search)
+	}
+	return nil, nil
+}
+
+func (m *MockFollowerRepository) GetFollowees(userID, otherUser int, page domain.PageRequest, sort, search string) ([]domain.User, error) {
+	if m.GetFolloweesFunc != nil {
+		return m.GetFolloweesFunc(userID, otherUser, page, sort, search)
+	}
+	return nil, nil
+}
+
+func (m *MockFollowerRepository) GetFollowerIDs(userID int) ([]int, error) {
+	if m.GetFollowerIDsFunc != nil {
+		return m.GetFollowerIDsFunc(userID)
+	}
+	return nil, nil
+}
+
+func (m *MockFollowerRepository) IsFollowing(followerID, followeeID int) (bool, error) {
+	if m.IsFollowingFunc != nil {
+		return m.IsFollowingFunc(followerID, followeeID)
+	}
+	return false, nil
+}
+
+func (m *MockFollowerRepository) CountFollowers(userID int) (int, error) {
+	if m.CountFollowersFunc != nil {
+		return m.CountFollowersFunc(userID)
+	}
+	return 0, nil
+}
+
+func (m *MockFollowerRepository) GetPopularFolloweeIDs(userID, minFollowers int) ([]int, error) {
+	if m.GetPopularFolloweeIDsFunc != nil {
+		return m.GetPopularFolloweeIDsFunc(userID, minFollowers)
+	}
+	return nil, nil
+}
+
+func (m *MockFollowerRepository) GetFolloweeIDs(userID int) ([]int, error) {
+	if m.GetFolloweeIDsFunc != nil {
+		return m.GetFolloweeIDsFunc(userID)
+	}
+	return nil, nil
+}
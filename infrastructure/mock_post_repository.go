@@ -0,0 +1,158 @@
+package infrastructure
+
+import "github.com/bandvov/social-media-go/domain"
+
+type MockPostRepository struct {
+	CreateFunc                func(post *domain.CreatePostRequest) (*domain.Post, error)
+	GetByIDFunc               func(id int) (*domain.Post, error)
+	UpdateFunc                func(id int, post *domain.Post) error
+	SetContentWarningFunc     func(postID int, warning string, sensitive bool) error
+	SetVisibilityFunc         func(postID int, visibility domain.PostVisibility) error
+	SetThreadVisibilityFunc   func(rootID int, visibility domain.PostVisibility) error
+	HoldFunc                  func(postID int) error
+	ReleaseFunc               func(postID int) error
+	DeleteFunc                func(id int) error
+	FindByUserIDFunc          func(userID, otherUserId int, visibilities []domain.PostVisibility, page domain.PageRequest) ([]domain.Post, error)
+	GetCountPostsByUserFunc   func(userId int) (int, error)
+	GetPostsFunc              func(authorID int, visibilities []domain.PostVisibility, page domain.PageRequest) ([]domain.Post, error)
+	GetFeedPostsByIDsFunc     func(viewerID int, postIDs []int) ([]domain.Post, error)
+	GetFeedPostsByAuthorsFunc func(viewerID int, authorIDs []int, before *domain.Cursor, limit int) ([]domain.Post, error)
+	GetPostsWithoutHTMLFunc   func(limit int) ([]domain.Post, error)
+	SetContentHTMLFunc        func(id int, html string) error
+	GetThreadFunc             func(rootID int) ([]domain.Post, error)
+	SetCommentPolicyFunc      func(postID int, policy domain.CommentPolicy) error
+	SetPinnedCommentFunc      func(postID int, commentID *int) error
+}
+
+func (m *MockPostRepository) Create(post *domain.CreatePostRequest) (*domain.Post, error) {
+	if m.CreateFunc != nil {
+		return m.CreateFunc(post)
+	}
+	return nil, nil
+}
+
+func (m *MockPostRepository) GetByID(id int) (*domain.Post, error) {
+	if m.GetByIDFunc != nil {
+		return m.GetByIDFunc(id)
+	}
+	return nil, nil
+}
+
+func (m *MockPostRepository) Update(id int, post *domain.Post) error {
+	if m.UpdateFunc != nil {
+		return m.UpdateFunc(id, post)
+	}
+	return nil
+}
+
+func (m *MockPostRepository) Delete(id int) error {
+	if m.DeleteFunc != nil {
+		return m.DeleteFunc(id)
+	}
+	return nil
+}
+
+func (m *MockPostRepository) FindByUserID(userID, otherUserId int, visibilities []domain.PostVisibility, page domain.PageRequest) ([]domain.Post, error) {
+	if m.FindByUserIDFunc != nil {
+		return m.FindByUserIDFunc(userID, otherUserId, visibilities, page)
+	}
+	return nil, nil
+}
+
+func (m *MockPostRepository) GetCountPostsByUser(userId int) (int, error) {
+	if m.GetCountPostsByUserFunc != nil {
+		return m.GetCountPostsByUserFunc(userId)
+	}
+	return 0, nil
+}
+
+func (m *MockPostRepository) GetPosts(authorID int, visibilities []domain.PostVisibility, page domain.PageRequest) ([]domain.Post, error) {
+	if m.GetPostsFunc != nil {
+		return m.GetPostsFunc(authorID, visibilities, page)
+	}
+	return nil, nil
+}
+
+func (m *MockPostRepository) GetFeedPostsByIDs(viewerID int, postIDs []int) ([]domain.Post, error) {
+	if m.GetFeedPostsByIDsFunc != nil {
+		return m.GetFeedPostsByIDsFunc(viewerID, postIDs)
+	}
+	return nil, nil
+}
+
+func (m *MockPostRepository) GetFeedPostsByAuthors(viewerID int, authorIDs []int, before *domain.Cursor, limit int) ([]domain.Post, error) {
+	if m.GetFeedPostsByAuthorsFunc != nil {
+		return m.GetFeedPostsByAuthorsFunc(viewerID, authorIDs, before, limit)
+	}
+	return nil, nil
+}
+
+func (m *MockPostRepository) SetContentWarning(postID int, warning string, sensitive bool) error {
+	if m.SetContentWarningFunc != nil {
+		return m.SetContentWarningFunc(postID, warning, sensitive)
+	}
+	return nil
+}
+
+func (m *MockPostRepository) SetVisibility(postID int, visibility domain.PostVisibility) error {
+	if m.SetVisibilityFunc != nil {
+		return m.SetVisibilityFunc(postID, visibility)
+	}
+	return nil
+}
+
+func (m *MockPostRepository) SetThreadVisibility(rootID int, visibility domain.PostVisibility) error {
+	if m.SetThreadVisibilityFunc != nil {
+		return m.SetThreadVisibilityFunc(rootID, visibility)
+	}
+	return nil
+}
+
+func (m *MockPostRepository) Hold(postID int) error {
+	if m.HoldFunc != nil {
+		return m.HoldFunc(postID)
+	}
+	return nil
+}
+
+func (m *MockPostRepository) Release(postID int) error {
+	if m.ReleaseFunc != nil {
+		return m.ReleaseFunc(postID)
+	}
+	return nil
+}
+
+func (m *MockPostRepository) GetPostsWithoutHTML(limit int) ([]domain.Post, error) {
+	if m.GetPostsWithoutHTMLFunc != nil {
+		return m.GetPostsWithoutHTMLFunc(limit)
+	}
+	return nil, nil
+}
+
+func (m *MockPostRepository) SetContentHTML(id int, html string) error {
+	if m.SetContentHTMLFunc != nil {
+		return m.SetContentHTMLFunc(id, html)
+	}
+	return nil
+}
+
+func (m *MockPostRepository) GetThread(rootID int) ([]domain.Post, error) {
+	if m.GetThreadFunc != nil {
+		return m.GetThreadFunc(rootID)
+	}
+	return nil, nil
+}
+
+func (m *MockPostRepository) SetCommentPolicy(postID int, policy domain.CommentPolicy) error {
+	if m.SetCommentPolicyFunc != nil {
+		return m.SetCommentPolicyFunc(postID, policy)
+	}
+	return nil
+}
+
+func (m *MockPostRepository) SetPinnedComment(postID int, commentID *int) error {
+	if m.SetPinnedCommentFunc != nil {
+		return m.SetPinnedCommentFunc(postID, commentID)
+	}
+	return nil
+}
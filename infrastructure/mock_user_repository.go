@@ -7,15 +7,18 @@ import (
 )
 
 type MockUserRepository struct {
-	CreateUserFunc         func(user *domain.User) error
-	GetUserByUsernameFunc  func(username string) (*domain.User, error)
-	GetUserByEmailFunc     func(email string) (*domain.User, error)
-	GetUserByIDFunc        func(id int) (*domain.User, error)
-	GetPublicProfilesFunc  func(limit, offset int) ([]domain.User, error)
-	GetAdminProfilesFunc   func(limit, offset int) ([]domain.User, error)
-	GetUserProfileInfoFunc func(id, authenticatedUser int) (*domain.User, error)
-	UpdateUserFunc         func(user *domain.User) error
-	GetUsersByIDFunc       func(ctx context.Context, userIDs []int) ([]domain.User, error)
+	CreateUserFunc             func(user *domain.User) error
+	GetUserByUsernameFunc      func(username string) (*domain.User, error)
+	GetUserByEmailFunc         func(email string) (*domain.User, error)
+	GetUserByIDFunc            func(id int) (*domain.User, error)
+	GetPublicProfilesFunc      func(page domain.PageRequest) ([]domain.User, error)
+	GetAdminProfilesFunc       func(limit, offset int) ([]domain.User, error)
+	GetUserProfileInfoFunc     func(id, authenticatedUser int) (*domain.User, error)
+	UpdateUserFunc             func(user *domain.User) error
+	UpdateSensitiveContentFunc func(userID int, preference string) error
+	UpdatePrivacyFunc          func(userID int, private bool) error
+	UpdateStatusFunc           func(userID int, status string) error
+	GetUsersByIDFunc           func(ctx context.Context, userIDs []int) ([]domain.User, error)
 }
 
 func (m *MockUserRepository) CreateUser(user *domain.User) error {
@@ -39,9 +42,9 @@ func (m *MockUserRepository) GetUserByID(id int) (*domain.User, error) {
 	return nil, nil
 }
 
-func (m *MockUserRepository) GetPublicProfiles(limit, offset int) ([]domain.User, error) {
+func (m *MockUserRepository) GetPublicProfiles(page domain.PageRequest) ([]domain.User, error) {
 	if m.GetPublicProfilesFunc != nil {
-		return m.GetPublicProfilesFunc(limit, offset)
+		return m.GetPublicProfilesFunc(page)
 	}
 	return nil, nil
 }
@@ -79,3 +82,24 @@ func (m *MockUserRepository) GetUsersByID(ctx context.Context, userIDs []int) ([
 	}
 	return nil, nil
 }
+
+func (m *MockUserRepository) UpdateSensitiveContent(userID int, preference string) error {
+	if m.UpdateSensitiveContentFunc != nil {
+		return m.UpdateSensitiveContentFunc(userID, preference)
+	}
+	return nil
+}
+
+func (m *MockUserRepository) UpdatePrivacy(userID int, private bool) error {
+	if m.UpdatePrivacyFunc != nil {
+		return m.UpdatePrivacyFunc(userID, private)
+	}
+	return nil
+}
+
+func (m *MockUserRepository) UpdateStatus(userID int, status string) error {
+	if m.UpdateStatusFunc != nil {
+		return m.UpdateStatusFunc(userID, status)
+	}
+	return nil
+}
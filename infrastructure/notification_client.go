@@ -0,0 +1,45 @@
+package infrastructure
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/bandvov/social-media-go/domain"
+)
+
+// HTTPNotifier sends notifications to the notifications service.
+type HTTPNotifier struct {
+	baseURL string
+	client  *http.Client
+}
+
+func NewHTTPNotifier(baseURL string, timeout time.Duration) *HTTPNotifier {
+	return &HTTPNotifier{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		client:  &http.Client{Timeout: timeout},
+	}
+}
+
+func (n *HTTPNotifier) Notify(notification domain.Notification) error {
+	body, err := json.Marshal(notification)
+	if err != nil {
+		return err
+	}
+
+	resp, err := n.client.Post(n.baseURL+"/send", "application/json", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to send notification: %v", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("notifications service responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
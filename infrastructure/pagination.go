@@ -0,0 +1,13 @@
+package infrastructure
+
+import "github.com/bandvov/social-media-go/domain"
+
+// keysetArgs returns the query arguments for a keyset condition written as
+// `($n::timestamp IS NULL OR (created_at, id) < ($n::timestamp, $n+1))`.
+// On the first page the timestamp is NULL and the condition matches every row.
+func keysetArgs(after *domain.Cursor) (interface{}, int) {
+	if after == nil {
+		return nil, 0
+	}
+	return after.CreatedAt, after.ID
+}
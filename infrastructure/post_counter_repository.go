@@ -0,0 +1,149 @@
+package infrastructure
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/bandvov/social-media-go/domain"
+	"github.com/lib/pq"
+)
+
+type PostCounterRepository struct {
+	db *sql.DB
+}
+
+func NewPostCounterRepository(db *sql.DB) *PostCounterRepository {
+	return &PostCounterRepository{db: db}
+}
+
+func (r *PostCounterRepository) GetPostCounters(postIDs []int) ([]domain.PostCounters, error) {
+	if len(postIDs) == 0 {
+		return nil, nil
+	}
+
+	rows, err := r.db.Query(`
+		SELECT id, reactions_count, comments_count, replies_count
+		FROM posts
+		WHERE id = ANY($1)`, pq.Array(postIDs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get post counters: %v", err)
+	}
+	defer rows.Close()
+
+	return scanPostCounters(rows)
+}
+
+// SavePostCounters writes counters to the denormalized columns, skipping
+// posts whose columns already hold them.
+func (r *PostCounterRepository) SavePostCounters(counters []domain.PostCounters) error {
+	if len(counters) == 0 {
+		return nil
+	}
+
+	ids, reactions, comments, replies := postCounterArrays(counters)
+	_, err := r.db.Exec(`
+		UPDATE posts p
+		SET reactions_count = c.reactions, comments_count = c.comments, replies_count = c.replies
+		FROM unnest($1::int[], $2::int[], $3::int[], $4::int[]) AS c(id, reactions, comments, replies)
+		WHERE p.id = c.id
+		  AND (p.reactions_count, p.comments_count, p.replies_count) IS DISTINCT FROM (c.reactions, c.comments, c.replies)`,
+		pq.Array(ids), pq.Array(reactions), pq.Array(comments), pq.Array(replies))
+	if err != nil {
+		return fmt.Errorf("failed to save post counters: %v", err)
+	}
+	return nil
+}
+
+// RepairPostCounters compares and sets, so counters flushed after expected
+// was read are not overwritten.
+func (r *PostCounterRepository) RepairPostCounters(counters []domain.PostCounters, expected map[int]domain.PostCounters) (int, error) {
+	var checked, old []domain.PostCounters
+	for _, counter := range counters {
+		if previous, ok := expected[counter.PostID]; ok {
+			checked = append(checked, counter)
+			old = append(old, previous)
+		}
+	}
+	if len(checked) == 0 {
+		return 0, nil
+	}
+
+	ids, reactions, comments, replies := postCounterArrays(checked)
+	_, oldReactions, oldComments, oldReplies := postCounterArrays(old)
+	result, err := r.db.Exec(`
+		UPDATE posts p
+		SET reactions_count = c.reactions, comments_count = c.comments, replies_count = c.replies
+		FROM unnest($1::int[], $2::int[], $3::int[], $4::int[], $5::int[], $6::int[], $7::int[])
+			AS c(id, reactions, comments, replies, old_reactions, old_comments, old_replies)
+		WHERE p.id = c.id
+		  AND (p.reactions_count, p.comments_count, p.replies_count) = (c.old_reactions, c.old_comments, c.old_replies)
+		  AND (p.reactions_count, p.comments_count, p.replies_count) IS DISTINCT FROM (c.reactions, c.comments, c.replies)`,
+		pq.Array(ids), pq.Array(reactions), pq.Array(comments), pq.Array(replies),
+		pq.Array(oldReactions), pq.Array(oldComments), pq.Array(oldReplies))
+	if err != nil {
+		return 0, fmt.Errorf("failed to repair post counters: %v", err)
+	}
+	affected, err := result.RowsAffected()
+	return int(affected), err
+}
+
+func postCounterArrays(counters []domain.PostCounters) (ids, reactions, comments, replies []int64) {
+	for _, counter := range counters {
+		ids = append(ids, int64(counter.PostID))
+		reactions = append(reactions, int64(counter.Reactions))
+		comments = append(comments, int64(counter.Comments))
+		replies = append(replies, int64(counter.Replies))
+	}
+	return ids, reactions, comments, replies
+}
+
+func (r *PostCounterRepository) ListPostIDs(afterID, limit int) ([]int, error) {
+	rows, err := r.db.Query("SELECT id FROM posts WHERE id > $1 ORDER BY id LIMIT $2", afterID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list posts: %v", err)
+	}
+	defer rows.Close()
+
+	var postIDs []int
+	for rows.Next() {
+		var postID int
+		if err := rows.Scan(&postID); err != nil {
+			return nil, err
+		}
+		postIDs = append(postIDs, postID)
+	}
+	return postIDs, rows.Err()
+}
+
+func (r *PostCounterRepository) RecountPosts(postIDs []int) ([]domain.PostCounters, error) {
+	if len(postIDs) == 0 {
+		return nil, nil
+	}
+
+	rows, err := r.db.Query(`
+		SELECT p.id,
+			(SELECT COUNT(*) FROM reactions r WHERE r.entity_type = 'post' AND r.entity_id = p.id),
+			(SELECT COUNT(*) FROM comments c WHERE c.post_id = p.id AND c.parent_id IS NULL AND c.status = 'active'),
+			(SELECT COUNT(*) FROM comments c WHERE c.post_id = p.id AND c.parent_id IS NOT NULL AND c.status = 'active')
+		FROM posts p
+		WHERE p.id = ANY($1)
+		ORDER BY p.id`, pq.Array(postIDs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to recount posts: %v", err)
+	}
+	defer rows.Close()
+
+	return scanPostCounters(rows)
+}
+
+func scanPostCounters(rows *sql.Rows) ([]domain.PostCounters, error) {
+	var counters []domain.PostCounters
+	for rows.Next() {
+		var counter domain.PostCounters
+		if err := rows.Scan(&counter.PostID, &counter.Reactions, &counter.Comments, &counter.Replies); err != nil {
+			return nil, err
+		}
+		counters = append(counters, counter)
+	}
+	return counters, rows.Err()
+}
@@ -5,8 +5,25 @@ import (
 	"fmt"
 
 	"github.com/bandvov/social-media-go/domain"
+	"github.com/lib/pq"
 )
 
+// postColumns are the columns of a post read by postFields. Queries using
+// them join the author as u.
+const postColumns = `p.id, p.author_id, COALESCE(u.username, ''), p.content, COALESCE(p.content_html, ''),
+	p.visibility, p.pinned, COALESCE(p.content_warning, ''), p.sensitive, p.warning_forced,
+	p.continues_post_id, p.thread_root_id, p.held_visibility IS NOT NULL, p.comment_policy, p.pinned_comment_id,
+	p.created_at, p.updated_at`
+
+func postFields(post *domain.Post) []interface{} {
+	return []interface{}{
+		&post.ID, &post.AuthorID, &post.AuthorName, &post.Content, &post.ContentHTML,
+		&post.Visibility, &post.Pinned, &post.ContentWarning, &post.Sensitive, &post.WarningForced,
+		&post.ContinuesPostID, &post.ThreadRootID, &post.HeldForReview, &post.CommentPolicy, &post.PinnedCommentID,
+		&post.CreatedAt, &post.UpdatedAt,
+	}
+}
+
 type PostRepository struct {
 	db *sql.DB
 }
@@ -15,92 +32,284 @@ func NewPostRepository(db *sql.DB) *PostRepository {
 	return &PostRepository{db: db}
 }
 
-func (r *PostRepository) Create(post *domain.CreatePostRequest) error {
-	_, err := r.db.Exec("INSERT INTO posts (author_id, content, visibility, pinned) VALUES ($1, $2, $3, $4);",
-		post.AuthorID, post.Content, post.Visibility, post.Pinned)
-	return err
+// Create stores a post. A post held for review is stored hidden and gets
+// its requested visibility back when a moderator releases it.
+func (r *PostRepository) Create(post *domain.CreatePostRequest) (*domain.Post, error) {
+	visibility := post.Visibility
+	var heldVisibility *domain.PostVisibility
+	if post.HoldForReview {
+		visibility = domain.Hidden
+		heldVisibility = &post.Visibility
+	}
+
+	created := domain.Post{
+		AuthorID:        post.AuthorID,
+		Content:         post.Content,
+		ContentHTML:     post.ContentHTML,
+		Pinned:          post.Pinned,
+		Tags:            post.Tags,
+		Visibility:      &visibility,
+		ContentWarning:  post.ContentWarning,
+		Sensitive:       post.Sensitive,
+		WarningForced:   post.WarningForced,
+		ContinuesPostID: post.ContinuesPostID,
+		HeldForReview:   post.HoldForReview,
+		CommentPolicy:   post.CommentPolicy,
+	}
+	err := r.db.QueryRow(`
+	INSERT INTO posts (author_id, content, content_html, visibility, pinned, content_warning, sensitive, warning_forced, continues_post_id, held_visibility, comment_policy, thread_root_id)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, COALESCE(NULLIF($11, ''), 'everyone'), (SELECT COALESCE(prev.thread_root_id, prev.id) FROM posts prev WHERE prev.id = $9))
+	RETURNING id, thread_root_id, created_at, updated_at;`,
+		post.AuthorID, post.Content, post.ContentHTML, visibility, post.Pinned, post.ContentWarning, post.Sensitive, post.WarningForced, post.ContinuesPostID, heldVisibility, post.CommentPolicy).
+		Scan(&created.ID, &created.ThreadRootID, &created.CreatedAt, &created.UpdatedAt)
+	if pgErr, ok := err.(*pq.Error); ok && pgErr.Code == "23505" && pgErr.Constraint == "idx_posts_continues_post_id" {
+		return nil, domain.ErrPostAlreadyContinued
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &created, nil
 }
 
 func (r *PostRepository) Update(postId int, post *domain.Post) error {
-	_, err := r.db.Exec("UPDATE posts SET content = $1, visibility = $2, pinned = $3, WHERE id = $4",
-		post.Content, post.Visibility, post.Pinned, postId)
+	_, err := r.db.Exec("UPDATE posts SET content = $1, content_html = $2, visibility = $3, pinned = $4, content_warning = $5, sensitive = $6 WHERE id = $7",
+		post.Content, post.ContentHTML, post.Visibility, post.Pinned, post.ContentWarning, post.Sensitive, postId)
 	return err
 }
-func (r *PostRepository) Delete(id int) error {
-	_, err := r.db.Exec("DELETE from posts WHERE id = $1;", id)
+
+// SetContentWarning puts a moderator's content warning on a post. Forced
+// warnings are kept when the author edits the post.
+func (r *PostRepository) SetContentWarning(postID int, warning string, sensitive bool) error {
+	result, err := r.db.Exec("UPDATE posts SET content_warning = $1, sensitive = $2, warning_forced = TRUE WHERE id = $3",
+		warning, sensitive, postID)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// SetVisibility changes who can see a post, e.g. when a moderator hides it.
+// It ends any hold on the post.
+func (r *PostRepository) SetVisibility(postID int, visibility domain.PostVisibility) error {
+	result, err := r.db.Exec("UPDATE posts SET visibility = $1, held_visibility = NULL WHERE id = $2", visibility, postID)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (r *PostRepository) SetCommentPolicy(postID int, policy domain.CommentPolicy) error {
+	result, err := r.db.Exec("UPDATE posts SET comment_policy = $1 WHERE id = $2", policy, postID)
+	if err != nil {
+		return err
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (r *PostRepository) SetPinnedComment(postID int, commentID *int) error {
+	result, err := r.db.Exec("UPDATE posts SET pinned_comment_id = $1 WHERE id = $2", commentID, postID)
+	if err != nil {
+		return err
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// SetThreadVisibility gives the continuations of a thread a new visibility.
+// Held continuations get it back when they are released; hidden ones stay
+// hidden.
+func (r *PostRepository) SetThreadVisibility(rootID int, visibility domain.PostVisibility) error {
+	_, err := r.db.Exec(`
+	UPDATE posts SET
+		visibility = CASE WHEN held_visibility IS NULL THEN $1 ELSE visibility END,
+		held_visibility = CASE WHEN held_visibility IS NULL THEN NULL ELSE $1 END
+	WHERE thread_root_id = $2 AND (visibility <> $3 OR held_visibility IS NOT NULL)`,
+		visibility, rootID, domain.Hidden)
+	return err
+}
+
+// Hold hides a post until a moderator releases it. Posts that are already
+// hidden or held are left alone.
+func (r *PostRepository) Hold(postID int) error {
+	_, err := r.db.Exec("UPDATE posts SET held_visibility = visibility, visibility = $1 WHERE id = $2 AND held_visibility IS NULL AND visibility <> $1",
+		domain.Hidden, postID)
+	return err
+}
+
+// Release gives a held post its visibility back. It returns sql.ErrNoRows
+// when the post is not held.
+func (r *PostRepository) Release(postID int) error {
+	result, err := r.db.Exec("UPDATE posts SET visibility = held_visibility, held_visibility = NULL WHERE id = $1 AND held_visibility IS NOT NULL", postID)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// GetPostsWithoutHTML returns posts whose Markdown has not been rendered yet.
+func (r *PostRepository) GetPostsWithoutHTML(limit int) ([]domain.Post, error) {
+	rows, err := r.db.Query("SELECT id, content FROM posts WHERE content_html IS NULL ORDER BY id LIMIT $1", limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var posts []domain.Post
+	for rows.Next() {
+		var post domain.Post
+		if err := rows.Scan(&post.ID, &post.Content); err != nil {
+			return nil, err
+		}
+		posts = append(posts, post)
+	}
+	return posts, rows.Err()
+}
+
+func (r *PostRepository) SetContentHTML(id int, html string) error {
+	_, err := r.db.Exec("UPDATE posts SET content_html = $1 WHERE id = $2", html, id)
 	return err
 }
 
+// GetThread returns the posts of a thread, first post first.
+func (r *PostRepository) GetThread(rootID int) ([]domain.Post, error) {
+	rows, err := r.db.Query(`
+	SELECT `+postColumns+`
+	FROM posts p
+	LEFT JOIN users u ON u.id = p.author_id
+	WHERE p.id = $1 OR p.thread_root_id = $1
+	ORDER BY p.created_at, p.id`, rootID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var posts []domain.Post
+	for rows.Next() {
+		var post domain.Post
+		if err := rows.Scan(postFields(&post)...); err != nil {
+			return nil, err
+		}
+		posts = append(posts, post)
+	}
+	return posts, rows.Err()
+}
+
+// Delete removes a post in one transaction with what points at it: the next
+// post of its thread continues the previous one instead (and starts the
+// thread when the first post goes away), and its links and mentions are
+// cleared. Bookmarks and analytics go with the post by cascade.
+func (r *PostRepository) Delete(id int) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var rootID int
+	err = tx.QueryRow("SELECT COALESCE(thread_root_id, id) FROM posts WHERE id = $1", id).Scan(&rootID)
+	if err == sql.ErrNoRows {
+		return tx.Commit()
+	}
+	if err != nil {
+		return err
+	}
+
+	rows, err := tx.Query("SELECT id, continues_post_id, thread_root_id FROM posts WHERE id = $1 OR thread_root_id = $1 FOR UPDATE", rootID)
+	if err != nil {
+		return err
+	}
+	var thread []domain.Post
+	for rows.Next() {
+		var post domain.Post
+		if err := rows.Scan(&post.ID, &post.ContinuesPostID, &post.ThreadRootID); err != nil {
+			rows.Close()
+			return err
+		}
+		thread = append(thread, post)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, statement := range unlinkStatements(thread, id) {
+		if _, err := tx.Exec(statement.query, statement.args...); err != nil {
+			return err
+		}
+	}
+
+	if _, err := tx.Exec("DELETE FROM content_links WHERE entity_type = $1 AND entity_id = $2", domain.LinkEntityPost, id); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM mentions WHERE entity_type = $1 AND entity_id = $2", domain.MentionEntityPost, id); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM posts WHERE id = $1", id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// sqlStatement is a query and its arguments, run in order in a transaction.
+type sqlStatement struct {
+	query string
+	args  []interface{}
+}
+
+// unlinkStatements returns the updates that take the post with id out of its
+// thread. The post lets go of the one it continues first: continues_post_id
+// is unique and the check is not deferred, so the next post can only take
+// the link over once it is free.
+func unlinkStatements(thread []domain.Post, id int) []sqlStatement {
+	relinked := domain.UnlinkFromThread(thread, id)
+	if len(relinked) == 0 {
+		return nil
+	}
+
+	statements := []sqlStatement{{query: "UPDATE posts SET continues_post_id = NULL WHERE id = $1", args: []interface{}{id}}}
+	for _, post := range relinked {
+		statements = append(statements, sqlStatement{
+			query: "UPDATE posts SET continues_post_id = $1, thread_root_id = $2 WHERE id = $3",
+			args:  []interface{}{post.ContinuesPostID, post.ThreadRootID, post.ID},
+		})
+	}
+	return statements
+}
+
 func (r *PostRepository) GetByID(id int) (*domain.Post, error) {
 	var post domain.Post
 	err := r.db.QueryRow(`
-	SELECT 
-    p.id AS post_id,
-    p.author_id,
-    u.username AS author_name,
-    p.content,
-    p.visibility,
-    p.pinned,
-    p.created_at,
-    p.updated_at,
-    COALESCE(
-        json_agg(
-            json_build_object(
-                'reaction_type', grouped_reactions.reaction_type,
-                'count', grouped_reactions.reaction_count
-            )
-        ) FILTER (WHERE grouped_reactions.reaction_type IS NOT NULL),
-        '[]'
-    ) AS reactions,
-    COALESCE(SUM(grouped_reactions.reaction_count), 0) AS total_reactions_count,
-    COALESCE(comment_counts.total_comments_and_replies, 0) AS total_comments_and_replies,
-    COALESCE(user_reactions.reaction_type, '') AS user_reaction -- User's specific reaction
-	FROM 
-		posts p
-	LEFT JOIN 
-		users u ON p.author_id = u.id
-	LEFT JOIN (
-    SELECT 
-        r.entity_id AS post_id,
-        rt.name AS reaction_type,
-        COUNT(r.id) AS reaction_count
-    FROM 
-        reactions r
-    LEFT JOIN 
-        reaction_types rt ON r.reaction_type_id = rt.id
-    GROUP BY 
-        r.entity_id, rt.name
-	) grouped_reactions ON p.id = grouped_reactions.post_id
-	LEFT JOIN (
-    SELECT 
-        c.entity_id,
-        COUNT(c.id) AS total_comments_and_replies
-    FROM 
-        comments c
-    WHERE 
-        c.entity_type IN ('comment', 'reply') -- Adjust entity_type values as needed
-    GROUP BY 
-        c.entity_id
-	) comment_counts ON p.id = comment_counts.entity_id
-	LEFT JOIN (
-    SELECT 
-        r.entity_id AS post_id,
-        rt.name AS reaction_type
-    FROM 
-        reactions r
-    LEFT JOIN 
-        reaction_types rt ON r.reaction_type_id = rt.id
-    WHERE 
-        r.user_id = $2 -- User ID to check for their reaction
-	) user_reactions ON p.id = user_reactions.post_id
-	WHERE 
-		p.author_id = $1 -- Author ID
-	GROUP BY 
-		p.id, u.username, comment_counts.total_comments_and_replies, user_reactions.reaction_type
-	ORDER BY 
-		p.id;
-`, id).
-		Scan(&post.ID, &post.AuthorID, post.AuthorName, &post.Content, &post.Pinned, &post.Visibility, &post.CreatedAt, &post.UpdatedAt, &post.Reactions, &post.TotaReactionslCount, &post.TotalCommentsCount, &post.UserReaction)
+	SELECT `+postColumns+`
+	FROM posts p
+	LEFT JOIN users u ON u.id = p.author_id
+	WHERE p.id = $1`, id).
+		Scan(postFields(&post)...)
 	if err != nil {
 		return nil, err
 	}
@@ -108,75 +317,77 @@ func (r *PostRepository) GetByID(id int) (*domain.Post, error) {
 	return &post, nil
 }
 
-func (r *PostRepository) FindByUserID(userID, otherUserId, offset, limit int) ([]domain.Post, error) {
+// FindByUserID lists every post of a user, thread continuations included,
+// for feeds read outside the app.
+func (r *PostRepository) FindByUserID(userID, otherUserId int, visibilities []domain.PostVisibility, page domain.PageRequest) ([]domain.Post, error) {
+	afterTime, afterID := keysetArgs(page.After)
 	rows, err := r.db.Query(`	
 	SELECT 
     p.id AS post_id,
     p.author_id,
     u.username AS author_name,
     p.content,
+    COALESCE(p.content_html, '') AS content_html,
     p.visibility,
     p.pinned,
+    COALESCE(p.content_warning, '') AS content_warning,
+    p.sensitive,
+    p.warning_forced,
+    p.continues_post_id,
+    p.thread_root_id,
+    (SELECT COUNT(*) FROM posts t WHERE t.thread_root_id = p.id) AS thread_continuations,
     p.created_at,
     p.updated_at,
-    COALESCE(
-        json_agg(
-            json_build_object(
-                'reaction_type', grouped_reactions.reaction_type,
-                'count', grouped_reactions.reaction_count
-            )
-        ) FILTER (WHERE grouped_reactions.reaction_type IS NOT NULL),
-        '[]'
-    ) AS reactions,
-    COALESCE(SUM(grouped_reactions.reaction_count), 0) AS total_reactions_count,
-    COALESCE(comment_counts.total_comments_and_replies, 0) AS total_comments_and_replies,
+    COALESCE(grouped_reactions.reactions, '[]') AS reactions,
+    p.reactions_count AS total_reactions_count,
+    p.comments_count + p.replies_count AS total_comments_and_replies,
     COALESCE(user_reactions.reaction_type, '') AS user_reaction -- User's specific reaction
 	FROM 
 		posts p
 	LEFT JOIN 
 		users u ON p.author_id = u.id
-	LEFT JOIN (
-    SELECT 
-        r.entity_id AS post_id,
-        rt.name AS reaction_type,
-        COUNT(r.id) AS reaction_count
-    FROM 
-        reactions r
-    LEFT JOIN 
-        reaction_types rt ON r.reaction_type_id = rt.id
-    GROUP BY 
-        r.entity_id, rt.name
-	) grouped_reactions ON p.id = grouped_reactions.post_id
-	LEFT JOIN (
+	LEFT JOIN LATERAL (
     SELECT 
-        c.entity_id,
-        COUNT(c.id) AS total_comments_and_replies
-    FROM 
-        comments c
-    WHERE 
-        c.entity_type IN ('post', 'comment') -- Adjust entity_type values as needed
-    GROUP BY 
-        c.entity_id
-	) comment_counts ON p.id = comment_counts.entity_id
-	LEFT JOIN (
+        json_agg(
+            json_build_object(
+                'reaction_type', counts.reaction_type,
+                'count', counts.reaction_count
+            )
+        ) AS reactions
+    FROM (
+        SELECT 
+            rt.name AS reaction_type,
+            COUNT(r.id) AS reaction_count
+        FROM 
+            reactions r
+        LEFT JOIN 
+            reaction_types rt ON r.reaction_type_id = rt.id
+        WHERE 
+            r.entity_type = 'post' AND r.entity_id = p.id
+        GROUP BY 
+            rt.name
+    ) counts
+	) grouped_reactions ON TRUE
+	LEFT JOIN LATERAL (
     SELECT 
-        r.entity_id AS post_id,
         rt.name AS reaction_type
     FROM 
         reactions r
     LEFT JOIN 
         reaction_types rt ON r.reaction_type_id = rt.id
     WHERE 
-        r.user_id = $2 -- User ID to check for their reaction
-	) user_reactions ON p.id = user_reactions.post_id
+        r.entity_type = 'post'
+        AND r.entity_id = p.id
+        AND r.user_id = $2 -- User ID to check for their reaction
+	) user_reactions ON TRUE
 	WHERE 
 		p.author_id = $1 -- Author ID
-	GROUP BY 
-		p.id, u.username, comment_counts.total_comments_and_replies, user_reactions.reaction_type
+		AND p.visibility = ANY($7)
+		AND ($3::timestamp IS NULL OR (p.created_at, p.id) < ($3::timestamp, $4))
 	ORDER BY 
-		p.id
-	OFFSET $3
-	LIMIT $4;`, userID, otherUserId, offset, limit)
+		p.created_at DESC, p.id DESC
+	OFFSET $5
+	LIMIT $6;`, userID, otherUserId, afterTime, afterID, page.Offset, page.Limit+1, visibilityArray(visibilities))
 	if err != nil {
 		return nil, err
 	}
@@ -185,7 +396,7 @@ func (r *PostRepository) FindByUserID(userID, otherUserId, offset, limit int) ([
 	var posts []domain.Post
 	for rows.Next() {
 		var post domain.Post
-		if err := rows.Scan(&post.ID, &post.AuthorID, &post.AuthorName, &post.Content, &post.Visibility, &post.Pinned, &post.CreatedAt, &post.UpdatedAt, &post.Reactions, &post.TotaReactionslCount, &post.TotalCommentsCount, &post.UserReaction); err != nil {
+		if err := rows.Scan(&post.ID, &post.AuthorID, &post.AuthorName, &post.Content, &post.ContentHTML, &post.Visibility, &post.Pinned, &post.ContentWarning, &post.Sensitive, &post.WarningForced, &post.ContinuesPostID, &post.ThreadRootID, &post.ThreadContinuations, &post.CreatedAt, &post.UpdatedAt, &post.Reactions, &post.TotaReactionslCount, &post.TotalCommentsCount, &post.UserReaction); err != nil {
 			return nil, err
 		}
 		posts = append(posts, post)
@@ -215,13 +426,18 @@ func (r *PostRepository) GetCountPostsByUser(authorID int) (int, error) {
 	return postsCount, nil
 }
 
-func (r *PostRepository) GetPosts(authorID int, offset int, limit int) ([]domain.Post, error) {
+func (r *PostRepository) GetPosts(authorID int, visibilities []domain.PostVisibility, page domain.PageRequest) ([]domain.Post, error) {
+	afterTime, afterID := keysetArgs(page.After)
 	rows, err := r.db.Query(`
-        SELECT id, author_id, content, visibility, pinned, created_at, updated_at
+        SELECT id, author_id, content, COALESCE(content_html, ''), visibility, pinned, COALESCE(content_warning, ''), sensitive, warning_forced,
+          continues_post_id, thread_root_id, (SELECT COUNT(*) FROM posts t WHERE t.thread_root_id = posts.id), created_at, updated_at
         FROM posts
         WHERE author_id = $1
-        ORDER BY id
-        OFFSET $2 LIMIT $3`, authorID, offset, limit)
+          AND continues_post_id IS NULL
+          AND visibility = ANY($6)
+          AND ($2::timestamp IS NULL OR (created_at, id) < ($2::timestamp, $3))
+        ORDER BY created_at DESC, id DESC
+        OFFSET $4 LIMIT $5`, authorID, afterTime, afterID, page.Offset, page.Limit+1, visibilityArray(visibilities))
 	if err != nil {
 		return nil, err
 	}
@@ -230,10 +446,94 @@ func (r *PostRepository) GetPosts(authorID int, offset int, limit int) ([]domain
 	var posts []domain.Post
 	for rows.Next() {
 		var post domain.Post
-		if err := rows.Scan(&post.ID, &post.AuthorID, &post.Content, &post.Visibility, &post.Pinned, &post.CreatedAt, &post.UpdatedAt); err != nil {
+		if err := rows.Scan(&post.ID, &post.AuthorID, &post.Content, &post.ContentHTML, &post.Visibility, &post.Pinned, &post.ContentWarning, &post.Sensitive, &post.WarningForced, &post.ContinuesPostID, &post.ThreadRootID, &post.ThreadContinuations, &post.CreatedAt, &post.UpdatedAt); err != nil {
 			return nil, err
 		}
 		posts = append(posts, post)
 	}
 	return posts, nil
 }
+
+// visibilityArray converts the visibilities allowed by the application's
+// visibility policy into a Postgres int array.
+func visibilityArray(visibilities []domain.PostVisibility) interface{} {
+	values := make([]int64, 0, len(visibilities))
+	for _, v := range visibilities {
+		values = append(values, int64(v))
+	}
+	return pq.Array(values)
+}
+
+// feedVisibilityFilter limits posts to the ones that belong in viewer $2's
+// home timeline: their own posts that are not hidden, and public or
+// followers-only posts of users they currently follow.
+const feedVisibilityFilter = `(
+		(p.author_id = $2 AND p.visibility <> $3)
+		OR (p.visibility IN ($4, $5) AND EXISTS (
+			SELECT 1 FROM followers f WHERE f.follower_id = $2 AND f.followee_id = p.author_id
+		))
+	)`
+
+// GetFeedPostsByIDs loads the timeline posts with the given IDs that are
+// still visible to the viewer.
+func (r *PostRepository) GetFeedPostsByIDs(viewerID int, postIDs []int) ([]domain.Post, error) {
+	if len(postIDs) == 0 {
+		return nil, nil
+	}
+
+	rows, err := r.db.Query(`
+	SELECT `+postColumns+`
+	FROM posts p
+	LEFT JOIN users u ON u.id = p.author_id
+	WHERE p.id = ANY($1) AND `+feedVisibilityFilter,
+		pq.Array(postIDs), viewerID, domain.Hidden, domain.Public, domain.Followers)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanFeedPosts(rows)
+}
+
+// GetFeedPostsByAuthors reads the newest posts of the given authors directly
+// from Postgres (fan-out-on-read), starting after the cursor.
+func (r *PostRepository) GetFeedPostsByAuthors(viewerID int, authorIDs []int, before *domain.Cursor, limit int) ([]domain.Post, error) {
+	if len(authorIDs) == 0 {
+		return nil, nil
+	}
+
+	var beforeTime interface{}
+	beforeID := 0
+	if before != nil {
+		beforeTime = before.CreatedAt
+		beforeID = before.ID
+	}
+
+	rows, err := r.db.Query(`
+	SELECT `+postColumns+`
+	FROM posts p
+	LEFT JOIN users u ON u.id = p.author_id
+	WHERE p.author_id = ANY($1) AND `+feedVisibilityFilter+`
+		AND ($6::timestamp IS NULL OR (p.created_at, p.id) < ($6::timestamp, $7))
+	ORDER BY p.created_at DESC, p.id DESC
+	LIMIT $8`,
+		pq.Array(authorIDs), viewerID, domain.Hidden, domain.Public, domain.Followers, beforeTime, beforeID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanFeedPosts(rows)
+}
+
+func scanFeedPosts(rows *sql.Rows) ([]domain.Post, error) {
+	var posts []domain.Post
+	for rows.Next() {
+		var post domain.Post
+		if err := rows.Scan(postFields(&post)...); err != nil {
+			return nil, err
+		}
+		posts = append(posts, post)
+	}
+	return posts, rows.Err()
+}
@@ -0,0 +1,55 @@
+package infrastructure
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/bandvov/social-media-go/domain"
+)
+
+func TestUnlinkStatements(t *testing.T) {
+	intPtr := func(i int) *int { return &i }
+	// Thread 1 <- 2 <- 3.
+	thread := []domain.Post{
+		{ID: 1},
+		{ID: 2, ContinuesPostID: intPtr(1), ThreadRootID: intPtr(1)},
+		{ID: 3, ContinuesPostID: intPtr(2), ThreadRootID: intPtr(1)},
+	}
+	detach := func(id int) sqlStatement {
+		return sqlStatement{query: "UPDATE posts SET continues_post_id = NULL WHERE id = $1", args: []interface{}{id}}
+	}
+	relink := func(id int, continuesPostID, threadRootID *int) sqlStatement {
+		return sqlStatement{
+			query: "UPDATE posts SET continues_post_id = $1, thread_root_id = $2 WHERE id = $3",
+			args:  []interface{}{continuesPostID, threadRootID, id},
+		}
+	}
+
+	tests := []struct {
+		name     string
+		removed  int
+		expected []sqlStatement
+	}{
+		{
+			// Post 3 can only continue post 1 once post 2 no longer does.
+			name:     "middle post",
+			removed:  2,
+			expected: []sqlStatement{detach(2), relink(3, intPtr(1), intPtr(1))},
+		},
+		{
+			name:     "first post",
+			removed:  1,
+			expected: []sqlStatement{detach(1), relink(2, nil, nil), relink(3, intPtr(2), intPtr(2))},
+		},
+		{name: "last post", removed: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := unlinkStatements(thread, tt.removed)
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("expected %+v, got %+v", tt.expected, got)
+			}
+		})
+	}
+}
@@ -2,10 +2,9 @@ package infrastructure
 
 import (
 	"database/sql"
-	"fmt"
 
 	"github.com/bandvov/social-media-go/domain"
-	"github.com/bandvov/social-media-go/utils"
+	"github.com/lib/pq"
 )
 
 type ReactionRepository struct {
@@ -16,37 +15,44 @@ func NewReactionRepository(db *sql.DB) *ReactionRepository {
 	return &ReactionRepository{db: db}
 }
 
-func (r *ReactionRepository) AddOrUpdateReaction(userID int, reaction domain.Reaction) error {
+func (r *ReactionRepository) AddOrUpdateReaction(userID int, reaction domain.Reaction) (bool, error) {
+	// xmax is only set on rows the upsert updated.
 	query := `
-        INSERT INTO reactions (user_id, entity_id, reaction_type_id)
-        VALUES ($1, $2, $3)
-        ON CONFLICT (user_id, entity_id)
-        DO UPDATE SET reaction_type_id = $3
+        INSERT INTO reactions (user_id, entity_type, entity_id, reaction_type_id)
+        VALUES ($1, $2, $3, $4)
+        ON CONFLICT (user_id, entity_type, entity_id)
+        DO UPDATE SET reaction_type_id = $4
+        RETURNING xmax = 0
     `
-	_, err := r.db.Exec(query, userID, reaction.EntityId, reaction.Reaction)
-	return err
+	var created bool
+	err := r.db.QueryRow(query, userID, entityTypeOrPost(reaction.EntityType), reaction.EntityId, reaction.Reaction).Scan(&created)
+	return created, err
 }
 
-func (r *ReactionRepository) RemoveReaction(userID, entityID string) error {
-	query := `DELETE FROM reactions WHERE user_id = $1 AND entity_id = $2`
-	_, err := r.db.Exec(query, userID, entityID)
-	return err
+func (r *ReactionRepository) RemoveReaction(userID, entityType, entityID string) (bool, error) {
+	query := `DELETE FROM reactions WHERE user_id = $1 AND entity_type = $2 AND entity_id = $3`
+	result, err := r.db.Exec(query, userID, entityTypeOrPost(entityType), entityID)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected > 0, err
 }
 
-// Fetch reactions by post IDs
-func (r *ReactionRepository) GetReactionsByEntityIDs(postIDs []int) ([]domain.Reaction, error) {
-	if len(postIDs) == 0 {
+// Fetch reactions by post or comment IDs
+func (r *ReactionRepository) GetReactionsByEntityIDs(entityType string, entityIDs []int) ([]domain.Reaction, error) {
+	if len(entityIDs) == 0 {
 		return nil, nil
 	}
 
-	query := fmt.Sprintf(`
+	query := `
         SELECT r.entity_id, rt.name AS reaction, COUNT(r.id) AS count
         FROM reactions r
         JOIN reaction_types rt ON r.reaction_type_id = rt.id
-        WHERE r.entity_id IN (%s)
-        GROUP BY r.entity_id, rt.name`, utils.Placeholders(len(postIDs)))
+        WHERE r.entity_type = $1 AND r.entity_id = ANY($2)
+        GROUP BY r.entity_id, rt.name`
 
-	rows, err := r.db.Query(query, utils.ToInterface(postIDs)...)
+	rows, err := r.db.Query(query, entityTypeOrPost(entityType), pq.Array(entityIDs))
 	if err != nil {
 		return nil, err
 	}
@@ -58,23 +64,27 @@ func (r *ReactionRepository) GetReactionsByEntityIDs(postIDs []int) ([]domain.Re
 		if err := rows.Scan(&reaction.EntityId, &reaction.Reaction, &reaction.Count); err != nil {
 			return nil, err
 		}
+		reaction.EntityType = entityTypeOrPost(entityType)
 		reactions = append(reactions, reaction)
 	}
 
 	return reactions, nil
 }
 
-func (r *ReactionRepository) CountByEntityIDs(entityIDs []int) ([]domain.Reaction, error) {
+func (r *ReactionRepository) CountByEntityIDs(entityType string, entityIDs []int) ([]domain.Reaction, error) {
+	if len(entityIDs) == 0 {
+		return nil, nil
+	}
 
-	query := fmt.Sprintf(`
+	query := `
         SELECT
 			entity_id,
             COUNT(*) AS count
         FROM reactions
-        WHERE entity_id IN (%s)
-		GROUP BY entity_id`, utils.Placeholders(len(entityIDs)))
+        WHERE entity_type = $1 AND entity_id = ANY($2)
+		GROUP BY entity_id`
 
-	rows, err := r.db.Query(query, utils.ToInterface(entityIDs)...)
+	rows, err := r.db.Query(query, entityTypeOrPost(entityType), pq.Array(entityIDs))
 	if err != nil {
 		return nil, err
 	}
@@ -86,8 +96,52 @@ func (r *ReactionRepository) CountByEntityIDs(entityIDs []int) ([]domain.Reactio
 		if err := rows.Scan(&count.EntityId, &count.Count); err != nil {
 			return nil, err
 		}
+		count.EntityType = entityTypeOrPost(entityType)
 		counts = append(counts, count)
 	}
 
 	return counts, nil
 }
+
+func (r *ReactionRepository) GetReactors(entityType string, entityID, viewerID int, reactionType string, page domain.PageRequest) ([]domain.Reactor, error) {
+	afterTime, afterID := keysetArgs(page.After)
+	rows, err := r.db.Query(`
+	SELECT r.id, r.user_id, rt.name, r.created_at
+	FROM reactions r
+	JOIN reaction_types rt ON rt.id = r.reaction_type_id
+	JOIN users u ON u.id = r.user_id
+	WHERE r.entity_type = $1 AND r.entity_id = $2
+		AND ($4 = '' OR LOWER(rt.name) = LOWER($4))
+		AND NOT EXISTS (
+			SELECT 1 FROM blocks b
+			WHERE (b.blocker_id = $3 AND b.blocked_id = r.user_id) OR (b.blocker_id = r.user_id AND b.blocked_id = $3)
+		)
+		AND (NOT u.is_private OR u.id = $3 OR EXISTS (
+			SELECT 1 FROM followers f WHERE f.follower_id = $3 AND f.followee_id = u.id
+		))
+		AND ($5::timestamp IS NULL OR (r.created_at, r.id) < ($5::timestamp, $6))
+	ORDER BY r.created_at DESC, r.id DESC
+	OFFSET $7 LIMIT $8`, entityTypeOrPost(entityType), entityID, viewerID, reactionType, afterTime, afterID, page.Offset, page.Limit+1)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var reactors []domain.Reactor
+	for rows.Next() {
+		var reactor domain.Reactor
+		if err := rows.Scan(&reactor.ReactionID, &reactor.UserID, &reactor.Reaction, &reactor.ReactedAt); err != nil {
+			return nil, err
+		}
+		reactors = append(reactors, reactor)
+	}
+	return reactors, rows.Err()
+}
+
+// entityTypeOrPost keeps reactions sent without an entity type on posts.
+func entityTypeOrPost(entityType string) string {
+	if entityType == "" {
+		return domain.ReactionEntityPost
+	}
+	return entityType
+}
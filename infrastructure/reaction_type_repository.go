@@ -0,0 +1,143 @@
+package infrastructure
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/bandvov/social-media-go/domain"
+	"github.com/lib/pq"
+)
+
+type ReactionTypeRepository struct {
+	db *sql.DB
+}
+
+func NewReactionTypeRepository(db *sql.DB) *ReactionTypeRepository {
+	return &ReactionTypeRepository{db: db}
+}
+
+const reactionTypeColumns = `id, name, COALESCE(emoji, ''), COALESCE(image_url, ''), verb, polarity, position, enabled,
+	COALESCE(created_at, CURRENT_TIMESTAMP), COALESCE(updated_at, CURRENT_TIMESTAMP)`
+
+func scanReactionType(row rowScanner) (*domain.ReactionType, error) {
+	var reactionType domain.ReactionType
+	err := row.Scan(&reactionType.ID, &reactionType.Name, &reactionType.Emoji, &reactionType.ImageURL, &reactionType.Verb,
+		&reactionType.Polarity, &reactionType.Position, &reactionType.Enabled, &reactionType.CreatedAt, &reactionType.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &reactionType, nil
+}
+
+// reactionTypeError turns a violation of the unique name index into
+// ErrReactionTypeExists.
+func reactionTypeError(err error) error {
+	if pgErr, ok := err.(*pq.Error); ok && pgErr.Code == "23505" {
+		return domain.ErrReactionTypeExists
+	}
+	return err
+}
+
+func (r *ReactionTypeRepository) List(all bool) ([]domain.ReactionType, error) {
+	rows, err := r.db.Query("SELECT "+reactionTypeColumns+" FROM reaction_types WHERE $1 OR enabled ORDER BY position, id", all)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list reaction types: %v", err)
+	}
+	defer rows.Close()
+
+	var reactionTypes []domain.ReactionType
+	for rows.Next() {
+		reactionType, err := scanReactionType(rows)
+		if err != nil {
+			return nil, err
+		}
+		reactionTypes = append(reactionTypes, *reactionType)
+	}
+	return reactionTypes, rows.Err()
+}
+
+func (r *ReactionTypeRepository) GetByID(id int) (*domain.ReactionType, error) {
+	return scanReactionType(r.db.QueryRow("SELECT "+reactionTypeColumns+" FROM reaction_types WHERE id = $1", id))
+}
+
+func (r *ReactionTypeRepository) Create(reactionType *domain.ReactionType) error {
+	created, err := scanReactionType(r.db.QueryRow(`
+		INSERT INTO reaction_types (name, emoji, image_url, verb, polarity, position, enabled)
+		VALUES ($1, NULLIF($2, ''), NULLIF($3, ''), $4, $5, $6, $7)
+		RETURNING `+reactionTypeColumns,
+		reactionType.Name, reactionType.Emoji, reactionType.ImageURL, reactionType.Verb, reactionType.Polarity, reactionType.Position, reactionType.Enabled))
+	if err != nil {
+		return reactionTypeError(err)
+	}
+	*reactionType = *created
+	return nil
+}
+
+// Update replaces the definition of a reaction type. When the polarity
+// changes, the scores of the comments reacted to with the type are
+// recounted.
+func (r *ReactionTypeRepository) Update(reactionType *domain.ReactionType) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var polarity int
+	if err := tx.QueryRow("SELECT polarity FROM reaction_types WHERE id = $1 FOR UPDATE", reactionType.ID).Scan(&polarity); err != nil {
+		return err
+	}
+
+	updated, err := scanReactionType(tx.QueryRow(`
+		UPDATE reaction_types
+		SET name = $2, emoji = NULLIF($3, ''), image_url = NULLIF($4, ''), verb = $5, polarity = $6, position = $7, enabled = $8, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1
+		RETURNING `+reactionTypeColumns,
+		reactionType.ID, reactionType.Name, reactionType.Emoji, reactionType.ImageURL, reactionType.Verb, reactionType.Polarity, reactionType.Position, reactionType.Enabled))
+	if err != nil {
+		return reactionTypeError(err)
+	}
+
+	if updated.Polarity != polarity {
+		_, err = tx.Exec(`
+		UPDATE comments c
+		SET positive_reactions = s.positive, negative_reactions = s.negative
+		FROM (
+			SELECT r.entity_id,
+				COUNT(*) FILTER (WHERE rt.polarity > 0) AS positive,
+				COUNT(*) FILTER (WHERE rt.polarity < 0) AS negative
+			FROM reactions r
+			JOIN reaction_types rt ON rt.id = r.reaction_type_id
+			WHERE r.entity_type = 'comment' AND r.entity_id IN (
+				SELECT entity_id FROM reactions WHERE entity_type = 'comment' AND reaction_type_id = $1
+			)
+			GROUP BY r.entity_id
+		) s
+		WHERE c.id = s.entity_id`, reactionType.ID)
+		if err != nil {
+			return fmt.Errorf("failed to rescore comments: %v", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	*reactionType = *updated
+	return nil
+}
+
+func (r *ReactionTypeRepository) Delete(id int) error {
+	result, err := r.db.Exec(`
+		DELETE FROM reaction_types
+		WHERE id = $1 AND NOT EXISTS (SELECT 1 FROM reactions WHERE reaction_type_id = $1)`, id)
+	if err != nil {
+		return err
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		if _, err := r.GetByID(id); err != nil {
+			return err
+		}
+		return domain.ErrReactionTypeInUse
+	}
+	return nil
+}
@@ -0,0 +1,155 @@
+package infrastructure
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/bandvov/social-media-go/domain"
+	"github.com/lib/pq"
+)
+
+type ReportRepository struct {
+	db *sql.DB
+}
+
+func NewReportRepository(db *sql.DB) *ReportRepository {
+	return &ReportRepository{db: db}
+}
+
+const reportColumns = `id, COALESCE(reporter_id, 0), target_type, target_id, target_owner_id, reason, COALESCE(details, ''), status,
+	assignee_id, COALESCE(resolution, ''), COALESCE(resolution_note, ''), resolved_by, created_at, resolved_at`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanReport(row rowScanner) (*domain.Report, error) {
+	var report domain.Report
+	var assigneeID, resolvedBy sql.NullInt64
+	var resolvedAt sql.NullTime
+	err := row.Scan(&report.ID, &report.ReporterID, &report.TargetType, &report.TargetID, &report.TargetOwnerID, &report.Reason, &report.Details, &report.Status,
+		&assigneeID, &report.Resolution, &report.ResolutionNote, &resolvedBy, &report.CreatedAt, &resolvedAt)
+	if err != nil {
+		return nil, err
+	}
+	if assigneeID.Valid {
+		id := int(assigneeID.Int64)
+		report.AssigneeID = &id
+	}
+	if resolvedBy.Valid {
+		id := int(resolvedBy.Int64)
+		report.ResolvedBy = &id
+	}
+	if resolvedAt.Valid {
+		report.ResolvedAt = &resolvedAt.Time
+	}
+	return &report, nil
+}
+
+func (r *ReportRepository) Create(report *domain.Report) error {
+	err := r.db.QueryRow(`
+		INSERT INTO reports (reporter_id, target_type, target_id, target_owner_id, reason, details)
+		VALUES (NULLIF($1, 0), $2, $3, $4, $5, NULLIF($6, ''))
+		RETURNING id, status, created_at`,
+		report.ReporterID, report.TargetType, report.TargetID, report.TargetOwnerID, report.Reason, report.Details).
+		Scan(&report.ID, &report.Status, &report.CreatedAt)
+	if pgErr, ok := err.(*pq.Error); ok && pgErr.Code == "23505" {
+		return domain.ErrDuplicateReport
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create report: %v", err)
+	}
+	return nil
+}
+
+func (r *ReportRepository) GetByID(id int) (*domain.Report, error) {
+	return scanReport(r.db.QueryRow("SELECT "+reportColumns+" FROM reports WHERE id = $1", id))
+}
+
+func (r *ReportRepository) ListQueue(filter domain.ReportQueueFilter, page domain.PageRequest) ([]domain.Report, error) {
+	afterTime, afterID := keysetArgs(page.After)
+	rows, err := r.db.Query(`
+	SELECT `+reportColumns+`
+	FROM reports
+	WHERE ($1 = '' OR status = $1)
+		AND ($2 = '' OR target_type = $2)
+		AND ($3 = 0 OR ($3 = -1 AND assignee_id IS NULL) OR assignee_id = $3)
+		AND ($4::timestamp IS NULL OR (created_at, id) > ($4::timestamp, $5))
+	ORDER BY created_at, id
+	OFFSET $6 LIMIT $7`,
+		string(filter.Status), string(filter.TargetType), filter.AssigneeID, afterTime, afterID, page.Offset, page.Limit+1)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list reports: %v", err)
+	}
+	defer rows.Close()
+
+	var reports []domain.Report
+	for rows.Next() {
+		report, err := scanReport(rows)
+		if err != nil {
+			return nil, err
+		}
+		reports = append(reports, *report)
+	}
+	return reports, rows.Err()
+}
+
+// Assign sets or, with a nil assignee, clears the moderator working on an
+// open report.
+func (r *ReportRepository) Assign(id int, assigneeID *int) error {
+	result, err := r.db.Exec("UPDATE reports SET assignee_id = $2 WHERE id = $1 AND status = 'open'", id, assigneeID)
+	if err != nil {
+		return fmt.Errorf("failed to assign report: %v", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		if _, err := r.GetByID(id); err != nil {
+			return err
+		}
+		return domain.ErrReportAlreadyResolved
+	}
+	return nil
+}
+
+func (r *ReportRepository) Resolve(id, moderatorID int, resolution domain.ReportResolution, note string) ([]domain.Report, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var targetType string
+	var targetID int
+	var status domain.ReportStatus
+	err = tx.QueryRow("SELECT target_type, target_id, status FROM reports WHERE id = $1 FOR UPDATE", id).
+		Scan(&targetType, &targetID, &status)
+	if err != nil {
+		return nil, err
+	}
+	if status != domain.ReportStatusOpen {
+		return nil, domain.ErrReportAlreadyResolved
+	}
+
+	rows, err := tx.Query(`
+		UPDATE reports
+		SET status = 'resolved', resolution = $3, resolution_note = NULLIF($4, ''), resolved_by = $5, resolved_at = CURRENT_TIMESTAMP
+		WHERE target_type = $1 AND target_id = $2 AND status = 'open'
+		RETURNING `+reportColumns, targetType, targetID, resolution, note, moderatorID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve reports: %v", err)
+	}
+
+	var reports []domain.Report
+	for rows.Next() {
+		report, err := scanReport(rows)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		reports = append(reports, *report)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return reports, tx.Commit()
+}
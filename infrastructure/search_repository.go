@@ -0,0 +1,191 @@
+package infrastructure
+
+import (
+	"database/sql"
+	"fmt"
+	"html"
+	"strings"
+
+	"github.com/bandvov/social-media-go/domain"
+)
+
+// Headlines are cut from raw user text, so matching words are wrapped in
+// control characters instead of tags. The text is escaped afterwards and
+// the markers swapped for <mark> tags. Markers already in the text are
+// stripped before highlighting, so they cannot be forged.
+const (
+	highlightStart = "\x02"
+	highlightStop  = "\x03"
+
+	headlineOptions = "StartSel=" + highlightStart + ", StopSel=" + highlightStop + ", MaxFragments=2, MaxWords=30, MinWords=10"
+)
+
+var highlightTags = strings.NewReplacer(highlightStart, "<mark>", highlightStop, "</mark>")
+
+// highlightHTML turns a headline into HTML that is safe to render, with
+// matching words in <mark> tags.
+func highlightHTML(headline string) string {
+	return highlightTags.Replace(html.EscapeString(headline))
+}
+
+// notBlockedFilter drops rows whose user (%s) blocked viewer $1 or was
+// blocked by them.
+const notBlockedFilter = `NOT EXISTS (
+			SELECT 1 FROM blocks bl
+			WHERE (bl.blocker_id = $1 AND bl.blocked_id = %[1]s)
+				OR (bl.blocker_id = %[1]s AND bl.blocked_id = $1)
+		)`
+
+type SearchRepository struct {
+	db *sql.DB
+}
+
+func NewSearchRepository(db *sql.DB) *SearchRepository {
+	return &SearchRepository{db: db}
+}
+
+// SearchPosts ranks the posts visible to the viewer against the query. Only
+// listed posts are searched: the viewer's own, public ones and followers-only
+// posts of users they follow.
+func (r *SearchRepository) SearchPosts(viewerID int, query domain.SearchQuery) ([]domain.PostSearchResult, error) {
+	rows, err := r.db.Query(`
+	SELECT
+		p.id,
+		p.author_id,
+		COALESCE(u.username, ''),
+		p.content,
+		COALESCE(p.content_html, ''),
+		p.visibility,
+		p.pinned,
+		COALESCE(p.content_warning, ''),
+		p.sensitive,
+		p.warning_forced,
+		p.created_at,
+		p.updated_at,
+		ts_rank(p.search_vector, q.query) AS rank,
+		ts_headline('english', translate(p.content, E'\x02\x03', ''), q.query, $4)
+	FROM posts p
+	CROSS JOIN websearch_to_tsquery('english', $2) AS q(query)
+	LEFT JOIN users u ON u.id = p.author_id
+	WHERE p.search_vector @@ q.query
+		AND (
+			(p.author_id = $1 AND p.visibility <> $5)
+			OR p.visibility = $6
+			OR (p.visibility = $7 AND EXISTS (
+				SELECT 1 FROM followers f WHERE f.follower_id = $1 AND f.followee_id = p.author_id
+			))
+		)
+		AND `+fmt.Sprintf(notBlockedFilter, "p.author_id")+`
+	ORDER BY rank DESC, p.created_at DESC, p.id DESC
+	OFFSET $3 LIMIT $8`,
+		viewerID, query.Query, query.Offset, headlineOptions,
+		domain.Hidden, domain.Public, domain.Followers, query.Limit+1)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search posts: %v", err)
+	}
+	defer rows.Close()
+
+	var results []domain.PostSearchResult
+	for rows.Next() {
+		var result domain.PostSearchResult
+		if err := rows.Scan(
+			&result.ID,
+			&result.AuthorID,
+			&result.AuthorName,
+			&result.Content,
+			&result.ContentHTML,
+			&result.Visibility,
+			&result.Pinned,
+			&result.ContentWarning,
+			&result.Sensitive,
+			&result.WarningForced,
+			&result.CreatedAt,
+			&result.UpdatedAt,
+			&result.Rank,
+			&result.Highlight,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan post: %v", err)
+		}
+		result.Highlight = highlightHTML(result.Highlight)
+		results = append(results, result)
+	}
+	return results, rows.Err()
+}
+
+// SearchUsers matches users by full text over handle, name and bio, and
+// fuzzily by handle so that typos still find the account. Handles and names
+// are indexed as they are written, bios with English stemming, so the bio
+// (weight C) is matched with an English query of its own.
+func (r *SearchRepository) SearchUsers(viewerID int, query domain.SearchQuery) ([]domain.UserSearchResult, error) {
+	rows, err := r.db.Query(`
+	SELECT
+		u.id,
+		u.username,
+		u.first_name,
+		u.last_name,
+		u.profile_pic,
+		GREATEST(
+			ts_rank(u.search_vector, q.query),
+			ts_rank(ts_filter(u.search_vector, '{c}'), b.query),
+			similarity(COALESCE(u.username, ''), $2)
+		) AS rank,
+		COALESCE(ts_headline('english', translate(u.bio, E'\x02\x03', ''), b.query, $4), '')
+	FROM users u
+	CROSS JOIN websearch_to_tsquery('simple', $2) AS q(query)
+	CROSS JOIN websearch_to_tsquery('english', $2) AS b(query)
+	WHERE (u.search_vector @@ q.query
+		OR (u.search_vector @@ b.query AND ts_filter(u.search_vector, '{c}') @@ b.query)
+		OR u.username % $2)
+		AND u.status = 'active'
+		AND `+fmt.Sprintf(notBlockedFilter, "u.id")+`
+	ORDER BY rank DESC, u.id
+	OFFSET $3 LIMIT $5`,
+		viewerID, query.Query, query.Offset, headlineOptions, query.Limit+1)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search users: %v", err)
+	}
+	defer rows.Close()
+
+	var results []domain.UserSearchResult
+	for rows.Next() {
+		var result domain.UserSearchResult
+		if err := rows.Scan(
+			&result.ID,
+			&result.Username,
+			&result.FirstName,
+			&result.LastName,
+			&result.ProfilePic,
+			&result.Rank,
+			&result.Highlight,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan user: %v", err)
+		}
+		result.Highlight = highlightHTML(result.Highlight)
+		results = append(results, result)
+	}
+	return results, rows.Err()
+}
+
+// SearchTags finds tags by prefix or trigram similarity of their name.
+func (r *SearchRepository) SearchTags(query domain.SearchQuery) ([]domain.TagSearchResult, error) {
+	rows, err := r.db.Query(`
+	SELECT id, name, similarity(name, $1) AS rank
+	FROM tags
+	WHERE name % $1 OR starts_with(lower(name), lower($1))
+	ORDER BY starts_with(lower(name), lower($1)) DESC, rank DESC, name
+	OFFSET $2 LIMIT $3`, query.Query, query.Offset, query.Limit+1)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search tags: %v", err)
+	}
+	defer rows.Close()
+
+	var results []domain.TagSearchResult
+	for rows.Next() {
+		var result domain.TagSearchResult
+		if err := rows.Scan(&result.ID, &result.Name, &result.Rank); err != nil {
+			return nil, fmt.Errorf("failed to scan tag: %v", err)
+		}
+		results = append(results, result)
+	}
+	return results, rows.Err()
+}
@@ -0,0 +1,28 @@
+package infrastructure
+
+import "testing"
+
+func TestHighlightHTML(t *testing.T) {
+	tests := []struct {
+		name     string
+		headline string
+		expected string
+	}{
+		{"marks matches", "a \x02quick\x03 fox", "a <mark>quick</mark> fox"},
+		{
+			"escapes markup",
+			"<script>alert('\x02hi\x03')</script>",
+			"&lt;script&gt;alert(&#39;<mark>hi</mark>&#39;)&lt;/script&gt;",
+		},
+		{"escapes literal mark tags", "<mark>fake</mark>", "&lt;mark&gt;fake&lt;/mark&gt;"},
+		{"event handlers", `<img src=x onerror="alert(1)">`, "&lt;img src=x onerror=&#34;alert(1)&#34;&gt;"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := highlightHTML(tt.headline); got != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
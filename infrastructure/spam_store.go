@@ -0,0 +1,51 @@
+package infrastructure
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/bandvov/social-media-go/domain"
+	"github.com/go-redis/redis/v8"
+)
+
+// RedisSpamStore counts what accounts do in fixed windows. Counters expire
+// on their own, so nothing has to clean them up.
+type RedisSpamStore struct {
+	client *redis.Client
+	now    func() time.Time
+}
+
+func NewRedisSpamStore(client *redis.Client) *RedisSpamStore {
+	return &RedisSpamStore{client: client, now: time.Now}
+}
+
+func (s *RedisSpamStore) RecordActivity(userID int, activity domain.SpamActivity, window time.Duration) (int, error) {
+	bucket := s.now().UnixNano() / int64(window)
+	key := fmt.Sprintf("spam:activity:%s:%d:%d", activity, userID, bucket)
+
+	ctx := context.Background()
+	pipe := s.client.TxPipeline()
+	count := pipe.Incr(ctx, key)
+	pipe.Expire(ctx, key, window)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, fmt.Errorf("failed to record activity: %v", err)
+	}
+	return int(count.Val()), nil
+}
+
+func (s *RedisSpamStore) RecordContent(userID int, hash string, window time.Duration) (int, int, error) {
+	userKey := fmt.Sprintf("spam:content:%s:%d", hash, userID)
+	globalKey := fmt.Sprintf("spam:content:%s", hash)
+
+	ctx := context.Background()
+	pipe := s.client.TxPipeline()
+	byUser := pipe.Incr(ctx, userKey)
+	pipe.Expire(ctx, userKey, window)
+	byEveryone := pipe.Incr(ctx, globalKey)
+	pipe.Expire(ctx, globalKey, window)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, 0, fmt.Errorf("failed to record content: %v", err)
+	}
+	return int(byUser.Val()), int(byEveryone.Val()), nil
+}
@@ -0,0 +1,144 @@
+package infrastructure
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/bandvov/social-media-go/domain"
+	"github.com/lib/pq"
+)
+
+type StoryRepository struct {
+	db *sql.DB
+}
+
+func NewStoryRepository(db *sql.DB) *StoryRepository {
+	return &StoryRepository{db: db}
+}
+
+func (r *StoryRepository) Create(authorID int, story *domain.CreateStoryRequest, expiresAt time.Time) (*domain.Story, error) {
+	created := domain.Story{
+		AuthorID:  authorID,
+		Content:   story.Content,
+		MediaURL:  story.MediaURL,
+		ExpiresAt: expiresAt,
+	}
+	err := r.db.QueryRow(`
+		INSERT INTO stories (author_id, content, media_url, expires_at)
+		VALUES ($1, NULLIF($2, ''), NULLIF($3, ''), $4)
+		RETURNING id, created_at`, authorID, story.Content, story.MediaURL, expiresAt).
+		Scan(&created.ID, &created.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create story: %v", err)
+	}
+	return &created, nil
+}
+
+func (r *StoryRepository) GetByID(id int) (*domain.Story, error) {
+	var story domain.Story
+	err := r.db.QueryRow(`
+		SELECT id, author_id, COALESCE(content, ''), COALESCE(media_url, ''), created_at, expires_at
+		FROM stories
+		WHERE id = $1`, id).
+		Scan(&story.ID, &story.AuthorID, &story.Content, &story.MediaURL, &story.CreatedAt, &story.ExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+	return &story, nil
+}
+
+func (r *StoryRepository) Delete(id int) error {
+	_, err := r.db.Exec("DELETE FROM stories WHERE id = $1", id)
+	if err != nil {
+		return fmt.Errorf("failed to delete story: %v", err)
+	}
+	return nil
+}
+
+func (r *StoryRepository) GetActiveByAuthors(viewerID int, authorIDs []int, now time.Time) ([]domain.Story, error) {
+	if len(authorIDs) == 0 {
+		return nil, nil
+	}
+
+	rows, err := r.db.Query(`
+	SELECT
+		s.id,
+		s.author_id,
+		COALESCE(s.content, ''),
+		COALESCE(s.media_url, ''),
+		s.created_at,
+		s.expires_at,
+		EXISTS (SELECT 1 FROM story_views sv WHERE sv.story_id = s.id AND sv.viewer_id = $1) AS seen,
+		CASE WHEN s.author_id = $1
+			THEN (SELECT COUNT(*) FROM story_views sv WHERE sv.story_id = s.id)
+			ELSE 0
+		END AS views_count
+	FROM stories s
+	WHERE s.author_id = ANY($2)
+		AND s.expires_at > $3
+		AND `+fmt.Sprintf(notBlockedFilter, "s.author_id")+`
+	ORDER BY s.author_id, s.created_at, s.id`, viewerID, pq.Array(authorIDs), now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stories: %v", err)
+	}
+	defer rows.Close()
+
+	var stories []domain.Story
+	for rows.Next() {
+		var story domain.Story
+		if err := rows.Scan(&story.ID, &story.AuthorID, &story.Content, &story.MediaURL, &story.CreatedAt, &story.ExpiresAt, &story.Seen, &story.ViewsCount); err != nil {
+			return nil, fmt.Errorf("failed to scan story: %v", err)
+		}
+		stories = append(stories, story)
+	}
+	return stories, rows.Err()
+}
+
+func (r *StoryRepository) MarkSeen(storyID, viewerID int) error {
+	_, err := r.db.Exec(`
+		INSERT INTO story_views (story_id, viewer_id)
+		VALUES ($1, $2)
+		ON CONFLICT (story_id, viewer_id) DO NOTHING`, storyID, viewerID)
+	if err != nil {
+		return fmt.Errorf("failed to mark story as seen: %v", err)
+	}
+	return nil
+}
+
+// GetViewers lists who has seen the story, most recent viewers first.
+func (r *StoryRepository) GetViewers(storyID int, page domain.PageRequest) ([]domain.StoryViewer, error) {
+	afterTime, afterID := keysetArgs(page.After)
+	rows, err := r.db.Query(`
+	SELECT sv.viewer_id, COALESCE(u.username, ''), COALESCE(u.profile_pic, ''), sv.viewed_at
+	FROM story_views sv
+	JOIN users u ON u.id = sv.viewer_id
+	WHERE sv.story_id = $1
+		AND ($2::timestamp IS NULL OR (sv.viewed_at, sv.viewer_id) < ($2::timestamp, $3))
+	ORDER BY sv.viewed_at DESC, sv.viewer_id DESC
+	OFFSET $4 LIMIT $5`, storyID, afterTime, afterID, page.Offset, page.Limit+1)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get story viewers: %v", err)
+	}
+	defer rows.Close()
+
+	var viewers []domain.StoryViewer
+	for rows.Next() {
+		var viewer domain.StoryViewer
+		if err := rows.Scan(&viewer.UserID, &viewer.Username, &viewer.ProfilePic, &viewer.ViewedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan story viewer: %v", err)
+		}
+		viewers = append(viewers, viewer)
+	}
+	return viewers, rows.Err()
+}
+
+// DeleteExpired removes stories that expired before the given time together
+// with their views.
+func (r *StoryRepository) DeleteExpired(before time.Time) (int64, error) {
+	result, err := r.db.Exec("DELETE FROM stories WHERE expires_at <= $1", before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired stories: %v", err)
+	}
+	return result.RowsAffected()
+}
@@ -91,6 +91,8 @@ func (r *UserRepository) GetUserByID(id int) (*domain.User, error) {
 		u.profile_pic,
 		u.created_at,
 		u.updated_at,
+		COALESCE(u.sensitive_content, 'blur') AS sensitive_content,
+		u.is_private,
 		COALESCE(pc.post_count, 0) AS post_count,
 		COALESCE(fs.follower_count, 0) AS followers_count,
 		COALESCE(fs.followee_count, 0) AS followees_count
@@ -104,7 +106,7 @@ func (r *UserRepository) GetUserByID(id int) (*domain.User, error) {
 	defer stmt.Close()
 
 	err = stmt.QueryRow(id).
-		Scan(&user.ID, &user.Username, &user.Password, &user.Email, &user.Status, &user.Role, &user.ProfilePic, &user.CreatedAt, &user.UpdatedAt, &user.PostsCount, &user.FollowersCount, &user.FolloweesCount)
+		Scan(&user.ID, &user.Username, &user.Password, &user.Email, &user.Status, &user.Role, &user.ProfilePic, &user.CreatedAt, &user.UpdatedAt, &user.SensitiveContent, &user.IsPrivate, &user.PostsCount, &user.FollowersCount, &user.FolloweesCount)
 	if err != nil {
 		return nil, err
 	}
@@ -115,8 +117,9 @@ func (r *UserRepository) GetUserByID(id int) (*domain.User, error) {
 	return &user, nil
 }
 
-func (r *UserRepository) GetPublicProfiles(offset, limit int) ([]domain.User, error) {
-	cacheKey := fmt.Sprintf("public_profiles:limit:%d:offset:%d", limit, offset)
+func (r *UserRepository) GetPublicProfiles(page domain.PageRequest) ([]domain.User, error) {
+	afterTime, afterID := keysetArgs(page.After)
+	cacheKey := fmt.Sprintf("public_profiles:limit:%d:offset:%d:after:%v:%d", page.Limit, page.Offset, afterTime, afterID)
 
 	ctx := context.Background()
 	cachedData, err := r.cache.Get(ctx, cacheKey)
@@ -127,14 +130,19 @@ func (r *UserRepository) GetPublicProfiles(offset, limit int) ([]domain.User, er
 		}
 	}
 
-	stmt, err := r.db.Prepare(`SELECT id, username, profile_pic FROM users OFFSET $1 LIMIT $2`)
+	stmt, err := r.db.Prepare(`
+		SELECT id, username, profile_pic, created_at
+		FROM users
+		WHERE ($1::timestamp IS NULL OR (created_at, id) < ($1::timestamp, $2))
+		ORDER BY created_at DESC, id DESC
+		OFFSET $3 LIMIT $4`)
 	if err != nil {
 		return nil, fmt.Errorf("failed to prepare statement: %v", err)
 	}
 	defer stmt.Close()
 
 	// Execute the prepared statement with parameters
-	rows, err := stmt.Query(offset, limit)
+	rows, err := stmt.Query(afterTime, afterID, page.Offset, page.Limit+1)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch public profiles: %v", err)
 	}
@@ -143,7 +151,7 @@ func (r *UserRepository) GetPublicProfiles(offset, limit int) ([]domain.User, er
 	var users []domain.User
 	for rows.Next() {
 		var user domain.User
-		if err := rows.Scan(&user.ID, &user.Username, &user.ProfilePic); err != nil {
+		if err := rows.Scan(&user.ID, &user.Username, &user.ProfilePic, &user.CreatedAt); err != nil {
 			return nil, fmt.Errorf("failed to scan row: %v", err)
 		}
 		users = append(users, user)
@@ -348,6 +356,49 @@ func (r *UserRepository) UpdateUser(user *domain.User) error {
 	return err
 }
 
+// UpdateSensitiveContent stores how the user wants flagged posts to be shown
+// and drops the cached user so the next request picks it up.
+func (r *UserRepository) UpdateSensitiveContent(userID int, preference string) error {
+	var email string
+	err := r.db.QueryRow("UPDATE users SET sensitive_content = $1 WHERE id = $2 RETURNING email", preference, userID).Scan(&email)
+	if err != nil {
+		return err
+	}
+	return r.dropCachedUser(userID, email)
+}
+
+// UpdatePrivacy makes the user's account private or public and drops the
+// cached user.
+func (r *UserRepository) UpdatePrivacy(userID int, private bool) error {
+	var email string
+	err := r.db.QueryRow("UPDATE users SET is_private = $1 WHERE id = $2 RETURNING email", private, userID).Scan(&email)
+	if err != nil {
+		return err
+	}
+	return r.dropCachedUser(userID, email)
+}
+
+// UpdateStatus activates, deactivates or bans a user and drops the cached
+// user, so a ban takes effect on the user's next request.
+func (r *UserRepository) UpdateStatus(userID int, status string) error {
+	var email string
+	err := r.db.QueryRow("UPDATE users SET status = $1 WHERE id = $2 RETURNING email", status, userID).Scan(&email)
+	if err != nil {
+		return err
+	}
+	return r.dropCachedUser(userID, email)
+}
+
+// dropCachedUser removes the user from the cache under both keys it is
+// cached by, its ID and its email.
+func (r *UserRepository) dropCachedUser(userID int, email string) error {
+	ctx := context.Background()
+	if err := r.cache.Delete(ctx, fmt.Sprintf("user:%d", userID)); err != nil {
+		return err
+	}
+	return r.cache.Delete(ctx, fmt.Sprintf("user:%v", email))
+}
+
 func (u *UserRepository) buildUpdateQuery(user *domain.User) (string, error) {
 	var setClauses []string
 
@@ -403,7 +454,7 @@ func (r *UserRepository) GetUsersByID(ctx context.Context, userIDs []int) ([]dom
 	}
 
 	query := fmt.Sprintf(`
-		SELECT id, username, profile_pic
+		SELECT id, username, profile_pic, is_private
 		FROM users
 		WHERE id IN (%s)
 	`, strings.Join(placeholders, ", "))
@@ -417,7 +468,7 @@ func (r *UserRepository) GetUsersByID(ctx context.Context, userIDs []int) ([]dom
 	var users []domain.User
 	for rows.Next() {
 		var user domain.User
-		if err := rows.Scan(&user.ID, &user.Username, &user.ProfilePic); err != nil {
+		if err := rows.Scan(&user.ID, &user.Username, &user.ProfilePic, &user.IsPrivate); err != nil {
 			return nil, err
 		}
 		users = append(users, user)
@@ -0,0 +1,48 @@
+package infrastructure
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// RedisViewDeduplicator keeps a short-lived key per viewer and post, so a
+// post that is opened or scrolled past repeatedly counts as one view per
+// window.
+type RedisViewDeduplicator struct {
+	client *redis.Client
+}
+
+func NewRedisViewDeduplicator(client *redis.Client) *RedisViewDeduplicator {
+	return &RedisViewDeduplicator{client: client}
+}
+
+func postViewKey(viewerID, postID int) string {
+	return fmt.Sprintf("post_view:%d:%d", postID, viewerID)
+}
+
+func (d *RedisViewDeduplicator) MarkViewed(viewerID int, postIDs []int, window time.Duration) ([]int, error) {
+	if len(postIDs) == 0 {
+		return nil, nil
+	}
+
+	ctx := context.Background()
+	pipe := d.client.Pipeline()
+	results := make([]*redis.BoolCmd, len(postIDs))
+	for i, postID := range postIDs {
+		results[i] = pipe.SetNX(ctx, postViewKey(viewerID, postID), 1, window)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, fmt.Errorf("failed to deduplicate views: %v", err)
+	}
+
+	var fresh []int
+	for i, result := range results {
+		if result.Val() {
+			fresh = append(fresh, postIDs[i])
+		}
+	}
+	return fresh, nil
+}
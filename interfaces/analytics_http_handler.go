@@ -0,0 +1,132 @@
+package interfaces
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/bandvov/social-media-go/application"
+	"github.com/bandvov/social-media-go/domain"
+)
+
+// Ranges returned when the request does not give `from`.
+const (
+	defaultHourlyAnalyticsRange = 48 * time.Hour
+	defaultDailyAnalyticsRange  = 30 * 24 * time.Hour
+)
+
+type AnalyticsHandler struct {
+	service application.AnalyticsServiceInterface
+}
+
+func NewAnalyticsHandler(service application.AnalyticsServiceInterface) *AnalyticsHandler {
+	return &AnalyticsHandler{service: service}
+}
+
+// GetMyAnalytics returns the combined stats of the authenticated user's posts.
+func (h *AnalyticsHandler) GetMyAnalytics(w http.ResponseWriter, r *http.Request) {
+	viewer, ok := viewerFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthenticated", http.StatusBadRequest)
+		return
+	}
+
+	query, err := parseAnalyticsQuery(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	analytics, err := h.service.GetAuthorAnalytics(viewer.ID, query)
+	if err != nil {
+		writeAnalyticsError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"data": analytics})
+}
+
+// GetPostAnalytics returns the stats of one post to its author.
+func (h *AnalyticsHandler) GetPostAnalytics(w http.ResponseWriter, r *http.Request) {
+	viewer, ok := viewerFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthenticated", http.StatusBadRequest)
+		return
+	}
+
+	postID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "invalid post ID", http.StatusBadRequest)
+		return
+	}
+
+	query, err := parseAnalyticsQuery(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	analytics, err := h.service.GetPostAnalytics(viewer, postID, query)
+	if err != nil {
+		writeAnalyticsError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"data": analytics})
+}
+
+// parseAnalyticsQuery reads `granularity` (hour or day) and the RFC 3339
+// `from` and `to` bounds from the query string.
+func parseAnalyticsQuery(r *http.Request) (domain.AnalyticsQuery, error) {
+	values := r.URL.Query()
+
+	query := domain.AnalyticsQuery{
+		Granularity: domain.AnalyticsGranularity(values.Get("granularity")),
+		To:          time.Now(),
+	}
+	if query.Granularity == "" {
+		query.Granularity = domain.GranularityDay
+	}
+
+	if to := values.Get("to"); to != "" {
+		t, err := time.Parse(time.RFC3339, to)
+		if err != nil {
+			return query, errors.New("invalid to")
+		}
+		query.To = t
+	}
+
+	if from := values.Get("from"); from != "" {
+		t, err := time.Parse(time.RFC3339, from)
+		if err != nil {
+			return query, errors.New("invalid from")
+		}
+		query.From = t
+	} else if query.Granularity == domain.GranularityHour {
+		query.From = query.To.Add(-defaultHourlyAnalyticsRange)
+	} else {
+		query.From = query.To.Add(-defaultDailyAnalyticsRange)
+	}
+
+	return query, nil
+}
+
+func writeAnalyticsError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, domain.ErrInvalidGranularity),
+		errors.Is(err, domain.ErrInvalidAnalyticsRange),
+		errors.Is(err, domain.ErrAnalyticsRangeTooLong):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		status := accessErrorStatus(err)
+		if status == http.StatusInternalServerError {
+			fmt.Println(err)
+		}
+		http.Error(w, "Failed to fetch analytics", status)
+	}
+}
@@ -0,0 +1,150 @@
+package interfaces
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/bandvov/social-media-go/application"
+	"github.com/bandvov/social-media-go/domain"
+)
+
+// AutomodHandler serves the admin endpoints of auto-moderation rules.
+type AutomodHandler struct {
+	service application.AutomodServiceInterface
+}
+
+func NewAutomodHandler(service application.AutomodServiceInterface) *AutomodHandler {
+	return &AutomodHandler{service: service}
+}
+
+func writeAutomodError(w http.ResponseWriter, err error, message string) {
+	if errors.Is(err, domain.ErrInvalidAutomodRule) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	fmt.Println(err)
+	http.Error(w, message, accessErrorStatus(err))
+}
+
+func (h *AutomodHandler) ListRules(w http.ResponseWriter, r *http.Request) {
+	rules, err := h.service.ListRules()
+	if err != nil {
+		writeAutomodError(w, err, "Failed to fetch rules")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"data": rules})
+}
+
+func (h *AutomodHandler) GetRule(w http.ResponseWriter, r *http.Request) {
+	ruleID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "invalid rule ID", http.StatusBadRequest)
+		return
+	}
+
+	rule, err := h.service.GetRule(ruleID)
+	if err != nil {
+		writeAutomodError(w, err, "Failed to fetch rule")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"data": rule})
+}
+
+func (h *AutomodHandler) CreateRule(w http.ResponseWriter, r *http.Request) {
+	viewer, ok := viewerFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthenticated", http.StatusBadRequest)
+		return
+	}
+
+	var req struct {
+		Data domain.AutomodRuleRequest `json:"data"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	rule, err := h.service.CreateRule(viewer.ID, &req.Data)
+	if err != nil {
+		writeAutomodError(w, err, "Failed to create rule")
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{"message": "Rule created successfully", "data": rule})
+}
+
+func (h *AutomodHandler) UpdateRule(w http.ResponseWriter, r *http.Request) {
+	ruleID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "invalid rule ID", http.StatusBadRequest)
+		return
+	}
+
+	var req struct {
+		Data domain.AutomodRuleRequest `json:"data"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	rule, err := h.service.UpdateRule(ruleID, &req.Data)
+	if err != nil {
+		writeAutomodError(w, err, "Failed to update rule")
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{"message": "Rule updated successfully", "data": rule})
+}
+
+func (h *AutomodHandler) DeleteRule(w http.ResponseWriter, r *http.Request) {
+	ruleID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "invalid rule ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.DeleteRule(ruleID); err != nil {
+		writeAutomodError(w, err, "Failed to delete rule")
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"message": "rule deleted successfully"})
+}
+
+// TestContent shows which rules a piece of content would match, including
+// rules in dry-run mode, without counting the hits.
+func (h *AutomodHandler) TestContent(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Data struct {
+			Scope   domain.AutomodScope `json:"scope"`
+			Content string              `json:"content"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.Data.Scope != domain.AutomodScopePosts && req.Data.Scope != domain.AutomodScopeComments {
+		http.Error(w, "scope must be posts or comments", http.StatusBadRequest)
+		return
+	}
+
+	verdict, err := h.service.TestContent(req.Data.Scope, req.Data.Content)
+	if err != nil {
+		writeAutomodError(w, err, "Failed to evaluate content")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"data": verdict})
+}
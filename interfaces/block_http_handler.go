@@ -0,0 +1,61 @@
+package interfaces
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/bandvov/social-media-go/application"
+)
+
+type BlockHandler struct {
+	service application.BlockServiceInterface
+}
+
+func NewBlockHandler(service application.BlockServiceInterface) *BlockHandler {
+	return &BlockHandler{service: service}
+}
+
+func (h *BlockHandler) BlockUser(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(userIDKey).(interface{}).(int)
+	if !ok || userID == 0 {
+		http.Error(w, "unauthenticated", http.StatusBadRequest)
+		return
+	}
+
+	blockedID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.BlockUser(userID, blockedID); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "User blocked successfully")
+}
+
+func (h *BlockHandler) UnblockUser(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(userIDKey).(interface{}).(int)
+	if !ok || userID == 0 {
+		http.Error(w, "unauthenticated", http.StatusBadRequest)
+		return
+	}
+
+	blockedID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.UnblockUser(userID, blockedID); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "User unblocked successfully")
+}
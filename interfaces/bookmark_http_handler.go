@@ -0,0 +1,194 @@
+package interfaces
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/bandvov/social-media-go/application"
+)
+
+type BookmarkHandler struct {
+	service application.BookmarkServiceInterface
+}
+
+func NewBookmarkHandler(service application.BookmarkServiceInterface) *BookmarkHandler {
+	return &BookmarkHandler{service: service}
+}
+
+func (h *BookmarkHandler) AddBookmark(w http.ResponseWriter, r *http.Request) {
+	viewer, ok := viewerFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthenticated", http.StatusBadRequest)
+		return
+	}
+
+	var req struct {
+		Data struct {
+			PostID       int  `json:"post_id"`
+			CollectionID *int `json:"collection_id"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Data.PostID == 0 {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	bookmark, err := h.service.AddBookmark(viewer, req.Data.PostID, req.Data.CollectionID)
+	if errors.Is(err, application.ErrPostNotFound) || errors.Is(err, application.ErrCollectionNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		fmt.Println(err)
+		http.Error(w, "Failed to add bookmark", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{"message": "Bookmark added successfully", "data": bookmark})
+}
+
+// RemoveBookmark unsaves a post; {id} is the id of the bookmarked post.
+func (h *BookmarkHandler) RemoveBookmark(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(userIDKey).(interface{}).(int)
+	if !ok || userID == 0 {
+		http.Error(w, "unauthenticated", http.StatusBadRequest)
+		return
+	}
+
+	postID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "invalid post ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.RemoveBookmark(userID, postID); err != nil {
+		fmt.Println(err)
+		http.Error(w, "Failed to remove bookmark", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"message": "bookmark removed successfully"})
+}
+
+// GetBookmarks lists the user's bookmarks, optionally limited to one
+// collection with ?collection={id}.
+func (h *BookmarkHandler) GetBookmarks(w http.ResponseWriter, r *http.Request) {
+	viewer, ok := viewerFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthenticated", http.StatusBadRequest)
+		return
+	}
+
+	page, err := parsePageRequest(w, r)
+	if err != nil {
+		http.Error(w, "invalid cursor", http.StatusBadRequest)
+		return
+	}
+
+	var collectionID *int
+	if c := r.URL.Query().Get("collection"); c != "" {
+		id, err := strconv.Atoi(c)
+		if err != nil {
+			http.Error(w, "invalid collection ID", http.StatusBadRequest)
+			return
+		}
+		collectionID = &id
+	}
+
+	bookmarks, next, err := h.service.GetBookmarks(viewer.ID, collectionID, page)
+	if errors.Is(err, application.ErrCollectionNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		fmt.Println(err)
+		http.Error(w, "Failed to fetch bookmarks", http.StatusInternalServerError)
+		return
+	}
+
+	// Saved posts are collapsed but never hidden.
+	for _, bookmark := range bookmarks {
+		if bookmark.Post != nil {
+			application.ApplyContentPreferenceToPost(viewer, bookmark.Post)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(pageResponse(bookmarks, next))
+}
+
+func (h *BookmarkHandler) CreateCollection(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(userIDKey).(interface{}).(int)
+	if !ok || userID == 0 {
+		http.Error(w, "unauthenticated", http.StatusBadRequest)
+		return
+	}
+
+	var req struct {
+		Data struct {
+			Name string `json:"name"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	collection, err := h.service.CreateCollection(userID, req.Data.Name)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{"message": "Collection created successfully", "data": collection})
+}
+
+func (h *BookmarkHandler) GetCollections(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(userIDKey).(interface{}).(int)
+	if !ok || userID == 0 {
+		http.Error(w, "unauthenticated", http.StatusBadRequest)
+		return
+	}
+
+	collections, err := h.service.GetCollections(userID)
+	if err != nil {
+		fmt.Println(err)
+		http.Error(w, "Failed to fetch collections", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"data": collections})
+}
+
+func (h *BookmarkHandler) DeleteCollection(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(userIDKey).(interface{}).(int)
+	if !ok || userID == 0 {
+		http.Error(w, "unauthenticated", http.StatusBadRequest)
+		return
+	}
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "invalid collection ID", http.StatusBadRequest)
+		return
+	}
+
+	err = h.service.DeleteCollection(userID, id)
+	if errors.Is(err, application.ErrCollectionNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		fmt.Println(err)
+		http.Error(w, "Failed to delete collection", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"message": "collection deleted successfully"})
+}
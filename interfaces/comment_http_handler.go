@@ -2,6 +2,7 @@ package interfaces
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
@@ -23,6 +24,12 @@ func NewCommentHandler(service *application.CommentService) *CommentHandler {
 }
 
 func (h *CommentHandler) AddComment(w http.ResponseWriter, r *http.Request) {
+	viewer, ok := viewerFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthenticated", http.StatusBadRequest)
+		return
+	}
+
 	var req struct {
 		Data *domain.Comment `json:"data"`
 	}
@@ -30,22 +37,245 @@ func (h *CommentHandler) AddComment(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Invalid request", http.StatusBadRequest)
 		return
 	}
+	if req.Data == nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	req.Data.AuthorID = viewer.ID
 	if !req.Data.IsValidAuthorId() || !req.Data.IsValidEntityId() || !req.Data.IsValidContent() {
 		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
 	}
 
-	if err := h.service.AddComment(req.Data); err != nil {
+	if err := h.service.AddComment(viewer, req.Data); err != nil {
+		if writeRateLimited(w, err) {
+			return
+		}
+		if errors.Is(err, domain.ErrContentRejected) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if errors.Is(err, domain.ErrCommentsDisabled) || errors.Is(err, domain.ErrCommentsRestricted) {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
 		fmt.Println(err)
-		http.Error(w, "Failed to add comment", http.StatusInternalServerError)
+		http.Error(w, "Failed to add comment", accessErrorStatus(err))
 		return
 	}
 
+	if req.Data.Status == domain.CommentFlagged {
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(map[string]interface{}{"message": "Comment held for review", "id": req.Data.ID})
+		return
+	}
 	w.WriteHeader(http.StatusCreated)
 }
 
+// EditComment lets the author change the content of a comment.
+func (h *CommentHandler) EditComment(w http.ResponseWriter, r *http.Request) {
+	viewer, ok := viewerFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthenticated", http.StatusBadRequest)
+		return
+	}
+
+	commentID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "invalid comment ID", http.StatusBadRequest)
+		return
+	}
+
+	var req struct {
+		Data struct {
+			Content string `json:"content"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	comment, err := h.service.EditComment(viewer, commentID, req.Data.Content)
+	if err != nil {
+		switch {
+		case errors.Is(err, domain.ErrCommentContentRequired), errors.Is(err, domain.ErrContentRejected):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, domain.ErrCommentNotEditable):
+			http.Error(w, err.Error(), http.StatusConflict)
+		default:
+			fmt.Println(err)
+			http.Error(w, "Failed to edit comment", accessErrorStatus(err))
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"message": "Comment updated successfully", "data": comment})
+}
+
+// DeleteComment lets the author of a comment, the author of the post or a
+// moderator delete a comment.
+func (h *CommentHandler) DeleteComment(w http.ResponseWriter, r *http.Request) {
+	viewer, ok := viewerFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthenticated", http.StatusBadRequest)
+		return
+	}
+
+	commentID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "invalid comment ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.DeleteComment(viewer, commentID); err != nil {
+		fmt.Println(err)
+		http.Error(w, "Failed to delete comment", accessErrorStatus(err))
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"message": "comment deleted successfully"})
+}
+
+// SetCommentPolicy lets the author of a post choose who may comment on it.
+func (h *CommentHandler) SetCommentPolicy(w http.ResponseWriter, r *http.Request) {
+	viewer, ok := viewerFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthenticated", http.StatusBadRequest)
+		return
+	}
+
+	postID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "invalid post ID", http.StatusBadRequest)
+		return
+	}
+
+	var req struct {
+		Data struct {
+			CommentPolicy domain.CommentPolicy `json:"comment_policy"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.SetCommentPolicy(viewer, postID, req.Data.CommentPolicy); err != nil {
+		if errors.Is(err, domain.ErrInvalidCommentPolicy) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		fmt.Println(err)
+		http.Error(w, "Failed to update comment policy", accessErrorStatus(err))
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"message": "comment policy updated successfully"})
+}
+
+// PinComment lets the author of a post pin one of its comments.
+func (h *CommentHandler) PinComment(w http.ResponseWriter, r *http.Request) {
+	viewer, ok := viewerFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthenticated", http.StatusBadRequest)
+		return
+	}
+
+	postID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "invalid post ID", http.StatusBadRequest)
+		return
+	}
+
+	var req struct {
+		Data struct {
+			CommentID int `json:"comment_id"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Data.CommentID <= 0 {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.PinComment(viewer, postID, &req.Data.CommentID); err != nil {
+		if errors.Is(err, domain.ErrInvalidPinnedComment) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		fmt.Println(err)
+		http.Error(w, "Failed to pin comment", accessErrorStatus(err))
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"message": "comment pinned successfully"})
+}
+
+func (h *CommentHandler) UnpinComment(w http.ResponseWriter, r *http.Request) {
+	viewer, ok := viewerFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthenticated", http.StatusBadRequest)
+		return
+	}
+
+	postID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "invalid post ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.PinComment(viewer, postID, nil); err != nil {
+		fmt.Println(err)
+		http.Error(w, "Failed to unpin comment", accessErrorStatus(err))
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"message": "comment unpinned successfully"})
+}
+
+// HideComment lets the author of a post hide a comment on it.
+func (h *CommentHandler) HideComment(w http.ResponseWriter, r *http.Request) {
+	h.setCommentHidden(w, r, true)
+}
+
+// UnhideComment lists a comment hidden by the author of the post again.
+func (h *CommentHandler) UnhideComment(w http.ResponseWriter, r *http.Request) {
+	h.setCommentHidden(w, r, false)
+}
+
+func (h *CommentHandler) setCommentHidden(w http.ResponseWriter, r *http.Request, hidden bool) {
+	viewer, ok := viewerFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthenticated", http.StatusBadRequest)
+		return
+	}
+
+	commentID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "invalid comment ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.SetCommentHidden(viewer, commentID, hidden); err != nil {
+		fmt.Println(err)
+		http.Error(w, "Failed to update comment", accessErrorStatus(err))
+		return
+	}
+
+	message := "comment hidden successfully"
+	if !hidden {
+		message = "comment unhidden successfully"
+	}
+	json.NewEncoder(w).Encode(map[string]string{"message": message})
+}
+
+// GetCommentsByEntityID lists a page of a post's top-level comments, ordered
+// by `sort`: newest (the default), oldest, top or controversial. The
+// pinned comment leads the first page.
 func (h *CommentHandler) GetCommentsByEntityID(w http.ResponseWriter, r *http.Request) {
-	userID, ok := r.Context().Value(userIDKey).(interface{}).(int)
-	if !ok || userID == 0 {
+	viewer, ok := viewerFromContext(r.Context())
+	if !ok {
 		http.Error(w, "unauthenticated", http.StatusBadRequest)
 		return
 	}
@@ -57,35 +287,165 @@ func (h *CommentHandler) GetCommentsByEntityID(w http.ResponseWriter, r *http.Re
 		return
 	}
 
+	page, err := parsePageRequest(w, r)
+	if err != nil {
+		http.Error(w, "invalid cursor", http.StatusBadRequest)
+		return
+	}
+
+	comments, next, err := h.service.GetCommentsByEntityID(entityID, viewer, parseCommentSort(r), page)
+	if err != nil {
+		if writeCommentSortError(w, err) {
+			return
+		}
+		fmt.Println(err)
+		http.Error(w, "Failed to get comments", accessErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(pageResponse(comments, next))
+}
+
+// commentNodeResponse is a comment node with the token for loading more of
+// its replies from GET /api/comments/{id}/replies.
+type commentNodeResponse struct {
+	domain.CommentNode
+	Replies       []commentNodeResponse `json:"replies,omitempty"`
+	RepliesCursor string                `json:"replies_cursor,omitempty"`
+}
+
+func commentTreeResponse(nodes []domain.CommentNode) []commentNodeResponse {
+	response := make([]commentNodeResponse, 0, len(nodes))
+	for _, node := range nodes {
+		response = append(response, commentNodeResponse{
+			CommentNode:   node,
+			Replies:       commentTreeResponse(node.Replies),
+			RepliesCursor: encodeNextCursor(node.RepliesAfter),
+		})
+	}
+	return response
+}
+
+// parseTreeOptions reads `depth`, the levels of replies loaded below the
+// listed comments, and `replies`, the replies loaded per branch.
+// parseCommentSort reads the `sort` query parameter, newest by default.
+func parseCommentSort(r *http.Request) domain.CommentSort {
+	if sort := r.URL.Query().Get("sort"); sort != "" {
+		return domain.CommentSort(sort)
+	}
+	return domain.CommentSortNewest
+}
+
+// writeCommentSortError answers 400 when the sort or the cursor of a comment
+// listing is invalid, and reports whether it did.
+func writeCommentSortError(w http.ResponseWriter, err error) bool {
+	if !errors.Is(err, domain.ErrInvalidCommentSort) && !errors.Is(err, domain.ErrCommentSortMismatch) {
+		return false
+	}
+	http.Error(w, err.Error(), http.StatusBadRequest)
+	return true
+}
+
+func parseTreeOptions(r *http.Request) (int, int, error) {
 	query := r.URL.Query()
+	depth, perBranch := domain.DefaultCommentTreeDepth, domain.DefaultRepliesPerBranch
 
-	// Parse `limit` and `offset` with default values
-	page, err := strconv.Atoi(query.Get("page"))
-	if err != nil || page < 1 {
-		page = 1 // Default offset
+	if value := query.Get("depth"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 || n > domain.MaxCommentTreeDepth {
+			return 0, 0, fmt.Errorf("depth must be between 0 and %d", domain.MaxCommentTreeDepth)
+		}
+		depth = n
+	}
+	if value := query.Get("replies"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 || n > domain.MaxRepliesPerBranch {
+			return 0, 0, fmt.Errorf("replies must be between 1 and %d", domain.MaxRepliesPerBranch)
+		}
+		perBranch = n
 	}
+	return depth, perBranch, nil
+}
 
-	limit, err := strconv.Atoi(query.Get("limit"))
-	if err != nil || limit <= 0 {
-		limit = 10 // Default limit
+// GetCommentTree lists a page of a post's comments with their nested
+// replies. Branches with more replies than were loaded carry
+// `more_replies` and, past the first replies, a `replies_cursor`.
+func (h *CommentHandler) GetCommentTree(w http.ResponseWriter, r *http.Request) {
+	viewer, ok := viewerFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthenticated", http.StatusBadRequest)
+		return
 	}
 
-	offset := (page - 1) * limit
+	postID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "invalid post ID", http.StatusBadRequest)
+		return
+	}
+
+	depth, perBranch, err := parseTreeOptions(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	page, err := parsePageRequest(w, r)
+	if err != nil {
+		http.Error(w, "invalid cursor", http.StatusBadRequest)
+		return
+	}
 
-	comments, err := h.service.GetCommentsByEntityID(entityID, userID, offset, limit)
+	nodes, next, err := h.service.GetCommentTree(postID, viewer, parseCommentSort(r), page, depth, perBranch)
 	if err != nil {
+		if writeCommentSortError(w, err) {
+			return
+		}
 		fmt.Println(err)
-		http.Error(w, "Failed to get comments", http.StatusInternalServerError)
+		http.Error(w, "Failed to get comments", accessErrorStatus(err))
 		return
 	}
 
-	response := map[string]interface{}{
-		"data":    comments,
-		"hasMore": true,
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(pageResponse(commentTreeResponse(nodes), next))
+}
+
+// GetReplies lists the replies of a comment with their own nested replies,
+// continuing from a branch's `replies_cursor`.
+func (h *CommentHandler) GetReplies(w http.ResponseWriter, r *http.Request) {
+	viewer, ok := viewerFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthenticated", http.StatusBadRequest)
+		return
+	}
+
+	commentID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "invalid comment ID", http.StatusBadRequest)
+		return
+	}
+
+	depth, perBranch, err := parseTreeOptions(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	page, err := parsePageRequest(w, r)
+	if err != nil {
+		http.Error(w, "invalid cursor", http.StatusBadRequest)
+		return
+	}
+
+	nodes, next, err := h.service.GetReplies(commentID, viewer, page, depth, perBranch)
+	if err != nil {
+		fmt.Println(err)
+		http.Error(w, "Failed to get replies", accessErrorStatus(err))
+		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+	json.NewEncoder(w).Encode(pageResponse(commentTreeResponse(nodes), next))
 }
 
 func (h *CommentHandler) GetCommentsAndRepliesCount(w http.ResponseWriter, r *http.Request) {
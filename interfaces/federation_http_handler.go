@@ -0,0 +1,171 @@
+package interfaces
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/bandvov/social-media-go/application"
+	"github.com/bandvov/social-media-go/domain"
+	"github.com/bandvov/social-media-go/utils"
+)
+
+// maxInboxBodyBytes bounds the size of an incoming activity.
+const maxInboxBodyBytes = 1 << 20
+
+// FederationHandler serves the ActivityPub and WebFinger endpoints. They
+// are public: inbox requests are authenticated by their HTTP signature.
+type FederationHandler struct {
+	service application.FederationServiceInterface
+}
+
+func NewFederationHandler(service application.FederationServiceInterface) *FederationHandler {
+	return &FederationHandler{service: service}
+}
+
+func writeActivityJSON(w http.ResponseWriter, document interface{}) {
+	w.Header().Set("Content-Type", domain.ActivityContentType)
+	json.NewEncoder(w).Encode(document)
+}
+
+func writeFederationError(w http.ResponseWriter, err error, message string) {
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+	fmt.Println(err)
+	http.Error(w, message, http.StatusInternalServerError)
+}
+
+func (h *FederationHandler) WebFinger(w http.ResponseWriter, r *http.Request) {
+	resource := r.URL.Query().Get("resource")
+	if resource == "" {
+		http.Error(w, "missing resource", http.StatusBadRequest)
+		return
+	}
+
+	webFinger, err := h.service.WebFinger(resource)
+	if err != nil {
+		writeFederationError(w, err, "Failed to resolve resource")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/jrd+json")
+	json.NewEncoder(w).Encode(webFinger)
+}
+
+func (h *FederationHandler) GetActor(w http.ResponseWriter, r *http.Request) {
+	actor, err := h.service.GetActor(r.PathValue("username"))
+	if err != nil {
+		writeFederationError(w, err, "Failed to fetch actor")
+		return
+	}
+	writeActivityJSON(w, actor)
+}
+
+// GetOutbox returns the outbox collection, or one of its pages when the
+// request has a `limit` or a `cursor`.
+func (h *FederationHandler) GetOutbox(w http.ResponseWriter, r *http.Request) {
+	username := r.PathValue("username")
+	outboxURI := h.service.ActorURI(username) + "/outbox"
+
+	if !r.URL.Query().Has("limit") && !r.URL.Query().Has("cursor") {
+		if _, err := h.service.GetActor(username); err != nil {
+			writeFederationError(w, err, "Failed to fetch outbox")
+			return
+		}
+		writeActivityJSON(w, domain.OrderedCollection{
+			Context: domain.ActivityStreamsContext,
+			ID:      outboxURI,
+			Type:    "OrderedCollection",
+			First:   fmt.Sprintf("%s?limit=%d", outboxURI, defaultPageLimit),
+		})
+		return
+	}
+
+	page, err := parsePageRequest(w, r)
+	if err != nil {
+		http.Error(w, "invalid cursor", http.StatusBadRequest)
+		return
+	}
+
+	activities, next, err := h.service.GetOutbox(username, page)
+	if err != nil {
+		writeFederationError(w, err, "Failed to fetch outbox")
+		return
+	}
+
+	collection := domain.OrderedCollection{
+		Context:      domain.ActivityStreamsContext,
+		ID:           outboxURI + "?" + r.URL.RawQuery,
+		Type:         "OrderedCollectionPage",
+		PartOf:       outboxURI,
+		OrderedItems: make([]interface{}, 0, len(activities)),
+	}
+	for _, activity := range activities {
+		collection.OrderedItems = append(collection.OrderedItems, activity)
+	}
+	if cursor := encodeNextCursor(next); cursor != "" {
+		collection.Next = fmt.Sprintf("%s?limit=%d&cursor=%s", outboxURI, page.Limit, url.QueryEscape(cursor))
+	}
+	writeActivityJSON(w, collection)
+}
+
+// GetNote returns a public post as an ActivityPub object.
+func (h *FederationHandler) GetNote(w http.ResponseWriter, r *http.Request) {
+	postID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "invalid post ID", http.StatusBadRequest)
+		return
+	}
+
+	note, err := h.service.GetNote(postID)
+	if err != nil {
+		writeFederationError(w, err, "Failed to fetch post")
+		return
+	}
+	writeActivityJSON(w, note)
+}
+
+// Inbox receives activities from remote servers, both on the per-user
+// inboxes and on the shared one.
+func (h *FederationHandler) Inbox(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxInboxBodyBytes))
+	if err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	keyID, err := utils.VerifyRequest(r, body, h.service.PublicKey)
+	if err != nil {
+		fmt.Println(err)
+		http.Error(w, "invalid signature", http.StatusUnauthorized)
+		return
+	}
+
+	var activity domain.Activity
+	if err := json.Unmarshal(body, &activity); err != nil || activity.Type == "" || activity.Actor == "" {
+		http.Error(w, "invalid activity", http.StatusBadRequest)
+		return
+	}
+
+	err = h.service.HandleInbox(keyID, &activity)
+	switch {
+	case err == nil:
+		w.WriteHeader(http.StatusAccepted)
+	case errors.Is(err, domain.ErrInvalidActivity):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, application.ErrForbidden):
+		http.Error(w, "forbidden", http.StatusForbidden)
+	case errors.Is(err, sql.ErrNoRows):
+		http.Error(w, "not found", http.StatusNotFound)
+	default:
+		fmt.Println(err)
+		http.Error(w, "Failed to process activity", http.StatusInternalServerError)
+	}
+}
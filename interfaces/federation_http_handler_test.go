@@ -0,0 +1,717 @@
+package interfaces
+
+import (
+	"bytes"
+	"crypto/rsa"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bandvov/social-media-go/application"
+	"github.com/bandvov/social-media-go/domain"
+	"github.com/bandvov/social-media-go/infrastructure"
+	"github.com/bandvov/social-media-go/utils"
+)
+
+const testFederationBaseURL = "https://social.example"
+
+// memFederationRepository keeps federation state in memory.
+type memFederationRepository struct {
+	mu          sync.Mutex
+	keys        map[int]*domain.ActorKey
+	actors      map[string]*domain.RemoteActor
+	remotePosts map[string]int
+	deliveries  map[int64]*domain.Delivery
+	nextAt      map[int64]time.Time
+	failed      map[int64]bool
+	nextID      int64
+	nextUserID  int
+}
+
+func newMemFederationRepository() *memFederationRepository {
+	return &memFederationRepository{
+		keys:        make(map[int]*domain.ActorKey),
+		actors:      make(map[string]*domain.RemoteActor),
+		remotePosts: make(map[string]int),
+		deliveries:  make(map[int64]*domain.Delivery),
+		nextAt:      make(map[int64]time.Time),
+		failed:      make(map[int64]bool),
+		nextUserID:  100,
+	}
+}
+
+func (m *memFederationRepository) GetActorKey(userID int) (*domain.ActorKey, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if key, ok := m.keys[userID]; ok {
+		return key, nil
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (m *memFederationRepository) SaveActorKey(key *domain.ActorKey) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.keys[key.UserID]; !ok {
+		m.keys[key.UserID] = key
+	}
+	return nil
+}
+
+func (m *memFederationRepository) GetRemoteActorByURI(actorURI string) (*domain.RemoteActor, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if actor, ok := m.actors[actorURI]; ok {
+		return actor, nil
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (m *memFederationRepository) GetRemoteActorByKeyID(keyID string) (*domain.RemoteActor, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, actor := range m.actors {
+		if actor.PublicKeyID == keyID {
+			return actor, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (m *memFederationRepository) SaveRemoteActor(actor *domain.RemoteActor) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if existing, ok := m.actors[actor.ActorURI]; ok {
+		actor.UserID = existing.UserID
+	} else {
+		m.nextUserID++
+		actor.UserID = m.nextUserID
+	}
+	saved := *actor
+	m.actors[actor.ActorURI] = &saved
+	return nil
+}
+
+func (m *memFederationRepository) GetFollowerInboxes(userID int) ([]string, error) {
+	return nil, nil
+}
+
+func (m *memFederationRepository) GetRemotePostID(objectURI string) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if id, ok := m.remotePosts[objectURI]; ok {
+		return id, nil
+	}
+	return 0, sql.ErrNoRows
+}
+
+func (m *memFederationRepository) SaveRemotePost(objectURI string, postID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.remotePosts[objectURI] = postID
+	return nil
+}
+
+func (m *memFederationRepository) GetReactionTypeID(name string) (int, error) {
+	return 1, nil
+}
+
+func (m *memFederationRepository) EnqueueDeliveries(deliveries []domain.Delivery) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, delivery := range deliveries {
+		m.nextID++
+		delivery.ID = m.nextID
+		m.deliveries[delivery.ID] = &delivery
+		m.nextAt[delivery.ID] = time.Time{}
+	}
+	return nil
+}
+
+func (m *memFederationRepository) ClaimDeliveries(now time.Time, lease time.Duration, limit int) ([]domain.Delivery, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var claimed []domain.Delivery
+	for id, delivery := range m.deliveries {
+		if m.failed[id] || m.nextAt[id].After(now) || len(claimed) == limit {
+			continue
+		}
+		m.nextAt[id] = now.Add(lease)
+		claimed = append(claimed, *delivery)
+	}
+	return claimed, nil
+}
+
+func (m *memFederationRepository) CompleteDelivery(id int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.deliveries, id)
+	return nil
+}
+
+func (m *memFederationRepository) RetryDelivery(id int64, attempts int, nextAttemptAt time.Time, lastError string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.deliveries[id].Attempts = attempts
+	m.deliveries[id].LastError = lastError
+	m.nextAt[id] = nextAttemptAt
+	return nil
+}
+
+func (m *memFederationRepository) FailDelivery(id int64, attempts int, lastError string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.deliveries[id].Attempts = attempts
+	m.failed[id] = true
+	return nil
+}
+
+type stubBlockRepository struct {
+	domain.BlockRepository
+	blocked bool
+}
+
+func (s *stubBlockRepository) IsBlocked(userID, otherUserID int) (bool, error) {
+	return s.blocked, nil
+}
+
+type stubReactionRepository struct {
+	domain.ReactionRepository
+	added   []domain.Reaction
+	removed []string
+	stored  map[string]bool // By user and entity ID, like the unique index
+}
+
+func (s *stubReactionRepository) AddOrUpdateReaction(userID int, reaction domain.Reaction) (bool, error) {
+	s.added = append(s.added, reaction)
+	if s.stored == nil {
+		s.stored = make(map[string]bool)
+	}
+	key := fmt.Sprintf("%d:%d", userID, reaction.EntityId)
+	created := !s.stored[key]
+	s.stored[key] = true
+	return created, nil
+}
+
+func (s *stubReactionRepository) RemoveReaction(userID, entityType, entityID string) (bool, error) {
+	s.removed = append(s.removed, entityID)
+	key := userID + ":" + entityID
+	removed := s.stored[key]
+	delete(s.stored, key)
+	return removed, nil
+}
+
+// stubPostCounters adds up the changes of post counters.
+type stubPostCounters struct {
+	reactions map[int]int
+}
+
+func (c *stubPostCounters) GetPostCounters(postIDs []int) (map[int]domain.PostCounters, error) {
+	return map[int]domain.PostCounters{}, nil
+}
+
+func (c *stubPostCounters) Add(postID int, field string, delta int) {
+	if field == domain.CounterReactions {
+		c.reactions[postID] += delta
+	}
+}
+
+// fakeRemoteServer plays a remote ActivityPub server with one actor, bob,
+// whose inbox checks the signatures of the activities it receives.
+type fakeRemoteServer struct {
+	*httptest.Server
+	key        *rsa.PrivateKey
+	publicPEM  string
+	localKeys  func(keyID string) (*rsa.PublicKey, error)
+	mu         sync.Mutex
+	received   []domain.Activity
+	inboxFails bool
+}
+
+func newFakeRemoteServer(t *testing.T) *fakeRemoteServer {
+	t.Helper()
+	publicPEM, privatePEM, err := utils.GenerateRSAKeyPair(1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := utils.ParseRSAPrivateKey(privatePEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	remote := &fakeRemoteServer{key: key, publicPEM: publicPEM}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /users/bob", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", domain.ActivityContentType)
+		json.NewEncoder(w).Encode(domain.Actor{
+			ID:                remote.actorURI(),
+			Type:              "Person",
+			PreferredUsername: "bob",
+			Inbox:             remote.URL + "/users/bob/inbox",
+			PublicKey: domain.ActorPublicKey{
+				ID:           remote.keyID(),
+				Owner:        remote.actorURI(),
+				PublicKeyPem: remote.publicPEM,
+			},
+		})
+	})
+	mux.HandleFunc("POST /users/bob/inbox", func(w http.ResponseWriter, r *http.Request) {
+		remote.mu.Lock()
+		defer remote.mu.Unlock()
+		if remote.inboxFails {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		var body bytes.Buffer
+		body.ReadFrom(r.Body)
+		if _, err := utils.VerifyRequest(r, body.Bytes(), remote.localKeys); err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		var activity domain.Activity
+		json.Unmarshal(body.Bytes(), &activity)
+		remote.received = append(remote.received, activity)
+		w.WriteHeader(http.StatusAccepted)
+	})
+	remote.Server = httptest.NewServer(mux)
+	t.Cleanup(remote.Close)
+	return remote
+}
+
+func (f *fakeRemoteServer) actorURI() string { return f.URL + "/users/bob" }
+func (f *fakeRemoteServer) keyID() string    { return f.actorURI() + "#main-key" }
+
+// signedInboxRequest builds an inbox request signed by bob.
+func (f *fakeRemoteServer) signedInboxRequest(t *testing.T, activity interface{}) *http.Request {
+	t.Helper()
+	body, err := json.Marshal(activity)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest(http.MethodPost, testFederationBaseURL+"/users/alice/inbox", bytes.NewReader(body))
+	if err := utils.SignRequest(req, body, f.keyID(), f.key); err != nil {
+		t.Fatal(err)
+	}
+	return req
+}
+
+type federationTestEnv struct {
+	handler    *FederationHandler
+	service    *application.FederationService
+	repo       *memFederationRepository
+	remote     *fakeRemoteServer
+	followers  []*domain.Follower
+	posts      []*domain.CreatePostRequest
+	reactions  *stubReactionRepository
+	counters   *stubPostCounters
+	blockRepo  *stubBlockRepository
+	aliceLocal domain.User
+}
+
+func newFederationTestEnv(t *testing.T) *federationTestEnv {
+	t.Helper()
+	env := &federationTestEnv{
+		repo:      newMemFederationRepository(),
+		remote:    newFakeRemoteServer(t),
+		reactions: &stubReactionRepository{},
+		counters:  &stubPostCounters{reactions: make(map[int]int)},
+		blockRepo: &stubBlockRepository{},
+	}
+	alice := "alice"
+	env.aliceLocal = domain.User{ID: 1, Username: &alice, Status: "active"}
+
+	userRepo := &infrastructure.MockUserRepository{
+		GetUserByUsernameFunc: func(username string) (*domain.User, error) {
+			if username == "alice" {
+				user := env.aliceLocal
+				return &user, nil
+			}
+			return nil, sql.ErrNoRows
+		},
+		GetUserByIDFunc: func(id int) (*domain.User, error) {
+			if id == env.aliceLocal.ID {
+				user := env.aliceLocal
+				return &user, nil
+			}
+			return nil, sql.ErrNoRows
+		},
+	}
+	followerRepo := &infrastructure.MockFollowerRepository{
+		IsFollowingFunc: func(followerID, followeeID int) (bool, error) {
+			for _, f := range env.followers {
+				if f.FollowerID == followerID && f.FolloweeID == followeeID {
+					return true, nil
+				}
+			}
+			return false, nil
+		},
+		AddFollowerFunc: func(follower *domain.Follower) error {
+			env.followers = append(env.followers, follower)
+			return nil
+		},
+		RemoveFollowerFunc: func(follower *domain.Follower) error {
+			kept := env.followers[:0]
+			for _, f := range env.followers {
+				if *f != *follower {
+					kept = append(kept, f)
+				}
+			}
+			env.followers = kept
+			return nil
+		},
+	}
+	public := domain.Public
+	postRepo := &infrastructure.MockPostRepository{
+		GetByIDFunc: func(id int) (*domain.Post, error) {
+			if id == 7 {
+				return &domain.Post{ID: 7, AuthorID: 1, Visibility: &public}, nil
+			}
+			return nil, sql.ErrNoRows
+		},
+		CreateFunc: func(post *domain.CreatePostRequest) (*domain.Post, error) {
+			env.posts = append(env.posts, post)
+			return &domain.Post{ID: 100 + len(env.posts), AuthorID: post.AuthorID}, nil
+		},
+		FindByUserIDFunc: func(userID, otherUserId int, visibilities []domain.PostVisibility, page domain.PageRequest) ([]domain.Post, error) {
+			// A thread: post 8 continues post 7.
+			first := 7
+			return []domain.Post{
+				{ID: 8, AuthorID: 1, Visibility: &public, ContentHTML: "<p>2/2</p>", ContinuesPostID: &first, ThreadRootID: &first},
+				{ID: 7, AuthorID: 1, Visibility: &public, ContentHTML: "<p>1/2</p>"},
+			}, nil
+		},
+	}
+
+	client := infrastructure.NewHTTPFederationClient(5*time.Second, true)
+	env.service = application.NewFederationService(env.repo, client, userRepo, followerRepo, env.blockRepo, postRepo, env.reactions, env.counters, testFederationBaseURL)
+	env.handler = NewFederationHandler(env.service)
+
+	// The fake remote verifies our deliveries against alice's published key.
+	env.remote.localKeys = func(keyID string) (*rsa.PublicKey, error) {
+		actor, err := env.service.GetActor("alice")
+		if err != nil {
+			return nil, err
+		}
+		if keyID != actor.PublicKey.ID {
+			return nil, domain.ErrInvalidSignature
+		}
+		return utils.ParseRSAPublicKey(actor.PublicKey.PublicKeyPem)
+	}
+	return env
+}
+
+func (env *federationTestEnv) postInbox(t *testing.T, req *http.Request) *httptest.ResponseRecorder {
+	t.Helper()
+	rr := httptest.NewRecorder()
+	env.handler.Inbox(rr, req)
+	return rr
+}
+
+func TestFederation_WebFingerAndActor(t *testing.T) {
+	env := newFederationTestEnv(t)
+
+	req := httptest.NewRequest(http.MethodGet, "/.well-known/webfinger?resource=acct:alice@social.example", nil)
+	rr := httptest.NewRecorder()
+	env.handler.WebFinger(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rr.Code)
+	}
+	var webFinger domain.WebFinger
+	json.NewDecoder(rr.Body).Decode(&webFinger)
+	if webFinger.Subject != "acct:alice@social.example" || webFinger.Links[0].Href != testFederationBaseURL+"/users/alice" {
+		t.Errorf("unexpected webfinger response %+v", webFinger)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/.well-known/webfinger?resource=acct:alice@other.example", nil)
+	rr = httptest.NewRecorder()
+	env.handler.WebFinger(rr, req)
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for another domain, got %d", rr.Code)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /users/{username}", env.handler.GetActor)
+	rr = httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/users/alice", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rr.Code)
+	}
+	var actor domain.Actor
+	json.NewDecoder(rr.Body).Decode(&actor)
+	if actor.Inbox != testFederationBaseURL+"/users/alice/inbox" || !strings.Contains(actor.PublicKey.PublicKeyPem, "PUBLIC KEY") {
+		t.Errorf("unexpected actor document %+v", actor)
+	}
+}
+
+func TestFederation_OutboxListsThreads(t *testing.T) {
+	env := newFederationTestEnv(t)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /users/{username}/outbox", env.handler.GetOutbox)
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/users/alice/outbox?limit=20", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	var page struct {
+		OrderedItems []domain.Activity `json:"orderedItems"`
+	}
+	json.NewDecoder(rr.Body).Decode(&page)
+	if len(page.OrderedItems) != 2 {
+		t.Fatalf("expected both posts of the thread, got %d activities", len(page.OrderedItems))
+	}
+	var notes []domain.Note
+	for _, activity := range page.OrderedItems {
+		var note domain.Note
+		json.Unmarshal(activity.Object, &note)
+		notes = append(notes, note)
+	}
+	if notes[0].ID != testFederationBaseURL+"/posts/8" || notes[0].InReplyTo != testFederationBaseURL+"/posts/7" {
+		t.Errorf("expected the continuation to reply to the first post, got %+v", notes[0])
+	}
+	if notes[1].InReplyTo != "" {
+		t.Errorf("expected the first post to reply to nothing, got %q", notes[1].InReplyTo)
+	}
+}
+
+func TestFederation_FollowIsAcceptedAndDelivered(t *testing.T) {
+	env := newFederationTestEnv(t)
+	follow := domain.Activity{
+		ID:     env.remote.URL + "/follows/1",
+		Type:   domain.ActivityFollow,
+		Actor:  env.remote.actorURI(),
+		Object: json.RawMessage(`"` + testFederationBaseURL + `/users/alice"`),
+	}
+
+	rr := env.postInbox(t, env.remote.signedInboxRequest(t, follow))
+	if rr.Code != http.StatusAccepted {
+		t.Fatalf("expected status 202, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	bob, err := env.repo.GetRemoteActorByURI(env.remote.actorURI())
+	if err != nil {
+		t.Fatalf("expected bob to be stored as a remote actor: %v", err)
+	}
+	if len(env.followers) != 1 || env.followers[0].FollowerID != bob.UserID || env.followers[0].FolloweeID != 1 {
+		t.Fatalf("expected bob's shadow user to follow alice, got %+v", env.followers)
+	}
+
+	if err := env.service.DeliverDue(); err != nil {
+		t.Fatalf("delivery failed: %v", err)
+	}
+	if len(env.remote.received) != 1 || env.remote.received[0].Type != domain.ActivityAccept {
+		t.Fatalf("expected the remote inbox to receive an Accept, got %+v", env.remote.received)
+	}
+	if got := env.remote.received[0].ObjectID(); got != follow.ID {
+		t.Errorf("expected Accept of %s, got %s", follow.ID, got)
+	}
+	if len(env.repo.deliveries) != 0 {
+		t.Errorf("expected the delivery to be removed from the queue")
+	}
+
+	undo := domain.Activity{
+		ID:     env.remote.URL + "/follows/1/undo",
+		Type:   domain.ActivityUndo,
+		Actor:  env.remote.actorURI(),
+		Object: mustMarshal(t, follow),
+	}
+	rr = env.postInbox(t, env.remote.signedInboxRequest(t, undo))
+	if rr.Code != http.StatusAccepted {
+		t.Fatalf("expected status 202 for Undo, got %d", rr.Code)
+	}
+	if len(env.followers) != 0 {
+		t.Errorf("expected Undo to remove the follow, got %+v", env.followers)
+	}
+}
+
+func TestFederation_FailedDeliveryIsRetried(t *testing.T) {
+	env := newFederationTestEnv(t)
+	env.remote.inboxFails = true
+
+	follow := domain.Activity{
+		ID:     env.remote.URL + "/follows/2",
+		Type:   domain.ActivityFollow,
+		Actor:  env.remote.actorURI(),
+		Object: json.RawMessage(`"` + testFederationBaseURL + `/users/alice"`),
+	}
+	if rr := env.postInbox(t, env.remote.signedInboxRequest(t, follow)); rr.Code != http.StatusAccepted {
+		t.Fatalf("expected status 202, got %d", rr.Code)
+	}
+
+	before := time.Now()
+	if err := env.service.DeliverDue(); err != nil {
+		t.Fatalf("DeliverDue returned %v", err)
+	}
+	if len(env.repo.deliveries) != 1 {
+		t.Fatalf("expected the delivery to stay queued, got %d", len(env.repo.deliveries))
+	}
+	for id, delivery := range env.repo.deliveries {
+		if delivery.Attempts != 1 || delivery.LastError == "" {
+			t.Errorf("expected one failed attempt to be recorded, got %+v", delivery)
+		}
+		if next := env.repo.nextAt[id]; next.Before(before.Add(time.Minute)) {
+			t.Errorf("expected the retry to be backed off, next attempt at %v", next)
+		}
+	}
+
+	// Nothing is due until the backoff has passed.
+	env.remote.inboxFails = false
+	if err := env.service.DeliverDue(); err != nil {
+		t.Fatal(err)
+	}
+	if len(env.remote.received) != 0 {
+		t.Errorf("expected no delivery before the backoff, got %d", len(env.remote.received))
+	}
+}
+
+func TestFederation_LikeAndCreate(t *testing.T) {
+	env := newFederationTestEnv(t)
+
+	like := domain.Activity{
+		ID:     env.remote.URL + "/likes/1",
+		Type:   domain.ActivityLike,
+		Actor:  env.remote.actorURI(),
+		Object: json.RawMessage(`"` + testFederationBaseURL + `/posts/7"`),
+	}
+	if rr := env.postInbox(t, env.remote.signedInboxRequest(t, like)); rr.Code != http.StatusAccepted {
+		t.Fatalf("expected status 202 for Like, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if len(env.reactions.added) != 1 || env.reactions.added[0].EntityId != 7 {
+		t.Errorf("expected a reaction on post 7, got %+v", env.reactions.added)
+	}
+
+	// A repeated Like, and a repeated Undo, are only counted once.
+	like.ID = env.remote.URL + "/likes/2"
+	env.postInbox(t, env.remote.signedInboxRequest(t, like))
+	if got := env.counters.reactions[7]; got != 1 {
+		t.Errorf("expected post 7 to count one reaction, got %d", got)
+	}
+	for i := 1; i <= 2; i++ {
+		undo := domain.Activity{
+			ID:     fmt.Sprintf("%s/likes/1/undo/%d", env.remote.URL, i),
+			Type:   domain.ActivityUndo,
+			Actor:  env.remote.actorURI(),
+			Object: mustMarshal(t, like),
+		}
+		if rr := env.postInbox(t, env.remote.signedInboxRequest(t, undo)); rr.Code != http.StatusAccepted {
+			t.Fatalf("expected status 202 for Undo, got %d: %s", rr.Code, rr.Body.String())
+		}
+	}
+	if got := env.counters.reactions[7]; got != 0 {
+		t.Errorf("expected the undone like to be counted down once, got %d", got)
+	}
+
+	note := domain.Note{
+		ID:           env.remote.URL + "/notes/1",
+		Type:         "Note",
+		AttributedTo: env.remote.actorURI(),
+		Content:      `<p>Hello from <a href="https://remote.test">afar</a></p><script>alert(1)</script>`,
+		To:           domain.Audience{domain.PublicAudience},
+	}
+	create := domain.Activity{
+		ID:     note.ID + "/activity",
+		Type:   domain.ActivityCreate,
+		Actor:  env.remote.actorURI(),
+		Object: mustMarshal(t, note),
+	}
+	for i := 0; i < 2; i++ {
+		if rr := env.postInbox(t, env.remote.signedInboxRequest(t, create)); rr.Code != http.StatusAccepted {
+			t.Fatalf("expected status 202 for Create, got %d: %s", rr.Code, rr.Body.String())
+		}
+	}
+	if len(env.posts) != 1 {
+		t.Fatalf("expected the note to be stored once, got %d posts", len(env.posts))
+	}
+	if env.posts[0].Content != "Hello from afaralert(1)" || strings.Contains(env.posts[0].ContentHTML, "<script") {
+		t.Errorf("expected remote HTML to be reduced to text, got %q / %q", env.posts[0].Content, env.posts[0].ContentHTML)
+	}
+}
+
+func TestFederation_RejectsBadRequests(t *testing.T) {
+	env := newFederationTestEnv(t)
+	follow := domain.Activity{
+		ID:     env.remote.URL + "/follows/3",
+		Type:   domain.ActivityFollow,
+		Actor:  env.remote.actorURI(),
+		Object: json.RawMessage(`"` + testFederationBaseURL + `/users/alice"`),
+	}
+
+	t.Run("unsigned", func(t *testing.T) {
+		body := mustMarshal(t, follow)
+		req := httptest.NewRequest(http.MethodPost, testFederationBaseURL+"/inbox", bytes.NewReader(body))
+		if rr := env.postInbox(t, req); rr.Code != http.StatusUnauthorized {
+			t.Errorf("expected 401, got %d", rr.Code)
+		}
+	})
+
+	t.Run("tampered", func(t *testing.T) {
+		req := env.remote.signedInboxRequest(t, follow)
+		req.Body = io.NopCloser(strings.NewReader(`{"type":"Follow","actor":"x"}`))
+		if rr := env.postInbox(t, req); rr.Code != http.StatusUnauthorized {
+			t.Errorf("expected 401, got %d", rr.Code)
+		}
+	})
+
+	t.Run("actor does not match signer", func(t *testing.T) {
+		spoofed := follow
+		spoofed.Actor = env.remote.URL + "/users/mallory"
+		if rr := env.postInbox(t, env.remote.signedInboxRequest(t, spoofed)); rr.Code != http.StatusForbidden {
+			t.Errorf("expected 403, got %d", rr.Code)
+		}
+	})
+
+	t.Run("blocked", func(t *testing.T) {
+		env.blockRepo.blocked = true
+		defer func() { env.blockRepo.blocked = false }()
+
+		like := domain.Activity{
+			ID:     env.remote.URL + "/likes/3",
+			Type:   domain.ActivityLike,
+			Actor:  env.remote.actorURI(),
+			Object: json.RawMessage(`"` + testFederationBaseURL + `/posts/7"`),
+		}
+		reply := domain.Note{
+			ID:           env.remote.URL + "/notes/2",
+			Type:         "Note",
+			AttributedTo: env.remote.actorURI(),
+			Content:      "<p>a reply</p>",
+			To:           domain.Audience{domain.PublicAudience},
+			InReplyTo:    testFederationBaseURL + "/posts/7",
+		}
+		create := domain.Activity{
+			ID:     reply.ID + "/activity",
+			Type:   domain.ActivityCreate,
+			Actor:  env.remote.actorURI(),
+			Object: mustMarshal(t, reply),
+		}
+		for _, activity := range []domain.Activity{follow, like, create} {
+			if rr := env.postInbox(t, env.remote.signedInboxRequest(t, activity)); rr.Code != http.StatusForbidden {
+				t.Errorf("expected 403 for %s, got %d", activity.Type, rr.Code)
+			}
+		}
+	})
+
+	if len(env.followers) != 0 {
+		t.Errorf("expected no follows from rejected requests, got %+v", env.followers)
+	}
+	if len(env.reactions.added) != 0 || len(env.posts) != 0 {
+		t.Errorf("expected no likes or replies from rejected requests, got %+v and %+v", env.reactions.added, env.posts)
+	}
+}
+
+func mustMarshal(t *testing.T, v interface{}) json.RawMessage {
+	t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
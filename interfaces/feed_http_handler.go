@@ -0,0 +1,74 @@
+package interfaces
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/bandvov/social-media-go/application"
+	"github.com/bandvov/social-media-go/domain"
+)
+
+type FeedHandler struct {
+	feedService        application.FeedServiceInterface
+	commentService     application.CommentServiceInterface
+	reactionService    application.ReactionServiceInterface
+	bookmarkService    application.BookmarkServiceInterface
+	linkPreviewService application.LinkPreviewServiceInterface
+	analyticsService   application.AnalyticsServiceInterface
+	mentionService     application.MentionServiceInterface
+}
+
+func NewFeedHandler(
+	feedService application.FeedServiceInterface,
+	commentService application.CommentServiceInterface,
+	reactionService application.ReactionServiceInterface,
+	bookmarkService application.BookmarkServiceInterface,
+	linkPreviewService application.LinkPreviewServiceInterface,
+	analyticsService application.AnalyticsServiceInterface,
+	mentionService application.MentionServiceInterface,
+) *FeedHandler {
+	return &FeedHandler{
+		feedService:        feedService,
+		commentService:     commentService,
+		reactionService:    reactionService,
+		bookmarkService:    bookmarkService,
+		linkPreviewService: linkPreviewService,
+		analyticsService:   analyticsService,
+		mentionService:     mentionService,
+	}
+}
+
+// GetFeed returns the home timeline of the authenticated user.
+func (h *FeedHandler) GetFeed(w http.ResponseWriter, r *http.Request) {
+	viewer, ok := viewerFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthenticated", http.StatusBadRequest)
+		return
+	}
+
+	page, err := parsePageRequest(w, r)
+	if err != nil {
+		http.Error(w, "invalid cursor", http.StatusBadRequest)
+		return
+	}
+
+	posts, next, err := h.feedService.GetFeed(viewer.ID, page.After, page.Limit)
+	if err != nil {
+		fmt.Println(err)
+		http.Error(w, "Failed to fetch feed", http.StatusInternalServerError)
+		return
+	}
+
+	if err := enrichPosts(posts, viewer.ID, h.commentService, h.reactionService, h.bookmarkService, h.linkPreviewService, h.mentionService); err != nil {
+		fmt.Println(err)
+		http.Error(w, "Failed to fetch feed", http.StatusInternalServerError)
+		return
+	}
+	posts = application.ApplyContentPreference(viewer, posts)
+
+	go h.analyticsService.RecordViews(viewer.ID, posts, domain.ViewSourceFeed)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(pageResponse(posts, next))
+}
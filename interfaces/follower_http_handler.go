@@ -34,6 +34,9 @@ func (h *FollowerHandler) AddFollower(w http.ResponseWriter, r *http.Request) {
 
 	// Call the service to add the follower
 	err = h.service.AddFollower(userID, followeeID)
+	if writeRateLimited(w, err) {
+		return
+	}
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
@@ -85,18 +88,13 @@ func (h *FollowerHandler) GetFollowers(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	query := r.URL.Query()
-
-	// Parse `limit` and `offset` with default values
-	limit, err := strconv.Atoi(query.Get("limit"))
-	if err != nil || limit <= 0 {
-		limit = 10 // Default limit
+	page, err := parsePageRequest(w, r)
+	if err != nil {
+		http.Error(w, "invalid cursor", http.StatusBadRequest)
+		return
 	}
 
-	offset, err := strconv.Atoi(query.Get("offset"))
-	if err != nil || offset < 0 {
-		offset = 0 // Default offset
-	}
+	query := r.URL.Query()
 
 	// Parse `sort` with default value
 	sort := query.Get("sort")
@@ -104,10 +102,9 @@ func (h *FollowerHandler) GetFollowers(w http.ResponseWriter, r *http.Request) {
 		sort = "desc" // Default sort
 	}
 	search := query.Get("search")
-	orderBy := query.Get("order_by")
 
 	// Call the service to get followers
-	followers, err := h.service.GetFollowers(userIDFromUrl,userId, limit, offset, sort, orderBy, search)
+	followers, next, err := h.service.GetFollowers(userIDFromUrl, userId, page, sort, search)
 	if err != nil {
 		fmt.Println(err)
 		http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -115,9 +112,9 @@ func (h *FollowerHandler) GetFollowers(w http.ResponseWriter, r *http.Request) {
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(followers)
+	json.NewEncoder(w).Encode(pageResponse(followers, next))
 }
-func (h *FollowerHandler) GetFollowees(w http.ResponseWriter, r *http.Request) {	
+func (h *FollowerHandler) GetFollowees(w http.ResponseWriter, r *http.Request) {
 	userId, ok := r.Context().Value(userIDKey).(interface{}).(int)
 	if !ok || userId == 0 {
 		http.Error(w, "Unauthorized", http.StatusForbidden)
@@ -130,18 +127,13 @@ func (h *FollowerHandler) GetFollowees(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	query := r.URL.Query()
-
-	// Parse `limit` and `offset` with default values
-	limit, err := strconv.Atoi(query.Get("limit"))
-	if err != nil || limit <= 0 {
-		limit = 10 // Default limit
+	page, err := parsePageRequest(w, r)
+	if err != nil {
+		http.Error(w, "invalid cursor", http.StatusBadRequest)
+		return
 	}
 
-	offset, err := strconv.Atoi(query.Get("offset"))
-	if err != nil || offset < 0 {
-		offset = 0 // Default offset
-	}
+	query := r.URL.Query()
 
 	// Parse `sort` with default value
 	sort := query.Get("sort")
@@ -149,15 +141,14 @@ func (h *FollowerHandler) GetFollowees(w http.ResponseWriter, r *http.Request) {
 		sort = "desc" // Default sort
 	}
 	search := query.Get("search")
-	orderBy := query.Get("order_by")
 
 	// Call the service to get followers
-	followers, err := h.service.GetFollowees(userIDFromUrl,userId, limit, offset, sort, orderBy, search)
+	followers, next, err := h.service.GetFollowees(userIDFromUrl, userId, page, sort, search)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(followers)
+	json.NewEncoder(w).Encode(pageResponse(followers, next))
 }
@@ -2,11 +2,15 @@ package interfaces
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"time"
 
+	"github.com/bandvov/social-media-go/application"
+	"github.com/bandvov/social-media-go/domain"
 	"github.com/bandvov/social-media-go/utils"
 )
 
@@ -14,8 +18,10 @@ import (
 type contextKey string
 
 const (
-	userIDKey  contextKey = "userID"
-	isAdminKey contextKey = "isAdmin"
+	userIDKey           contextKey = "userID"
+	isAdminKey          contextKey = "isAdmin"
+	roleKey             contextKey = "role"
+	sensitiveContentKey contextKey = "sensitiveContent"
 )
 
 func AdminOnlyMiddleware(next http.Handler) http.Handler {
@@ -78,12 +84,18 @@ func (h *UserHTTPHandler) AuthMiddleware(next http.HandlerFunc) http.HandlerFunc
 			return
 		}
 		fmt.Println("here4========================")
+		if user.Status == domain.UserStatusBanned {
+			http.Error(w, "Forbidden: user is banned", http.StatusForbidden)
+			return
+		}
 
 		isAdmin := user.Role == "admin"
 
 		// Add userID and isAdmin to context
 		ctx := context.WithValue(r.Context(), userIDKey, user.ID)
 		ctx = context.WithValue(ctx, isAdminKey, isAdmin)
+		ctx = context.WithValue(ctx, roleKey, user.Role)
+		ctx = context.WithValue(ctx, sensitiveContentKey, user.SensitiveContent)
 		// Call the next handler with updated context
 		next(w, r.WithContext(ctx))
 	}
@@ -122,3 +134,32 @@ func CorsMiddleware(next http.Handler) http.Handler {
 		next.ServeHTTP(w, r)
 	})
 }
+
+// viewerFromContext returns the user authenticated by AuthMiddleware.
+func viewerFromContext(ctx context.Context) (application.Viewer, bool) {
+	userID, ok := ctx.Value(userIDKey).(int)
+	if !ok || userID == 0 {
+		return application.Viewer{}, false
+	}
+	isAdmin, _ := ctx.Value(isAdminKey).(bool)
+	role, _ := ctx.Value(roleKey).(string)
+	sensitiveContent, _ := ctx.Value(sensitiveContentKey).(string)
+	return application.Viewer{
+		ID:               userID,
+		IsAdmin:          isAdmin,
+		IsModerator:      isAdmin || role == "moderator",
+		SensitiveContent: sensitiveContent,
+	}, true
+}
+
+// accessErrorStatus maps errors of the visibility policy to HTTP statuses.
+func accessErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return http.StatusNotFound
+	case errors.Is(err, application.ErrForbidden):
+		return http.StatusForbidden
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,73 @@
+package interfaces
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/bandvov/social-media-go/domain"
+	"github.com/bandvov/social-media-go/utils"
+)
+
+const (
+	defaultPageLimit = 10
+	maxPageLimit     = 100
+)
+
+// parsePageRequest reads `limit` and `cursor` from the query string. The
+// legacy `page` and `offset` parameters are still honoured when no cursor is
+// given, but the response is marked with a Deprecation header.
+func parsePageRequest(w http.ResponseWriter, r *http.Request) (domain.PageRequest, error) {
+	query := r.URL.Query()
+
+	limit, err := strconv.Atoi(query.Get("limit"))
+	if err != nil || limit <= 0 {
+		limit = defaultPageLimit
+	}
+	if limit > maxPageLimit {
+		limit = maxPageLimit
+	}
+	page := domain.PageRequest{Limit: limit}
+
+	if token := query.Get("cursor"); token != "" {
+		createdAt, id, score, err := utils.DecodeScoreCursor(token)
+		if err != nil {
+			return page, err
+		}
+		page.After = &domain.Cursor{CreatedAt: createdAt, ID: id, Score: score}
+		return page, nil
+	}
+
+	if query.Has("page") || query.Has("offset") {
+		w.Header().Set("Deprecation", "true")
+		w.Header().Set("Warning", `299 - "page and offset are deprecated, use cursor"`)
+
+		if offset, err := strconv.Atoi(query.Get("offset")); err == nil && offset > 0 {
+			page.Offset = offset
+		} else if p, err := strconv.Atoi(query.Get("page")); err == nil && p > 1 {
+			page.Offset = (p - 1) * limit
+		}
+	}
+
+	return page, nil
+}
+
+// encodeNextCursor returns the opaque token for the next page, or an empty
+// string when there is none.
+func encodeNextCursor(next *domain.Cursor) string {
+	if next == nil {
+		return ""
+	}
+	if next.Score != nil {
+		return utils.EncodeScoreCursor(*next.Score, next.CreatedAt, next.ID)
+	}
+	return utils.EncodeCursor(next.CreatedAt, next.ID)
+}
+
+// pageResponse is the envelope shared by every paginated list endpoint.
+func pageResponse(data interface{}, next *domain.Cursor) map[string]interface{} {
+	return map[string]interface{}{
+		"data":        data,
+		"next_cursor": encodeNextCursor(next),
+		"hasMore":     next != nil,
+	}
+}
@@ -0,0 +1,94 @@
+package interfaces
+
+import (
+	"github.com/bandvov/social-media-go/application"
+	"github.com/bandvov/social-media-go/domain"
+	"golang.org/x/sync/errgroup"
+)
+
+// enrichPosts fills in reactions, counters, link previews, mentions and the
+// viewer's bookmark flag for a page of posts.
+func enrichPosts(
+	posts []domain.Post,
+	viewerID int,
+	commentService application.CommentServiceInterface,
+	reactionService application.ReactionServiceInterface,
+	bookmarkService application.BookmarkServiceInterface,
+	linkPreviewService application.LinkPreviewServiceInterface,
+	mentionService application.MentionServiceInterface,
+) error {
+	if len(posts) == 0 {
+		return nil
+	}
+
+	postIDs := make([]int, 0, len(posts))
+	contents := make(map[int]string, len(posts))
+	for _, post := range posts {
+		postIDs = append(postIDs, post.ID)
+		contents[post.ID] = post.Content
+	}
+
+	var (
+		reactionMap   map[int][]domain.Reaction
+		bookmarkedMap map[int]bool
+		previewMap    map[int][]domain.LinkPreview
+		mentionMap    map[int][]domain.Mention
+		eg            errgroup.Group
+	)
+	commentsCountsMap := make(map[int]domain.CommentCount)
+	reactionsCountsMap := make(map[int]domain.Reaction)
+
+	eg.Go(func() error {
+		counts, err := commentService.GetCommentsAndRepliesCount(postIDs)
+		for _, count := range counts {
+			commentsCountsMap[count.EntityID] = count
+		}
+		return err
+	})
+
+	eg.Go(func() error {
+		counts, err := reactionService.GetReactionsCount(domain.ReactionEntityPost, postIDs)
+		for _, count := range counts {
+			reactionsCountsMap[count.EntityId] = count
+		}
+		return err
+	})
+
+	eg.Go(func() error {
+		var err error
+		reactionMap, err = reactionService.GetReactions(domain.ReactionEntityPost, postIDs)
+		return err
+	})
+
+	eg.Go(func() error {
+		var err error
+		bookmarkedMap, err = bookmarkService.GetBookmarkedPostIDs(viewerID, postIDs)
+		return err
+	})
+
+	eg.Go(func() error {
+		var err error
+		previewMap, err = linkPreviewService.GetPreviews(domain.LinkEntityPost, postIDs)
+		return err
+	})
+
+	eg.Go(func() error {
+		var err error
+		mentionMap, err = mentionService.GetMentions(domain.MentionEntityPost, contents)
+		return err
+	})
+
+	if err := eg.Wait(); err != nil {
+		return err
+	}
+
+	for i, post := range posts {
+		posts[i].Reactions = reactionMap[post.ID]
+		posts[i].TotalCommentsCount = commentsCountsMap[post.ID].CommentCount + commentsCountsMap[post.ID].ReplyCount
+		posts[i].TotaReactionslCount = reactionsCountsMap[post.ID].Count
+		posts[i].Bookmarked = bookmarkedMap[post.ID]
+		posts[i].LinkPreviews = previewMap[post.ID]
+		posts[i].Mentions = mentionMap[post.ID]
+	}
+	return nil
+}
@@ -2,6 +2,7 @@ package interfaces
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
@@ -9,14 +10,17 @@ import (
 
 	"github.com/bandvov/social-media-go/application"
 	"github.com/bandvov/social-media-go/domain"
-	"golang.org/x/sync/errgroup"
 )
 
 type PostHTTPHandler struct {
-	postService     application.PostServiceInterface
-	commentService  application.CommentServiceInterface
-	userService     application.UserServiceInterface
-	reactionService application.ReactionServiceInterface
+	postService        application.PostServiceInterface
+	commentService     application.CommentServiceInterface
+	userService        application.UserServiceInterface
+	reactionService    application.ReactionServiceInterface
+	bookmarkService    application.BookmarkServiceInterface
+	linkPreviewService application.LinkPreviewServiceInterface
+	analyticsService   application.AnalyticsServiceInterface
+	mentionService     application.MentionServiceInterface
 }
 
 func NewPostHTTPHandler(
@@ -24,12 +28,20 @@ func NewPostHTTPHandler(
 	commentService application.CommentServiceInterface,
 	userService application.UserServiceInterface,
 	reactionService application.ReactionServiceInterface,
+	bookmarkService application.BookmarkServiceInterface,
+	linkPreviewService application.LinkPreviewServiceInterface,
+	analyticsService application.AnalyticsServiceInterface,
+	mentionService application.MentionServiceInterface,
 ) *PostHTTPHandler {
 	return &PostHTTPHandler{
-		postService:     postService,
-		commentService:  commentService,
-		userService:     userService,
-		reactionService: reactionService}
+		postService:        postService,
+		commentService:     commentService,
+		userService:        userService,
+		reactionService:    reactionService,
+		bookmarkService:    bookmarkService,
+		linkPreviewService: linkPreviewService,
+		analyticsService:   analyticsService,
+		mentionService:     mentionService}
 }
 
 func (p *PostHTTPHandler) CreatePost(w http.ResponseWriter, r *http.Request) {
@@ -53,22 +65,55 @@ func (p *PostHTTPHandler) CreatePost(w http.ResponseWriter, r *http.Request) {
 
 	newPost.Data.AuthorID = authorID
 
-	err := p.postService.CreatePost(&newPost.Data)
+	post, err := p.postService.CreatePost(&newPost.Data)
+	if writeRateLimited(w, err) {
+		return
+	}
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
+	if post.HeldForReview {
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(map[string]interface{}{"message": "Post held for review", "id": post.ID, "held_for_review": true})
+		return
+	}
+
 	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(map[string]string{"message": "Post created successfully"})
+	json.NewEncoder(w).Encode(map[string]interface{}{"message": "Post created successfully", "id": post.ID})
 }
 
 func (p *PostHTTPHandler) DeletePost(w http.ResponseWriter, r *http.Request) {
+	viewer, ok := viewerFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthenticated", http.StatusBadRequest)
+		return
+	}
+
+	postID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "invalid post ID", http.StatusBadRequest)
+		return
+	}
+
+	err = p.postService.DeletePost(postID, viewer)
+	if err != nil {
+		fmt.Println(err)
+		http.Error(w, "error deleting post", accessErrorStatus(err))
+		return
+	}
 
 	json.NewEncoder(w).Encode(map[string]string{"message": "post deleted successfully"})
 }
 
 func (p *PostHTTPHandler) UpdatePost(w http.ResponseWriter, r *http.Request) {
+	viewer, ok := viewerFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthenticated", http.StatusBadRequest)
+		return
+	}
+
 	id := r.PathValue("id")
 	postID, err := strconv.Atoi(id)
 	if err != nil {
@@ -83,25 +128,69 @@ func (p *PostHTTPHandler) UpdatePost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = p.postService.UpdatePost(postID, &domain.Post{
+	err = p.postService.UpdatePost(postID, viewer, &domain.Post{
 		Content: post.Content, Visibility: &post.Visibility, Tags: post.Tags, Pinned: post.Pinned,
+		ContentWarning: post.ContentWarning, Sensitive: post.Sensitive,
 	})
 
 	if err != nil {
-		http.Error(w, "error updating post: "+err.Error(), http.StatusInternalServerError)
+		if errors.Is(err, domain.ErrContentWarningTooLong) || errors.Is(err, domain.ErrContentRejected) || errors.Is(err, domain.ErrThreadVisibility) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, "error updating post: "+err.Error(), accessErrorStatus(err))
 		return
 	}
 	json.NewEncoder(w).Encode(map[string]string{"message": "post updated successfully"})
 }
 
-func (p *PostHTTPHandler) GetPost(w http.ResponseWriter, r *http.Request) {
-	userID, ok := r.Context().Value(userIDKey).(interface{}).(int)
-	if !ok || userID == 0 {
+// SetContentWarning lets a moderator force a content warning onto a post.
+func (p *PostHTTPHandler) SetContentWarning(w http.ResponseWriter, r *http.Request) {
+	viewer, ok := viewerFromContext(r.Context())
+	if !ok {
 		http.Error(w, "unauthenticated", http.StatusBadRequest)
 		return
 	}
 
-	isAdmin := r.Context().Value(isAdminKey).(bool)
+	postID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "invalid post ID", http.StatusBadRequest)
+		return
+	}
+
+	var req struct {
+		ContentWarning string `json:"content_warning"`
+		Sensitive      bool   `json:"sensitive"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.ContentWarning == "" && !req.Sensitive {
+		http.Error(w, "content warning or sensitive flag is required", http.StatusBadRequest)
+		return
+	}
+
+	err = p.postService.SetContentWarning(postID, viewer, req.ContentWarning, req.Sensitive)
+	if err != nil {
+		if errors.Is(err, domain.ErrContentWarningTooLong) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		fmt.Println(err)
+		http.Error(w, "error setting content warning", accessErrorStatus(err))
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"message": "content warning set successfully"})
+}
+
+func (p *PostHTTPHandler) GetPost(w http.ResponseWriter, r *http.Request) {
+	viewer, ok := viewerFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthenticated", http.StatusBadRequest)
+		return
+	}
 
 	id := r.PathValue("id")
 	postID, err := strconv.Atoi(id)
@@ -110,22 +199,72 @@ func (p *PostHTTPHandler) GetPost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	post, err := p.postService.GetPostByID(postID)
+	post, err := p.postService.GetPostByID(postID, viewer)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		if status := accessErrorStatus(err); status != http.StatusInternalServerError {
+			http.Error(w, http.StatusText(status), status)
+			return
+		}
+		fmt.Println(err)
+		http.Error(w, "Failed to fetch post", http.StatusInternalServerError)
 		return
 	}
-	fmt.Println("here")
-	if !isAdmin || (*post.Visibility == domain.Private && post.AuthorID != userID) {
-		fmt.Println("here1")
-		http.Error(w, "Access forbidden", http.StatusForbidden)
+
+	posts := []domain.Post{*post}
+	if err := enrichPosts(posts, viewer.ID, p.commentService, p.reactionService, p.bookmarkService, p.linkPreviewService, p.mentionService); err != nil {
+		fmt.Println(err)
+		http.Error(w, "Failed to fetch post", http.StatusInternalServerError)
 		return
 	}
-	fmt.Println("here2")
+	post = &posts[0]
+	application.ApplyContentPreferenceToPost(viewer, post)
+
+	go p.analyticsService.RecordViews(viewer.ID, posts, domain.ViewSourcePost)
+
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(post)
 }
 
+// GetThread returns the whole thread a post belongs to, in order.
+func (p *PostHTTPHandler) GetThread(w http.ResponseWriter, r *http.Request) {
+	viewer, ok := viewerFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthenticated", http.StatusBadRequest)
+		return
+	}
+
+	postID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "invalid post ID", http.StatusBadRequest)
+		return
+	}
+
+	posts, err := p.postService.GetThread(postID, viewer)
+	if err != nil {
+		if status := accessErrorStatus(err); status != http.StatusInternalServerError {
+			http.Error(w, http.StatusText(status), status)
+			return
+		}
+		fmt.Println(err)
+		http.Error(w, "Failed to fetch thread", http.StatusInternalServerError)
+		return
+	}
+
+	if err := enrichPosts(posts, viewer.ID, p.commentService, p.reactionService, p.bookmarkService, p.linkPreviewService, p.mentionService); err != nil {
+		fmt.Println(err)
+		http.Error(w, "Failed to fetch thread", http.StatusInternalServerError)
+		return
+	}
+	for i := range posts {
+		application.ApplyContentPreferenceToPost(viewer, &posts[i])
+	}
+
+	go p.analyticsService.RecordViews(viewer.ID, posts, domain.ViewSourcePost)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"data": posts})
+}
+
 // func (h *PostHTTPHandler) GetPostsByUser(w http.ResponseWriter, r *http.Request) {
 // 	userID, ok := r.Context().Value(userIDKey).(interface{}).(int)
 // 	if !ok || userID == 0 {
@@ -200,8 +339,8 @@ func (p *PostHTTPHandler) GetPost(w http.ResponseWriter, r *http.Request) {
 // }
 
 func (h *PostHTTPHandler) GetPostsByUser(w http.ResponseWriter, r *http.Request) {
-	userID, ok := r.Context().Value(userIDKey).(interface{}).(int)
-	if !ok || userID == 0 {
+	viewer, ok := viewerFromContext(r.Context())
+	if !ok {
 		http.Error(w, "unauthenticated", http.StatusBadRequest)
 		return
 	}
@@ -213,84 +352,27 @@ func (h *PostHTTPHandler) GetPostsByUser(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	query := r.URL.Query()
-
-	// Parse `limit` and `offset` with default values
-	page, err := strconv.Atoi(query.Get("page"))
-	if err != nil || page < 1 {
-		page = 1 // Default offset
-	}
-
-	limit, err := strconv.Atoi(query.Get("limit"))
-	if err != nil || limit <= 0 {
-		limit = 10 // Default limit
+	page, err := parsePageRequest(w, r)
+	if err != nil {
+		http.Error(w, "invalid cursor", http.StatusBadRequest)
+		return
 	}
 
-	offset := (page - 1) * limit
-
-	posts, postIDs, err := h.postService.GetPostsByUser(authorIDFromUrl, offset, limit)
+	posts, next, _, err := h.postService.GetPostsByUser(authorIDFromUrl, viewer, page)
 	if err != nil || len(posts) == 0 {
 		http.Error(w, "invalid user ID", http.StatusBadRequest)
 		return
 	}
 
-	var (
-		reactionMap map[int][]domain.Reaction
-		postsCount  int
-		eg          errgroup.Group
-	)
-	commentsCountsMap := make(map[int]domain.CommentCount)
-	reactionsCountsMap := make(map[int]domain.Reaction)
-
-	eg.Go(func() error {
-		counts, err := h.commentService.GetCommentsAndRepliesCount(postIDs)
-		for _, count := range counts {
-			commentsCountsMap[count.EntityID] = count
-		}
-		return err
-	})
-
-	eg.Go(func() error {
-		counts, err := h.reactionService.GetReactionsCount(postIDs)
-		fmt.Println(counts)
-		for _, count := range counts {
-			reactionsCountsMap[count.EntityId] = count
-		}
-		return err
-	})
-
-	eg.Go(func() error {
-		reactionMap, err = h.reactionService.GetReactions(postIDs)
-		return err
-	})
-
-	// Second task: Fetch posts count
-	eg.Go(func() error {
-		var err error
-		postsCount, err = h.postService.GetCountPostsByUser(authorIDFromUrl)
-		if err != nil {
-			return err
-		}
-		return nil
-	})
-
-	if err := eg.Wait(); err != nil {
+	if err := enrichPosts(posts, viewer.ID, h.commentService, h.reactionService, h.bookmarkService, h.linkPreviewService, h.mentionService); err != nil {
 		fmt.Println(err)
 		http.Error(w, "Failed to fetch posts", http.StatusBadRequest)
 		return
 	}
 
-	for i, post := range posts {
-		posts[i].Reactions = reactionMap[post.ID]
-		posts[i].TotalCommentsCount = commentsCountsMap[post.ID].CommentCount + commentsCountsMap[post.ID].CommentCount
-		posts[i].TotaReactionslCount = reactionsCountsMap[post.ID].Count
-	}
+	posts = application.ApplyContentPreference(viewer, posts)
 
-	response := map[string]interface{}{
-		"data":    posts,
-		"hasMore": postsCount > offset+limit,
-	}
 	fmt.Println(time.Since(s))
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+	json.NewEncoder(w).Encode(pageResponse(posts, next))
 }
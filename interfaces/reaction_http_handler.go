@@ -2,7 +2,10 @@ package interfaces
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
 
 	"github.com/bandvov/social-media-go/application"
 	"github.com/bandvov/social-media-go/domain"
@@ -17,8 +20,8 @@ func NewReactionHandler(service application.ReactionServiceInterface) *ReactionH
 }
 
 func (h *ReactionHandler) AddOrUpdateReaction(w http.ResponseWriter, r *http.Request) {
-	userId, ok := r.Context().Value(userIDKey).(interface{}).(int)
-	if !ok || userId == 0 {
+	viewer, ok := viewerFromContext(r.Context())
+	if !ok {
 		http.Error(w, "Unauthorized", http.StatusForbidden)
 		return
 	}
@@ -28,8 +31,13 @@ func (h *ReactionHandler) AddOrUpdateReaction(w http.ResponseWriter, r *http.Req
 		return
 	}
 
-	if err := h.service.AddOrUpdateReaction(userId, reaction); err != nil {
-		http.Error(w, "Failed to add or update reaction", http.StatusInternalServerError)
+	if err := h.service.AddOrUpdateReaction(viewer, reaction); err != nil {
+		fmt.Println(err)
+		if errors.Is(err, domain.ErrInvalidReactionEntity) || errors.Is(err, domain.ErrUnknownReactionType) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, "Failed to add or update reaction", accessErrorStatus(err))
 		return
 	}
 
@@ -37,18 +45,71 @@ func (h *ReactionHandler) AddOrUpdateReaction(w http.ResponseWriter, r *http.Req
 }
 
 func (h *ReactionHandler) RemoveReaction(w http.ResponseWriter, r *http.Request) {
-	userID := r.URL.Query().Get("user_id")
+	viewer, ok := viewerFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusForbidden)
+		return
+	}
+	// Users can only remove their own reactions.
+	userID := strconv.Itoa(viewer.ID)
 	entityID := r.URL.Query().Get("entity_id")
+	entityType := r.URL.Query().Get("entity_type")
 
-	if userID == "" || entityID == "" {
+	if entityID == "" {
 		http.Error(w, "Missing parameters", http.StatusBadRequest)
 		return
 	}
 
-	if err := h.service.RemoveReaction(userID, entityID); err != nil {
+	if err := h.service.RemoveReaction(userID, entityType, entityID); err != nil {
+		if errors.Is(err, domain.ErrInvalidReactionEntity) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 		http.Error(w, "Failed to remove reaction", http.StatusInternalServerError)
 		return
 	}
 
 	w.WriteHeader(http.StatusOK)
 }
+
+// GetPostReactors lists who reacted to a post, optionally only with the
+// reaction type given as `type`.
+func (h *ReactionHandler) GetPostReactors(w http.ResponseWriter, r *http.Request) {
+	h.getReactors(w, r, domain.ReactionEntityPost)
+}
+
+// GetCommentReactors lists who reacted to a comment, optionally only with the
+// reaction type given as `type`.
+func (h *ReactionHandler) GetCommentReactors(w http.ResponseWriter, r *http.Request) {
+	h.getReactors(w, r, domain.ReactionEntityComment)
+}
+
+func (h *ReactionHandler) getReactors(w http.ResponseWriter, r *http.Request, entityType string) {
+	viewer, ok := viewerFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthenticated", http.StatusBadRequest)
+		return
+	}
+
+	entityID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "invalid "+entityType+" ID", http.StatusBadRequest)
+		return
+	}
+
+	page, err := parsePageRequest(w, r)
+	if err != nil {
+		http.Error(w, "invalid cursor", http.StatusBadRequest)
+		return
+	}
+
+	reactors, next, err := h.service.GetReactors(viewer, entityType, entityID, r.URL.Query().Get("type"), page)
+	if err != nil {
+		fmt.Println(err)
+		http.Error(w, "Failed to fetch reactions", accessErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(pageResponse(reactors, next))
+}
@@ -0,0 +1,133 @@
+package interfaces
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/bandvov/social-media-go/application"
+	"github.com/bandvov/social-media-go/domain"
+)
+
+// ReactionTypeHandler serves the catalog of reaction types and its admin
+// endpoints.
+type ReactionTypeHandler struct {
+	service application.ReactionTypeServiceInterface
+}
+
+func NewReactionTypeHandler(service application.ReactionTypeServiceInterface) *ReactionTypeHandler {
+	return &ReactionTypeHandler{service: service}
+}
+
+func writeReactionTypeError(w http.ResponseWriter, err error, message string) {
+	switch {
+	case errors.Is(err, domain.ErrInvalidReactionType):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, domain.ErrReactionTypeExists), errors.Is(err, domain.ErrReactionTypeInUse):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		fmt.Println(err)
+		http.Error(w, message, accessErrorStatus(err))
+	}
+}
+
+// ListReactionTypes returns the reaction types users can pick from.
+func (h *ReactionTypeHandler) ListReactionTypes(w http.ResponseWriter, r *http.Request) {
+	reactionTypes, err := h.service.ListReactionTypes()
+	if err != nil {
+		writeReactionTypeError(w, err, "Failed to fetch reaction types")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"data": reactionTypes})
+}
+
+// ListAllReactionTypes returns every reaction type, disabled ones included.
+func (h *ReactionTypeHandler) ListAllReactionTypes(w http.ResponseWriter, r *http.Request) {
+	reactionTypes, err := h.service.ListAllReactionTypes()
+	if err != nil {
+		writeReactionTypeError(w, err, "Failed to fetch reaction types")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"data": reactionTypes})
+}
+
+func (h *ReactionTypeHandler) GetReactionType(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "invalid reaction type ID", http.StatusBadRequest)
+		return
+	}
+
+	reactionType, err := h.service.GetReactionType(id)
+	if err != nil {
+		writeReactionTypeError(w, err, "Failed to fetch reaction type")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"data": reactionType})
+}
+
+func (h *ReactionTypeHandler) CreateReactionType(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Data domain.ReactionTypeRequest `json:"data"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	reactionType, err := h.service.CreateReactionType(&req.Data)
+	if err != nil {
+		writeReactionTypeError(w, err, "Failed to create reaction type")
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{"message": "Reaction type created successfully", "data": reactionType})
+}
+
+func (h *ReactionTypeHandler) UpdateReactionType(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "invalid reaction type ID", http.StatusBadRequest)
+		return
+	}
+
+	var req struct {
+		Data domain.ReactionTypeRequest `json:"data"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	reactionType, err := h.service.UpdateReactionType(id, &req.Data)
+	if err != nil {
+		writeReactionTypeError(w, err, "Failed to update reaction type")
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{"message": "Reaction type updated successfully", "data": reactionType})
+}
+
+func (h *ReactionTypeHandler) DeleteReactionType(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "invalid reaction type ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.DeleteReactionType(id); err != nil {
+		writeReactionTypeError(w, err, "Failed to delete reaction type")
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"message": "reaction type deleted successfully"})
+}
@@ -0,0 +1,223 @@
+package interfaces
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/bandvov/social-media-go/application"
+	"github.com/bandvov/social-media-go/domain"
+)
+
+type ReportHandler struct {
+	service application.ReportServiceInterface
+}
+
+func NewReportHandler(service application.ReportServiceInterface) *ReportHandler {
+	return &ReportHandler{service: service}
+}
+
+// reportErrorStatus maps report errors to HTTP statuses, falling back to
+// the visibility policy errors.
+func reportErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, domain.ErrInvalidReportTarget),
+		errors.Is(err, domain.ErrInvalidReportReason),
+		errors.Is(err, domain.ErrReportDetailsTooLong),
+		errors.Is(err, domain.ErrReportDetailsRequired),
+		errors.Is(err, domain.ErrInvalidResolution),
+		errors.Is(err, domain.ErrCannotReportOwnContent),
+		errors.Is(err, domain.ErrResolutionNotApplicable),
+		errors.Is(err, application.ErrInvalidAssignee):
+		return http.StatusBadRequest
+	case errors.Is(err, domain.ErrDuplicateReport),
+		errors.Is(err, domain.ErrReportAlreadyResolved):
+		return http.StatusConflict
+	default:
+		return accessErrorStatus(err)
+	}
+}
+
+func writeReportError(w http.ResponseWriter, err error, message string) {
+	status := reportErrorStatus(err)
+	if status == http.StatusBadRequest || status == http.StatusConflict {
+		http.Error(w, err.Error(), status)
+		return
+	}
+	fmt.Println(err)
+	http.Error(w, message, status)
+}
+
+func (h *ReportHandler) CreateReport(w http.ResponseWriter, r *http.Request) {
+	viewer, ok := viewerFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthenticated", http.StatusBadRequest)
+		return
+	}
+
+	var req struct {
+		Data domain.CreateReportRequest `json:"data"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	report, err := h.service.CreateReport(viewer, &req.Data)
+	if err != nil {
+		writeReportError(w, err, "Failed to create report")
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{"message": "Report submitted successfully", "data": report})
+}
+
+// GetQueue lists reports for moderators, oldest first. By default only open
+// reports are listed; `status=all` lists resolved ones too. `assignee` is
+// `me`, `unassigned` or a user ID.
+func (h *ReportHandler) GetQueue(w http.ResponseWriter, r *http.Request) {
+	viewer, ok := viewerFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthenticated", http.StatusBadRequest)
+		return
+	}
+
+	query := r.URL.Query()
+	filter := domain.ReportQueueFilter{
+		Status:     domain.ReportStatus(query.Get("status")),
+		TargetType: domain.ReportTargetType(query.Get("target_type")),
+	}
+	switch filter.Status {
+	case "":
+		filter.Status = domain.ReportStatusOpen
+	case "all":
+		filter.Status = ""
+	case domain.ReportStatusOpen, domain.ReportStatusResolved:
+	default:
+		http.Error(w, "invalid status", http.StatusBadRequest)
+		return
+	}
+	if filter.TargetType != "" && !domain.IsValidReportTarget(filter.TargetType) {
+		http.Error(w, "invalid target type", http.StatusBadRequest)
+		return
+	}
+	switch assignee := query.Get("assignee"); assignee {
+	case "":
+	case "me":
+		filter.AssigneeID = viewer.ID
+	case "unassigned":
+		filter.AssigneeID = -1
+	default:
+		id, err := strconv.Atoi(assignee)
+		if err != nil || id <= 0 {
+			http.Error(w, "invalid assignee", http.StatusBadRequest)
+			return
+		}
+		filter.AssigneeID = id
+	}
+
+	page, err := parsePageRequest(w, r)
+	if err != nil {
+		http.Error(w, "invalid cursor", http.StatusBadRequest)
+		return
+	}
+
+	reports, next, err := h.service.ListQueue(viewer, filter, page)
+	if err != nil {
+		writeReportError(w, err, "Failed to fetch reports")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(pageResponse(reports, next))
+}
+
+func (h *ReportHandler) GetReport(w http.ResponseWriter, r *http.Request) {
+	viewer, ok := viewerFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthenticated", http.StatusBadRequest)
+		return
+	}
+
+	reportID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "invalid report ID", http.StatusBadRequest)
+		return
+	}
+
+	report, err := h.service.GetReport(viewer, reportID)
+	if err != nil {
+		writeReportError(w, err, "Failed to fetch report")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"data": report})
+}
+
+// AssignReport assigns a report to a moderator. A missing or null
+// assignee_id puts the report back in the unassigned queue.
+func (h *ReportHandler) AssignReport(w http.ResponseWriter, r *http.Request) {
+	viewer, ok := viewerFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthenticated", http.StatusBadRequest)
+		return
+	}
+
+	reportID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "invalid report ID", http.StatusBadRequest)
+		return
+	}
+
+	var req struct {
+		Data struct {
+			AssigneeID *int `json:"assignee_id"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.AssignReport(viewer, reportID, req.Data.AssigneeID); err != nil {
+		writeReportError(w, err, "Failed to assign report")
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"message": "report assigned successfully"})
+}
+
+func (h *ReportHandler) ResolveReport(w http.ResponseWriter, r *http.Request) {
+	viewer, ok := viewerFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthenticated", http.StatusBadRequest)
+		return
+	}
+
+	reportID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "invalid report ID", http.StatusBadRequest)
+		return
+	}
+
+	var req struct {
+		Data domain.ResolveReportRequest `json:"data"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	resolved, err := h.service.ResolveReport(viewer, reportID, &req.Data)
+	if err != nil {
+		writeReportError(w, err, "Failed to resolve report")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"message": "report resolved successfully", "data": resolved})
+}
@@ -0,0 +1,86 @@
+package interfaces
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/bandvov/social-media-go/application"
+	"github.com/bandvov/social-media-go/domain"
+)
+
+type SearchHandler struct {
+	service application.SearchServiceInterface
+}
+
+func NewSearchHandler(service application.SearchServiceInterface) *SearchHandler {
+	return &SearchHandler{service: service}
+}
+
+// Search handles GET /api/search?q=&type=posts|users|tags. Results are
+// ranked, so they are paged with `limit` and `offset` instead of a cursor.
+func (h *SearchHandler) Search(w http.ResponseWriter, r *http.Request) {
+	viewer, ok := viewerFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthenticated", http.StatusBadRequest)
+		return
+	}
+
+	query := r.URL.Query()
+
+	limit, err := strconv.Atoi(query.Get("limit"))
+	if err != nil || limit <= 0 {
+		limit = defaultPageLimit
+	}
+	if limit > maxPageLimit {
+		limit = maxPageLimit
+	}
+	offset, err := strconv.Atoi(query.Get("offset"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+
+	searchType := domain.SearchType(query.Get("type"))
+	if searchType == "" {
+		searchType = domain.SearchPosts
+	}
+
+	search := domain.SearchQuery{
+		Query:  strings.TrimSpace(query.Get("q")),
+		Type:   searchType,
+		Limit:  limit,
+		Offset: offset,
+	}
+	if err := search.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var (
+		results interface{}
+		hasMore bool
+	)
+	switch search.Type {
+	case domain.SearchUsers:
+		results, hasMore, err = h.service.SearchUsers(viewer.ID, search)
+	case domain.SearchTags:
+		results, hasMore, err = h.service.SearchTags(search)
+	default:
+		results, hasMore, err = h.service.SearchPosts(viewer, search)
+	}
+	if err != nil {
+		fmt.Println(err)
+		http.Error(w, "Failed to search", http.StatusInternalServerError)
+		return
+	}
+
+	response := map[string]interface{}{
+		"data":    results,
+		"hasMore": hasMore,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,127 @@
+package interfaces
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/bandvov/social-media-go/application"
+	"github.com/bandvov/social-media-go/domain"
+)
+
+// writeRateLimited answers 429 with a Retry-After header when the spam
+// filter throttled the request, and reports whether it did.
+func writeRateLimited(w http.ResponseWriter, err error) bool {
+	var rateLimited *domain.RateLimitError
+	if !errors.As(err, &rateLimited) {
+		return false
+	}
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(rateLimited.RetryAfter.Seconds()))))
+	http.Error(w, err.Error(), http.StatusTooManyRequests)
+	return true
+}
+
+type SpamHandler struct {
+	service application.SpamServiceInterface
+}
+
+func NewSpamHandler(service application.SpamServiceInterface) *SpamHandler {
+	return &SpamHandler{service: service}
+}
+
+// ListAccounts lists accounts with a stored standing, optionally only
+// `standing=trusted` or `standing=shadow_limited` ones.
+func (h *SpamHandler) ListAccounts(w http.ResponseWriter, r *http.Request) {
+	viewer, ok := viewerFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthenticated", http.StatusBadRequest)
+		return
+	}
+
+	standing := domain.AccountStanding(r.URL.Query().Get("standing"))
+	if standing != "" && standing != domain.StandingTrusted && standing != domain.StandingShadowLimited {
+		http.Error(w, "invalid standing", http.StatusBadRequest)
+		return
+	}
+
+	page, err := parsePageRequest(w, r)
+	if err != nil {
+		http.Error(w, "invalid cursor", http.StatusBadRequest)
+		return
+	}
+
+	limits, next, err := h.service.ListLimits(viewer, standing, page)
+	if err != nil {
+		fmt.Println(err)
+		http.Error(w, "Failed to fetch accounts", accessErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(pageResponse(limits, next))
+}
+
+func (h *SpamHandler) GetStanding(w http.ResponseWriter, r *http.Request) {
+	viewer, ok := viewerFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthenticated", http.StatusBadRequest)
+		return
+	}
+
+	userID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	limit, err := h.service.GetStanding(viewer, userID)
+	if err != nil {
+		fmt.Println(err)
+		http.Error(w, "Failed to fetch standing", accessErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"data": limit})
+}
+
+// SetStanding trusts, shadow-limits or, with `normal`, clears the standing
+// of an account.
+func (h *SpamHandler) SetStanding(w http.ResponseWriter, r *http.Request) {
+	viewer, ok := viewerFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthenticated", http.StatusBadRequest)
+		return
+	}
+
+	userID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	var req struct {
+		Data domain.SetStandingRequest `json:"data"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	limit, err := h.service.SetStanding(viewer, userID, &req.Data)
+	if err != nil {
+		if errors.Is(err, domain.ErrInvalidStanding) || errors.Is(err, domain.ErrReportDetailsTooLong) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		fmt.Println(err)
+		http.Error(w, "Failed to update standing", accessErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"message": "Standing updated successfully", "data": limit})
+}
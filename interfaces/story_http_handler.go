@@ -0,0 +1,144 @@
+package interfaces
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/bandvov/social-media-go/application"
+	"github.com/bandvov/social-media-go/domain"
+)
+
+type StoryHandler struct {
+	service application.StoryServiceInterface
+}
+
+func NewStoryHandler(service application.StoryServiceInterface) *StoryHandler {
+	return &StoryHandler{service: service}
+}
+
+func (h *StoryHandler) CreateStory(w http.ResponseWriter, r *http.Request) {
+	viewer, ok := viewerFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthenticated", http.StatusBadRequest)
+		return
+	}
+
+	var req struct {
+		Data domain.CreateStoryRequest `json:"data"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	story, err := h.service.CreateStory(viewer.ID, &req.Data)
+	if err != nil {
+		if errors.Is(err, domain.ErrEmptyStory) || errors.Is(err, domain.ErrStoryTooLong) || errors.Is(err, domain.ErrInvalidStoryMedia) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		fmt.Println(err)
+		http.Error(w, "Failed to create story", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{"message": "Story created successfully", "data": story})
+}
+
+// GetTray returns the active stories of the user and the people they follow.
+func (h *StoryHandler) GetTray(w http.ResponseWriter, r *http.Request) {
+	viewer, ok := viewerFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthenticated", http.StatusBadRequest)
+		return
+	}
+
+	tray, err := h.service.GetTray(viewer.ID)
+	if err != nil {
+		fmt.Println(err)
+		http.Error(w, "Failed to fetch stories", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"data": tray})
+}
+
+func (h *StoryHandler) MarkSeen(w http.ResponseWriter, r *http.Request) {
+	viewer, ok := viewerFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthenticated", http.StatusBadRequest)
+		return
+	}
+
+	storyID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "invalid story ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.MarkSeen(viewer.ID, storyID); err != nil {
+		fmt.Println(err)
+		http.Error(w, "Failed to mark story as seen", accessErrorStatus(err))
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"message": "story marked as seen"})
+}
+
+// GetViewers lists who has seen a story, for its author.
+func (h *StoryHandler) GetViewers(w http.ResponseWriter, r *http.Request) {
+	viewer, ok := viewerFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthenticated", http.StatusBadRequest)
+		return
+	}
+
+	storyID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "invalid story ID", http.StatusBadRequest)
+		return
+	}
+
+	page, err := parsePageRequest(w, r)
+	if err != nil {
+		http.Error(w, "invalid cursor", http.StatusBadRequest)
+		return
+	}
+
+	viewers, next, err := h.service.GetViewers(viewer, storyID, page)
+	if err != nil {
+		fmt.Println(err)
+		http.Error(w, "Failed to fetch story viewers", accessErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(pageResponse(viewers, next))
+}
+
+func (h *StoryHandler) DeleteStory(w http.ResponseWriter, r *http.Request) {
+	viewer, ok := viewerFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthenticated", http.StatusBadRequest)
+		return
+	}
+
+	storyID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "invalid story ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.DeleteStory(viewer, storyID); err != nil {
+		fmt.Println(err)
+		http.Error(w, "Failed to delete story", accessErrorStatus(err))
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"message": "story deleted successfully"})
+}
@@ -0,0 +1,363 @@
+package interfaces
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/bandvov/social-media-go/application"
+	"github.com/bandvov/social-media-go/domain"
+)
+
+const (
+	// syndicationCacheTTL is how long a rendered feed is served from memory,
+	// and how long clients and proxies may cache it.
+	syndicationCacheTTL = 5 * time.Minute
+
+	// maxCachedFeeds bounds the in-memory cache of rendered feeds.
+	maxCachedFeeds = 1000
+
+	maxFeedTitleLength = 80
+)
+
+type feedFormat struct {
+	name        string
+	contentType string
+	render      func(feed *domain.UserFeed, baseURL string) ([]byte, error)
+}
+
+var (
+	rssFormat  = feedFormat{name: "rss", contentType: "application/rss+xml; charset=utf-8", render: renderRSS}
+	atomFormat = feedFormat{name: "atom", contentType: "application/atom+xml; charset=utf-8", render: renderAtom}
+)
+
+type renderedFeed struct {
+	body         []byte
+	etag         string
+	lastModified time.Time
+	expiresAt    time.Time
+}
+
+// SyndicationHandler serves the public RSS and Atom feeds of users. Feeds
+// need no authentication and only ever contain public posts. Links in feeds
+// point at the configured base URL, never at the Host of the request.
+type SyndicationHandler struct {
+	service application.SyndicationServiceInterface
+	baseURL string
+
+	mu    sync.Mutex
+	cache map[string]renderedFeed
+	now   func() time.Time
+}
+
+func NewSyndicationHandler(service application.SyndicationServiceInterface, baseURL string) *SyndicationHandler {
+	return &SyndicationHandler{
+		service: service,
+		baseURL: strings.TrimRight(baseURL, "/"),
+		cache:   make(map[string]renderedFeed),
+		now:     time.Now,
+	}
+}
+
+func (h *SyndicationHandler) GetRSSFeed(w http.ResponseWriter, r *http.Request) {
+	h.serveFeed(w, r, rssFormat)
+}
+
+func (h *SyndicationHandler) GetAtomFeed(w http.ResponseWriter, r *http.Request) {
+	h.serveFeed(w, r, atomFormat)
+}
+
+func (h *SyndicationHandler) serveFeed(w http.ResponseWriter, r *http.Request, format feedFormat) {
+	username := r.PathValue("username")
+	if username == "" {
+		http.Error(w, "invalid username", http.StatusBadRequest)
+		return
+	}
+
+	feed, err := h.renderedFeed(format, username)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "user not found", http.StatusNotFound)
+			return
+		}
+		fmt.Println(err)
+		http.Error(w, "Failed to build feed", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("ETag", feed.etag)
+	w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(syndicationCacheTTL.Seconds())))
+	if !feed.lastModified.IsZero() {
+		w.Header().Set("Last-Modified", feed.lastModified.UTC().Format(http.TimeFormat))
+	}
+
+	if notModified(r, feed) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("Content-Type", format.contentType)
+	w.Write(feed.body)
+}
+
+// renderedFeed returns the feed from the cache, or builds and caches it.
+func (h *SyndicationHandler) renderedFeed(format feedFormat, username string) (renderedFeed, error) {
+	key := format.name + ":" + username
+	now := h.now()
+
+	h.mu.Lock()
+	cached, ok := h.cache[key]
+	h.mu.Unlock()
+	if ok && now.Before(cached.expiresAt) {
+		return cached, nil
+	}
+
+	feed, err := h.service.GetUserFeed(username)
+	if err != nil {
+		return renderedFeed{}, err
+	}
+	body, err := format.render(feed, h.baseURL)
+	if err != nil {
+		return renderedFeed{}, err
+	}
+	sum := sha256.Sum256(body)
+	rendered := renderedFeed{
+		body:         body,
+		etag:         `"` + hex.EncodeToString(sum[:16]) + `"`,
+		lastModified: feed.Updated.Truncate(time.Second),
+		expiresAt:    now.Add(syndicationCacheTTL),
+	}
+
+	h.mu.Lock()
+	if len(h.cache) >= maxCachedFeeds {
+		for k, v := range h.cache {
+			if !now.Before(v.expiresAt) {
+				delete(h.cache, k)
+			}
+		}
+		if len(h.cache) >= maxCachedFeeds {
+			h.cache = make(map[string]renderedFeed)
+		}
+	}
+	h.cache[key] = rendered
+	h.mu.Unlock()
+
+	return rendered, nil
+}
+
+// notModified evaluates the request's conditional headers. If-None-Match
+// takes precedence over If-Modified-Since.
+func notModified(r *http.Request, feed renderedFeed) bool {
+	if match := r.Header.Get("If-None-Match"); match != "" {
+		for _, tag := range strings.Split(match, ",") {
+			tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+			if tag == "*" || tag == feed.etag {
+				return true
+			}
+		}
+		return false
+	}
+
+	if since := r.Header.Get("If-Modified-Since"); since != "" && !feed.lastModified.IsZero() {
+		t, err := http.ParseTime(since)
+		return err == nil && !feed.lastModified.After(t)
+	}
+	return false
+}
+
+func userURL(baseURL, username string) string {
+	return baseURL + "/users/" + username
+}
+
+func postURL(baseURL string, postID int) string {
+	return fmt.Sprintf("%s/posts/%d", baseURL, postID)
+}
+
+// feedEntryTitle is the first line of a post, shortened. Flagged posts are
+// titled with their content warning instead.
+func feedEntryTitle(post domain.Post) string {
+	if post.IsFlagged() {
+		if post.ContentWarning != "" {
+			return "CW: " + post.ContentWarning
+		}
+		return "Sensitive content"
+	}
+
+	title := strings.TrimSpace(post.Content)
+	if i := strings.IndexByte(title, '\n'); i >= 0 {
+		title = strings.TrimSpace(title[:i])
+	}
+	if utf8.RuneCountInString(title) > maxFeedTitleLength {
+		title = string([]rune(title)[:maxFeedTitleLength-1]) + "…"
+	}
+	return title
+}
+
+// feedEntryContent is the rendered HTML of a post. The text of flagged
+// posts is left out since feed readers cannot collapse it.
+func feedEntryContent(post domain.Post, baseURL string) string {
+	if post.IsFlagged() {
+		return fmt.Sprintf(`<p>This post is marked as sensitive. <a href="%s">View it on the site</a>.</p>`, postURL(baseURL, post.ID))
+	}
+	return post.ContentHTML
+}
+
+func feedAuthorName(author domain.User) string {
+	if author.Username != nil {
+		return *author.Username
+	}
+	return ""
+}
+
+type rssDocument struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	AtomNS  string     `xml:"xmlns:atom,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	LastBuildDate string    `xml:"lastBuildDate,omitempty"`
+	SelfLink      rssLink   `xml:"atom:link"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr"`
+	Type string `xml:"type,attr"`
+}
+
+type rssItem struct {
+	Title       string  `xml:"title"`
+	Link        string  `xml:"link"`
+	GUID        rssGUID `xml:"guid"`
+	PubDate     string  `xml:"pubDate"`
+	Description string  `xml:"description"`
+}
+
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+func renderRSS(feed *domain.UserFeed, baseURL string) ([]byte, error) {
+	username := feedAuthorName(feed.Author)
+	doc := rssDocument{
+		Version: "2.0",
+		AtomNS:  "http://www.w3.org/2005/Atom",
+		Channel: rssChannel{
+			Title:       username,
+			Link:        userURL(baseURL, username),
+			Description: "Public posts by " + username,
+			SelfLink: rssLink{
+				Href: userURL(baseURL, username) + "/feed.rss",
+				Rel:  "self",
+				Type: "application/rss+xml",
+			},
+		},
+	}
+	if !feed.Updated.IsZero() {
+		doc.Channel.LastBuildDate = feed.Updated.UTC().Format(time.RFC1123Z)
+	}
+	for _, post := range feed.Posts {
+		link := postURL(baseURL, post.ID)
+		doc.Channel.Items = append(doc.Channel.Items, rssItem{
+			Title:       feedEntryTitle(post),
+			Link:        link,
+			GUID:        rssGUID{IsPermaLink: true, Value: link},
+			PubDate:     post.CreatedAt.UTC().Format(time.RFC1123Z),
+			Description: feedEntryContent(post, baseURL),
+		})
+	}
+	return marshalFeed(doc)
+}
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Links   []atomLink  `xml:"link"`
+	Author  atomAuthor  `xml:"author"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+	URI  string `xml:"uri"`
+}
+
+type atomEntry struct {
+	ID        string      `xml:"id"`
+	Title     string      `xml:"title"`
+	Link      atomLink    `xml:"link"`
+	Published string      `xml:"published"`
+	Updated   string      `xml:"updated"`
+	Content   atomContent `xml:"content"`
+}
+
+type atomContent struct {
+	Type  string `xml:"type,attr"`
+	Value string `xml:",chardata"`
+}
+
+func renderAtom(feed *domain.UserFeed, baseURL string) ([]byte, error) {
+	username := feedAuthorName(feed.Author)
+	profile := userURL(baseURL, username)
+	doc := atomFeed{
+		ID:      profile,
+		Title:   username,
+		Updated: feed.Updated.UTC().Format(time.RFC3339),
+		Links: []atomLink{
+			{Href: profile + "/feed.atom", Rel: "self", Type: "application/atom+xml"},
+			{Href: profile, Rel: "alternate", Type: "text/html"},
+		},
+		Author: atomAuthor{Name: username, URI: profile},
+	}
+	for _, post := range feed.Posts {
+		updated := post.UpdatedAt
+		if updated.Before(post.CreatedAt) {
+			updated = post.CreatedAt
+		}
+		link := postURL(baseURL, post.ID)
+		doc.Entries = append(doc.Entries, atomEntry{
+			ID:        link,
+			Title:     feedEntryTitle(post),
+			Link:      atomLink{Href: link, Rel: "alternate", Type: "text/html"},
+			Published: post.CreatedAt.UTC().Format(time.RFC3339),
+			Updated:   updated.UTC().Format(time.RFC3339),
+			Content:   atomContent{Type: "html", Value: feedEntryContent(post, baseURL)},
+		})
+	}
+	return marshalFeed(doc)
+}
+
+func marshalFeed(doc interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	enc := xml.NewEncoder(&buf)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return nil, fmt.Errorf("failed to render feed: %v", err)
+	}
+	return buf.Bytes(), nil
+}
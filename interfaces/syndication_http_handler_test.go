@@ -0,0 +1,162 @@
+package interfaces
+
+import (
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bandvov/social-media-go/domain"
+)
+
+type stubSyndicationService struct {
+	feed  *domain.UserFeed
+	err   error
+	calls int
+}
+
+func (s *stubSyndicationService) GetUserFeed(username string) (*domain.UserFeed, error) {
+	s.calls++
+	return s.feed, s.err
+}
+
+func newTestUserFeed() *domain.UserFeed {
+	username := "alice"
+	created := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	return &domain.UserFeed{
+		Author: domain.User{ID: 1, Username: &username},
+		Posts: []domain.Post{
+			{ID: 2, Content: "Second post", ContentHTML: "<p>Second <strong>post</strong></p>", CreatedAt: created.Add(time.Hour)},
+			{ID: 1, Content: "First", ContentHTML: "<p>First</p>", ContentWarning: "spoilers", CreatedAt: created},
+		},
+		Updated: created.Add(time.Hour),
+	}
+}
+
+func serveSyndication(handler http.HandlerFunc, path string, header http.Header) *httptest.ResponseRecorder {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /users/{username}/"+path[strings.LastIndex(path, "/")+1:], handler)
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+	return rr
+}
+
+func TestGetRSSFeed(t *testing.T) {
+	handler := NewSyndicationHandler(&stubSyndicationService{feed: newTestUserFeed()}, testFederationBaseURL)
+
+	rr := serveSyndication(handler.GetRSSFeed, "/users/alice/feed.rss", nil)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rr.Code)
+	}
+	if ct := rr.Header().Get("Content-Type"); !strings.HasPrefix(ct, "application/rss+xml") {
+		t.Errorf("unexpected content type %q", ct)
+	}
+	if rr.Header().Get("ETag") == "" {
+		t.Error("expected an ETag")
+	}
+	if lm := rr.Header().Get("Last-Modified"); lm != "Wed, 01 May 2024 13:00:00 GMT" {
+		t.Errorf("unexpected Last-Modified %q", lm)
+	}
+
+	// The request is made to example.com, links point at the base URL.
+	body := rr.Body.String()
+	for _, want := range []string{
+		`<rss version="2.0"`,
+		`<link>https://social.example/users/alice</link>`,
+		`<guid isPermaLink="true">https://social.example/posts/2</guid>`,
+		`&lt;p&gt;Second &lt;strong&gt;post&lt;/strong&gt;&lt;/p&gt;`,
+		`<title>CW: spoilers</title>`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected feed to contain %q\n%s", want, body)
+		}
+	}
+	if strings.Contains(body, "&lt;p&gt;First") {
+		t.Error("expected the text of a post with a content warning to be left out")
+	}
+}
+
+func TestGetAtomFeed(t *testing.T) {
+	handler := NewSyndicationHandler(&stubSyndicationService{feed: newTestUserFeed()}, testFederationBaseURL)
+
+	rr := serveSyndication(handler.GetAtomFeed, "/users/alice/feed.atom", nil)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rr.Code)
+	}
+	body := rr.Body.String()
+	for _, want := range []string{
+		`<feed xmlns="http://www.w3.org/2005/Atom">`,
+		`<updated>2024-05-01T13:00:00Z</updated>`,
+		`<link href="https://social.example/users/alice/feed.atom" rel="self" type="application/atom+xml">`,
+		`<content type="html">`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected feed to contain %q\n%s", want, body)
+		}
+	}
+}
+
+func TestGetRSSFeed_ConditionalRequests(t *testing.T) {
+	service := &stubSyndicationService{feed: newTestUserFeed()}
+	handler := NewSyndicationHandler(service, testFederationBaseURL)
+
+	first := serveSyndication(handler.GetRSSFeed, "/users/alice/feed.rss", nil)
+	etag := first.Header().Get("ETag")
+
+	rr := serveSyndication(handler.GetRSSFeed, "/users/alice/feed.rss", http.Header{"If-None-Match": {etag}})
+	if rr.Code != http.StatusNotModified {
+		t.Errorf("expected 304 for a matching ETag, got %d", rr.Code)
+	}
+	if rr.Body.Len() != 0 {
+		t.Error("expected an empty body for 304")
+	}
+
+	rr = serveSyndication(handler.GetRSSFeed, "/users/alice/feed.rss", http.Header{"If-None-Match": {`"stale"`}})
+	if rr.Code != http.StatusOK {
+		t.Errorf("expected 200 for a stale ETag, got %d", rr.Code)
+	}
+
+	rr = serveSyndication(handler.GetRSSFeed, "/users/alice/feed.rss", http.Header{"If-Modified-Since": {"Wed, 01 May 2024 13:00:00 GMT"}})
+	if rr.Code != http.StatusNotModified {
+		t.Errorf("expected 304 when not modified since, got %d", rr.Code)
+	}
+
+	rr = serveSyndication(handler.GetRSSFeed, "/users/alice/feed.rss", http.Header{"If-Modified-Since": {"Wed, 01 May 2024 12:59:59 GMT"}})
+	if rr.Code != http.StatusOK {
+		t.Errorf("expected 200 when modified since, got %d", rr.Code)
+	}
+
+	if service.calls != 1 {
+		t.Errorf("expected the feed to be built once and then cached, built %d times", service.calls)
+	}
+}
+
+func TestGetRSSFeed_CacheExpires(t *testing.T) {
+	service := &stubSyndicationService{feed: newTestUserFeed()}
+	handler := NewSyndicationHandler(service, testFederationBaseURL)
+	now := time.Now()
+	handler.now = func() time.Time { return now }
+
+	serveSyndication(handler.GetRSSFeed, "/users/alice/feed.rss", nil)
+	now = now.Add(syndicationCacheTTL)
+	serveSyndication(handler.GetRSSFeed, "/users/alice/feed.rss", nil)
+
+	if service.calls != 2 {
+		t.Errorf("expected the feed to be rebuilt after the cache expired, built %d times", service.calls)
+	}
+}
+
+func TestGetRSSFeed_UnknownUser(t *testing.T) {
+	handler := NewSyndicationHandler(&stubSyndicationService{err: sql.ErrNoRows}, testFederationBaseURL)
+
+	rr := serveSyndication(handler.GetRSSFeed, "/users/nobody/feed.rss", nil)
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("expected status 404, got %d", rr.Code)
+	}
+}
@@ -81,6 +81,10 @@ func (h *UserHTTPHandler) Login(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, "User not found", http.StatusNotFound)
 			return
 		}
+		if errors.Is(err, application.ErrUserBanned) {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -187,14 +191,18 @@ func (h *UserHTTPHandler) ChangeUserRole(w http.ResponseWriter, r *http.Request)
 }
 
 func (h *UserHTTPHandler) GetPublicProfiles(w http.ResponseWriter, r *http.Request) {
-	limit, offset := utils.ParsePagination(r)
-	users, err := h.UserService.GetPublicProfiles(limit, offset)
+	page, err := parsePageRequest(w, r)
+	if err != nil {
+		http.Error(w, "invalid cursor", http.StatusBadRequest)
+		return
+	}
+	users, next, err := h.UserService.GetPublicProfiles(page)
 	if err != nil {
 		http.Error(w, "Failed to fetch public profiles", http.StatusInternalServerError)
 		return
 	}
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(users)
+	json.NewEncoder(w).Encode(pageResponse(users, next))
 }
 
 func (h *UserHTTPHandler) GetAdminProfiles(w http.ResponseWriter, r *http.Request) {
@@ -246,6 +254,61 @@ func (h *UserHTTPHandler) GetUserProfile(w http.ResponseWriter, r *http.Request)
 	json.NewEncoder(w).Encode(user)
 }
 
+// UpdatePreferences changes the authenticated user's display preferences.
+func (h *UserHTTPHandler) UpdatePreferences(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(userIDKey).(int)
+	if !ok || userID == 0 {
+		http.Error(w, "unauthenticated", http.StatusBadRequest)
+		return
+	}
+
+	var req struct {
+		SensitiveContent string `json:"sensitive_content"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	err := h.UserService.UpdateSensitiveContent(userID, req.SensitiveContent)
+	if err != nil {
+		if errors.Is(err, application.ErrInvalidSensitiveContent) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		fmt.Println(err)
+		http.Error(w, "error updating preferences", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"message": "preferences updated successfully"})
+}
+
+// UpdatePrivacy makes the authenticated user's account private or public.
+func (h *UserHTTPHandler) UpdatePrivacy(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(userIDKey).(int)
+	if !ok || userID == 0 {
+		http.Error(w, "unauthenticated", http.StatusBadRequest)
+		return
+	}
+
+	var req struct {
+		IsPrivate *bool `json:"is_private"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.IsPrivate == nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.UserService.UpdatePrivacy(userID, *req.IsPrivate); err != nil {
+		fmt.Println(err)
+		http.Error(w, "error updating privacy", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"message": "privacy updated successfully"})
+}
+
 func (h *UserHTTPHandler) IsAdmin(ctx context.Context) bool {
 	return ctx.Value(isAdminKey).(bool)
 }
@@ -409,6 +409,9 @@ func TestGetUserProfile(t *testing.T) {
 			// Set up the mock service
 			mockService := &application.MockUserService{
 				GetUserByIDFunc: tt.mockGetUserByIDFunc,
+				GetUserProfileInfoFunc: func(id, otherUser int) (*domain.User, error) {
+					return tt.mockGetUserByIDFunc(id)
+				},
 			}
 
 			// Create handler with mock service
@@ -1,12 +1,14 @@
 package main
 
 import (
+	"context"
 	"crypto/tls"
 	"database/sql"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
+	"time"
 
 	"github.com/bandvov/social-media-go/application"
 	"github.com/bandvov/social-media-go/infrastructure"
@@ -63,21 +65,112 @@ func main() {
 	// Initialize HTTP handler
 	userHandler := interfaces.NewUserHTTPHandler(userService)
 
+	reportRepo := infrastructure.NewReportRepository(db)
+	accountLimitRepo := infrastructure.NewAccountLimitRepository(db)
+	spamStore := infrastructure.NewRedisSpamStore(redisClient)
+	spamService := application.NewSpamService(spamStore, accountLimitRepo, userRepo, reportRepo, application.DefaultSpamPolicy)
+	spamHandler := interfaces.NewSpamHandler(spamService)
+
+	followerRepo := infrastructure.NewFollowerRepository(db)
+	Followerservice := application.NewFollowerService(followerRepo, spamService)
+	followerHandler := interfaces.NewFollowerHandler(Followerservice)
+
+	linkPreviewRepo := infrastructure.NewLinkPreviewRepository(db)
+	linkPreviewFetcher := infrastructure.NewHTTPLinkPreviewFetcher(5*time.Second, 512*1024)
+	linkPreviewService := application.NewLinkPreviewService(linkPreviewRepo, linkPreviewFetcher, application.DefaultLinkPreviewTTL)
+
+	postRepo := infrastructure.NewPostRepository(db)
 	commentRepo := infrastructure.NewPostgresCommentRepository(db)
-	commentService := application.NewCommentService(commentRepo)
+	visibilityPolicy := application.NewVisibilityPolicy(followerRepo, postRepo, commentRepo)
+
+	go func() {
+		if err := application.BackfillContentHTML(postRepo, commentRepo); err != nil {
+			log.Printf("failed to render stored content: %v", err)
+		}
+	}()
+
+	automodRepo := infrastructure.NewAutomodRepository(db)
+	automodService := application.NewAutomodService(automodRepo, reportRepo, application.DefaultAutomodRefreshInterval)
+	automodHandler := interfaces.NewAutomodHandler(automodService)
+
+	blockRepo := infrastructure.NewBlockRepository(db)
+	blockService := application.NewBlockService(blockRepo, followerRepo)
+	blockHandler := interfaces.NewBlockHandler(blockService)
+
+	notificationsURL := os.Getenv("NOTIFICATIONS_URL")
+	if notificationsURL == "" {
+		notificationsURL = "http://localhost:8080"
+	}
+	notifier := infrastructure.NewHTTPNotifier(notificationsURL, 5*time.Second)
+
+	mentionRepo := infrastructure.NewMentionRepository(db)
+	mentionService := application.NewMentionService(mentionRepo, userRepo, blockRepo, visibilityPolicy, notifier)
+
+	counterStore := infrastructure.NewRedisCounterStore(redisClient)
+	postCounterRepo := infrastructure.NewPostCounterRepository(db)
+	counterService := application.NewCounterService(counterStore, postCounterRepo)
+	go counterService.RunFlush(context.Background(), application.DefaultCounterFlushInterval)
+	go counterService.RunReconciliation(context.Background(), application.DefaultCounterReconcileInterval)
+
+	commentService := application.NewCommentService(commentRepo, postRepo, userRepo, visibilityPolicy, linkPreviewService, automodService, spamService, mentionService, counterService)
 	commentHandler := interfaces.NewCommentHandler(commentService)
 
+	reactionTypeRepo := infrastructure.NewReactionTypeRepository(db)
+	reactionTypeService := application.NewReactionTypeService(reactionTypeRepo)
+	reactionTypeHandler := interfaces.NewReactionTypeHandler(reactionTypeService)
+
 	reactionRepo := infrastructure.NewReactionRepository(db)
-	reactionService := application.NewReactionService(reactionRepo)
+	reactionService := application.NewReactionService(reactionRepo, visibilityPolicy, userRepo, reactionTypeRepo, counterService)
 	reactionHandler := interfaces.NewReactionHandler(reactionService)
 
-	postRepo := infrastructure.NewPostRepository(db)
-	postService := application.NewPostService(postRepo)
-	postHandler := interfaces.NewPostHTTPHandler(postService, commentService, userService, reactionService)
+	searchRepo := infrastructure.NewSearchRepository(db)
+	searchService := application.NewSearchService(searchRepo)
+	searchHandler := interfaces.NewSearchHandler(searchService)
 
-	followerRepo := infrastructure.NewFollowerRepository(db)
-	Followerservice := application.NewFollowerService(followerRepo)
-	followerHandler := interfaces.NewFollowerHandler(Followerservice)
+	bookmarkRepo := infrastructure.NewBookmarkRepository(db)
+	bookmarkService := application.NewBookmarkService(bookmarkRepo, visibilityPolicy)
+	bookmarkHandler := interfaces.NewBookmarkHandler(bookmarkService)
+
+	analyticsRepo := infrastructure.NewAnalyticsRepository(db)
+	viewDeduplicator := infrastructure.NewRedisViewDeduplicator(redisClient)
+	analyticsService := application.NewAnalyticsService(analyticsRepo, viewDeduplicator, postRepo, application.DefaultViewDedupWindow)
+	analyticsHandler := interfaces.NewAnalyticsHandler(analyticsService)
+	go analyticsService.RunRollups(context.Background(), application.DefaultAnalyticsRollupInterval)
+
+	federationBaseURL := os.Getenv("FEDERATION_BASE_URL")
+	if federationBaseURL == "" {
+		federationBaseURL = "https://localhost" + PORT
+	}
+	federationRepo := infrastructure.NewFederationRepository(db)
+	federationClient := infrastructure.NewHTTPFederationClient(10*time.Second, os.Getenv("FEDERATION_ALLOW_PRIVATE_NETWORKS") == "true")
+	federationService := application.NewFederationService(federationRepo, federationClient, userRepo, followerRepo, blockRepo, postRepo, reactionRepo, counterService, federationBaseURL)
+	federationHandler := interfaces.NewFederationHandler(federationService)
+	go federationService.RunDeliveries(context.Background(), application.DefaultDeliveryInterval)
+
+	feedRepo := infrastructure.NewRedisFeedRepository(redisClient)
+	feedService := application.NewFeedService(feedRepo, followerRepo, postRepo, application.DefaultFanOutThreshold)
+	feedHandler := interfaces.NewFeedHandler(feedService, commentService, reactionService, bookmarkService, linkPreviewService, analyticsService, mentionService)
+
+	postService := application.NewPostService(postRepo, feedService, visibilityPolicy, linkPreviewService, federationService, automodService, spamService, mentionService)
+	postHandler := interfaces.NewPostHTTPHandler(postService, commentService, userService, reactionService, bookmarkService, linkPreviewService, analyticsService, mentionService)
+
+	storyTTL := application.DefaultStoryTTL
+	if ttl := os.Getenv("STORY_TTL"); ttl != "" {
+		storyTTL, err = time.ParseDuration(ttl)
+		if err != nil {
+			log.Fatalf("invalid STORY_TTL: %v", err)
+		}
+	}
+	storyRepo := infrastructure.NewStoryRepository(db)
+	storyService := application.NewStoryService(storyRepo, followerRepo, blockRepo, userRepo, storyTTL)
+	storyHandler := interfaces.NewStoryHandler(storyService)
+	go storyService.RunExpirySweeper(context.Background(), application.DefaultStorySweepInterval)
+
+	reportService := application.NewReportService(reportRepo, postRepo, commentRepo, userRepo, visibilityPolicy, postService, linkPreviewService, notifier, counterService)
+	reportHandler := interfaces.NewReportHandler(reportService)
+
+	syndicationService := application.NewSyndicationService(userRepo, postRepo)
+	syndicationHandler := interfaces.NewSyndicationHandler(syndicationService, federationBaseURL)
 
 	tagRepo := infrastructure.NewTagRepository(db)
 	tagService := application.NewTagService(tagRepo)
@@ -94,6 +187,30 @@ func main() {
 	// seeds.Seed(db, "./migrations/create_followers_table.sql")
 	// seeds.Seed(db, "./migrations/create_tags_table.sql")
 	// seeds.Seed(db, "./migrations/create_comments_table.sql")
+	// seeds.Seed(db, "./migrations/add_keyset_pagination.sql")
+	// seeds.Seed(db, "./migrations/create_bookmarks_table.sql")
+	// seeds.Seed(db, "./migrations/create_blocks_table.sql")
+	// seeds.Seed(db, "./migrations/add_search.sql")
+	// seeds.Seed(db, "./migrations/create_link_previews_table.sql")
+	// seeds.Seed(db, "./migrations/add_content_warnings.sql")
+	// seeds.Seed(db, "./migrations/create_post_analytics_tables.sql")
+	// seeds.Seed(db, "./migrations/add_content_html.sql")
+	// seeds.Seed(db, "./migrations/add_post_threads.sql")
+	// seeds.Seed(db, "./migrations/create_stories_tables.sql")
+	// seeds.Seed(db, "./migrations/create_federation_tables.sql")
+	// seeds.Seed(db, "./migrations/create_reports_table.sql")
+	// seeds.Seed(db, "./migrations/create_automod_tables.sql")
+	// seeds.Seed(db, "./migrations/create_account_limits_table.sql")
+	// seeds.Seed(db, "./migrations/add_comment_threads.sql")
+	// seeds.Seed(db, "./migrations/add_comment_lifecycle.sql")
+	// seeds.Seed(db, "./migrations/add_comment_scores.sql")
+	// seeds.Seed(db, "./migrations/add_comment_controls.sql")
+	// seeds.Seed(db, "./migrations/create_mentions_table.sql")
+	// seeds.Seed(db, "./migrations/add_reaction_entity_types.sql")
+	// seeds.Seed(db, "./migrations/add_private_accounts.sql")
+	// seeds.Seed(db, "./migrations/add_reaction_type_catalog.sql")
+	// seeds.Seed(db, "./migrations/add_post_counters.sql")
+	// seeds.Seed(db, "./migrations/add_follower_counts.sql")
 
 	// seeds.Seed(db, "./seeds/seed_users.sql")
 	// seeds.Seed(db, "./seeds/seed_posts.sql")
@@ -106,24 +223,80 @@ func main() {
 
 	// Define routes
 	router.HandleFunc("/api/admin/users", interfaces.LoggerMiddleware(userHandler.AuthMiddleware(userHandler.IsAdminMiddleware(userHandler.GetAdminProfiles))))
+	router.HandleFunc("GET /api/admin/automod/rules", interfaces.LoggerMiddleware(userHandler.AuthMiddleware(userHandler.IsAdminMiddleware(automodHandler.ListRules))))
+	router.HandleFunc("POST /api/admin/automod/rules", interfaces.LoggerMiddleware(userHandler.AuthMiddleware(userHandler.IsAdminMiddleware(automodHandler.CreateRule))))
+	router.HandleFunc("GET /api/admin/automod/rules/{id}", interfaces.LoggerMiddleware(userHandler.AuthMiddleware(userHandler.IsAdminMiddleware(automodHandler.GetRule))))
+	router.HandleFunc("PUT /api/admin/automod/rules/{id}", interfaces.LoggerMiddleware(userHandler.AuthMiddleware(userHandler.IsAdminMiddleware(automodHandler.UpdateRule))))
+	router.HandleFunc("DELETE /api/admin/automod/rules/{id}", interfaces.LoggerMiddleware(userHandler.AuthMiddleware(userHandler.IsAdminMiddleware(automodHandler.DeleteRule))))
+	router.HandleFunc("POST /api/admin/automod/test", interfaces.LoggerMiddleware(userHandler.AuthMiddleware(userHandler.IsAdminMiddleware(automodHandler.TestContent))))
+	router.HandleFunc("GET /api/admin/reaction-types", interfaces.LoggerMiddleware(userHandler.AuthMiddleware(userHandler.IsAdminMiddleware(reactionTypeHandler.ListAllReactionTypes))))
+	router.HandleFunc("POST /api/admin/reaction-types", interfaces.LoggerMiddleware(userHandler.AuthMiddleware(userHandler.IsAdminMiddleware(reactionTypeHandler.CreateReactionType))))
+	router.HandleFunc("GET /api/admin/reaction-types/{id}", interfaces.LoggerMiddleware(userHandler.AuthMiddleware(userHandler.IsAdminMiddleware(reactionTypeHandler.GetReactionType))))
+	router.HandleFunc("PUT /api/admin/reaction-types/{id}", interfaces.LoggerMiddleware(userHandler.AuthMiddleware(userHandler.IsAdminMiddleware(reactionTypeHandler.UpdateReactionType))))
+	router.HandleFunc("DELETE /api/admin/reaction-types/{id}", interfaces.LoggerMiddleware(userHandler.AuthMiddleware(userHandler.IsAdminMiddleware(reactionTypeHandler.DeleteReactionType))))
+
+	router.HandleFunc("GET /.well-known/webfinger", interfaces.LoggerMiddleware(federationHandler.WebFinger))
+	router.HandleFunc("GET /users/{username}", interfaces.LoggerMiddleware(federationHandler.GetActor))
+	router.HandleFunc("GET /users/{username}/outbox", interfaces.LoggerMiddleware(federationHandler.GetOutbox))
+	router.HandleFunc("POST /users/{username}/inbox", interfaces.LoggerMiddleware(federationHandler.Inbox))
+	router.HandleFunc("POST /inbox", interfaces.LoggerMiddleware(federationHandler.Inbox))
+	router.HandleFunc("GET /posts/{id}", interfaces.LoggerMiddleware(federationHandler.GetNote))
+
+	router.HandleFunc("GET /users/{username}/feed.rss", interfaces.LoggerMiddleware(syndicationHandler.GetRSSFeed))
+	router.HandleFunc("GET /users/{username}/feed.atom", interfaces.LoggerMiddleware(syndicationHandler.GetAtomFeed))
 
 	router.HandleFunc("GET /api/users", interfaces.LoggerMiddleware(userHandler.AuthMiddleware(userHandler.GetPublicProfiles)))
 	router.HandleFunc("GET /api/users/{id}/profile", interfaces.LoggerMiddleware(userHandler.AuthMiddleware(userHandler.GetUserProfile)))
 	router.HandleFunc("POST /api/users", interfaces.LoggerMiddleware(userHandler.RegisterUser))
 	router.HandleFunc("PUT /api/users/{id}", interfaces.LoggerMiddleware(userHandler.AuthMiddleware(userHandler.UpdateUser)))
 	router.HandleFunc("POST /api/users/login", interfaces.LoggerMiddleware(userHandler.Login))
+	router.HandleFunc("PUT /api/users/me/preferences", interfaces.LoggerMiddleware(userHandler.AuthMiddleware(userHandler.UpdatePreferences)))
+	router.HandleFunc("PUT /api/users/me/privacy", interfaces.LoggerMiddleware(userHandler.AuthMiddleware(userHandler.UpdatePrivacy)))
 	router.HandleFunc("PUT /api/users/{id}/role", interfaces.LoggerMiddleware(userHandler.AuthMiddleware(userHandler.ChangeUserRole)))
 
+	router.HandleFunc("GET /api/users/me/analytics", interfaces.LoggerMiddleware(userHandler.AuthMiddleware(analyticsHandler.GetMyAnalytics)))
 	router.HandleFunc("GET /api/users/{id}/posts", interfaces.LoggerMiddleware(userHandler.AuthMiddleware(postHandler.GetPostsByUser)))
 	router.HandleFunc("GET /api/users/{id}/followers", interfaces.LoggerMiddleware(userHandler.AuthMiddleware(followerHandler.GetFollowers)))
 	router.HandleFunc("GET /api/users/{id}/followees", interfaces.LoggerMiddleware(userHandler.AuthMiddleware(followerHandler.GetFollowees)))
 
+	router.HandleFunc("POST /api/users/{id}/block", interfaces.LoggerMiddleware(userHandler.AuthMiddleware(blockHandler.BlockUser)))
+	router.HandleFunc("DELETE /api/users/{id}/block", interfaces.LoggerMiddleware(userHandler.AuthMiddleware(blockHandler.UnblockUser)))
+
+	router.HandleFunc("GET /api/search", interfaces.LoggerMiddleware(userHandler.AuthMiddleware(searchHandler.Search)))
+
+	router.HandleFunc("GET /api/feed", interfaces.LoggerMiddleware(userHandler.AuthMiddleware(feedHandler.GetFeed)))
+
+	router.HandleFunc("GET /api/posts/{id}/analytics", interfaces.LoggerMiddleware(userHandler.AuthMiddleware(analyticsHandler.GetPostAnalytics)))
+	router.HandleFunc("GET /api/posts/{id}/reactions", interfaces.LoggerMiddleware(userHandler.AuthMiddleware(reactionHandler.GetPostReactors)))
+	router.HandleFunc("GET /api/posts/{id}/thread", interfaces.LoggerMiddleware(userHandler.AuthMiddleware(postHandler.GetThread)))
 	router.HandleFunc("GET /api/posts/{id}", interfaces.LoggerMiddleware(userHandler.AuthMiddleware(postHandler.GetPost)))
 	router.HandleFunc("POST /api/posts", interfaces.LoggerMiddleware(userHandler.AuthMiddleware(postHandler.CreatePost)))
 	router.HandleFunc("PUT /api/posts/{id}", interfaces.LoggerMiddleware(userHandler.AuthMiddleware(postHandler.UpdatePost)))
-	// this is mocked. Implement soft delete. make visibility = none
+	router.HandleFunc("PUT /api/posts/{id}/content-warning", interfaces.LoggerMiddleware(userHandler.AuthMiddleware(postHandler.SetContentWarning)))
 	router.HandleFunc("DELETE /api/posts/{id}", interfaces.LoggerMiddleware(userHandler.AuthMiddleware(postHandler.DeletePost)))
 
+	router.HandleFunc("GET /api/stories/tray", interfaces.LoggerMiddleware(userHandler.AuthMiddleware(storyHandler.GetTray)))
+	router.HandleFunc("POST /api/stories", interfaces.LoggerMiddleware(userHandler.AuthMiddleware(storyHandler.CreateStory)))
+	router.HandleFunc("POST /api/stories/{id}/seen", interfaces.LoggerMiddleware(userHandler.AuthMiddleware(storyHandler.MarkSeen)))
+	router.HandleFunc("GET /api/stories/{id}/viewers", interfaces.LoggerMiddleware(userHandler.AuthMiddleware(storyHandler.GetViewers)))
+	router.HandleFunc("DELETE /api/stories/{id}", interfaces.LoggerMiddleware(userHandler.AuthMiddleware(storyHandler.DeleteStory)))
+
+	router.HandleFunc("POST /api/reports", interfaces.LoggerMiddleware(userHandler.AuthMiddleware(reportHandler.CreateReport)))
+	router.HandleFunc("GET /api/moderation/reports", interfaces.LoggerMiddleware(userHandler.AuthMiddleware(reportHandler.GetQueue)))
+	router.HandleFunc("GET /api/moderation/reports/{id}", interfaces.LoggerMiddleware(userHandler.AuthMiddleware(reportHandler.GetReport)))
+	router.HandleFunc("PUT /api/moderation/reports/{id}/assign", interfaces.LoggerMiddleware(userHandler.AuthMiddleware(reportHandler.AssignReport)))
+	router.HandleFunc("PUT /api/moderation/reports/{id}/resolve", interfaces.LoggerMiddleware(userHandler.AuthMiddleware(reportHandler.ResolveReport)))
+	router.HandleFunc("GET /api/moderation/accounts", interfaces.LoggerMiddleware(userHandler.AuthMiddleware(spamHandler.ListAccounts)))
+	router.HandleFunc("GET /api/moderation/accounts/{id}/standing", interfaces.LoggerMiddleware(userHandler.AuthMiddleware(spamHandler.GetStanding)))
+	router.HandleFunc("PUT /api/moderation/accounts/{id}/standing", interfaces.LoggerMiddleware(userHandler.AuthMiddleware(spamHandler.SetStanding)))
+
+	router.HandleFunc("GET /api/bookmarks", interfaces.LoggerMiddleware(userHandler.AuthMiddleware(bookmarkHandler.GetBookmarks)))
+	router.HandleFunc("POST /api/bookmarks", interfaces.LoggerMiddleware(userHandler.AuthMiddleware(bookmarkHandler.AddBookmark)))
+	router.HandleFunc("DELETE /api/bookmarks/{id}", interfaces.LoggerMiddleware(userHandler.AuthMiddleware(bookmarkHandler.RemoveBookmark)))
+	router.HandleFunc("GET /api/bookmarks/collections", interfaces.LoggerMiddleware(userHandler.AuthMiddleware(bookmarkHandler.GetCollections)))
+	router.HandleFunc("POST /api/bookmarks/collections", interfaces.LoggerMiddleware(userHandler.AuthMiddleware(bookmarkHandler.CreateCollection)))
+	router.HandleFunc("DELETE /api/bookmarks/collections/{id}", interfaces.LoggerMiddleware(userHandler.AuthMiddleware(bookmarkHandler.DeleteCollection)))
+
 	router.HandleFunc("POST /api/followers", interfaces.LoggerMiddleware(userHandler.AuthMiddleware(followerHandler.AddFollower)))
 	router.HandleFunc("DELETE /api/followers/{id}", interfaces.LoggerMiddleware(userHandler.AuthMiddleware(followerHandler.RemoveFollower)))
 
@@ -133,9 +306,20 @@ func main() {
 
 	router.HandleFunc("POST /api/comments", interfaces.LoggerMiddleware(userHandler.AuthMiddleware(commentHandler.AddComment)))
 	router.HandleFunc("GET /api/comments/{id}", interfaces.LoggerMiddleware(userHandler.AuthMiddleware(commentHandler.GetCommentsByEntityID)))
+	router.HandleFunc("PUT /api/comments/{id}", interfaces.LoggerMiddleware(userHandler.AuthMiddleware(commentHandler.EditComment)))
+	router.HandleFunc("DELETE /api/comments/{id}", interfaces.LoggerMiddleware(userHandler.AuthMiddleware(commentHandler.DeleteComment)))
+	router.HandleFunc("GET /api/comments/{id}/reactions", interfaces.LoggerMiddleware(userHandler.AuthMiddleware(reactionHandler.GetCommentReactors)))
+	router.HandleFunc("GET /api/comments/{id}/replies", interfaces.LoggerMiddleware(userHandler.AuthMiddleware(commentHandler.GetReplies)))
+	router.HandleFunc("GET /api/posts/{id}/comments/tree", interfaces.LoggerMiddleware(userHandler.AuthMiddleware(commentHandler.GetCommentTree)))
+	router.HandleFunc("POST /api/comments/{id}/hide", interfaces.LoggerMiddleware(userHandler.AuthMiddleware(commentHandler.HideComment)))
+	router.HandleFunc("DELETE /api/comments/{id}/hide", interfaces.LoggerMiddleware(userHandler.AuthMiddleware(commentHandler.UnhideComment)))
+	router.HandleFunc("PUT /api/posts/{id}/comment-policy", interfaces.LoggerMiddleware(userHandler.AuthMiddleware(commentHandler.SetCommentPolicy)))
+	router.HandleFunc("PUT /api/posts/{id}/pinned-comment", interfaces.LoggerMiddleware(userHandler.AuthMiddleware(commentHandler.PinComment)))
+	router.HandleFunc("DELETE /api/posts/{id}/pinned-comment", interfaces.LoggerMiddleware(userHandler.AuthMiddleware(commentHandler.UnpinComment)))
 
-	router.HandleFunc("GET /api/reaction", reactionHandler.AddOrUpdateReaction)
-	router.HandleFunc("DELETE /api/reaction", reactionHandler.RemoveReaction)
+	router.HandleFunc("GET /api/reaction-types", interfaces.LoggerMiddleware(userHandler.AuthMiddleware(reactionTypeHandler.ListReactionTypes)))
+	router.HandleFunc("GET /api/reaction", interfaces.LoggerMiddleware(userHandler.AuthMiddleware(reactionHandler.AddOrUpdateReaction)))
+	router.HandleFunc("DELETE /api/reaction", interfaces.LoggerMiddleware(userHandler.AuthMiddleware(reactionHandler.RemoveReaction)))
 
 	// router.HandleFunc("/seed", seeds.SeedData(db))
 
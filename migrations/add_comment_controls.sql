@@ -0,0 +1,6 @@
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS comment_policy TEXT NOT NULL DEFAULT 'everyone' CHECK (comment_policy IN ('everyone', 'followers', 'mentioned', 'disabled'));
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS pinned_comment_id INT REFERENCES comments(id) ON DELETE SET NULL;
+
+-- Comments hidden by the author of the post rather than by a moderator; the
+-- author of the post can list them again.
+ALTER TABLE comments ADD COLUMN IF NOT EXISTS hidden_by_author BOOLEAN NOT NULL DEFAULT FALSE;
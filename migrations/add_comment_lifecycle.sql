@@ -0,0 +1,6 @@
+DO $$ BEGIN IF EXISTS (SELECT 1 FROM pg_enum e JOIN pg_type t ON t.oid = e.enumtypid WHERE t.typname = 'status_type' AND e.enumlabel = 'approved') THEN ALTER TYPE status_type RENAME VALUE 'approved' TO 'active'; END IF; END $$;
+DO $$ BEGIN IF EXISTS (SELECT 1 FROM pg_enum e JOIN pg_type t ON t.oid = e.enumtypid WHERE t.typname = 'status_type' AND e.enumlabel = 'rejected') THEN ALTER TYPE status_type RENAME VALUE 'rejected' TO 'hidden'; END IF; END $$;
+ALTER TYPE status_type ADD VALUE IF NOT EXISTS 'deleted';
+
+ALTER TABLE comments ALTER COLUMN status SET DEFAULT 'active';
+ALTER TABLE comments ADD COLUMN IF NOT EXISTS edited_at TIMESTAMP;
@@ -0,0 +1,21 @@
+ALTER TABLE reaction_types ADD COLUMN IF NOT EXISTS polarity SMALLINT NOT NULL DEFAULT 0 CHECK (polarity BETWEEN -1 AND 1);
+UPDATE reaction_types SET polarity = 1 WHERE name IN ('Like', 'Love');
+UPDATE reaction_types SET polarity = -1 WHERE name IN ('Dislike', 'Angry');
+
+ALTER TABLE comments ADD COLUMN IF NOT EXISTS reactions_count INT NOT NULL DEFAULT 0;
+ALTER TABLE comments ADD COLUMN IF NOT EXISTS positive_reactions INT NOT NULL DEFAULT 0;
+ALTER TABLE comments ADD COLUMN IF NOT EXISTS negative_reactions INT NOT NULL DEFAULT 0;
+
+-- Controversy grows with the number of reactions and is highest when they
+-- are split evenly between positive and negative.
+ALTER TABLE comments ADD COLUMN IF NOT EXISTS controversy DOUBLE PRECISION GENERATED ALWAYS AS (CASE WHEN positive_reactions = 0 OR negative_reactions = 0 THEN 0 ELSE power(positive_reactions + negative_reactions, LEAST(positive_reactions, negative_reactions)::float8 / GREATEST(positive_reactions, negative_reactions)) END) STORED;
+
+UPDATE comments c SET reactions_count = s.total, positive_reactions = s.positive, negative_reactions = s.negative FROM (SELECT r.entity_id, COUNT(*) AS total, COUNT(*) FILTER (WHERE rt.polarity > 0) AS positive, COUNT(*) FILTER (WHERE rt.polarity < 0) AS negative FROM reactions r JOIN reaction_types rt ON rt.id = r.reaction_type_id GROUP BY r.entity_id) s WHERE c.id = s.entity_id;
+
+-- Keep the counts in step with reactions.
+CREATE OR REPLACE FUNCTION update_comment_reaction_counts() RETURNS TRIGGER AS $$ DECLARE p SMALLINT; BEGIN IF TG_OP IN ('DELETE', 'UPDATE') THEN SELECT COALESCE(polarity, 0) INTO p FROM reaction_types WHERE id = OLD.reaction_type_id; UPDATE comments SET reactions_count = reactions_count - 1, positive_reactions = positive_reactions - (COALESCE(p, 0) > 0)::int, negative_reactions = negative_reactions - (COALESCE(p, 0) < 0)::int WHERE id = OLD.entity_id; END IF; IF TG_OP IN ('INSERT', 'UPDATE') THEN SELECT COALESCE(polarity, 0) INTO p FROM reaction_types WHERE id = NEW.reaction_type_id; UPDATE comments SET reactions_count = reactions_count + 1, positive_reactions = positive_reactions + (COALESCE(p, 0) > 0)::int, negative_reactions = negative_reactions + (COALESCE(p, 0) < 0)::int WHERE id = NEW.entity_id; END IF; RETURN NULL; END; $$ LANGUAGE plpgsql;
+DROP TRIGGER IF EXISTS count_comment_reactions ON reactions;
+CREATE TRIGGER count_comment_reactions AFTER INSERT OR DELETE OR UPDATE OF entity_id, reaction_type_id ON reactions FOR EACH ROW EXECUTE FUNCTION update_comment_reaction_counts();
+
+CREATE INDEX IF NOT EXISTS idx_comments_top ON comments (entity_id, entity_type, reactions_count DESC, id DESC);
+CREATE INDEX IF NOT EXISTS idx_comments_controversial ON comments (entity_id, entity_type, controversy DESC, id DESC);
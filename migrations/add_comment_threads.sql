@@ -0,0 +1,14 @@
+ALTER TABLE comments ADD COLUMN IF NOT EXISTS parent_id INT REFERENCES comments(id) ON DELETE CASCADE;
+ALTER TABLE comments ADD COLUMN IF NOT EXISTS post_id INT;
+ALTER TABLE comments ADD COLUMN IF NOT EXISTS depth INT NOT NULL DEFAULT 0;
+ALTER TABLE comments ADD COLUMN IF NOT EXISTS path TEXT COLLATE "C";
+
+WITH RECURSIVE tree AS (SELECT id, NULL::INT AS parent_id, entity_id AS post_id, 0 AS depth, lpad(id::text, 10, '0') AS path FROM comments WHERE entity_type = 'comment' UNION ALL SELECT c.id, t.id, t.post_id, t.depth + 1, t.path || '.' || lpad(c.id::text, 10, '0') FROM comments c JOIN tree t ON c.entity_type = 'reply' AND c.entity_id = t.id) UPDATE comments c SET parent_id = tree.parent_id, post_id = tree.post_id, depth = tree.depth, path = tree.path FROM tree WHERE c.id = tree.id AND c.path IS NULL;
+
+CREATE OR REPLACE FUNCTION set_comment_path() RETURNS TRIGGER AS $$ DECLARE parent RECORD; BEGIN IF NEW.entity_type = 'reply' THEN NEW.parent_id := NEW.entity_id; SELECT post_id, depth, path INTO parent FROM comments WHERE id = NEW.entity_id; NEW.post_id := parent.post_id; NEW.depth := parent.depth + 1; NEW.path := parent.path || '.' || lpad(NEW.id::text, 10, '0'); ELSE NEW.parent_id := NULL; NEW.post_id := NEW.entity_id; NEW.depth := 0; NEW.path := lpad(NEW.id::text, 10, '0'); END IF; RETURN NEW; END; $$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS set_comment_path ON comments;
+CREATE TRIGGER set_comment_path BEFORE INSERT ON comments FOR EACH ROW EXECUTE FUNCTION set_comment_path();
+
+CREATE INDEX IF NOT EXISTS idx_comments_parent_created_at ON comments (parent_id, created_at, id);
+CREATE INDEX IF NOT EXISTS idx_comments_post_path ON comments (post_id, path);
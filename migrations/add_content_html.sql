@@ -0,0 +1,2 @@
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS content_html TEXT;
+ALTER TABLE comments ADD COLUMN IF NOT EXISTS content_html TEXT;
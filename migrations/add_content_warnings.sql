@@ -0,0 +1,7 @@
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS content_warning TEXT;
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS sensitive BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS warning_forced BOOLEAN NOT NULL DEFAULT FALSE;
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS sensitive_content VARCHAR(10) NOT NULL DEFAULT 'blur';
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_sensitive_content_check;
+ALTER TABLE users ADD CONSTRAINT users_sensitive_content_check CHECK (sensitive_content IN ('show', 'blur', 'hide'));
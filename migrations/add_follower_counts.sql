@@ -0,0 +1,18 @@
+-- Follower counts of users, kept up to date by a trigger so that popular
+-- authors are found without counting the followers table on every feed read.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS followers_count INT NOT NULL DEFAULT 0;
+
+UPDATE users u SET followers_count = (SELECT COUNT(*) FROM followers f WHERE f.followee_id = u.id);
+
+CREATE INDEX IF NOT EXISTS idx_users_followers_count ON users (followers_count);
+
+CREATE OR REPLACE FUNCTION update_followers_count() RETURNS TRIGGER AS $$ BEGIN IF TG_OP = 'INSERT' THEN UPDATE users SET followers_count = followers_count + 1 WHERE id = NEW.followee_id; ELSE UPDATE users SET followers_count = followers_count - 1 WHERE id = OLD.followee_id; END IF; RETURN NULL; END; $$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS set_users_followers_count ON followers;
+
+CREATE TRIGGER set_users_followers_count AFTER INSERT OR DELETE ON followers FOR EACH ROW EXECUTE FUNCTION update_followers_count();
+
+-- Gaining or losing a follower is not an edit of the profile.
+DROP TRIGGER IF EXISTS set_users_updated_at ON users;
+
+CREATE TRIGGER set_users_updated_at BEFORE UPDATE ON users FOR EACH ROW WHEN (OLD.followers_count IS NOT DISTINCT FROM NEW.followers_count) EXECUTE FUNCTION update_updated_at_column();
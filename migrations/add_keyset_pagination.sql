@@ -0,0 +1,12 @@
+-- Keyset (cursor) pagination orders every list by (created_at, id).
+ALTER TABLE followers ADD COLUMN IF NOT EXISTS created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS idx_posts_author_created_at ON posts (author_id, created_at DESC, id DESC);
+
+CREATE INDEX IF NOT EXISTS idx_comments_entity_created_at ON comments (entity_id, entity_type, created_at DESC, id DESC);
+
+CREATE INDEX IF NOT EXISTS idx_followers_followee_created_at ON followers (followee_id, created_at DESC, follower_id DESC);
+
+CREATE INDEX IF NOT EXISTS idx_followers_follower_created_at ON followers (follower_id, created_at DESC, followee_id DESC);
+
+CREATE INDEX IF NOT EXISTS idx_users_created_at ON users (created_at DESC, id DESC);
@@ -0,0 +1,7 @@
+-- Reaction, comment and reply counts of posts, kept in Redis and flushed
+-- here periodically instead of being counted on every listing.
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS reactions_count INT NOT NULL DEFAULT 0;
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS comments_count INT NOT NULL DEFAULT 0;
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS replies_count INT NOT NULL DEFAULT 0;
+
+UPDATE posts p SET reactions_count = (SELECT COUNT(*) FROM reactions r WHERE r.entity_type = 'post' AND r.entity_id = p.id), comments_count = (SELECT COUNT(*) FROM comments c WHERE c.post_id = p.id AND c.parent_id IS NULL AND c.status = 'active'), replies_count = (SELECT COUNT(*) FROM comments c WHERE c.post_id = p.id AND c.parent_id IS NOT NULL AND c.status = 'active');
@@ -0,0 +1,5 @@
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS continues_post_id INT REFERENCES posts(id) ON DELETE SET NULL;
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS thread_root_id INT REFERENCES posts(id) ON DELETE SET NULL;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_posts_continues_post_id ON posts(continues_post_id);
+CREATE INDEX IF NOT EXISTS idx_posts_thread_root_id ON posts(thread_root_id);
@@ -0,0 +1,17 @@
+ALTER TABLE reactions ADD COLUMN IF NOT EXISTS entity_type TEXT NOT NULL DEFAULT 'post' CHECK (entity_type IN ('post', 'comment'));
+
+-- Reactions used to be keyed on the ID alone. Those pointing at no post but
+-- at a comment can only have been meant for the comment; the rest stay on
+-- the post they were counted for so far.
+UPDATE reactions r SET entity_type = 'comment' WHERE NOT EXISTS (SELECT 1 FROM posts p WHERE p.id = r.entity_id) AND EXISTS (SELECT 1 FROM comments c WHERE c.id = r.entity_id);
+
+ALTER TABLE reactions DROP CONSTRAINT IF EXISTS reactions_user_id_entity_id_key;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_reactions_user_entity ON reactions (user_id, entity_type, entity_id);
+CREATE INDEX IF NOT EXISTS idx_reactions_entity ON reactions (entity_type, entity_id);
+
+-- Comment scores only count reactions to comments.
+CREATE OR REPLACE FUNCTION update_comment_reaction_counts() RETURNS TRIGGER AS $$ DECLARE p SMALLINT; BEGIN IF TG_OP IN ('DELETE', 'UPDATE') AND OLD.entity_type = 'comment' THEN SELECT COALESCE(polarity, 0) INTO p FROM reaction_types WHERE id = OLD.reaction_type_id; UPDATE comments SET reactions_count = reactions_count - 1, positive_reactions = positive_reactions - (COALESCE(p, 0) > 0)::int, negative_reactions = negative_reactions - (COALESCE(p, 0) < 0)::int WHERE id = OLD.entity_id; END IF; IF TG_OP IN ('INSERT', 'UPDATE') AND NEW.entity_type = 'comment' THEN SELECT COALESCE(polarity, 0) INTO p FROM reaction_types WHERE id = NEW.reaction_type_id; UPDATE comments SET reactions_count = reactions_count + 1, positive_reactions = positive_reactions + (COALESCE(p, 0) > 0)::int, negative_reactions = negative_reactions + (COALESCE(p, 0) < 0)::int WHERE id = NEW.entity_id; END IF; RETURN NULL; END; $$ LANGUAGE plpgsql;
+DROP TRIGGER IF EXISTS count_comment_reactions ON reactions;
+CREATE TRIGGER count_comment_reactions AFTER INSERT OR DELETE OR UPDATE OF entity_type, entity_id, reaction_type_id ON reactions FOR EACH ROW EXECUTE FUNCTION update_comment_reaction_counts();
+
+UPDATE comments c SET reactions_count = COALESCE(s.total, 0), positive_reactions = COALESCE(s.positive, 0), negative_reactions = COALESCE(s.negative, 0) FROM comments c2 LEFT JOIN (SELECT r.entity_id, COUNT(*) AS total, COUNT(*) FILTER (WHERE rt.polarity > 0) AS positive, COUNT(*) FILTER (WHERE rt.polarity < 0) AS negative FROM reactions r JOIN reaction_types rt ON rt.id = r.reaction_type_id WHERE r.entity_type = 'comment' GROUP BY r.entity_id) s ON s.entity_id = c2.id WHERE c.id = c2.id;
@@ -0,0 +1,17 @@
+ALTER TABLE reaction_types ADD COLUMN IF NOT EXISTS emoji VARCHAR(32);
+ALTER TABLE reaction_types ADD COLUMN IF NOT EXISTS image_url VARCHAR(255);
+ALTER TABLE reaction_types ADD COLUMN IF NOT EXISTS verb VARCHAR(50) NOT NULL DEFAULT 'reacted to';
+ALTER TABLE reaction_types ADD COLUMN IF NOT EXISTS position INT NOT NULL DEFAULT 0;
+ALTER TABLE reaction_types ADD COLUMN IF NOT EXISTS enabled BOOLEAN NOT NULL DEFAULT TRUE;
+ALTER TABLE reaction_types ADD COLUMN IF NOT EXISTS created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP;
+ALTER TABLE reaction_types ADD COLUMN IF NOT EXISTS updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP;
+
+-- Names double as notification types, new_reaction_<name>, so they must be
+-- unique regardless of case.
+CREATE UNIQUE INDEX IF NOT EXISTS idx_reaction_types_lower_name ON reaction_types (LOWER(name));
+
+UPDATE reaction_types SET emoji = '👍', verb = 'liked', position = 1 WHERE name = 'Like' AND emoji IS NULL AND image_url IS NULL;
+UPDATE reaction_types SET emoji = '👎', verb = 'disliked', position = 2 WHERE name = 'Dislike' AND emoji IS NULL AND image_url IS NULL;
+UPDATE reaction_types SET emoji = '❤️', verb = 'loved', position = 3 WHERE name = 'Love' AND emoji IS NULL AND image_url IS NULL;
+UPDATE reaction_types SET emoji = '😠', verb = 'reacted angrily to', position = 4 WHERE name = 'Angry' AND emoji IS NULL AND image_url IS NULL;
+UPDATE reaction_types SET emoji = '😮', verb = 'were amazed by', position = 5 WHERE name = 'Wow' AND emoji IS NULL AND image_url IS NULL;
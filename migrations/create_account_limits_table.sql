@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS account_limits (
+    user_id INT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    standing VARCHAR(20) NOT NULL CHECK (standing IN ('trusted', 'shadow_limited')),
+    automatic BOOLEAN NOT NULL DEFAULT FALSE,
+    reason TEXT,
+    set_by INT REFERENCES users(id) ON DELETE SET NULL,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_account_limits_standing_updated_at ON account_limits(standing, updated_at DESC, user_id DESC);
@@ -0,0 +1,24 @@
+CREATE TABLE IF NOT EXISTS automod_rules (
+    id SERIAL PRIMARY KEY,
+    type VARCHAR(20) NOT NULL CHECK (type IN ('keyword', 'regex', 'link_domain')),
+    pattern TEXT NOT NULL,
+    action VARCHAR(20) NOT NULL CHECK (action IN ('reject', 'flag', 'content_warning')),
+    scope VARCHAR(20) NOT NULL DEFAULT 'all' CHECK (scope IN ('all', 'posts', 'comments')),
+    content_warning TEXT,
+    dry_run BOOLEAN NOT NULL DEFAULT FALSE,
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    hits INT NOT NULL DEFAULT 0,
+    dry_run_hits INT NOT NULL DEFAULT 0,
+    last_hit_at TIMESTAMP,
+    created_by INT REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+ALTER TYPE status_type ADD VALUE IF NOT EXISTS 'flagged';
+
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS held_visibility INT;
+
+ALTER TABLE reports ALTER COLUMN reporter_id DROP NOT NULL;
+ALTER TABLE reports DROP CONSTRAINT IF EXISTS reports_reason_check;
+ALTER TABLE reports ADD CONSTRAINT reports_reason_check CHECK (reason IN ('spam', 'harassment', 'hate', 'violence', 'nudity', 'misinformation', 'self_harm', 'other', 'automod'));
@@ -0,0 +1,39 @@
+CREATE TABLE IF NOT EXISTS actor_keys (
+    user_id INT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    public_key_pem TEXT NOT NULL,
+    private_key_pem TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS remote_actors (
+    user_id INT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    actor_uri TEXT UNIQUE NOT NULL,
+    username VARCHAR(255) NOT NULL,
+    domain VARCHAR(255) NOT NULL,
+    inbox_url TEXT NOT NULL,
+    shared_inbox_url TEXT,
+    public_key_id TEXT NOT NULL,
+    public_key_pem TEXT NOT NULL,
+    fetched_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_remote_actors_public_key_id ON remote_actors(public_key_id);
+
+CREATE TABLE IF NOT EXISTS remote_posts (
+    object_uri TEXT PRIMARY KEY,
+    post_id INT NOT NULL UNIQUE REFERENCES posts(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS deliveries (
+    id BIGSERIAL PRIMARY KEY,
+    sender_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    inbox_url TEXT NOT NULL,
+    activity TEXT NOT NULL,
+    attempts INT NOT NULL DEFAULT 0,
+    status VARCHAR(10) NOT NULL DEFAULT 'pending',
+    last_error TEXT,
+    next_attempt_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_deliveries_pending ON deliveries(next_attempt_at) WHERE status = 'pending';
@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS public.link_previews (
+    url TEXT PRIMARY KEY,
+    title TEXT,
+    description TEXT,
+    image_url TEXT,
+    site_name TEXT,
+    failed BOOLEAN DEFAULT FALSE,
+    fetched_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS public.content_links (
+    entity_type VARCHAR(20) NOT NULL,
+    entity_id INT NOT NULL,
+    url TEXT NOT NULL,
+    position INT NOT NULL DEFAULT 0,
+    PRIMARY KEY (entity_type, entity_id, url)
+);
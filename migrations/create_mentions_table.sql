@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS mentions (
+    entity_type VARCHAR(20) NOT NULL CHECK (entity_type IN ('post', 'comment')),
+    entity_id INT NOT NULL,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (entity_type, entity_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_mentions_user_created_at ON mentions (user_id, created_at DESC);
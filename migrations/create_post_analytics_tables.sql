@@ -0,0 +1,35 @@
+CREATE TABLE IF NOT EXISTS post_views (
+    id BIGSERIAL PRIMARY KEY,
+    post_id INT NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+    viewer_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    source VARCHAR(10) NOT NULL,
+    viewed_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_post_views_viewed_at ON post_views(viewed_at);
+
+CREATE TABLE IF NOT EXISTS post_stats_hourly (
+    post_id INT NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+    author_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    bucket TIMESTAMP NOT NULL,
+    views INT NOT NULL DEFAULT 0,
+    unique_viewers INT NOT NULL DEFAULT 0,
+    reactions INT NOT NULL DEFAULT 0,
+    comments INT NOT NULL DEFAULT 0,
+    PRIMARY KEY (post_id, bucket)
+);
+
+CREATE INDEX IF NOT EXISTS idx_post_stats_hourly_author_bucket ON post_stats_hourly(author_id, bucket);
+
+CREATE TABLE IF NOT EXISTS post_stats_daily (
+    post_id INT NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+    author_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    bucket TIMESTAMP NOT NULL,
+    views INT NOT NULL DEFAULT 0,
+    unique_viewers INT NOT NULL DEFAULT 0,
+    reactions INT NOT NULL DEFAULT 0,
+    comments INT NOT NULL DEFAULT 0,
+    PRIMARY KEY (post_id, bucket)
+);
+
+CREATE INDEX IF NOT EXISTS idx_post_stats_daily_author_bucket ON post_stats_daily(author_id, bucket);
@@ -0,0 +1,20 @@
+CREATE TABLE IF NOT EXISTS reports (
+    id SERIAL PRIMARY KEY,
+    reporter_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    target_type VARCHAR(20) NOT NULL CHECK (target_type IN ('post', 'comment', 'user')),
+    target_id INT NOT NULL,
+    target_owner_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    reason VARCHAR(30) NOT NULL CHECK (reason IN ('spam', 'harassment', 'hate', 'violence', 'nudity', 'misinformation', 'self_harm', 'other')),
+    details TEXT,
+    status VARCHAR(20) NOT NULL DEFAULT 'open' CHECK (status IN ('open', 'resolved')),
+    assignee_id INT REFERENCES users(id) ON DELETE SET NULL,
+    resolution VARCHAR(20) CHECK (resolution IN ('dismiss', 'hide', 'delete', 'ban')),
+    resolution_note TEXT,
+    resolved_by INT REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    resolved_at TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_reports_open_reporter_target ON reports(reporter_id, target_type, target_id) WHERE status = 'open';
+CREATE INDEX IF NOT EXISTS idx_reports_status_created_at ON reports(status, created_at, id);
+CREATE INDEX IF NOT EXISTS idx_reports_open_target ON reports(target_type, target_id) WHERE status = 'open';
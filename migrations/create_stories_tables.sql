@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS stories (
+    id SERIAL PRIMARY KEY,
+    author_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    content TEXT,
+    media_url TEXT,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_stories_author_expires_at ON stories(author_id, expires_at);
+CREATE INDEX IF NOT EXISTS idx_stories_expires_at ON stories(expires_at);
+
+CREATE TABLE IF NOT EXISTS story_views (
+    story_id INT NOT NULL REFERENCES stories(id) ON DELETE CASCADE,
+    viewer_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    viewed_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (story_id, viewer_id)
+);
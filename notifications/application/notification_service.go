@@ -4,23 +4,74 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"log"
 	"n/domain"
 	"strconv"
+	"sync"
 	"time"
 
 	"github.com/lib/pq"
 )
 
+// DefaultReactionCatalogRefresh is how long the reaction catalog is cached,
+// so reaction types added by admins show up without a restart.
+const DefaultReactionCatalogRefresh = time.Minute
+
 type NotificationService struct {
-	repo   domain.NotificationRepository
-	events domain.EventListener
+	repo      domain.NotificationRepository
+	events    domain.EventListener
+	reactions domain.ReactionCatalogRepository
+	refresh   time.Duration
+
+	mu       sync.Mutex
+	catalog  domain.ReactionCatalog
+	loadedAt time.Time
 }
 
-func NewNotificationService(repo domain.NotificationRepository, events domain.EventListener) *NotificationService {
-	return &NotificationService{repo: repo, events: events}
+func NewNotificationService(repo domain.NotificationRepository, events domain.EventListener, reactions domain.ReactionCatalogRepository, refresh time.Duration) *NotificationService {
+	return &NotificationService{repo: repo, events: events, reactions: reactions, refresh: refresh}
+}
+
+// reactionCatalog returns the cached reaction catalog, reloading it when it
+// is older than the refresh interval or when reload is set. A stale catalog
+// is kept when reloading fails.
+func (s *NotificationService) reactionCatalog(reload bool) (domain.ReactionCatalog, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.catalog != nil && !reload && time.Since(s.loadedAt) < s.refresh {
+		return s.catalog, nil
+	}
+	catalog, err := s.reactions.GetReactionCatalog()
+	if err != nil {
+		if s.catalog != nil {
+			log.Printf("failed to reload reaction catalog: %v", err)
+			return s.catalog, nil
+		}
+		return nil, err
+	}
+	s.catalog, s.loadedAt = catalog, time.Now()
+	return catalog, nil
 }
 
 func (s *NotificationService) SendNotification(n domain.NotificationRequest) error {
+	// Only reaction messages are worded from the catalog.
+	var catalog domain.ReactionCatalog
+	if n.Type.IsReaction() {
+		var err error
+		if catalog, err = s.reactionCatalog(false); err != nil {
+			return err
+		}
+		if _, ok := catalog[n.Type]; !ok {
+			// The type may have been added since the catalog was loaded.
+			if catalog, err = s.reactionCatalog(true); err != nil {
+				return err
+			}
+			if _, ok := catalog[n.Type]; !ok {
+				return domain.ErrUnknownReactionType
+			}
+		}
+	}
 
 	existing, err := s.repo.FindRecentNotification(n.UserID, n.EntityID, string(n.Type))
 	if err != nil {
@@ -31,7 +82,7 @@ func (s *NotificationService) SendNotification(n domain.NotificationRequest) err
 
 	if existing != nil {
 		existing.ActorIDs = append(existing.ActorIDs, int64(n.SenderId))
-		existing.Message = existing.GenerateMessage()
+		existing.Message = existing.GenerateMessage(catalog)
 		s.repo.Update(existing)
 
 		jsonNotification, err := json.Marshal(existing)
@@ -57,7 +108,7 @@ func (s *NotificationService) SendNotification(n domain.NotificationRequest) err
 			ActorIDs:  pq.Int64Array{n.SenderId},
 			CreatedAt: time.Now().Format(time.RFC3339),
 		}
-		notification.Message = notification.GenerateMessage()
+		notification.Message = notification.GenerateMessage(catalog)
 
 		if err := s.repo.Save(notification); err != nil {
 			return err
@@ -78,9 +129,24 @@ func (s *NotificationService) SendNotification(n domain.NotificationRequest) err
 	return nil
 }
 
-// Fetch unsent messages and mark them as sent
-func (s *NotificationService) FetchNotifications(userID string, limit, offset int) ([]domain.Notification, error) {
-	return s.repo.GetNotifications(userID, limit, offset)
+// Fetch unsent messages, newest first. One extra row is requested so the
+// returned cursor is only set when another page exists.
+func (s *NotificationService) FetchNotifications(userID string, before *domain.Cursor, limit, offset int) ([]domain.Notification, *domain.Cursor, error) {
+	notifications, err := s.repo.GetNotifications(userID, before, limit+1, offset)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(notifications) <= limit {
+		return notifications, nil, nil
+	}
+
+	notifications = notifications[:limit]
+	last := notifications[len(notifications)-1]
+	createdAt, err := time.Parse(time.RFC3339Nano, last.CreatedAt)
+	if err != nil {
+		return nil, nil, err
+	}
+	return notifications, &domain.Cursor{CreatedAt: createdAt, ID: last.ID}, nil
 }
 
 // Subscribe to real-time notifications for a specific user
@@ -1,7 +1,9 @@
 package domain
 
 import (
+	"errors"
 	"fmt"
+	"strings"
 
 	"github.com/lib/pq"
 )
@@ -9,25 +11,47 @@ import (
 type NotificationType string
 
 const (
-	NewFollower        NotificationType = "new_follower"
-	NewReactionLike    NotificationType = "new_reaction_like"
-	NewReactionDislike NotificationType = "new_reaction_dislike"
-	NewReactionLove    NotificationType = "new_reaction_love"
-	NewReactionLaugh   NotificationType = "new_reaction_laugh"
-	NewReactionAngry   NotificationType = "new_reaction_angry"
-	NewReactionWow     NotificationType = "new_reaction_wow"
-	NewMention         NotificationType = "new_mention"
-	NewDirectMessage   NotificationType = "new_direct_message"
-	NewPostComment     NotificationType = "new_post_comment"
-	NewCommentReply    NotificationType = "new_comment_reply"
+	NewFollower      NotificationType = "new_follower"
+	NewMention       NotificationType = "new_mention"
+	NewDirectMessage NotificationType = "new_direct_message"
+	NewPostComment   NotificationType = "new_post_comment"
+	NewCommentReply  NotificationType = "new_comment_reply"
+	ReportActioned   NotificationType = "report_actioned"
+	ReportDismissed  NotificationType = "report_dismissed"
+
+	// reactionTypePrefix starts the types of reaction notifications, which
+	// end with the lowercased name of the reaction type.
+	reactionTypePrefix = "new_reaction_"
+
+	// defaultReactionVerb is used for reaction types missing from the
+	// catalog.
+	defaultReactionVerb = "reacted to"
 )
 
+var ErrUnknownReactionType = errors.New("unknown reaction type")
+
+// ReactionCatalog holds the verb of every reaction type defined in the main
+// service, keyed by notification type, as in new_reaction_like: "liked".
+type ReactionCatalog map[NotificationType]string
+
+// ReactionNotificationType is the notification type for reactions of the
+// named type.
+func ReactionNotificationType(name string) NotificationType {
+	return NotificationType(reactionTypePrefix + strings.ToLower(name))
+}
+
+// IsReaction reports whether the notification type is about a reaction.
+func (t NotificationType) IsReaction() bool {
+	return strings.HasPrefix(string(t), reactionTypePrefix)
+}
+
 type EntityType string
 
 const (
 	Post    EntityType = "post"
 	Comment EntityType = "comment"
 	Reply   EntityType = "reply"
+	Report  EntityType = "report"
 )
 
 type NotificationRequest struct {
@@ -51,8 +75,9 @@ type Notification struct {
 	IsRead    bool          `json:"is_read"`
 }
 
-// GenerateMessage generates a notification message based on the type
-func (n Notification) GenerateMessage() string {
+// GenerateMessage generates a notification message based on the type.
+// Reaction messages use the verbs of the catalog.
+func (n Notification) GenerateMessage(reactions ReactionCatalog) string {
 	switch n.Type {
 	case NewFollower:
 		return fmt.Sprintf("You have a new follower!")
@@ -69,53 +94,33 @@ func (n Notification) GenerateMessage() string {
 	case NewCommentReply:
 		return fmt.Sprintf("Someone replied to your comment.")
 
+	case ReportActioned:
+		return "Thanks for your report. A moderator reviewed it and took action."
+
+	case ReportDismissed:
+		return "Thanks for your report. A moderator reviewed it and found no violation."
+
 	default:
 		// Handle reactions separately
-		if isReaction(n.Type) {
-			return generateReactionMessage(n.ActorIDs, n.Type, n.EntityType)
+		if n.Type.IsReaction() {
+			return generateReactionMessage(n.ActorIDs, reactionVerb(reactions, n.Type), n.EntityType)
 		}
 	}
 
 	return "You have a new notification."
 }
 
-// isReaction checks if the notification type is a reaction
-func isReaction(notificationType NotificationType) bool {
-	reactionTypes := map[NotificationType]bool{
-		NewReactionLike:    true,
-		NewReactionDislike: true,
-		NewReactionLove:    true,
-		NewReactionLaugh:   true,
-		NewReactionAngry:   true,
-		NewReactionWow:     true,
-	}
-	return reactionTypes[notificationType]
-}
-
-// getReactionVerb returns the correct verb based on the reaction type
-func getReactionVerb(notificationType NotificationType) string {
-	switch notificationType {
-	case NewReactionLike:
-		return "liked"
-	case NewReactionDislike:
-		return "disliked"
-	case NewReactionLove:
-		return "loved"
-	case NewReactionLaugh:
-		return "laughed at"
-	case NewReactionAngry:
-		return "reacted angrily to"
-	case NewReactionWow:
-		return "were amazed by"
-	default:
-		return "reacted to"
+// reactionVerb returns the verb of the reaction type from the catalog
+func reactionVerb(reactions ReactionCatalog, notificationType NotificationType) string {
+	if verb, ok := reactions[notificationType]; ok && verb != "" {
+		return verb
 	}
+	return defaultReactionVerb
 }
 
 // generateReactionMessage creates a message for reactions with formatted names
-func generateReactionMessage(ActorIDs pq.Int64Array, reactionType NotificationType, entityType EntityType) string {
+func generateReactionMessage(ActorIDs pq.Int64Array, verb string, entityType EntityType) string {
 	count := len(ActorIDs)
-	verb := getReactionVerb(reactionType)
 
 	if count == 0 {
 		return fmt.Sprintf("Someone %s your %s.", verb, entityType)
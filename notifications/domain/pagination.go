@@ -0,0 +1,10 @@
+package domain
+
+import "time"
+
+// Cursor marks the position of the last notification of a page in
+// (created_at, id) keyset order.
+type Cursor struct {
+	CreatedAt time.Time
+	ID        int
+}
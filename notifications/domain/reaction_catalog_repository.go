@@ -0,0 +1,8 @@
+package domain
+
+type ReactionCatalogRepository interface {
+	// GetReactionCatalog loads the reaction types of the main service.
+	// Disabled types are included, reactions made before a type was
+	// disabled can still be grouped with earlier ones.
+	GetReactionCatalog() (ReactionCatalog, error)
+}
@@ -3,19 +3,16 @@
 CREATE TABLE notifications (
     id SERIAL PRIMARY KEY,
     user_id INT NOT NULL, -- The user receiving the notification
-    type VARCHAR(50) NOT NULL CHECK (
-    type IN (
+    type VARCHAR(64) NOT NULL CHECK (
+    type LIKE 'new_reaction\_%' -- Reaction types come from the reaction_types catalog
+    OR type IN (
         'new_follower',
-        'new_reaction_like',
-        'new_reaction_dislike',
-        'new_reaction_love',
-        'new_reaction_laugh',
-        'new_reaction_angry',
-        'new_reaction_wow',
         'new_direct_message',
         'new_post_comment',
         'new_comment_reply',
-        'new_mention' 
+        'new_mention',
+        'report_actioned',
+        'report_dismissed'
         )),
         message TEXT NOT NULL,
         entity_type VARCHAR(50) NOT NULL CHECK (
@@ -23,10 +20,13 @@ CREATE TABLE notifications (
             'user',
             'post',
             'comment',
-            'reaction'
+            'reaction',
+            'report'
         )), 
         entity_id INT NOT NULL, -- ID of the related post, comment, reaction, or user
         actor_ids INT[], -- Array o user IDs who triggered the event
         created_at TIMESTAMP DEFAULT NOW (),
         is_read BOOLEAN DEFAULT FALSE
-);
\ No newline at end of file
+);
+
+CREATE INDEX IF NOT EXISTS idx_notifications_user_created_at ON notifications (user_id, created_at DESC, id DESC);
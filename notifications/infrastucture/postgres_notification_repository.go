@@ -3,8 +3,8 @@ package infrastructure
 import (
 	"database/sql"
 	"errors"
-	"fmt"
 	"n/domain"
+	"time"
 
 	pg "github.com/lib/pq"
 )
@@ -18,8 +18,6 @@ func NewPostgresNotificationRepository(db *sql.DB) *PostgresNotificationReposito
 }
 
 func (r *PostgresNotificationRepository) Save(notification domain.Notification) error {
-	fmt.Printf("here===========\n", "%+v", notification)
-
 	_, err := r.db.Exec(
 		"INSERT INTO notifications (user_id, actor_ids, message, type, entity_type, entity_id, created_at) VALUES($1, $2, $3, $4, $5, $6, NOW())",
 		notification.UserID, notification.ActorIDs, notification.Message, notification.Type, notification.EntityType, notification.EntityID,
@@ -39,21 +37,29 @@ func (r *PostgresNotificationRepository) Update(notification *domain.Notificatio
 	return err
 }
 
-// Get all unsent messages for a user
-func (r *PostgresNotificationRepository) GetNotifications(userID string, limit, offset int) ([]domain.Notification, error) {
+// Get all unsent messages for a user, newest first, starting after the cursor
+func (r *PostgresNotificationRepository) GetNotifications(userID string, before *domain.Cursor, limit, offset int) ([]domain.Notification, error) {
+	var beforeTime interface{}
+	beforeID := 0
+	if before != nil {
+		beforeTime = before.CreatedAt
+		beforeID = before.ID
+	}
+
 	// Use prepared statement
 	stmt, err := r.db.Prepare(`
 		SELECT id, user_id, actor_ids, message, type, entity_type, entity_id, is_read, created_at
 		FROM notifications
 		WHERE user_id = $1 AND is_read = false
-		ORDER BY created_at DESC
-		LIMIT $2 OFFSET $3`)
+			AND ($2::timestamp IS NULL OR (created_at, id) < ($2::timestamp, $3))
+		ORDER BY created_at DESC, id DESC
+		LIMIT $4 OFFSET $5`)
 	if err != nil {
 		return nil, err
 	}
 	defer stmt.Close()
 
-	rows, err := stmt.Query(userID, limit, offset)
+	rows, err := stmt.Query(userID, beforeTime, beforeID, limit, offset)
 	if err != nil {
 		return nil, err
 	}
@@ -62,9 +68,11 @@ func (r *PostgresNotificationRepository) GetNotifications(userID string, limit,
 	var notifications []domain.Notification
 	for rows.Next() {
 		var msg domain.Notification
-		if err := rows.Scan(&msg.ID, &msg.UserID, &msg.ActorIDs, &msg.Message, &msg.Type, &msg.EntityType, &msg.EntityID, &msg.IsRead, &msg.CreatedAt); err != nil {
+		var createdAt time.Time
+		if err := rows.Scan(&msg.ID, &msg.UserID, &msg.ActorIDs, &msg.Message, &msg.Type, &msg.EntityType, &msg.EntityID, &msg.IsRead, &createdAt); err != nil {
 			return nil, err
 		}
+		msg.CreatedAt = createdAt.Format(time.RFC3339Nano)
 		notifications = append(notifications, msg)
 	}
 
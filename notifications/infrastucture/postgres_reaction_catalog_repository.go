@@ -0,0 +1,34 @@
+package infrastructure
+
+import (
+	"database/sql"
+	"n/domain"
+)
+
+// PostgresReactionCatalogRepository reads the reaction_types table that the
+// main service manages in the same database.
+type PostgresReactionCatalogRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresReactionCatalogRepository(db *sql.DB) *PostgresReactionCatalogRepository {
+	return &PostgresReactionCatalogRepository{db: db}
+}
+
+func (r *PostgresReactionCatalogRepository) GetReactionCatalog() (domain.ReactionCatalog, error) {
+	rows, err := r.db.Query("SELECT name, verb FROM reaction_types")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	catalog := make(domain.ReactionCatalog)
+	for rows.Next() {
+		var name, verb string
+		if err := rows.Scan(&name, &verb); err != nil {
+			return nil, err
+		}
+		catalog[domain.ReactionNotificationType(name)] = verb
+	}
+	return catalog, rows.Err()
+}
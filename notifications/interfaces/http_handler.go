@@ -2,13 +2,12 @@ package interfaces
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"n/application"
 	"n/domain"
 	"n/utils"
 	"net/http"
-
-	"golang.org/x/sync/errgroup"
 )
 
 type NotificationHandler struct {
@@ -29,6 +28,10 @@ func (h *NotificationHandler) SendNotification(w http.ResponseWriter, r *http.Re
 	}
 
 	if err := h.service.SendNotification(req); err != nil {
+		if errors.Is(err, domain.ErrUnknownReactionType) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 		http.Error(w, "Failed to send notification", http.StatusInternalServerError)
 		return
 	}
@@ -91,41 +94,36 @@ func (h *NotificationHandler) GetNotifications(w http.ResponseWriter, r *http.Re
 
 	limit, page := utils.ParsePagination(r)
 
-	offset := (page - 1) * limit
-
-	var notifications []domain.Notification
-	var notificationsCount int
-
-	// Create a new errgroup
-	var g errgroup.Group
-	// First task: Fetch posts
-	g.Go(func() error {
-		var err error
-		notifications, err = h.service.FetchNotifications(userId, limit, offset)
-		if err != nil {
-			return err
-		}
-		return nil
-	})
-
-	g.Go(func() error {
-		var err error
-		notificationsCount, err = h.service.CountByUserID(userId)
+	var before *domain.Cursor
+	offset := 0
+	if token := query.Get("cursor"); token != "" {
+		createdAt, id, err := utils.DecodeCursor(token)
 		if err != nil {
-			return err
+			http.Error(w, "invalid cursor", http.StatusBadRequest)
+			return
 		}
-		return nil
-	})
+		before = &domain.Cursor{CreatedAt: createdAt, ID: id}
+	} else if query.Has("page") {
+		w.Header().Set("Deprecation", "true")
+		w.Header().Set("Warning", `299 - "page is deprecated, use cursor"`)
+		offset = (page - 1) * limit
+	}
 
-	// Wait for both goroutines to finish
-	if err := g.Wait(); err != nil {
+	notifications, next, err := h.service.FetchNotifications(userId, before, limit, offset)
+	if err != nil {
 		http.Error(w, "could not complete request", http.StatusInternalServerError)
 		return
 	}
 
+	nextCursor := ""
+	if next != nil {
+		nextCursor = utils.EncodeCursor(next.CreatedAt, next.ID)
+	}
+
 	response := map[string]interface{}{
-		"data":    notifications,
-		"hasMore": notificationsCount > offset+limit,
+		"data":        notifications,
+		"next_cursor": nextCursor,
+		"hasMore":     next != nil,
 	}
 
 	w.Header().Set("Content-Type", "application/json")
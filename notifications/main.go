@@ -45,7 +45,8 @@ func main() {
 
 	// Dependency Injection
 	repo := infrastructure.NewPostgresNotificationRepository(db)
-	service := application.NewNotificationService(repo, redis)
+	reactionCatalog := infrastructure.NewPostgresReactionCatalogRepository(db)
+	service := application.NewNotificationService(repo, redis, reactionCatalog, application.DefaultReactionCatalogRefresh)
 	handler := interfaces.NewNotificationHandler(service)
 
 	// HTTP Router
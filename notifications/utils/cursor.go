@@ -0,0 +1,37 @@
+package utils
+
+import (
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// EncodeCursor turns a (created_at, id) keyset position into an opaque token
+// that clients send back as the `cursor` query parameter.
+func EncodeCursor(createdAt time.Time, id int) string {
+	raw := strconv.FormatInt(createdAt.UnixMicro(), 10) + ":" + strconv.Itoa(id)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// DecodeCursor reverses EncodeCursor.
+func DecodeCursor(token string) (time.Time, int, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return time.Time{}, 0, errors.New("malformed cursor")
+	}
+	parts := strings.SplitN(string(raw), ":", 2)
+	if len(parts) != 2 {
+		return time.Time{}, 0, errors.New("malformed cursor")
+	}
+	micros, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return time.Time{}, 0, errors.New("malformed cursor")
+	}
+	id, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return time.Time{}, 0, errors.New("malformed cursor")
+	}
+	return time.UnixMicro(micros).UTC(), id, nil
+}
@@ -1,8 +1,8 @@
 INSERT INTO comments (author_id, entity_id,entity_type, content, status)
 VALUES 
-(1,1,'reply','Great post! Keep up the good work.','approved'), 
-(2,1,'comment','This article was really informative, thanks!','approved'), 
-(1,1,'reply','The quality of this product is excellent.','rejected'), 
-(1,4,'comment','Not satisfied with the product. It stopped working after a week.','rejected'), 
-(2,1,'comment', 'I disagree with some points in this post.','approved');
+(1,1,'reply','Great post! Keep up the good work.','active'), 
+(2,1,'comment','This article was really informative, thanks!','active'), 
+(1,1,'reply','The quality of this product is excellent.','hidden'), 
+(1,4,'comment','Not satisfied with the product. It stopped working after a week.','hidden'), 
+(2,1,'comment', 'I disagree with some points in this post.','active');
 
@@ -16,6 +16,30 @@ func SeedData(db *sql.DB) http.HandlerFunc {
 		Seed(db, "./migrations/create_followers_table.sql")
 		Seed(db, "./migrations/create_tags_table.sql")
 		Seed(db, "./migrations/create_comments_table.sql")
+		Seed(db, "./migrations/add_keyset_pagination.sql")
+		Seed(db, "./migrations/create_bookmarks_table.sql")
+		Seed(db, "./migrations/create_blocks_table.sql")
+		Seed(db, "./migrations/add_search.sql")
+		Seed(db, "./migrations/create_link_previews_table.sql")
+		Seed(db, "./migrations/add_content_warnings.sql")
+		Seed(db, "./migrations/create_post_analytics_tables.sql")
+		Seed(db, "./migrations/add_content_html.sql")
+		Seed(db, "./migrations/add_post_threads.sql")
+		Seed(db, "./migrations/create_stories_tables.sql")
+		Seed(db, "./migrations/create_federation_tables.sql")
+		Seed(db, "./migrations/create_reports_table.sql")
+		Seed(db, "./migrations/create_automod_tables.sql")
+		Seed(db, "./migrations/create_account_limits_table.sql")
+		Seed(db, "./migrations/add_comment_threads.sql")
+		Seed(db, "./migrations/add_comment_lifecycle.sql")
+		Seed(db, "./migrations/add_comment_scores.sql")
+		Seed(db, "./migrations/add_comment_controls.sql")
+		Seed(db, "./migrations/create_mentions_table.sql")
+		Seed(db, "./migrations/add_reaction_entity_types.sql")
+		Seed(db, "./migrations/add_private_accounts.sql")
+		Seed(db, "./migrations/add_reaction_type_catalog.sql")
+		Seed(db, "./migrations/add_post_counters.sql")
+		Seed(db, "./migrations/add_follower_counts.sql")
 
 		Seed(db, "./seeds/seed_users.sql")
 		Seed(db, "./seeds/seed_posts.sql")
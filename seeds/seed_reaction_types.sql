@@ -1,6 +1,6 @@
-INSERT INTO reaction_types (name) VALUES
-('Like'),
-('Dislike'),
-('Love'),
-('Angry'),
-('Wow');
\ No newline at end of file
+INSERT INTO reaction_types (name, polarity, emoji, verb, position) VALUES
+('Like', 1, '👍', 'liked', 1),
+('Dislike', -1, '👎', 'disliked', 2),
+('Love', 1, '❤️', 'loved', 3),
+('Angry', -1, '😠', 'reacted angrily to', 4),
+('Wow', 0, '😮', 'were amazed by', 5);
@@ -0,0 +1,69 @@
+package utils
+
+import (
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// EncodeCursor turns a (created_at, id) keyset position into an opaque token
+// that clients send back as the `cursor` query parameter.
+func EncodeCursor(createdAt time.Time, id int) string {
+	raw := strconv.FormatInt(createdAt.UnixMicro(), 10) + ":" + strconv.Itoa(id)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// DecodeCursor reverses EncodeCursor.
+func DecodeCursor(token string) (time.Time, int, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return time.Time{}, 0, errors.New("malformed cursor")
+	}
+	parts := strings.SplitN(string(raw), ":", 2)
+	if len(parts) != 2 {
+		return time.Time{}, 0, errors.New("malformed cursor")
+	}
+	micros, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return time.Time{}, 0, errors.New("malformed cursor")
+	}
+	id, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return time.Time{}, 0, errors.New("malformed cursor")
+	}
+	return time.UnixMicro(micros).UTC(), id, nil
+}
+
+// EncodeScoreCursor is EncodeCursor for lists ordered by a score first.
+func EncodeScoreCursor(score float64, createdAt time.Time, id int) string {
+	raw := strconv.FormatInt(createdAt.UnixMicro(), 10) + ":" + strconv.Itoa(id) + ":" + strconv.FormatFloat(score, 'g', -1, 64)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// DecodeScoreCursor reverses both EncodeCursor and EncodeScoreCursor. The
+// score is nil for cursors without one.
+func DecodeScoreCursor(token string) (time.Time, int, *float64, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return time.Time{}, 0, nil, errors.New("malformed cursor")
+	}
+	parts := strings.SplitN(string(raw), ":", 3)
+	if len(parts) == 2 {
+		createdAt, id, err := DecodeCursor(token)
+		return createdAt, id, nil, err
+	}
+	if len(parts) != 3 {
+		return time.Time{}, 0, nil, errors.New("malformed cursor")
+	}
+	score, err := strconv.ParseFloat(parts[2], 64)
+	if err != nil {
+		return time.Time{}, 0, nil, errors.New("malformed cursor")
+	}
+	createdAt, id, err := DecodeCursor(base64.RawURLEncoding.EncodeToString([]byte(parts[0] + ":" + parts[1])))
+	if err != nil {
+		return time.Time{}, 0, nil, err
+	}
+	return createdAt, id, &score, nil
+}
@@ -0,0 +1,103 @@
+package utils
+
+import (
+	"encoding/base64"
+	"testing"
+	"time"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	createdAt := time.Date(2024, 5, 1, 12, 30, 15, 123456000, time.UTC)
+
+	gotTime, gotID, err := DecodeCursor(EncodeCursor(createdAt, 42))
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if !gotTime.Equal(createdAt) || gotID != 42 {
+		t.Errorf("expected (%v, 42), got (%v, %d)", createdAt, gotTime, gotID)
+	}
+
+	// Cursors carry microseconds, like Postgres timestamps.
+	gotTime, _, err = DecodeCursor(EncodeCursor(createdAt.Add(789), 42))
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if !gotTime.Equal(createdAt) {
+		t.Errorf("expected nanoseconds to be dropped, got %v", gotTime)
+	}
+}
+
+func TestScoreCursorRoundTrip(t *testing.T) {
+	createdAt := time.Date(2024, 5, 1, 12, 30, 15, 123456000, time.UTC)
+
+	gotTime, gotID, score, err := DecodeScoreCursor(EncodeScoreCursor(-1.25, createdAt, 7))
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if !gotTime.Equal(createdAt) || gotID != 7 || score == nil || *score != -1.25 {
+		t.Errorf("expected (%v, 7, -1.25), got (%v, %d, %v)", createdAt, gotTime, gotID, score)
+	}
+
+	// Cursors without a score are accepted too.
+	gotTime, gotID, score, err = DecodeScoreCursor(EncodeCursor(createdAt, 7))
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if !gotTime.Equal(createdAt) || gotID != 7 || score != nil {
+		t.Errorf("expected (%v, 7, nil), got (%v, %d, %v)", createdAt, gotTime, gotID, score)
+	}
+}
+
+func TestDecodeCursorRejectsBadTokens(t *testing.T) {
+	encode := func(raw string) string {
+		return base64.RawURLEncoding.EncodeToString([]byte(raw))
+	}
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"empty", ""},
+		{"not base64", "not a cursor!"},
+		{"padded base64", base64.URLEncoding.EncodeToString([]byte("1714566615123456:42"))},
+		{"missing id", encode("1714566615123456")},
+		{"text time", encode("yesterday:42")},
+		{"text id", encode("1714566615123456:first")},
+		{"extra field", encode("1714566615123456:42:1")},
+		{"empty fields", encode(":")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := DecodeCursor(tt.token); err == nil {
+				t.Errorf("expected %q to be rejected", tt.token)
+			}
+		})
+	}
+}
+
+func TestDecodeScoreCursorRejectsBadTokens(t *testing.T) {
+	encode := func(raw string) string {
+		return base64.RawURLEncoding.EncodeToString([]byte(raw))
+	}
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"not base64", "not a cursor!"},
+		{"missing id", encode("1714566615123456")},
+		{"text score", encode("1714566615123456:42:high")},
+		{"text time", encode("yesterday:42:1.5")},
+		{"text id", encode("1714566615123456:first:1.5")},
+		{"extra field", encode("1714566615123456:42:1.5:9")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, _, err := DecodeScoreCursor(tt.token); err == nil {
+				t.Errorf("expected %q to be rejected", tt.token)
+			}
+		})
+	}
+}
@@ -0,0 +1,29 @@
+package utils
+
+import (
+	"html"
+	"regexp"
+	"strings"
+)
+
+var (
+	lineBreakPattern = regexp.MustCompile(`(?i)<br\s*/?>|</p>\s*<p[^>]*>`)
+	htmlTagPattern   = regexp.MustCompile(`<[^>]*>`)
+	blankLinePattern = regexp.MustCompile(`\n{3,}`)
+)
+
+// HTMLToText turns HTML received from other servers into plain text, keeping
+// line and paragraph breaks. The result is treated like user input and goes
+// through RenderMarkdown again before it is shown.
+func HTMLToText(source string) string {
+	text := lineBreakPattern.ReplaceAllStringFunc(source, func(tag string) string {
+		if strings.HasPrefix(strings.ToLower(tag), "<br") {
+			return "\n"
+		}
+		return "\n\n"
+	})
+	text = htmlTagPattern.ReplaceAllString(text, "")
+	text = html.UnescapeString(text)
+	text = blankLinePattern.ReplaceAllString(text, "\n\n")
+	return strings.TrimSpace(text)
+}
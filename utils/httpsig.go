@@ -0,0 +1,210 @@
+package utils
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// MaxSignatureAge is how far the Date of a signed request may be from now.
+const MaxSignatureAge = 12 * time.Hour
+
+var signedHeaders = []string{"(request-target)", "host", "date", "digest"}
+
+// GenerateRSAKeyPair returns a new key pair as PEM: the public key in PKIX
+// form, the private key in PKCS#1 form.
+func GenerateRSAKeyPair(bits int) (publicPEM, privatePEM string, err error) {
+	key, err := rsa.GenerateKey(rand.Reader, bits)
+	if err != nil {
+		return "", "", err
+	}
+	publicDER, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		return "", "", err
+	}
+	publicPEM = string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER}))
+	privatePEM = string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}))
+	return publicPEM, privatePEM, nil
+}
+
+func ParseRSAPrivateKey(privatePEM string) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode([]byte(privatePEM))
+	if block == nil {
+		return nil, errors.New("invalid private key PEM")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("private key is not an RSA key")
+	}
+	return key, nil
+}
+
+func ParseRSAPublicKey(publicPEM string) (*rsa.PublicKey, error) {
+	block, _ := pem.Decode([]byte(publicPEM))
+	if block == nil {
+		return nil, errors.New("invalid public key PEM")
+	}
+	if parsed, err := x509.ParsePKIXPublicKey(block.Bytes); err == nil {
+		key, ok := parsed.(*rsa.PublicKey)
+		if !ok {
+			return nil, errors.New("public key is not an RSA key")
+		}
+		return key, nil
+	}
+	return x509.ParsePKCS1PublicKey(block.Bytes)
+}
+
+// SignRequest signs the request with an rsa-sha256 HTTP signature over the
+// request target, host, date and body digest, setting the Date, Digest and
+// Signature headers.
+func SignRequest(req *http.Request, body []byte, keyID string, key *rsa.PrivateKey) error {
+	if req.Header.Get("Date") == "" {
+		req.Header.Set("Date", time.Now().UTC().Format(http.TimeFormat))
+	}
+	req.Header.Set("Digest", bodyDigest(body))
+
+	signingString := buildSigningString(req, signedHeaders)
+	hashed := sha256.Sum256([]byte(signingString))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, hashed[:])
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Signature", fmt.Sprintf(`keyId="%s",algorithm="rsa-sha256",headers="%s",signature="%s"`,
+		keyID, strings.Join(signedHeaders, " "), base64.StdEncoding.EncodeToString(signature)))
+	return nil
+}
+
+// VerifyRequest checks the HTTP signature of an incoming request and returns
+// the id of the key that signed it. lookup resolves a key id to the public
+// key. The signature must cover the request target, host and date, and the
+// digest when the request has a body.
+func VerifyRequest(req *http.Request, body []byte, lookup func(keyID string) (*rsa.PublicKey, error)) (string, error) {
+	params, err := parseSignatureHeader(req.Header.Get("Signature"))
+	if err != nil {
+		return "", err
+	}
+	keyID := params["keyId"]
+	if keyID == "" || params["signature"] == "" {
+		return "", errors.New("signature is missing keyId or signature")
+	}
+	if algorithm := params["algorithm"]; algorithm != "" && algorithm != "rsa-sha256" && algorithm != "hs2019" {
+		return "", fmt.Errorf("unsupported signature algorithm %q", algorithm)
+	}
+
+	headers := strings.Fields(strings.ToLower(params["headers"]))
+	if len(headers) == 0 {
+		headers = []string{"date"}
+	}
+	required := []string{"(request-target)", "host", "date"}
+	if len(body) > 0 {
+		required = append(required, "digest")
+	}
+	for _, name := range required {
+		if !containsString(headers, name) {
+			return "", fmt.Errorf("signature does not cover %s", name)
+		}
+	}
+
+	date, err := http.ParseTime(req.Header.Get("Date"))
+	if err != nil {
+		return "", errors.New("invalid Date header")
+	}
+	if age := time.Since(date); age > MaxSignatureAge || age < -MaxSignatureAge {
+		return "", errors.New("signature date is out of range")
+	}
+	if containsString(headers, "digest") && req.Header.Get("Digest") != bodyDigest(body) {
+		return "", errors.New("digest does not match body")
+	}
+
+	signature, err := base64.StdEncoding.DecodeString(params["signature"])
+	if err != nil {
+		return "", errors.New("invalid signature encoding")
+	}
+	key, err := lookup(keyID)
+	if err != nil {
+		return "", err
+	}
+
+	hashed := sha256.Sum256([]byte(buildSigningString(req, headers)))
+	if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, hashed[:], signature); err != nil {
+		return "", errors.New("signature verification failed")
+	}
+	return keyID, nil
+}
+
+func bodyDigest(body []byte) string {
+	sum := sha256.Sum256(body)
+	return "SHA-256=" + base64.StdEncoding.EncodeToString(sum[:])
+}
+
+func buildSigningString(req *http.Request, headers []string) string {
+	lines := make([]string, 0, len(headers))
+	for _, name := range headers {
+		switch name {
+		case "(request-target)":
+			lines = append(lines, fmt.Sprintf("(request-target): %s %s", strings.ToLower(req.Method), req.URL.RequestURI()))
+		case "host":
+			host := req.Host
+			if host == "" {
+				host = req.URL.Host
+			}
+			lines = append(lines, "host: "+host)
+		default:
+			lines = append(lines, name+": "+strings.Join(req.Header.Values(name), ", "))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// parseSignatureHeader splits `key="value",key="value"` pairs.
+func parseSignatureHeader(header string) (map[string]string, error) {
+	if header == "" {
+		return nil, errors.New("missing Signature header")
+	}
+	params := make(map[string]string)
+	for header != "" {
+		eq := strings.IndexByte(header, '=')
+		if eq < 0 {
+			return nil, errors.New("malformed Signature header")
+		}
+		key := strings.TrimSpace(header[:eq])
+		rest := header[eq+1:]
+		if !strings.HasPrefix(rest, `"`) {
+			return nil, errors.New("malformed Signature header")
+		}
+		end := strings.IndexByte(rest[1:], '"')
+		if end < 0 {
+			return nil, errors.New("malformed Signature header")
+		}
+		params[key] = rest[1 : end+1]
+		header = strings.TrimPrefix(strings.TrimSpace(rest[end+2:]), ",")
+		header = strings.TrimSpace(header)
+	}
+	return params, nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,122 @@
+package utils
+
+import (
+	"bytes"
+	"crypto/rsa"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newSignedRequest(t *testing.T, key *rsa.PrivateKey, body []byte) *http.Request {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "https://example.com/users/alice/inbox", bytes.NewReader(body))
+	if err := SignRequest(req, body, "https://remote.test/users/bob#main-key", key); err != nil {
+		t.Fatalf("failed to sign request: %v", err)
+	}
+	return req
+}
+
+func testKeyPair(t *testing.T) (*rsa.PrivateKey, *rsa.PublicKey) {
+	t.Helper()
+	publicPEM, privatePEM, err := GenerateRSAKeyPair(1024)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	private, err := ParseRSAPrivateKey(privatePEM)
+	if err != nil {
+		t.Fatalf("failed to parse private key: %v", err)
+	}
+	public, err := ParseRSAPublicKey(publicPEM)
+	if err != nil {
+		t.Fatalf("failed to parse public key: %v", err)
+	}
+	return private, public
+}
+
+func TestVerifyRequest(t *testing.T) {
+	private, public := testKeyPair(t)
+	_, otherPublic := testKeyPair(t)
+	body := []byte(`{"type":"Follow"}`)
+	lookup := func(key *rsa.PublicKey) func(string) (*rsa.PublicKey, error) {
+		return func(string) (*rsa.PublicKey, error) { return key, nil }
+	}
+
+	t.Run("valid signature", func(t *testing.T) {
+		req := newSignedRequest(t, private, body)
+		keyID, err := VerifyRequest(req, body, lookup(public))
+		if err != nil {
+			t.Fatalf("expected signature to verify, got %v", err)
+		}
+		if keyID != "https://remote.test/users/bob#main-key" {
+			t.Errorf("unexpected key id %q", keyID)
+		}
+	})
+
+	t.Run("tampered body", func(t *testing.T) {
+		req := newSignedRequest(t, private, body)
+		if _, err := VerifyRequest(req, []byte(`{"type":"Delete"}`), lookup(public)); err == nil {
+			t.Error("expected a tampered body to be rejected")
+		}
+	})
+
+	t.Run("wrong key", func(t *testing.T) {
+		req := newSignedRequest(t, private, body)
+		if _, err := VerifyRequest(req, body, lookup(otherPublic)); err == nil {
+			t.Error("expected a signature by another key to be rejected")
+		}
+	})
+
+	t.Run("changed target", func(t *testing.T) {
+		req := newSignedRequest(t, private, body)
+		req.URL.Path = "/users/carol/inbox"
+		if _, err := VerifyRequest(req, body, lookup(public)); err == nil {
+			t.Error("expected a signature for another target to be rejected")
+		}
+	})
+
+	t.Run("stale date", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "https://example.com/inbox", bytes.NewReader(body))
+		req.Header.Set("Date", time.Now().Add(-2*MaxSignatureAge).UTC().Format(http.TimeFormat))
+		if err := SignRequest(req, body, "key", private); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := VerifyRequest(req, body, lookup(public)); err == nil {
+			t.Error("expected an old signature to be rejected")
+		}
+	})
+
+	t.Run("missing signature", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "https://example.com/inbox", bytes.NewReader(body))
+		if _, err := VerifyRequest(req, body, lookup(public)); err == nil {
+			t.Error("expected an unsigned request to be rejected")
+		}
+	})
+
+	t.Run("lookup error", func(t *testing.T) {
+		req := newSignedRequest(t, private, body)
+		lookupErr := errors.New("unknown key")
+		_, err := VerifyRequest(req, body, func(string) (*rsa.PublicKey, error) { return nil, lookupErr })
+		if !errors.Is(err, lookupErr) {
+			t.Errorf("expected the lookup error, got %v", err)
+		}
+	})
+}
+
+func TestSignRequest_Headers(t *testing.T) {
+	private, _ := testKeyPair(t)
+	req := newSignedRequest(t, private, []byte("{}"))
+
+	signature := req.Header.Get("Signature")
+	for _, want := range []string{`keyId="https://remote.test/users/bob#main-key"`, `algorithm="rsa-sha256"`, `headers="(request-target) host date digest"`} {
+		if !strings.Contains(signature, want) {
+			t.Errorf("expected Signature header to contain %s, got %s", want, signature)
+		}
+	}
+	if !strings.HasPrefix(req.Header.Get("Digest"), "SHA-256=") {
+		t.Errorf("unexpected Digest header %q", req.Header.Get("Digest"))
+	}
+}
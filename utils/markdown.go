@@ -0,0 +1,307 @@
+package utils
+
+import (
+	"html"
+	"net/url"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// maxInlineSpan bounds how far ahead a closing `*`, `_` or backtick is looked
+// for, so pathological input cannot make rendering quadratic.
+const maxInlineSpan = 1000
+
+var (
+	unorderedItemPattern = regexp.MustCompile(`^[-*+]\s+(.*)$`)
+	orderedItemPattern   = regexp.MustCompile(`^\d{1,9}[.)]\s+(.*)$`)
+	linkPattern          = regexp.MustCompile(`^\[([^\[\]]+)\]\(([^()\s]+)\)`)
+	bareURLPattern       = regexp.MustCompile(`^` + urlPattern.String())
+	mentionPattern       = regexp.MustCompile(`^@([A-Za-z0-9_]{1,30})`)
+	hashtagPattern       = regexp.MustCompile(`^#([\p{L}\p{N}_]{1,50})`)
+)
+
+// RenderMarkdown renders the Markdown subset supported in posts and comments
+// to HTML: paragraphs, **bold**, *italics*, `code`, fenced code blocks,
+// [links](https://...), bullet and numbered lists, bare URLs, @mentions and
+// #hashtags. Anything else is kept as text.
+//
+// The output is safe to embed as is: every piece of user text is escaped, the
+// only tags are the ones produced here and links are limited to http, https
+// and mailto URLs.
+func RenderMarkdown(source string) string {
+	lines := strings.Split(strings.ReplaceAll(source, "\r\n", "\n"), "\n")
+
+	var out strings.Builder
+	var paragraph []string
+	flush := func() {
+		if len(paragraph) == 0 {
+			return
+		}
+		out.WriteString("<p>")
+		for i, line := range paragraph {
+			if i > 0 {
+				out.WriteString("<br>\n")
+			}
+			out.WriteString(renderInline(line, true))
+		}
+		out.WriteString("</p>\n")
+		paragraph = paragraph[:0]
+	}
+
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+		switch {
+		case line == "":
+			flush()
+
+		case strings.HasPrefix(line, "```"):
+			flush()
+			var code []string
+			for i++; i < len(lines) && !strings.HasPrefix(strings.TrimSpace(lines[i]), "```"); i++ {
+				code = append(code, lines[i])
+			}
+			out.WriteString("<pre><code>" + html.EscapeString(strings.Join(code, "\n")) + "</code></pre>\n")
+
+		case unorderedItemPattern.MatchString(line), orderedItemPattern.MatchString(line):
+			flush()
+			pattern, tag := unorderedItemPattern, "ul"
+			if orderedItemPattern.MatchString(line) {
+				pattern, tag = orderedItemPattern, "ol"
+			}
+			out.WriteString("<" + tag + ">\n")
+			for ; i < len(lines); i++ {
+				match := pattern.FindStringSubmatch(strings.TrimSpace(lines[i]))
+				if match == nil {
+					break
+				}
+				out.WriteString("<li>" + renderInline(match[1], true) + "</li>\n")
+			}
+			i--
+			out.WriteString("</" + tag + ">\n")
+
+		default:
+			paragraph = append(paragraph, line)
+		}
+	}
+	flush()
+
+	return strings.TrimSuffix(out.String(), "\n")
+}
+
+// renderInline renders the inline syntax of a single line. Links are not
+// allowed inside link text, so anchors are never nested.
+func renderInline(text string, links bool) string {
+	var out strings.Builder
+
+	for i := 0; i < len(text); {
+		rest := text[i:]
+		prev, _ := utf8.DecodeLastRuneInString(text[:i])
+
+		switch c := text[i]; {
+		case c == '`':
+			if end := indexWithin(rest[1:], "`"); end > 0 {
+				out.WriteString("<code>" + html.EscapeString(rest[1:end+1]) + "</code>")
+				i += end + 2
+				continue
+			}
+
+		case c == '*' || c == '_':
+			if width, inner, ok := emphasis(rest, prev); ok {
+				tag := "em"
+				if width == 2 {
+					tag = "strong"
+				}
+				out.WriteString("<" + tag + ">" + renderInline(inner, links) + "</" + tag + ">")
+				i += len(inner) + 2*width
+				continue
+			}
+
+		case c == '[' && links:
+			if match := linkPattern.FindStringSubmatch(rest); match != nil && isSafeHref(match[2]) {
+				out.WriteString(anchor(match[2], renderInline(match[1], false), ""))
+				i += len(match[0])
+				continue
+			}
+
+		case c == 'h' && links && isBoundary(prev):
+			if match := bareURLPattern.FindString(rest); match != "" {
+				match = strings.TrimRight(match, ".,;:!?)]}")
+				if isSafeHref(match) {
+					out.WriteString(anchor(match, html.EscapeString(match), ""))
+					i += len(match)
+					continue
+				}
+			}
+
+		case c == '@' && links && isBoundary(prev):
+			if match := mentionPattern.FindStringSubmatch(rest); match != nil {
+				out.WriteString(anchor("/users/"+url.PathEscape(match[1]), "@"+html.EscapeString(match[1]), "mention"))
+				i += len(match[0])
+				continue
+			}
+
+		case c == '#' && links && isBoundary(prev):
+			if match := hashtagPattern.FindStringSubmatch(rest); match != nil && strings.IndexFunc(match[1], unicode.IsLetter) >= 0 {
+				out.WriteString(anchor("/tags/"+url.PathEscape(match[1]), "#"+html.EscapeString(match[1]), "hashtag"))
+				i += len(match[0])
+				continue
+			}
+		}
+
+		r, size := utf8.DecodeRuneInString(rest)
+		out.WriteString(html.EscapeString(string(r)))
+		i += size
+	}
+
+	return out.String()
+}
+
+// emphasis matches `**strong**`, `__strong__`, `*em*` or `_em_` at the start
+// of text. Underscores only count at word boundaries, so snake_case is left
+// alone.
+func emphasis(text string, prev rune) (width int, inner string, ok bool) {
+	delimiter := text[:1]
+	if strings.HasPrefix(text, delimiter+delimiter) {
+		delimiter += delimiter
+	}
+	if delimiter[0] == '_' && !isBoundary(prev) {
+		return 0, "", false
+	}
+
+	body := text[len(delimiter):]
+	end := indexWithin(body, delimiter)
+	if end <= 0 {
+		return 0, "", false
+	}
+	inner = body[:end]
+	if strings.TrimSpace(inner) != inner {
+		return 0, "", false
+	}
+	if delimiter[0] == '_' {
+		next, _ := utf8.DecodeRuneInString(body[end+len(delimiter):])
+		if next != utf8.RuneError && !isBoundary(next) {
+			return 0, "", false
+		}
+	}
+	return len(delimiter), inner, true
+}
+
+// indexWithin is strings.Index limited to the first maxInlineSpan bytes.
+func indexWithin(s, substr string) int {
+	if len(s) > maxInlineSpan {
+		s = s[:maxInlineSpan]
+	}
+	return strings.Index(s, substr)
+}
+
+// isBoundary reports whether r, the rune before or after a token, separates
+// words. utf8.RuneError stands for the start or end of the text.
+func isBoundary(r rune) bool {
+	return r == utf8.RuneError || !(unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_')
+}
+
+// isSafeHref allows absolute http(s) links and mailto links only.
+func isSafeHref(href string) bool {
+	parsed, err := url.Parse(href)
+	if err != nil {
+		return false
+	}
+	switch strings.ToLower(parsed.Scheme) {
+	case "http", "https":
+		return parsed.Host != ""
+	case "mailto":
+		return parsed.Opaque != ""
+	default:
+		return false
+	}
+}
+
+// MentionSpan is an @mention in Markdown source. Start and End are the
+// character offsets of the @ and of the end of the handle.
+type MentionSpan struct {
+	Username string
+	Start    int
+	End      int
+}
+
+// FindMentions returns the @mentions of Markdown source in the order they
+// appear. Like RenderMarkdown it skips code, link text and URLs.
+func FindMentions(source string) []MentionSpan {
+	var spans []MentionSpan
+	fenced := false
+	lineStart := 0
+	for _, line := range strings.SplitAfter(source, "\n") {
+		offset := lineStart
+		lineStart += len(line)
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			fenced = !fenced
+			continue
+		}
+		if fenced {
+			continue
+		}
+
+		for i := 0; i < len(line); {
+			rest := line[i:]
+			prev, _ := utf8.DecodeLastRuneInString(line[:i])
+
+			switch c := line[i]; {
+			case c == '`':
+				if end := indexWithin(rest[1:], "`"); end > 0 {
+					i += end + 2
+					continue
+				}
+
+			case c == '[':
+				if match := linkPattern.FindStringSubmatch(rest); match != nil && isSafeHref(match[2]) {
+					i += len(match[0])
+					continue
+				}
+
+			case c == 'h' && isBoundary(prev):
+				if match := strings.TrimRight(bareURLPattern.FindString(rest), ".,;:!?)]}"); match != "" && isSafeHref(match) {
+					i += len(match)
+					continue
+				}
+
+			case c == '@' && isBoundary(prev):
+				if match := mentionPattern.FindStringSubmatch(rest); match != nil {
+					start := utf8.RuneCountInString(source[:offset+i])
+					spans = append(spans, MentionSpan{Username: match[1], Start: start, End: start + len(match[0])})
+					i += len(match[0])
+					continue
+				}
+			}
+
+			_, size := utf8.DecodeRuneInString(rest)
+			i += size
+		}
+	}
+	return spans
+}
+
+// ExtractMentions returns the distinct usernames @mentioned in Markdown
+// source, in the order they appear.
+func ExtractMentions(source string) []string {
+	var usernames []string
+	seen := make(map[string]bool)
+	for _, span := range FindMentions(source) {
+		if !seen[span.Username] {
+			seen[span.Username] = true
+			usernames = append(usernames, span.Username)
+		}
+	}
+	return usernames
+}
+
+func anchor(href, content, class string) string {
+	attributes := `href="` + html.EscapeString(href) + `"`
+	if class != "" {
+		attributes += ` class="` + class + `"`
+	} else {
+		attributes += ` rel="nofollow noopener noreferrer"`
+	}
+	return "<a " + attributes + ">" + content + "</a>"
+}
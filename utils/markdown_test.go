@@ -0,0 +1,121 @@
+package utils
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRenderMarkdown(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   string
+		expected string
+	}{
+		{"plain text", "hello world", "<p>hello world</p>"},
+		{"line breaks", "first\nsecond", "<p>first<br>\nsecond</p>"},
+		{"paragraphs", "first\n\nsecond", "<p>first</p>\n<p>second</p>"},
+		{"bold", "a **bold** word", "<p>a <strong>bold</strong> word</p>"},
+		{"italics", "an *italic* and _another_", "<p>an <em>italic</em> and <em>another</em></p>"},
+		{"nested emphasis", "**bold _and italic_**", "<p><strong>bold <em>and italic</em></strong></p>"},
+		{"snake case", "call some_func_name now", "<p>call some_func_name now</p>"},
+		{"inline code", "run `go test ./...`", "<p>run <code>go test ./...</code></p>"},
+		{"code is not formatted", "`**not bold** @nobody`", "<p><code>**not bold** @nobody</code></p>"},
+		{
+			"fenced code",
+			"```\nif a < b {\n\treturn\n}\n```",
+			"<pre><code>if a &lt; b {\n\treturn\n}</code></pre>",
+		},
+		{
+			"link",
+			"see [the docs](https://example.com/docs?a=1&b=2)",
+			`<p>see <a href="https://example.com/docs?a=1&amp;b=2" rel="nofollow noopener noreferrer">the docs</a></p>`,
+		},
+		{
+			"bare url",
+			"visit https://example.com/page#top.",
+			`<p>visit <a href="https://example.com/page#top" rel="nofollow noopener noreferrer">https://example.com/page#top</a>.</p>`,
+		},
+		{"bullet list", "- one\n- **two**", "<ul>\n<li>one</li>\n<li><strong>two</strong></li>\n</ul>"},
+		{"numbered list", "1. one\n2. two", "<ol>\n<li>one</li>\n<li>two</li>\n</ol>"},
+		{"mention", "hi @jane_doe!", `<p>hi <a href="/users/jane_doe" class="mention">@jane_doe</a>!</p>`},
+		{"email is not a mention", "mail me at jane@example.com", "<p>mail me at jane@example.com</p>"},
+		{"hashtag", "#golang rocks", `<p><a href="/tags/golang" class="hashtag">#golang</a> rocks</p>`},
+		{"number is not a hashtag", "issue #42", "<p>issue #42</p>"},
+		{"no mentions in link text", "[@jane](https://example.com)", `<p><a href="https://example.com" rel="nofollow noopener noreferrer">@jane</a></p>`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := RenderMarkdown(tt.source); got != tt.expected {
+				t.Errorf("RenderMarkdown(%q)\n got: %q\nwant: %q", tt.source, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestRenderMarkdownEscapesHTML(t *testing.T) {
+	tests := []string{
+		`<script>alert(1)</script>`,
+		`<img src=x onerror=alert(1)>`,
+		`[click](javascript:alert(1))`,
+		`[click](JaVaScRiPt:alert(1))`,
+		`[click](data:text/html;base64,PHNjcmlwdD4=)`,
+		`[click](https://example.com/"onmouseover="alert(1))`,
+		"[click](java\tscript:alert(1))",
+		`**<b>bold</b>**`,
+		"`<script>`",
+		"```\n</code></pre><script>alert(1)</script>\n```",
+		`@<script>`,
+		`#<svg/onload=alert(1)>`,
+		`https://example.com/"><script>alert(1)</script>`,
+	}
+
+	for _, source := range tests {
+		t.Run(source, func(t *testing.T) {
+			got := RenderMarkdown(source)
+			lower := strings.ToLower(got)
+			for _, forbidden := range []string{"<script", "<img", "<svg", "<b>", `href="javascript:`, `href="data:`, `" onmouseover`, `"onmouseover`} {
+				if strings.Contains(lower, forbidden) {
+					t.Errorf("RenderMarkdown(%q) = %q contains %q", source, got, forbidden)
+				}
+			}
+		})
+	}
+}
+
+func TestHTMLToText(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   string
+	}{
+		{"paragraphs", "<p>Hello</p><p>World</p>", "Hello\n\nWorld"},
+		{"line breaks", "one<br>two<br/>three", "one\ntwo\nthree"},
+		{"links and mentions", `<p><span class="h-card"><a href="https://remote.test/@bob">@<span>bob</span></a></span> look at <a href="https://example.com">example.com</a></p>`, "@bob look at example.com"},
+		{"entities", "<p>a &lt;b&gt; &amp; &quot;c&quot;</p>", `a <b> & "c"`},
+		{"scripts are stripped", `<script>alert(1)</script>text`, "alert(1)text"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := HTMLToText(tt.source); got != tt.want {
+				t.Errorf("HTMLToText(%q) = %q, want %q", tt.source, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtractMentions(t *testing.T) {
+	got := ExtractMentions("hi @jane and @Bob, cc @jane\n\n`@code` [@link](https://example.com) jane@example.com https://example.com/@site @bob\n```\n@fenced\n```")
+	if want := []string{"jane", "Bob", "bob"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ExtractMentions() = %q, want %q", got, want)
+	}
+}
+
+func TestFindMentions(t *testing.T) {
+	got := FindMentions("héllo @jane\n- **@bob**")
+	want := []MentionSpan{{Username: "jane", Start: 6, End: 11}, {Username: "bob", Start: 16, End: 20}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("FindMentions() = %+v, want %+v", got, want)
+	}
+}